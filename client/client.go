@@ -21,6 +21,15 @@ import (
 	"github.com/decred/vspd/types/v3"
 )
 
+const (
+	// clientSigHeader contains request signatures created with the commitment
+	// key of a ticket.
+	clientSigHeader = "VSP-Client-Signature"
+	// votingSigHeader contains request signatures created with the voting key
+	// of a ticket.
+	votingSigHeader = "VSP-Voting-Signature"
+)
+
 type Client struct {
 	http.Client
 	URL    string
@@ -41,6 +50,24 @@ func (c *Client) VspInfo(ctx context.Context) (*types.VspInfoResponse, error) {
 	return resp, nil
 }
 
+func (c *Client) Status(ctx context.Context) (*types.StatusResponse, error) {
+	var resp *types.StatusResponse
+	err := c.get(ctx, "/api/v3/status", &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) VoteTallies(ctx context.Context) (*types.VoteTalliesResponse, error) {
+	var resp *types.VoteTalliesResponse
+	err := c.get(ctx, "/api/v3/votetallies", &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 func (c *Client) FeeAddress(ctx context.Context, req types.FeeAddressRequest,
 	commitmentAddr stdaddr.Address) (*types.FeeAddressResponse, error) {
 
@@ -63,6 +90,28 @@ func (c *Client) FeeAddress(ctx context.Context, req types.FeeAddressRequest,
 	return resp, nil
 }
 
+func (c *Client) FeeTxTemplate(ctx context.Context, req types.FeeTxTemplateRequest,
+	commitmentAddr stdaddr.Address) (*types.FeeTxTemplateResponse, error) {
+
+	requestBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *types.FeeTxTemplateResponse
+	err = c.post(ctx, "/api/v3/feetxtemplate", commitmentAddr, &resp, json.RawMessage(requestBody))
+	if err != nil {
+		return nil, err
+	}
+
+	// verify initial request matches server
+	if !bytes.Equal(requestBody, resp.Request) {
+		return nil, fmt.Errorf("server response contains differing request")
+	}
+
+	return resp, nil
+}
+
 func (c *Client) PayFee(ctx context.Context, req types.PayFeeRequest,
 	commitmentAddr stdaddr.Address) (*types.PayFeeResponse, error) {
 
@@ -129,15 +178,63 @@ func (c *Client) SetVoteChoices(ctx context.Context, req types.SetVoteChoicesReq
 	return resp, nil
 }
 
+// SetVoteChoicesWithVotingKey is the same as SetVoteChoices, except the request
+// is signed with the voting key of the ticket rather than its commitment key.
+func (c *Client) SetVoteChoicesWithVotingKey(ctx context.Context, req types.SetVoteChoicesRequest,
+	votingAddr stdaddr.Address) (*types.SetVoteChoicesResponse, error) {
+
+	requestBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *types.SetVoteChoicesResponse
+	err = c.do(ctx, http.MethodPost, "/api/v3/setvotechoices", votingSigHeader, votingAddr,
+		&resp, json.RawMessage(requestBody))
+	if err != nil {
+		return nil, err
+	}
+
+	// verify initial request matches server
+	if !bytes.Equal(requestBody, resp.Request) {
+		return nil, fmt.Errorf("server response contains differing request")
+	}
+
+	return resp, nil
+}
+
+func (c *Client) BroadcastFee(ctx context.Context, req types.BroadcastFeeRequest,
+	commitmentAddr stdaddr.Address) (*types.BroadcastFeeResponse, error) {
+
+	requestBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *types.BroadcastFeeResponse
+	err = c.post(ctx, "/api/v3/broadcastfee", commitmentAddr, &resp, json.RawMessage(requestBody))
+	if err != nil {
+		return nil, err
+	}
+
+	// verify initial request matches server
+	if !bytes.Equal(requestBody, resp.Request) {
+		return nil, fmt.Errorf("server response contains differing request")
+	}
+
+	return resp, nil
+}
+
 func (c *Client) post(ctx context.Context, path string, addr stdaddr.Address, resp, req any) error {
-	return c.do(ctx, http.MethodPost, path, addr, resp, req)
+	return c.do(ctx, http.MethodPost, path, clientSigHeader, addr, resp, req)
 }
 
 func (c *Client) get(ctx context.Context, path string, resp any) error {
-	return c.do(ctx, http.MethodGet, path, nil, resp, nil)
+	return c.do(ctx, http.MethodGet, path, "", nil, resp, nil)
 }
 
-func (c *Client) do(ctx context.Context, method, path string, addr stdaddr.Address, resp, req any) error {
+func (c *Client) do(ctx context.Context, method, path, sigHeader string, addr stdaddr.Address,
+	resp, req any) error {
 	var reqBody io.Reader
 	var sig []byte
 
@@ -159,7 +256,7 @@ func (c *Client) do(ctx context.Context, method, path string, addr stdaddr.Addre
 		return fmt.Errorf("new request: %w", err)
 	}
 	if sig != nil {
-		httpReq.Header.Set("VSP-Client-Signature", base64.StdEncoding.EncodeToString(sig))
+		httpReq.Header.Set(sigHeader, base64.StdEncoding.EncodeToString(sig))
 	}
 
 	if c.Log.Level() == slog.LevelTrace {
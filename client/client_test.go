@@ -87,7 +87,7 @@ func TestErrorDetails(t *testing.T) {
 			}
 
 			var resp any
-			err := client.do(context.TODO(), http.MethodGet, "", nil, &resp, nil)
+			err := client.do(context.TODO(), http.MethodGet, "", "", nil, &resp, nil)
 
 			testServer.Close()
 
@@ -172,7 +172,7 @@ func TestSignatureValidation(t *testing.T) {
 			}
 
 			var resp any
-			err := client.do(context.TODO(), http.MethodGet, "", nil, &resp, nil)
+			err := client.do(context.TODO(), http.MethodGet, "", "", nil, &resp, nil)
 
 			testServer.Close()
 
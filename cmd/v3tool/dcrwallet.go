@@ -128,7 +128,8 @@ func (w *dcrwallet) getTickets(ctx context.Context) (*wallettypes.GetTicketsResu
 
 // getTicketDetails returns the ticket hex, privkey for voting, and the
 // commitment address.
-func (w *dcrwallet) getTicketDetails(ctx context.Context, ticketHash string) (string, string, stdaddr.Address, error) {
+func (w *dcrwallet) getTicketDetails(ctx context.Context, ticketHash string,
+	params *chaincfg.Params) (string, string, stdaddr.Address, error) {
 	var getTransactionResult wallettypes.GetTransactionResult
 	err := w.Call(ctx, "gettransaction", &getTransactionResult, ticketHash, false)
 	if err != nil {
@@ -146,7 +147,7 @@ func (w *dcrwallet) getTicketDetails(ctx context.Context, ticketHash string) (st
 
 	const scriptVersion = 0
 	scriptType, submissionAddr := stdscript.ExtractAddrs(scriptVersion,
-		msgTx.TxOut[0].PkScript, chaincfg.TestNet3Params())
+		msgTx.TxOut[0].PkScript, params)
 	if scriptType == stdscript.STNonStandard {
 		return "", "", nil, fmt.Errorf("invalid script version %d", scriptVersion)
 	}
@@ -154,8 +155,7 @@ func (w *dcrwallet) getTicketDetails(ctx context.Context, ticketHash string) (st
 		return "", "", nil, errors.New("submissionAddr != 1")
 	}
 
-	addr, err := stake.AddrFromSStxPkScrCommitment(msgTx.TxOut[1].PkScript,
-		chaincfg.TestNet3Params())
+	addr, err := stake.AddrFromSStxPkScrCommitment(msgTx.TxOut[1].PkScript, params)
 	if err != nil {
 		return "", "", nil, err
 	}
@@ -0,0 +1,171 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/decred/dcrd/txscript/v4/stdaddr"
+	"github.com/decred/slog"
+	"github.com/decred/vspd/client/v4"
+	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/internal/config"
+	"github.com/decred/vspd/internal/signal"
+	"github.com/decred/vspd/types/v3"
+	"github.com/jessevdk/go-flags"
+)
+
+// e2eConfig defines the command line options of the e2etest command.
+type e2eConfig struct {
+	VspdURL     string `long:"vspdurl" description:"URL of the vspd instance to test."`
+	RPCURL      string `long:"rpcurl" description:"Websocket URL of the dcrwallet JSON-RPC server."`
+	RPCUser     string `long:"rpcuser" description:"Username for dcrwallet RPC connections."`
+	RPCPass     string `long:"rpcpass" description:"Password for dcrwallet RPC connections."`
+	NetworkName string `long:"network" description:"Decred network of vspd and dcrwallet." choice:"testnet" choice:"simnet"`
+	Ticket      string `long:"ticket" required:"true" description:"Hash of a mempool, immature or live ticket owned by dcrwallet to register with vspd."`
+}
+
+// runE2ETest registers a single ticket with vspd by performing every step of
+// the registration flow against its real HTTP API, and reports the outcome of
+// each step. A non-zero exit code is returned if any step fails.
+func runE2ETest(args []string) int {
+	cfg := e2eConfig{
+		VspdURL:     vspdURL,
+		RPCURL:      rpcURL,
+		RPCUser:     rpcUser,
+		RPCPass:     rpcPass,
+		NetworkName: "testnet",
+	}
+	parser := flags.NewParser(&cfg, flags.Default)
+	parser.Usage = "e2etest [OPTIONS]"
+	_, err := parser.ParseArgs(args)
+	if err != nil {
+		if flags.WroteHelp(err) {
+			return 0
+		}
+		return 1
+	}
+
+	network := &config.TestNet3
+	if cfg.NetworkName == "simnet" {
+		network = &config.SimNet
+	}
+
+	log := slog.NewBackend(os.Stdout).Logger("")
+	log.SetLevel(slog.LevelInfo)
+
+	ctx := signal.ShutdownListener(log)
+
+	// step runs a single step of the test and logs whether it succeeded.
+	step := func(name string, f func() error) bool {
+		err := f()
+		if err != nil {
+			log.Errorf("FAIL %s: %v", name, err)
+			return false
+		}
+		log.Infof("PASS %s", name)
+		return true
+	}
+
+	var walletRPC *dcrwallet
+	ok := step("connect to dcrwallet", func() error {
+		walletRPC, err = newWalletRPC(ctx, cfg.RPCURL, cfg.RPCUser, cfg.RPCPass)
+		return err
+	})
+	if !ok {
+		return 1
+	}
+	defer walletRPC.Close()
+
+	var vClient client.Client
+	ok = step("vspinfo", func() error {
+		pubKey, err := getVspPubKey(cfg.VspdURL)
+		if err != nil {
+			return err
+		}
+		vClient = client.Client{
+			Client: http.Client{Timeout: time.Minute},
+			URL:    cfg.VspdURL,
+			PubKey: pubKey,
+			Sign:   walletRPC.SignMessage,
+			Log:    log,
+		}
+		return nil
+	})
+	if !ok {
+		return 1
+	}
+
+	ticketHash := cfg.Ticket
+	var ticketHex, votingKey string
+	var commitmentAddr stdaddr.Address
+	ok = step("get ticket details from dcrwallet", func() error {
+		ticketHex, votingKey, commitmentAddr, err = walletRPC.getTicketDetails(ctx, ticketHash, network.Params)
+		return err
+	})
+	if !ok {
+		return 1
+	}
+
+	var feeAddrResp *types.FeeAddressResponse
+	ok = step("feeaddress", func() error {
+		feeAddrResp, err = vClient.FeeAddress(ctx, types.FeeAddressRequest{
+			Timestamp:  time.Now().Unix(),
+			TicketHash: ticketHash,
+			TicketHex:  ticketHex,
+			// The parent is only needed if vspd has to broadcast the ticket,
+			// which is not the case for tickets owned by a synced wallet.
+			ParentHex: ticketHex,
+		}, commitmentAddr)
+		return err
+	})
+	if !ok {
+		return 1
+	}
+
+	var feeTx string
+	ok = step("create fee tx", func() error {
+		feeTx, err = walletRPC.createFeeTx(ctx, feeAddrResp.FeeAddress, feeAddrResp.FeeAmount)
+		return err
+	})
+	if !ok {
+		return 1
+	}
+
+	ok = step("payfee", func() error {
+		_, err := vClient.PayFee(ctx, types.PayFeeRequest{
+			Timestamp:  time.Now().Unix(),
+			TicketHash: ticketHash,
+			FeeTx:      feeTx,
+			VotingKey:  votingKey,
+		}, commitmentAddr)
+		return err
+	})
+	if !ok {
+		return 1
+	}
+
+	ok = step("ticketstatus", func() error {
+		status, err := vClient.TicketStatus(ctx, types.TicketStatusRequest{
+			TicketHash: ticketHash,
+		}, commitmentAddr)
+		if err != nil {
+			return err
+		}
+		if status.FeeTxStatus == string(database.NoFee) {
+			return fmt.Errorf("unexpected fee tx status %q", status.FeeTxStatus)
+		}
+		return nil
+	})
+	if !ok {
+		return 1
+	}
+
+	log.Infof("Ticket %s registered successfully", ticketHash)
+	return 0
+}
@@ -114,7 +114,8 @@ func run() int {
 		}
 
 		ticketHash := tickets.Hashes[i]
-		hex, privKeyStr, commitmentAddr, err := walletRPC.getTicketDetails(ctx, ticketHash)
+		hex, privKeyStr, commitmentAddr, err := walletRPC.getTicketDetails(ctx, ticketHash,
+			config.TestNet3.Params)
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				return 0
@@ -245,5 +246,8 @@ func run() int {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "e2etest" {
+		os.Exit(runE2ETest(os.Args[2:]))
+	}
 	os.Exit(run())
 }
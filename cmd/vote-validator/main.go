@@ -74,7 +74,7 @@ func run() int {
 
 	// Open database.
 	log := slog.NewBackend(os.Stdout).Logger("")
-	vdb, err := database.Open(cfg.DatabaseFile, log, 999)
+	vdb, err := database.Open(cfg.DatabaseFile, log, 999, 0)
 	if err != nil {
 		log.Error(err)
 		return 1
@@ -0,0 +1,253 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrd/dcrutil/v4"
+	"github.com/decred/dcrd/txscript/v4/stdaddr"
+	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/internal/config"
+)
+
+// Names of the admin operations which require approval when approvers are
+// configured.
+const (
+	actionRetireXPub   = "retirexpub"
+	actionSetApprovers = "setapprovers"
+)
+
+// parseApprovers parses and validates a threshold and list of approver
+// addresses. A threshold of zero with no addresses removes the approvers, in
+// which case nil is returned.
+func parseApprovers(thresholdStr string, addrs []string, network *config.Network) (*database.Approvers, error) {
+	threshold, err := strconv.Atoi(thresholdStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid threshold: %w", err)
+	}
+
+	if threshold == 0 && len(addrs) == 0 {
+		return nil, nil
+	}
+
+	if threshold < 1 || threshold > len(addrs) {
+		return nil, fmt.Errorf("threshold must be between 1 and the number of "+
+			"approver addresses (%d)", len(addrs))
+	}
+
+	seen := make(map[string]struct{}, len(addrs))
+	for _, addr := range addrs {
+		// Approvals are signed with signmessage, which requires a P2PKH
+		// address.
+		decoded, err := stdaddr.DecodeAddress(addr, network.Params)
+		if err != nil {
+			return nil, fmt.Errorf("invalid approver address %q: %w", addr, err)
+		}
+		if _, ok := decoded.(*stdaddr.AddressPubKeyHashEcdsaSecp256k1V0); !ok {
+			return nil, fmt.Errorf("approver address %q is not a P2PKH address", addr)
+		}
+
+		if _, ok := seen[addr]; ok {
+			return nil, fmt.Errorf("approver address %q is duplicated", addr)
+		}
+		seen[addr] = struct{}{}
+	}
+
+	return &database.Approvers{
+		Threshold: threshold,
+		Addresses: addrs,
+	}, nil
+}
+
+// proposeAction records an admin operation which will not take effect until it
+// has been approved, and prints the message approvers need to sign. Any action
+// which was already pending is replaced.
+func proposeAction(db *database.VspDatabase, approvers *database.Approvers, action, arg string) error {
+	existing, err := db.PendingAction()
+	if err != nil {
+		return fmt.Errorf("db.PendingAction failed: %w", err)
+	}
+	if existing != nil {
+		log("Replacing pending %s action which had %d of %d approvals",
+			existing.Action, len(existing.Approvals), approvers.Threshold)
+	}
+
+	nonce := make([]byte, 16)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	pending := &database.PendingAction{
+		Action:    action,
+		Arg:       arg,
+		Nonce:     hex.EncodeToString(nonce),
+		Created:   time.Now().Unix(),
+		Approvals: make(map[string]string),
+	}
+
+	err = db.SetPendingAction(pending)
+	if err != nil {
+		return fmt.Errorf("db.SetPendingAction failed: %w", err)
+	}
+
+	log("%s requires approval by %d of %d approvers before it takes effect",
+		action, approvers.Threshold, len(approvers.Addresses))
+	log("Each approver should sign this message with their approver address:")
+	log("")
+	log("%s", pending.Message())
+	log("")
+	log("and then run: vspadmin approve <address> <signature>")
+
+	return nil
+}
+
+// approve records an approval of the pending action, checking the signature
+// with the same message verification used for requests from clients. The
+// action is performed once enough approvals have been recorded.
+func approve(homeDir string, addr, signature string, network *config.Network) error {
+	dataDir := filepath.Join(homeDir, "data", network.Name)
+	dbFile := filepath.Join(dataDir, dbFilename)
+
+	// Approval may result in the xpub being retired, which openDB ensures
+	// cannot happen while vspd is running.
+	db, err := openDB(dbFile)
+	if err != nil {
+		return err
+	}
+	defer db.Close(false)
+
+	pending, err := db.PendingAction()
+	if err != nil {
+		return fmt.Errorf("db.PendingAction failed: %w", err)
+	}
+	if pending == nil {
+		return errors.New("no action is awaiting approval")
+	}
+
+	approvers, err := db.Approvers()
+	if err != nil {
+		return fmt.Errorf("db.Approvers failed: %w", err)
+	}
+	if approvers == nil {
+		return errors.New("no approvers are configured")
+	}
+
+	if !approvers.Contains(addr) {
+		return fmt.Errorf("%s is not an approver address", addr)
+	}
+
+	err = dcrutil.VerifyMessage(addr, signature, pending.Message(), network.Params)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+
+	pending.Approvals[addr] = signature
+
+	// Only count approvals from current approvers, in case approvers have
+	// changed since the action was proposed.
+	var approvals int
+	for a := range pending.Approvals {
+		if approvers.Contains(a) {
+			approvals++
+		}
+	}
+
+	if approvals < approvers.Threshold {
+		err = db.SetPendingAction(pending)
+		if err != nil {
+			return fmt.Errorf("db.SetPendingAction failed: %w", err)
+		}
+
+		log("Approval recorded, %s has %d of %d required approvals",
+			pending.Action, approvals, approvers.Threshold)
+		return nil
+	}
+
+	switch pending.Action {
+	case actionRetireXPub:
+		err = db.RetireXPub(pending.Arg)
+		if err != nil {
+			return fmt.Errorf("db.RetireXPub failed: %w", err)
+		}
+
+		log("Xpub successfully retired, all future tickets will use the new xpub")
+
+	case actionSetApprovers:
+		fields := strings.Fields(pending.Arg)
+		newApprovers, err := parseApprovers(fields[0], fields[1:], network)
+		if err != nil {
+			return err
+		}
+
+		err = db.SetApprovers(newApprovers)
+		if err != nil {
+			return fmt.Errorf("db.SetApprovers failed: %w", err)
+		}
+
+		log("Approvers successfully updated")
+
+	default:
+		return fmt.Errorf("pending action %q is not recognized", pending.Action)
+	}
+
+	err = db.SetPendingAction(nil)
+	if err != nil {
+		return fmt.Errorf("db.SetPendingAction failed: %w", err)
+	}
+
+	return nil
+}
+
+// setApprovers configures the operators who must approve critical admin
+// operations. If approvers are already configured, the change itself must be
+// approved before it takes effect.
+func setApprovers(homeDir string, thresholdStr string, addrs []string, network *config.Network) error {
+	newApprovers, err := parseApprovers(thresholdStr, addrs, network)
+	if err != nil {
+		return err
+	}
+
+	dataDir := filepath.Join(homeDir, "data", network.Name)
+	dbFile := filepath.Join(dataDir, dbFilename)
+
+	db, err := openDB(dbFile)
+	if err != nil {
+		return err
+	}
+	defer db.Close(false)
+
+	approvers, err := db.Approvers()
+	if err != nil {
+		return fmt.Errorf("db.Approvers failed: %w", err)
+	}
+
+	if approvers != nil {
+		arg := strings.Join(append([]string{thresholdStr}, addrs...), " ")
+		return proposeAction(db, approvers, actionSetApprovers, arg)
+	}
+
+	err = db.SetApprovers(newApprovers)
+	if err != nil {
+		return fmt.Errorf("db.SetApprovers failed: %w", err)
+	}
+
+	if newApprovers == nil {
+		log("No approvers are configured")
+	} else {
+		log("Approvers configured, %d of %d approvals are now required",
+			newApprovers.Threshold, len(newApprovers.Addresses))
+	}
+
+	return nil
+}
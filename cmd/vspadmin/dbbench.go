@@ -0,0 +1,170 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/decred/slog"
+	"github.com/decred/vspd/database"
+)
+
+// defaultBenchTickets is the number of synthetic tickets used by dbbench if no
+// number is specified.
+const defaultBenchTickets = 1000
+
+// benchResult holds the latency of every call to a single database method.
+type benchResult struct {
+	name      string
+	latencies []time.Duration
+	total     time.Duration
+}
+
+func (r *benchResult) record(start time.Time) {
+	elapsed := time.Since(start)
+	r.latencies = append(r.latencies, elapsed)
+	r.total += elapsed
+}
+
+// percentile returns the latency which p percent of calls completed within.
+// The latencies must already be sorted.
+func (r *benchResult) percentile(p int) time.Duration {
+	idx := (len(r.latencies)*p + 99) / 100
+	if idx > 0 {
+		idx--
+	}
+	return r.latencies[idx]
+}
+
+func (r *benchResult) print() {
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+	opsPerSec := float64(len(r.latencies)) / r.total.Seconds()
+	log("%-16s %8.0f ops/sec   p50 %-10v p90 %-10v p99 %-10v max %v", r.name, opsPerSec,
+		r.percentile(50), r.percentile(90), r.percentile(99),
+		r.latencies[len(r.latencies)-1])
+}
+
+// randomHex returns a random hex string encoding n bytes.
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// syntheticTicket returns a ticket with random values of realistic sizes.
+func syntheticTicket() (database.Ticket, error) {
+	var strs [4]string
+	for i, n := range []int{32, 32, 250, 32} {
+		s, err := randomHex(n)
+		if err != nil {
+			return database.Ticket{}, err
+		}
+		strs[i] = s
+	}
+
+	return database.Ticket{
+		Hash:              strs[0],
+		CommitmentAddress: strs[1][:35],
+		FeeAddress:        strs[1][29:],
+		FeeAmount:         10000000,
+		FeeExpiration:     time.Now().Add(time.Hour).Unix(),
+		VoteChoices:       map[string]string{"agenda": "yes"},
+		TSpendPolicy:      map[string]string{},
+		TreasuryPolicy:    map[string]string{},
+		FeeTxHex:          strs[2],
+		FeeTxHash:         strs[3],
+		FeeTxStatus:       database.FeeReceieved,
+	}, nil
+}
+
+// dbBench measures the performance of the database methods used to store and
+// retrieve tickets. It uses a new database in a temporary directory populated
+// with synthetic tickets, so the vspd database is never modified. The ticket
+// cache is disabled so every read is served by the database.
+func dbBench(numTickets int) error {
+	if numTickets < 1 {
+		return fmt.Errorf("number of tickets must be at least 1")
+	}
+
+	dir, err := os.MkdirTemp("", "vspd-dbbench")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dbFile := filepath.Join(dir, dbFilename)
+
+	err = database.CreateNew(dbFile, "benchxpub")
+	if err != nil {
+		return fmt.Errorf("error creating db file %s: %w", dbFile, err)
+	}
+
+	db, err := database.Open(dbFile, slog.Disabled, 999, 0)
+	if err != nil {
+		return fmt.Errorf("error opening db file %s: %w", dbFile, err)
+	}
+	defer db.Close(false)
+
+	tickets := make([]database.Ticket, numTickets)
+	for i := range tickets {
+		tickets[i], err = syntheticTicket()
+		if err != nil {
+			return fmt.Errorf("failed to generate ticket: %w", err)
+		}
+	}
+
+	log("Benchmarking database with %d synthetic tickets", numTickets)
+
+	insert := &benchResult{name: "InsertNewTicket"}
+	for _, ticket := range tickets {
+		start := time.Now()
+		err = db.InsertNewTicket(ticket)
+		insert.record(start)
+		if err != nil {
+			return fmt.Errorf("db.InsertNewTicket failed: %w", err)
+		}
+	}
+
+	get := &benchResult{name: "GetTicketByHash"}
+	for _, ticket := range tickets {
+		start := time.Now()
+		_, found, err := db.GetTicketByHash(ticket.Hash)
+		get.record(start)
+		if err != nil {
+			return fmt.Errorf("db.GetTicketByHash failed: %w", err)
+		}
+		if !found {
+			return fmt.Errorf("ticket %s not found in database", ticket.Hash)
+		}
+	}
+
+	update := &benchResult{name: "UpdateTicket"}
+	for _, ticket := range tickets {
+		ticket.FeeTxStatus = database.FeeConfirmed
+		ticket.Confirmed = true
+
+		start := time.Now()
+		err = db.UpdateTicket(ticket)
+		update.record(start)
+		if err != nil {
+			return fmt.Errorf("db.UpdateTicket failed: %w", err)
+		}
+	}
+
+	for _, result := range []*benchResult{insert, get, update} {
+		result.print()
+	}
+
+	return nil
+}
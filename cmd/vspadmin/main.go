@@ -5,10 +5,16 @@
 package main
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/decred/dcrd/dcrutil/v4"
 	"github.com/decred/dcrd/hdkeychain/v3"
@@ -16,6 +22,7 @@ import (
 	"github.com/decred/vspd/database"
 	"github.com/decred/vspd/internal/config"
 	"github.com/decred/vspd/internal/vspd"
+	"github.com/decred/vspd/internal/webapi"
 	"github.com/jessevdk/go-flags"
 )
 
@@ -25,8 +32,10 @@ const (
 )
 
 type conf struct {
-	HomeDir string `long:"homedir" description:"Path to application home directory."`
-	Network string `long:"network" description:"Decred network to use." choice:"mainnet" choice:"testnet" choice:"simnet"`
+	HomeDir         string `long:"homedir" description:"Path to application home directory."`
+	Network         string `long:"network" description:"Decred network to use." choice:"mainnet" choice:"testnet" choice:"simnet"`
+	UseBackup       bool   `long:"usebackup" description:"Read from the latest backup written by vspd if the database is locked. Only used by commands which do not modify the database."`
+	XPubFingerprint string `long:"xpubfingerprint" description:"Expected fingerprint of the fee xpub, as 8 hex characters. createdatabase refuses to create a database if the provided xpub has a different fingerprint."`
 }
 
 var defaultConf = conf{
@@ -46,6 +55,60 @@ func fileExists(name string) bool {
 	return true
 }
 
+// lockedError returns a descriptive error for a database file which could not
+// be opened because it is locked by another process.
+func lockedError(dbFile string) error {
+	return fmt.Errorf("database %s is in use by another process, most likely a "+
+		"running instance of vspd, which must be stopped before this command can "+
+		"be used", dbFile)
+}
+
+// openDB opens the database file for reading and writing. vspd holds an
+// exclusive lock on the database file for as long as it is running, so this
+// fails if vspd is live. This guarantees that commands which modify the
+// database, such as changing the fee xpub, never race with vspd.
+func openDB(dbFile string) (*database.VspDatabase, error) {
+	db, err := database.Open(dbFile, slog.Disabled, 999, 0)
+	if errors.Is(err, database.ErrLocked) {
+		return nil, lockedError(dbFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening db file %s: %w", dbFile, err)
+	}
+	return db, nil
+}
+
+// openDBReadOnly opens the database file for reading only. If the database is
+// locked and useBackup is true, the latest backup file written by vspd is
+// opened instead. Backups are written periodically, so may not include the most
+// recent changes.
+func openDBReadOnly(dbFile string, useBackup bool) (*database.VspDatabase, error) {
+	db, err := database.OpenReadOnly(dbFile, slog.Disabled, 0)
+	if errors.Is(err, database.ErrLocked) {
+		if !useBackup {
+			return nil, fmt.Errorf("%w, or run with --usebackup to read from the "+
+				"latest backup", lockedError(dbFile))
+		}
+
+		backupFile := database.BackupFile(dbFile)
+		info, statErr := os.Stat(backupFile)
+		if statErr != nil {
+			return nil, fmt.Errorf("database %s is locked and backup cannot be read: %w",
+				dbFile, statErr)
+		}
+
+		log("Database is locked, reading from backup written at %s",
+			info.ModTime().Format(time.RFC3339))
+
+		dbFile = backupFile
+		db, err = database.OpenReadOnly(dbFile, slog.Disabled, 0)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening db file %s: %w", dbFile, err)
+	}
+	return db, nil
+}
+
 // validatePubkey returns an error if the provided key is invalid, not for the
 // expected network, or it is public instead of private.
 func validatePubkey(key string, network *config.Network) error {
@@ -61,7 +124,23 @@ func validatePubkey(key string, network *config.Network) error {
 	return nil
 }
 
-func createDatabase(homeDir string, feeXPub string, network *config.Network) error {
+// xpubFingerprint returns the fingerprint of the provided extended key as 8 hex
+// characters. This is the same fingerprint which is recorded as the parent
+// fingerprint of keys derived from it.
+func xpubFingerprint(key string, network *config.Network) (string, error) {
+	parsedKey, err := hdkeychain.NewKeyFromString(key, network.Params)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse feexpub: %w", err)
+	}
+
+	hash := dcrutil.Hash160(parsedKey.SerializedPubKey())
+	return hex.EncodeToString(hash[:4]), nil
+}
+
+// createDatabase creates a new database using the provided fee xpub. If
+// fingerprint is not empty, the database is only created if it matches the
+// fingerprint of the xpub.
+func createDatabase(homeDir string, feeXPub string, fingerprint string, network *config.Network) error {
 	dataDir := filepath.Join(homeDir, "data", network.Name)
 	dbFile := filepath.Join(dataDir, dbFilename)
 
@@ -76,6 +155,18 @@ func createDatabase(homeDir string, feeXPub string, network *config.Network) err
 		return err
 	}
 
+	// Catch copy-paste errors by ensuring the xpub is the one the operator
+	// expects.
+	if fingerprint != "" {
+		actual, err := xpubFingerprint(feeXPub, network)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(actual, fingerprint) {
+			return fmt.Errorf("feexpub fingerprint is %s, expected %s", actual, fingerprint)
+		}
+	}
+
 	// Ensure the data directory exists.
 	err = os.MkdirAll(dataDir, 0700)
 	if err != nil {
@@ -117,29 +208,331 @@ func writeConfig(homeDir string) error {
 	return nil
 }
 
-func retireXPub(homeDir string, feeXPub string, network *config.Network) error {
+// retireXPub replaces the current fee xpub with the provided one. If approvers
+// are configured, the change is recorded as a pending action instead and false
+// is returned.
+func retireXPub(homeDir string, feeXPub string, network *config.Network) (bool, error) {
 	dataDir := filepath.Join(homeDir, "data", network.Name)
 	dbFile := filepath.Join(dataDir, dbFilename)
 
 	// Ensure provided xpub is a valid key for the selected network.
 	err := validatePubkey(feeXPub, network)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	db, err := database.Open(dbFile, slog.Disabled, 999)
+	db, err := openDB(dbFile)
 	if err != nil {
-		return fmt.Errorf("error opening db file %s: %w", dbFile, err)
+		return false, err
+	}
+	defer db.Close(false)
+
+	approvers, err := db.Approvers()
+	if err != nil {
+		return false, fmt.Errorf("db.Approvers failed: %w", err)
+	}
+	if approvers != nil {
+		return false, proposeAction(db, approvers, actionRetireXPub, feeXPub)
 	}
 
 	err = db.RetireXPub(feeXPub)
 	if err != nil {
-		return fmt.Errorf("db.RetireXPub failed: %w", err)
+		return false, fmt.Errorf("db.RetireXPub failed: %w", err)
+	}
+
+	return true, nil
+}
+
+func reindex(homeDir string, network *config.Network) error {
+	dataDir := filepath.Join(homeDir, "data", network.Name)
+	dbFile := filepath.Join(dataDir, dbFilename)
+
+	db, err := openDB(dbFile)
+	if err != nil {
+		return err
+	}
+	defer db.Close(false)
+
+	updated, err := db.RecoverLastAddressIndexes()
+	if err != nil {
+		return fmt.Errorf("db.RecoverLastAddressIndexes failed: %w", err)
+	}
+
+	if len(updated) == 0 {
+		log("All xpub address indexes are already up-to-date")
+		return nil
+	}
+
+	for id, xpub := range updated {
+		log("Updated last used address index of xpub %d to %d", id, xpub.LastUsedIdx)
+	}
+
+	return nil
+}
+
+// simulateReorg updates the tickets with the provided hashes as if their ticket
+// tx had been reorged out of the main chain, so they are unconfirmed and have
+// no purchase height. When vspd is next started it will re-check the tickets
+// in the same way it would after a real reorg. This is only permitted on
+// simnet because the tickets are left in a state which does not reflect the
+// chain.
+func simulateReorg(homeDir string, ticketHashes []string, network *config.Network) error {
+	if network.Name != config.SimNet.Name {
+		return fmt.Errorf("simulatereorg can only be used on simnet, not %s", network.Name)
+	}
+
+	dataDir := filepath.Join(homeDir, "data", network.Name)
+	dbFile := filepath.Join(dataDir, dbFilename)
+
+	db, err := openDB(dbFile)
+	if err != nil {
+		return err
+	}
+	defer db.Close(false)
+
+	// Ensure every ticket can be updated before updating any of them.
+	tickets := make([]database.Ticket, len(ticketHashes))
+	for i, hash := range ticketHashes {
+		ticket, found, err := db.GetTicketByHash(hash)
+		if err != nil {
+			return fmt.Errorf("db.GetTicketByHash failed: %w", err)
+		}
+		if !found {
+			return fmt.Errorf("ticket %s not found in database", hash)
+		}
+		if ticket.Outcome != "" {
+			return fmt.Errorf("ticket %s has already been spent", hash)
+		}
+		tickets[i] = ticket
+	}
+
+	for _, ticket := range tickets {
+		ticket.Confirmed = false
+		ticket.PurchaseHeight = 0
+		err = db.UpdateTicket(ticket)
+		if err != nil {
+			return fmt.Errorf("db.UpdateTicket failed (ticketHash=%s): %w", ticket.Hash, err)
+		}
+		log("Ticket %s marked as reorged out", ticket.Hash)
+	}
+
+	return nil
+}
+
+// voteChange is a vote change record with the request and response bodies
+// included as raw JSON rather than escaped strings, so they remain readable in
+// the output of votehistory.
+type voteChange struct {
+	Request           json.RawMessage `json:"request"`
+	RequestSignature  string          `json:"requestsignature"`
+	Response          json.RawMessage `json:"response"`
+	ResponseSignature string          `json:"responsesignature"`
+}
+
+func voteHistory(homeDir string, ticketHash string, network *config.Network, useBackup bool) error {
+	dataDir := filepath.Join(homeDir, "data", network.Name)
+	dbFile := filepath.Join(dataDir, dbFilename)
+
+	db, err := openDBReadOnly(dbFile, useBackup)
+	if err != nil {
+		return err
+	}
+	defer db.Close(false)
+
+	_, found, err := db.GetTicketByHash(ticketHash)
+	if err != nil {
+		return fmt.Errorf("db.GetTicketByHash failed: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("ticket %s not found in database", ticketHash)
+	}
+
+	records, err := db.GetVoteChangeHistory(ticketHash)
+	if err != nil {
+		return fmt.Errorf("db.GetVoteChangeHistory failed: %w", err)
+	}
+
+	history := make([]voteChange, len(records))
+	for i, record := range records {
+		history[i] = voteChange{
+			Request:           json.RawMessage(record.Request),
+			RequestSignature:  record.RequestSignature,
+			Response:          json.RawMessage(record.Response),
+			ResponseSignature: record.ResponseSignature,
+		}
+	}
+
+	out, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal vote history: %w", err)
+	}
+
+	fmt.Println(string(out))
+
+	return nil
+}
+
+// checkFeeTx runs the validation which /payfee performs on fee transactions
+// against the provided fee tx hex, using the fee address and fee amount of the
+// ticket with the provided hash. The optional fee tx checks which can be
+// enabled in the vspd config are not applied.
+func checkFeeTx(homeDir string, ticketHash string, feeTxHex string, network *config.Network,
+	useBackup bool) error {
+	dataDir := filepath.Join(homeDir, "data", network.Name)
+	dbFile := filepath.Join(dataDir, dbFilename)
+
+	db, err := openDBReadOnly(dbFile, useBackup)
+	if err != nil {
+		return err
+	}
+	defer db.Close(false)
+
+	ticket, found, err := db.GetTicketByHash(ticketHash)
+	if err != nil {
+		return fmt.Errorf("db.GetTicketByHash failed: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("ticket %s not found in database", ticketHash)
+	}
+
+	if ticket.FeeExpired() {
+		log("Warning: the fee for this ticket has expired, /payfee would reject it")
+	}
+
+	minFee := dcrutil.Amount(ticket.FeeAmount)
+	feeTx, feePaid, err := webapi.ValidateFeeTx(feeTxHex, ticket.FeeAddress, minFee, network,
+		webapi.FeeTxPolicy{})
+	if err != nil {
+		return err
+	}
+
+	log("Fee tx %s is valid", feeTx.TxHash())
+	log("Pays %s to fee address %s, minimum fee is %s", feePaid, ticket.FeeAddress, minFee)
+
+	return nil
+}
+
+// xpubFees prints the number of tickets with a confirmed fee tx and the total
+// fees they paid less any refunds, for each xpub which has been used to derive
+// fee addresses.
+func xpubFees(homeDir string, network *config.Network, useBackup bool) error {
+	dataDir := filepath.Join(homeDir, "data", network.Name)
+	dbFile := filepath.Join(dataDir, dbFilename)
+
+	db, err := openDBReadOnly(dbFile, useBackup)
+	if err != nil {
+		return err
+	}
+	defer db.Close(false)
+
+	xpubs, err := db.AllXPubs()
+	if err != nil {
+		return fmt.Errorf("db.AllXPubs failed: %w", err)
+	}
+
+	fees, err := db.FeesByXPub()
+	if err != nil {
+		return fmt.Errorf("db.FeesByXPub failed: %w", err)
+	}
+
+	ids := make([]uint32, 0, len(xpubs))
+	for id := range xpubs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var totalTickets int64
+	var total, totalRefunds dcrutil.Amount
+	for _, id := range ids {
+		xpub := xpubs[id]
+		status := "active"
+		if xpub.Retired != 0 {
+			status = "retired " + time.Unix(xpub.Retired, 0).UTC().Format(time.RFC3339)
+		}
+
+		xpubFees := fees[id]
+		totalTickets += xpubFees.Tickets
+		total += dcrutil.Amount(xpubFees.Fees)
+		totalRefunds += dcrutil.Amount(xpubFees.Refunds)
+
+		fmt.Printf("XPub ID %d (%s)\n", id, status)
+		fmt.Printf("  Key:     %s\n", xpub.Key)
+		fmt.Printf("  Tickets: %d\n", xpubFees.Tickets)
+		fmt.Printf("  Fees:    %s\n", dcrutil.Amount(xpubFees.Fees))
+		if xpubFees.Refunds != 0 {
+			fmt.Printf("  Refunds: %s\n", dcrutil.Amount(xpubFees.Refunds))
+		}
+	}
+
+	fmt.Printf("Total: %s from %d tickets\n", total, totalTickets)
+	if totalRefunds != 0 {
+		fmt.Printf("Refunded: %s\n", totalRefunds)
 	}
 
 	return nil
 }
 
+// registrations prints the number of tickets registered on each UTC day from
+// the start date to the end date inclusive. Dates are formatted as YYYY-MM-DD.
+func registrations(homeDir string, start, end string, network *config.Network, useBackup bool) error {
+	from, err := time.Parse(time.DateOnly, start)
+	if err != nil {
+		return fmt.Errorf("invalid start date: %w", err)
+	}
+	to, err := time.Parse(time.DateOnly, end)
+	if err != nil {
+		return fmt.Errorf("invalid end date: %w", err)
+	}
+
+	dataDir := filepath.Join(homeDir, "data", network.Name)
+	dbFile := filepath.Join(dataDir, dbFilename)
+
+	db, err := openDBReadOnly(dbFile, useBackup)
+	if err != nil {
+		return err
+	}
+	defer db.Close(false)
+
+	counts, undated, err := db.CountRegistrationsByDay(from, to)
+	if err != nil {
+		return fmt.Errorf("db.CountRegistrationsByDay failed: %w", err)
+	}
+
+	var total int64
+	for _, count := range counts {
+		total += count.Tickets
+		fmt.Printf("%s  %d\n", count.Day.Format(time.DateOnly), count.Tickets)
+	}
+
+	fmt.Printf("Total: %d tickets registered in %d days\n", total, len(counts))
+	if undated > 0 {
+		fmt.Printf("%d tickets have no recorded registration time and are not included\n", undated)
+	}
+
+	return nil
+}
+
+// showConfig loads the vspd config from homeDir in the same way as vspd, with
+// any provided args taking precedence over the config file, and prints the
+// effective value of every option. Passwords are redacted.
+func showConfig(homeDir string, args []string) error {
+	args = append([]string{"--homedir=" + homeDir}, args...)
+
+	cfg, err := vspd.LoadConfigFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	redacted := cfg.Redacted()
+
+	log("; Effective config for %s vspd in %s", cfg.Network().Name, cfg.HomeDir)
+
+	parser := flags.NewParser(&redacted, flags.None)
+	flags.NewIniParser(parser).Write(os.Stdout, flags.IniIncludeDefaults)
+
+	return nil
+}
+
 // run is the real main function for vspadmin. It is necessary to work around
 // the fact that deferred functions do not run when os.Exit() is called.
 func run() int {
@@ -177,13 +570,16 @@ func run() int {
 
 		feeXPub := remainingArgs[1]
 
-		err = createDatabase(cfg.HomeDir, feeXPub, network)
+		err = createDatabase(cfg.HomeDir, feeXPub, cfg.XPubFingerprint, network)
 		if err != nil {
 			log("createdatabase failed: %v", err)
 			return 1
 		}
 
 		log("New %s vspd database created in %s", network.Name, cfg.HomeDir)
+		if fingerprint, err := xpubFingerprint(feeXPub, network); err == nil {
+			log("Fee xpub fingerprint is %s", fingerprint)
+		}
 
 	case "writeconfig":
 		err = writeConfig(cfg.HomeDir)
@@ -203,13 +599,131 @@ func run() int {
 
 		feeXPub := remainingArgs[1]
 
-		err = retireXPub(cfg.HomeDir, feeXPub, network)
+		retired, err := retireXPub(cfg.HomeDir, feeXPub, network)
 		if err != nil {
 			log("retirexpub failed: %v", err)
 			return 1
 		}
 
-		log("Xpub successfully retired, all future tickets will use the new xpub")
+		if retired {
+			log("Xpub successfully retired, all future tickets will use the new xpub")
+		}
+
+	case "setapprovers":
+		if len(remainingArgs) < 2 {
+			log("setapprovers requires a threshold followed by approver addresses")
+			return 1
+		}
+
+		err = setApprovers(cfg.HomeDir, remainingArgs[1], remainingArgs[2:], network)
+		if err != nil {
+			log("setapprovers failed: %v", err)
+			return 1
+		}
+
+	case "approve":
+		if len(remainingArgs) != 3 {
+			log("approve has two required arguments, approver address and signature")
+			return 1
+		}
+
+		err = approve(cfg.HomeDir, remainingArgs[1], remainingArgs[2], network)
+		if err != nil {
+			log("approve failed: %v", err)
+			return 1
+		}
+
+	case "reindex":
+		err = reindex(cfg.HomeDir, network)
+		if err != nil {
+			log("reindex failed: %v", err)
+			return 1
+		}
+
+	case "simulatereorg":
+		if len(remainingArgs) < 2 {
+			log("simulatereorg requires one or more ticket hashes")
+			return 1
+		}
+
+		err = simulateReorg(cfg.HomeDir, remainingArgs[1:], network)
+		if err != nil {
+			log("simulatereorg failed: %v", err)
+			return 1
+		}
+
+	case "votehistory":
+		if len(remainingArgs) != 2 {
+			log("votehistory has one required argument, ticket hash")
+			return 1
+		}
+
+		ticketHash := remainingArgs[1]
+
+		err = voteHistory(cfg.HomeDir, ticketHash, network, cfg.UseBackup)
+		if err != nil {
+			log("votehistory failed: %v", err)
+			return 1
+		}
+
+	case "checkfeetx":
+		if len(remainingArgs) != 3 {
+			log("checkfeetx has two required arguments, ticket hash and fee tx hex")
+			return 1
+		}
+
+		err = checkFeeTx(cfg.HomeDir, remainingArgs[1], remainingArgs[2], network, cfg.UseBackup)
+		if err != nil {
+			log("checkfeetx failed: %v", err)
+			return 1
+		}
+
+	case "xpubfees":
+		err = xpubFees(cfg.HomeDir, network, cfg.UseBackup)
+		if err != nil {
+			log("xpubfees failed: %v", err)
+			return 1
+		}
+
+	case "registrations":
+		if len(remainingArgs) != 3 {
+			log("registrations has two required arguments, start date and end date")
+			return 1
+		}
+
+		err = registrations(cfg.HomeDir, remainingArgs[1], remainingArgs[2], network, cfg.UseBackup)
+		if err != nil {
+			log("registrations failed: %v", err)
+			return 1
+		}
+
+	case "dbbench":
+		if len(remainingArgs) > 2 {
+			log("dbbench has one optional argument, number of tickets")
+			return 1
+		}
+
+		numTickets := defaultBenchTickets
+		if len(remainingArgs) == 2 {
+			numTickets, err = strconv.Atoi(remainingArgs[1])
+			if err != nil {
+				log("invalid number of tickets: %v", err)
+				return 1
+			}
+		}
+
+		err = dbBench(numTickets)
+		if err != nil {
+			log("dbbench failed: %v", err)
+			return 1
+		}
+
+	case "showconfig":
+		err = showConfig(cfg.HomeDir, remainingArgs[1:])
+		if err != nil {
+			log("showconfig failed: %v", err)
+			return 1
+		}
 
 	default:
 		log("%q is not a valid command", remainingArgs[0])
@@ -5,8 +5,11 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
 	"sync"
 	"time"
@@ -14,7 +17,13 @@ import (
 	"github.com/decred/dcrd/wire"
 	"github.com/decred/slog"
 	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/internal/alert"
+	"github.com/decred/vspd/internal/auditlog"
+	"github.com/decred/vspd/internal/broadcast"
 	"github.com/decred/vspd/internal/config"
+	"github.com/decred/vspd/internal/events"
+	"github.com/decred/vspd/internal/feesweep"
+	"github.com/decred/vspd/internal/metrics"
 	"github.com/decred/vspd/internal/signal"
 	"github.com/decred/vspd/internal/version"
 	"github.com/decred/vspd/internal/vspd"
@@ -28,6 +37,10 @@ const (
 	// storage space. When storing a new record breaches this limit, the oldest
 	// record in the database is deleted.
 	maxVoteChangeRecords = 10
+
+	// feeBroadcastTimeout is the maximum time allowed for a request to an
+	// external fee broadcast service.
+	feeBroadcastTimeout = 30 * time.Second
 )
 
 func main() {
@@ -75,12 +88,22 @@ func run() int {
 	log := makeLogger("VSP")
 
 	// Create a context that is canceled when a shutdown request is received
-	// through an interrupt signal such as SIGINT (Ctrl+C).
-	ctx := signal.ShutdownListener(log)
+	// through an interrupt signal such as SIGINT (Ctrl+C). If enabled, SIGHUP
+	// requests a config reload instead.
+	var ctx context.Context
+	var reload <-chan os.Signal
+	if cfg.ReloadOnSIGHUP {
+		ctx, reload = signal.ReloadListener(log)
+	} else {
+		ctx = signal.ShutdownListener(log)
+	}
 
 	defer log.Criticalf("Shutdown complete")
 	log.Criticalf("Version %s (Go version %s %s/%s)", version.String(),
 		runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	if version.BuildDate() != "" {
+		log.Infof("Build commit %s (built %s)", version.Commit(), version.BuildDate())
+	}
 
 	network := cfg.Network()
 
@@ -102,14 +125,34 @@ func run() int {
 		log.Warnf("")
 	}
 
+	for _, opt := range cfg.UnknownOptions() {
+		log.Warnf("Ignoring unknown option %q in config file", opt)
+	}
+
+	for _, warning := range cfg.Warnings() {
+		log.Warnf("Config: %s", warning)
+	}
+
 	if cfg.FeeXPub != "" {
 		log.Warnf("")
 		log.Warnf("\tWARNING: Config --feexpub is set. This behavior has been moved into vspadmin and will be removed from vspd in a future release")
 		log.Warnf("")
 	}
 
+	// The database contains voting keys, so ensure other users cannot read it.
+	// A missing database is reported when it is opened below.
+	err = database.CheckPermissions(cfg.DatabaseFile())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		if cfg.StrictDBPermissions {
+			log.Errorf("Insecure database permissions: %v", err)
+			return 1
+		}
+		log.Warnf("Insecure database permissions: %v", err)
+	}
+
 	// Open database.
-	db, err := database.Open(cfg.DatabaseFile(), makeLogger(" DB"), maxVoteChangeRecords)
+	db, err := database.Open(cfg.DatabaseFile(), makeLogger(" DB"), maxVoteChangeRecords,
+		cfg.TicketCacheSize)
 	if err != nil {
 		log.Errorf("Failed to open database: %v", err)
 		return 1
@@ -117,6 +160,24 @@ func run() int {
 	const writeBackup = true
 	defer db.Close(writeBackup)
 
+	// Ensure the database contains a usable signing key before starting any
+	// services, generating a new one if permitted by config.
+	_, _, err = db.KeyPair()
+	if errors.Is(err, database.ErrNoSigningKey) {
+		if !cfg.GenerateSigningKey {
+			log.Errorf("Database does not contain a signing key. Restore the database " +
+				"from a backup, or on testnet and simnet set --generatesigningkey to " +
+				"generate a new one")
+			return 1
+		}
+		log.Warnf("Database does not contain a signing key, generating a new one")
+		err = db.GenerateKeyPair()
+	}
+	if err != nil {
+		log.Errorf("Failed to load signing key: %v", err)
+		return 1
+	}
+
 	rpcLog := makeLogger("RPC")
 
 	// Create a channel to receive blockConnected notifications from dcrd.
@@ -125,30 +186,134 @@ func run() int {
 	// Create RPC client for local dcrd instance (used for broadcasting and
 	// checking the status of fee transactions).
 	dd := cfg.DcrdDetails()
-	dcrd := rpc.SetupDcrd(dd.User, dd.Password, dd.Host, dd.Cert, network.Params, rpcLog, blockNotifChan)
+	dcrd := rpc.SetupDcrd(dd.User, dd.Password, dd.Host, dd.Cert, network.Params, rpcLog, blockNotifChan,
+		cfg.SlowRPCThreshold)
 
 	defer dcrd.Close()
 
 	// Create RPC client for remote dcrwallet instances (used for voting).
 	wd := cfg.WalletDetails()
-	wallets := rpc.SetupWallet(wd.Users, wd.Passwords, wd.Hosts, wd.Certs, network.Params, rpcLog)
+	wallets, err := rpc.SetupWallet(wd.Users, wd.Passwords, wd.Hosts, wd.Certs, network.Params, rpcLog,
+		cfg.SlowRPCThreshold, cfg.MinWalletVersion)
+	if err != nil {
+		log.Errorf("Failed to set up voting wallet clients: %v", err)
+		return 1
+	}
 	defer wallets.Close()
 
+	// Fee transactions are broadcast by the local dcrd unless an external
+	// broadcast service is configured.
+	var broadcaster broadcast.Broadcaster = broadcast.NewDcrd(dcrd)
+	if cfg.FeeBroadcastURL != "" {
+		log.Infof("Broadcasting fee transactions via %s", cfg.FeeBroadcastURL)
+		broadcaster = broadcast.NewHTTP(cfg.FeeBroadcastURL, feeBroadcastTimeout)
+	}
+	broadcaster = broadcast.NewThrottled(broadcaster, cfg.BroadcastBackoff,
+		cfg.MaxBroadcastBackoff)
+
 	// Create webapi server.
+	minTicketPrice, maxTicketPrice := cfg.TicketPriceLimits()
+	ticketPolicy := webapi.TicketRules{
+		MaxInputs:     cfg.MaxTicketInputs,
+		RejectScripts: cfg.RejectedTicketScripts(),
+	}
 	apiCfg := webapi.Config{
-		Listen:               cfg.Listen,
-		VSPFee:               cfg.VSPFee,
-		Network:              network,
-		SupportEmail:         cfg.SupportEmail,
-		VspClosed:            cfg.VspClosed,
-		VspClosedMsg:         cfg.VspClosedMsg,
-		AdminPass:            cfg.AdminPass,
-		Debug:                cfg.WebServerDebug,
-		Designation:          cfg.Designation,
-		MaxVoteChangeRecords: maxVoteChangeRecords,
-		VspdVersion:          version.String(),
-	}
-	api, err := webapi.New(db, makeLogger("API"), dcrd, wallets, apiCfg)
+		Listen:                 cfg.Listen,
+		ReadTimeout:            cfg.HTTPReadTimeout,
+		ReadHeaderTimeout:      cfg.HTTPReadHeaderTimeout,
+		WriteTimeout:           cfg.HTTPWriteTimeout,
+		IdleTimeout:            cfg.HTTPIdleTimeout,
+		VSPFee:                 cfg.VSPFee,
+		FeeSchedule:            cfg.ScheduledFees(),
+		PriorityFee:            cfg.PriorityFee,
+		NominalFee:             cfg.NominalFee(),
+		Network:                network,
+		SupportEmail:           cfg.SupportEmail,
+		StatusPage:             cfg.StatusPage,
+		OpenAPISpec:            cfg.OpenAPISpec,
+		VspClosed:              cfg.VspClosed,
+		VspClosedMsg:           cfg.VspClosedMsg,
+		AdminPass:              cfg.AdminPass,
+		ReadOnlyPass:           cfg.ReadOnlyPass,
+		Debug:                  cfg.WebServerDebug,
+		Designation:            cfg.Designation,
+		MaxVoteChangeRecords:   maxVoteChangeRecords,
+		MaxTicketsPerAddress:   cfg.MaxTicketsPerAddress,
+		MaxActiveTickets:       cfg.MaxActiveTickets,
+		MinTicketPrice:         minTicketPrice,
+		MaxTicketPrice:         maxTicketPrice,
+		FeeConfirmations:       cfg.FeeConfirmations,
+		MaxFeeTxSize:           cfg.MaxFeeTxSize,
+		RequireStandardFeeTx:   cfg.RequireStandardFeeTx,
+		StandardFeeTxInputs:    cfg.StandardFeeTxInputs,
+		RequireFinalFeeTx:      cfg.RequireFinalFeeTx,
+		RejectImmatureCoinbase: cfg.RejectImmatureCoinbase,
+		CheckExistingFeeTx:     cfg.CheckExistingFeeTx,
+		MaxFeeTxNullData:       cfg.MaxFeeTxNullData,
+		TicketFeeLimitCheck:    cfg.TicketFeeLimitCheck,
+		TicketPolicy:           ticketPolicy,
+		RecomputeFee:           cfg.RecomputeFee,
+		APIVersionFees:         cfg.APIVersionFees,
+		RecordFeeSurplus:       cfg.RecordFeeSurplus,
+		LogSampleRate:          cfg.LogSampleRate,
+		FeeReservationTimeout:  cfg.FeeReservationTimeout,
+		FeeQuoteMinValidity:    cfg.FeeQuoteMinValidity,
+		FeeDeadlineBlocks:      cfg.FeeDeadlineBlocks,
+		AllowDeferredBroadcast: cfg.AllowDeferredBroadcast,
+		VspInfoValidity:        cfg.VspInfoValidity,
+		StatsMaxAge:            cfg.StatsMaxAge,
+		PayFeeValidity:         cfg.PayFeeValidity,
+		TicketStatusValidity:   cfg.TicketStatusValidity,
+		DisabledEndpoints:      cfg.DisabledEndpoints(),
+		RequestTimeout:         cfg.RequestTimeout,
+		EndpointTimeouts:       cfg.EndpointTimeoutOverrides(),
+		MaxClockSkew:           cfg.MaxClockSkew,
+		VoteChangeInterval:     cfg.VoteChangeInterval,
+		WalletLagThreshold:     cfg.WalletLagThreshold,
+		DiskSpaceDir:           filepath.Dir(cfg.DatabaseFile()),
+		LowDiskSpace:           cfg.LowDiskSpaceBytes(),
+		LowDiskMaintenance:     cfg.LowDiskMaintenance,
+		RejectUntilReady:       cfg.RejectUntilReady,
+		AdminListen:            cfg.AdminListen,
+		AdminTLSCert:           cfg.AdminTLSCert,
+		AdminTLSKey:            cfg.AdminTLSKey,
+		AdminClientCA:          cfg.AdminClientCA,
+		RecycleFeeAddresses:    cfg.RecycleFeeAddresses,
+		ReissueRetiredFeeAddrs: cfg.ReissueRetiredFeeAddrs,
+		VspdVersion:            version.String(),
+	}
+	// Metrics are always collected, but are only exported if configured.
+	registry := metrics.NewRegistry()
+
+	// Ticket lifecycle events are only published if configured. A nil
+	// publisher discards all events.
+	var publisher *events.Publisher
+	if cfg.EventsURL != "" {
+		publisher, err = events.New(cfg.EventsURL, cfg.EventsSubject, makeLogger("EVT"))
+		if err != nil {
+			log.Errorf("Failed to initialize event publisher: %v", err)
+			return 1
+		}
+	}
+
+	// Write a record of every API request to the audit log, if enabled.
+	if cfg.AuditLog {
+		auditLog, err := auditlog.New(auditlog.Config{
+			Path:     filepath.Join(cfg.LogDir(), "audit.log"),
+			MaxSize:  cfg.AuditLogMaxSize * 1024 * 1024,
+			MaxFiles: cfg.AuditLogMaxFiles,
+			MaxAge:   cfg.AuditLogMaxAge,
+		})
+		if err != nil {
+			log.Errorf("Failed to open audit log: %v", err)
+			return 1
+		}
+		defer auditLog.Close()
+		apiCfg.AuditLog = auditLog
+	}
+
+	api, err := webapi.New(db, makeLogger("API"), dcrd, wallets, broadcaster, registry,
+		publisher, apiCfg)
 	if err != nil {
 		log.Errorf("Failed to initialize webapi: %v", err)
 		return 1
@@ -157,6 +322,24 @@ func run() int {
 	// WaitGroup for services to signal when they have shutdown cleanly.
 	var wg sync.WaitGroup
 
+	// Apply reloadable config changes when a reload is requested.
+	if reload != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			current := *cfg
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case sig := <-reload:
+					log.Infof("Received signal (%s). Reloading config...", sig)
+					reloadConfig(&current, api, log)
+				}
+			}
+		}()
+	}
+
 	// Start the webapi server.
 	wg.Add(1)
 	go func() {
@@ -165,13 +348,61 @@ func run() int {
 	}()
 
 	// Start vspd.
-	vspd := vspd.New(network, log, db, dcrd, wallets, blockNotifChan)
+	alerter := alert.New(cfg.AlertConfig(), makeLogger("ALR"))
+	vspdCfg := vspd.Settings{
+		FeeConfirmations:    cfg.FeeConfirmations,
+		DegradedWallets:     cfg.DegradedWallets,
+		RecycleFeeAddresses: cfg.RecycleFeeAddresses,
+		PriorityEnabled:     cfg.PriorityFee > 0,
+		CheckFeeAddrReuse:   cfg.CheckFeeAddressReuse,
+		FeeExpiryNotice:     cfg.FeeExpiryNotice,
+		KeyImportRetries:    cfg.KeyImportRetries,
+		WalletLagThreshold:  cfg.WalletLagThreshold,
+	}
+	vspd := vspd.New(vspdCfg, network, log, db, dcrd, wallets, broadcaster, blockNotifChan,
+		alerter, publisher, registry)
 	wg.Add(1)
 	go func() {
 		vspd.Run(ctx)
 		wg.Done()
 	}()
 
+	// Push metrics to StatsD if enabled.
+	if cfg.StatsDHost != "" {
+		statsd := metrics.NewStatsD(cfg.StatsDHost, cfg.StatsDPrefix, cfg.StatsDInterval,
+			registry, makeLogger("MET"))
+		wg.Add(1)
+		go func() {
+			statsd.Run(ctx)
+			wg.Done()
+		}()
+	}
+
+	// Sweep fees to the operator's address if enabled. The fee wallet is a
+	// separate dcrwallet instance so the voting wallets never hold the private
+	// keys of the fee xpub.
+	if fd := cfg.FeeWalletDetails(); fd != nil {
+		feeWallet := rpc.SetupFeeWallet(fd.User, fd.Password, fd.Host, fd.Cert, network.Params,
+			rpcLog, cfg.SlowRPCThreshold)
+		defer feeWallet.Close()
+
+		sweeper := feesweep.New(cfg.FeeSweepConfig(), &feeWallet, makeLogger("SWP"))
+		wg.Add(1)
+		go func() {
+			sweeper.Run(ctx)
+			wg.Done()
+		}()
+	}
+
+	// Publish ticket lifecycle events if enabled.
+	if publisher != nil {
+		wg.Add(1)
+		go func() {
+			publisher.Run(ctx)
+			wg.Done()
+		}()
+	}
+
 	// Periodically write a database backup file.
 	wg.Add(1)
 	go func() {
@@ -189,9 +420,82 @@ func run() int {
 		}
 	}()
 
+	// Periodically write a database snapshot and prune old snapshots.
+	if cfg.SnapshotDir != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(cfg.SnapshotInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case now := <-ticker.C:
+					writeSnapshot(db, cfg.SnapshotDir, cfg.SnapshotRetention, now, log)
+				}
+			}
+		}()
+	}
+
 	// Wait for shutdown tasks to complete before running deferred tasks and
 	// returning.
 	wg.Wait()
 
 	return 0
 }
+
+// reloadConfig loads the config again and applies any changes to reloadable
+// options to the running webapi server, updating current to match. Changes to
+// options which require a restart are logged but not applied.
+func reloadConfig(current *vspd.Config, api *webapi.WebAPI, log slog.Logger) {
+	newCfg, err := vspd.LoadConfig()
+	if err != nil {
+		log.Errorf("Failed to reload config, continuing with current config: %v", err)
+		return
+	}
+
+	changes := current.ConfigChanges(newCfg)
+	if len(changes) == 0 {
+		log.Infof("Config reloaded, no changes found")
+		return
+	}
+
+	for _, change := range changes {
+		if change.Reloadable {
+			log.Infof("Config: %s changed from %q to %q", change.Option,
+				fmt.Sprint(change.Old), fmt.Sprint(change.New))
+		} else {
+			log.Warnf("Config: %s changed from %q to %q, restart vspd to apply this change",
+				change.Option, fmt.Sprint(change.Old), fmt.Sprint(change.New))
+		}
+	}
+
+	current.VspClosed = newCfg.VspClosed
+	current.VspClosedMsg = newCfg.VspClosedMsg
+	current.VSPFee = newCfg.VSPFee
+	api.Reload(webapi.ReloadableConfig{
+		VspClosed:    current.VspClosed,
+		VspClosedMsg: current.VspClosedMsg,
+		VSPFee:       current.VSPFee,
+	})
+}
+
+// writeSnapshot writes a new database snapshot to dir and deletes the oldest
+// snapshots so that no more than retention snapshots remain.
+func writeSnapshot(db *database.VspDatabase, dir string, retention int, now time.Time, log slog.Logger) {
+	path, err := db.WriteSnapshot(dir, now)
+	if err != nil {
+		log.Errorf("Failed to write database snapshot: %v", err)
+		return
+	}
+	log.Infof("Database snapshot written to %s", path)
+
+	pruned, err := database.PruneSnapshots(dir, retention)
+	for _, path := range pruned {
+		log.Infof("Deleted old database snapshot %s", path)
+	}
+	if err != nil {
+		log.Errorf("Failed to delete old database snapshots: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Approvers is serialized to json and stored in bbolt db. When approvers are
+// configured, critical admin operations do not take effect until they have been
+// approved by at least Threshold of the operators who control Addresses.
+type Approvers struct {
+	Threshold int      `json:"threshold"`
+	Addresses []string `json:"addresses"`
+}
+
+// Contains reports whether addr is one of the approver addresses.
+func (a *Approvers) Contains(addr string) bool {
+	for _, a := range a.Addresses {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// PendingAction is serialized to json and stored in bbolt db. It describes an
+// admin operation which is awaiting approval.
+type PendingAction struct {
+	// Action is the name of the operation, eg. "retirexpub".
+	Action string `json:"action"`
+	// Arg is the argument of the operation, eg. the new xpub.
+	Arg string `json:"arg"`
+	// Nonce is a random value included in the message signed by approvers, so
+	// approvals of one action can not be replayed for another.
+	Nonce string `json:"nonce"`
+	// Created is the unix time at which the action was proposed.
+	Created int64 `json:"created"`
+	// Approvals contains the signature of every approver who has approved
+	// the action so far, keyed by approver address.
+	Approvals map[string]string `json:"approvals"`
+}
+
+// Message returns the message which approvers must sign to approve the action.
+func (p *PendingAction) Message() string {
+	return fmt.Sprintf("vspd %s %s %s", p.Action, p.Arg, p.Nonce)
+}
+
+// Approvers retrieves the approvers from the database. Returns nil if no
+// approvers are configured.
+func (vdb *VspDatabase) Approvers() (*Approvers, error) {
+	var approvers *Approvers
+
+	err := vdb.db.View(func(tx *bolt.Tx) error {
+		approversBytes := tx.Bucket(vspBktK).Get(approversK)
+		if approversBytes == nil {
+			return nil
+		}
+
+		approvers = new(Approvers)
+		err := json.Unmarshal(approversBytes, approvers)
+		if err != nil {
+			return fmt.Errorf("could not unmarshal approvers: %w", err)
+		}
+
+		return nil
+	})
+
+	return approvers, err
+}
+
+// SetApprovers stores the provided approvers in the database, replacing any
+// existing approvers. Passing nil removes the approvers so admin operations
+// take effect without approval.
+func (vdb *VspDatabase) SetApprovers(approvers *Approvers) error {
+	return vdb.db.Update(func(tx *bolt.Tx) error {
+		vspBkt := tx.Bucket(vspBktK)
+
+		if approvers == nil {
+			return vspBkt.Delete(approversK)
+		}
+
+		approversBytes, err := json.Marshal(approvers)
+		if err != nil {
+			return fmt.Errorf("could not marshal approvers: %w", err)
+		}
+
+		return vspBkt.Put(approversK, approversBytes)
+	})
+}
+
+// PendingAction retrieves the admin operation which is awaiting approval from
+// the database. Returns nil if there is no pending action.
+func (vdb *VspDatabase) PendingAction() (*PendingAction, error) {
+	var action *PendingAction
+
+	err := vdb.db.View(func(tx *bolt.Tx) error {
+		actionBytes := tx.Bucket(vspBktK).Get(pendingActionK)
+		if actionBytes == nil {
+			return nil
+		}
+
+		action = new(PendingAction)
+		err := json.Unmarshal(actionBytes, action)
+		if err != nil {
+			return fmt.Errorf("could not unmarshal pending action: %w", err)
+		}
+
+		return nil
+	})
+
+	return action, err
+}
+
+// SetPendingAction stores the provided action in the database, replacing any
+// existing pending action. Passing nil removes the pending action.
+func (vdb *VspDatabase) SetPendingAction(action *PendingAction) error {
+	return vdb.db.Update(func(tx *bolt.Tx) error {
+		vspBkt := tx.Bucket(vspBktK)
+
+		if action == nil {
+			return vspBkt.Delete(pendingActionK)
+		}
+
+		actionBytes, err := json.Marshal(action)
+		if err != nil {
+			return fmt.Errorf("could not marshal pending action: %w", err)
+		}
+
+		return vspBkt.Put(pendingActionK, actionBytes)
+	})
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testApprovers(t *testing.T) {
+	// No approvers are configured in a new database.
+	approvers, err := db.Approvers()
+	if err != nil {
+		t.Fatalf("error retrieving approvers: %v", err)
+	}
+	if approvers != nil {
+		t.Fatalf("expected no approvers, got %v", approvers)
+	}
+
+	expected := &Approvers{
+		Threshold: 2,
+		Addresses: []string{randString(35, addrCharset), randString(35, addrCharset)},
+	}
+
+	err = db.SetApprovers(expected)
+	if err != nil {
+		t.Fatalf("error storing approvers: %v", err)
+	}
+
+	approvers, err = db.Approvers()
+	if err != nil {
+		t.Fatalf("error retrieving approvers: %v", err)
+	}
+	if !reflect.DeepEqual(approvers, expected) {
+		t.Fatalf("expected approvers %v, got %v", expected, approvers)
+	}
+
+	if !approvers.Contains(expected.Addresses[1]) {
+		t.Fatal("expected approvers to contain stored address")
+	}
+	if approvers.Contains(randString(35, addrCharset)) {
+		t.Fatal("expected approvers not to contain random address")
+	}
+
+	// Approvers can be removed.
+	err = db.SetApprovers(nil)
+	if err != nil {
+		t.Fatalf("error removing approvers: %v", err)
+	}
+
+	approvers, err = db.Approvers()
+	if err != nil {
+		t.Fatalf("error retrieving approvers: %v", err)
+	}
+	if approvers != nil {
+		t.Fatalf("expected no approvers, got %v", approvers)
+	}
+}
+
+func testPendingAction(t *testing.T) {
+	// There is no pending action in a new database.
+	action, err := db.PendingAction()
+	if err != nil {
+		t.Fatalf("error retrieving pending action: %v", err)
+	}
+	if action != nil {
+		t.Fatalf("expected no pending action, got %v", action)
+	}
+
+	expected := &PendingAction{
+		Action:  "retirexpub",
+		Arg:     randString(111, addrCharset),
+		Nonce:   randString(32, hexCharset),
+		Created: 1234,
+		Approvals: map[string]string{
+			randString(35, addrCharset): randString(88, addrCharset),
+		},
+	}
+
+	err = db.SetPendingAction(expected)
+	if err != nil {
+		t.Fatalf("error storing pending action: %v", err)
+	}
+
+	action, err = db.PendingAction()
+	if err != nil {
+		t.Fatalf("error retrieving pending action: %v", err)
+	}
+	if !reflect.DeepEqual(action, expected) {
+		t.Fatalf("expected pending action %v, got %v", expected, action)
+	}
+
+	// The pending action can be removed.
+	err = db.SetPendingAction(nil)
+	if err != nil {
+		t.Fatalf("error removing pending action: %v", err)
+	}
+
+	action, err = db.PendingAction()
+	if err != nil {
+		t.Fatalf("error retrieving pending action: %v", err)
+	}
+	if action != nil {
+		t.Fatalf("expected no pending action, got %v", action)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// commitmentAddrIndexKey returns the key of the entry in the commitment
+// address index which records that the ticket with the provided hash has
+// address as its commitment address. Keys are prefixed with the address so all
+// tickets of an address can be found with a single cursor seek.
+func commitmentAddrIndexKey(address, ticketHash string) []byte {
+	return []byte(address + "/" + ticketHash)
+}
+
+// indexCommitmentAddress adds an entry to the commitment address index
+// recording that the ticket with the provided hash has address as its
+// commitment address.
+func indexCommitmentAddress(tx *bolt.Tx, address, ticketHash string) error {
+	if address == "" {
+		return nil
+	}
+
+	err := tx.Bucket(vspBktK).Bucket(commitmentAddrIndexBktK).Put(commitmentAddrIndexKey(address, ticketHash), nil)
+	if err != nil {
+		return fmt.Errorf("could not index commitment address: %w", err)
+	}
+
+	return nil
+}
+
+// unindexCommitmentAddress removes the entry from the commitment address index
+// which records that the ticket with the provided hash has address as its
+// commitment address.
+func unindexCommitmentAddress(tx *bolt.Tx, address, ticketHash string) error {
+	if address == "" {
+		return nil
+	}
+
+	err := tx.Bucket(vspBktK).Bucket(commitmentAddrIndexBktK).Delete(commitmentAddrIndexKey(address, ticketHash))
+	if err != nil {
+		return fmt.Errorf("could not remove commitment address from index: %w", err)
+	}
+
+	return nil
+}
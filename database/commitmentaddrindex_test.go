@@ -0,0 +1,55 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func testCommitmentAddressIndex(t *testing.T) {
+	now := time.Now()
+
+	count := func(test, address string, expected int64) {
+		actual, err := db.CountActiveTicketsByCommitmentAddress(address, now)
+		if err != nil {
+			t.Fatalf("error counting tickets: %v", err)
+		}
+		if actual != expected {
+			t.Fatalf("test %s: expected %d active tickets, got %d", test, expected, actual)
+		}
+	}
+
+	ticket := exampleTicket()
+	ticket.FeeTxStatus = FeeConfirmed
+	err := db.InsertNewTicket(ticket)
+	if err != nil {
+		t.Fatalf("error storing ticket in database: %v", err)
+	}
+
+	count("inserted", ticket.CommitmentAddress, 1)
+
+	// A prefix of the commitment address must not match the ticket.
+	count("prefix", ticket.CommitmentAddress[:len(ticket.CommitmentAddress)-1], 0)
+
+	// Changing the commitment address of the ticket should update the index.
+	oldAddress := ticket.CommitmentAddress
+	ticket.CommitmentAddress = randString(35, addrCharset)
+	err = db.UpdateTicket(ticket)
+	if err != nil {
+		t.Fatalf("error updating ticket: %v", err)
+	}
+
+	count("previous address", oldAddress, 0)
+	count("new address", ticket.CommitmentAddress, 1)
+
+	// Deleting the ticket should remove its commitment address from the index.
+	err = db.DeleteTicket(ticket)
+	if err != nil {
+		t.Fatalf("error deleting ticket: %v", err)
+	}
+
+	count("deleted", ticket.CommitmentAddress, 0)
+}
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"crypto/ed25519"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -20,12 +21,21 @@ import (
 	bolt "go.etcd.io/bbolt"
 )
 
+// ErrNoSigningKey is returned when the database does not contain the key used
+// to sign API responses.
+var ErrNoSigningKey = errors.New("no signing key found in database")
+
+// ErrLocked is returned when the database file cannot be opened because it is
+// locked by another process, usually a running instance of vspd.
+var ErrLocked = errors.New("database file is locked by another process")
+
 // VspDatabase wraps an instance of bolt.DB and provides VSP specific
 // convenience functions.
 type VspDatabase struct {
 	db                   *bolt.DB
 	maxVoteChangeRecords int
 	log                  slog.Logger
+	ticketCache          *ticketCache
 }
 
 // The keys used in the database.
@@ -48,6 +58,18 @@ var (
 	privateKeyK = []byte("privatekey")
 	// altSignAddrBktK stores alternate signing addresses.
 	altSignAddrBktK = []byte("altsigbkt")
+	// orphanedAddrBktK stores fee addresses which were issued to tickets that
+	// were never mined, and which may be recycled.
+	orphanedAddrBktK = []byte("orphanedaddrbkt")
+	// feeAddrIndexBktK indexes the fee addresses issued to tickets.
+	feeAddrIndexBktK = []byte("feeaddrindexbkt")
+	// commitmentAddrIndexBktK indexes the commitment addresses of tickets.
+	commitmentAddrIndexBktK = []byte("commitmentaddrindexbkt")
+	// approvers is the set of operators who must approve critical admin
+	// operations.
+	approversK = []byte("approvers")
+	// pendingaction is an admin operation which is awaiting approval.
+	pendingActionK = []byte("pendingaction")
 )
 
 const (
@@ -64,7 +86,7 @@ func (vdb *VspDatabase) WriteHotBackupFile() error {
 	backupMtx.Lock()
 	defer backupMtx.Unlock()
 
-	backupPath := vdb.db.Path() + "-backup"
+	backupPath := BackupFile(vdb.db.Path())
 	tempPath := backupPath + "~"
 
 	// Write backup to temporary file.
@@ -166,6 +188,18 @@ func CreateNew(dbFile, feeXPub string) error {
 			return fmt.Errorf("failed to create %s bucket: %w", altSignAddrBktK, err)
 		}
 
+		// Create fee address index bucket (added in upgrade to v6).
+		_, err = vspBkt.CreateBucket(feeAddrIndexBktK)
+		if err != nil {
+			return fmt.Errorf("failed to create %s bucket: %w", feeAddrIndexBktK, err)
+		}
+
+		// Create commitment address index bucket (added in upgrade to v7).
+		_, err = vspBkt.CreateBucket(commitmentAddrIndexBktK)
+		if err != nil {
+			return fmt.Errorf("failed to create %s bucket: %w", commitmentAddrIndexBktK, err)
+		}
+
 		return nil
 	})
 
@@ -177,8 +211,10 @@ func CreateNew(dbFile, feeXPub string) error {
 }
 
 // Open initializes and returns an open database. An error is returned if no
-// database file is found at the provided path.
-func Open(dbFile string, log slog.Logger, maxVoteChangeRecords int) (*VspDatabase, error) {
+// database file is found at the provided path. Up to ticketCacheSize recently
+// accessed tickets are cached in memory, a size of zero disables the cache.
+func Open(dbFile string, log slog.Logger, maxVoteChangeRecords int,
+	ticketCacheSize int) (*VspDatabase, error) {
 	// Error if db file does not exist. This is needed because bolt.Open will
 	// silently create a new empty database if the file does not exist. A new
 	// vspd database should be created with the CreateNew() function.
@@ -189,13 +225,17 @@ func Open(dbFile string, log slog.Logger, maxVoteChangeRecords int) (*VspDatabas
 
 	db, err := bolt.Open(dbFile, 0600, &bolt.Options{Timeout: 1 * time.Second})
 	if err != nil {
+		if errors.Is(err, bolt.ErrTimeout) {
+			err = ErrLocked
+		}
 		return nil, fmt.Errorf("unable to open db file: %w", err)
 	}
 
 	vdb := &VspDatabase{
 		db:                   db,
 		log:                  log,
-		maxVoteChangeRecords: maxVoteChangeRecords}
+		maxVoteChangeRecords: maxVoteChangeRecords,
+		ticketCache:          newTicketCache(ticketCacheSize)}
 
 	dbVersion, err := vdb.Version()
 	if err != nil {
@@ -220,6 +260,49 @@ func Open(dbFile string, log slog.Logger, maxVoteChangeRecords int) (*VspDatabas
 	return vdb, nil
 }
 
+// OpenReadOnly opens an existing database file for reading only. Unlike Open,
+// the database is never upgraded, so an error is returned if its version is not
+// the latest. Any attempt to write to the returned database will fail.
+func OpenReadOnly(dbFile string, log slog.Logger, ticketCacheSize int) (*VspDatabase, error) {
+	_, err := os.Stat(dbFile)
+	if os.IsNotExist(err) {
+		return nil, err
+	}
+
+	db, err := bolt.Open(dbFile, 0600, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		if errors.Is(err, bolt.ErrTimeout) {
+			err = ErrLocked
+		}
+		return nil, fmt.Errorf("unable to open db file: %w", err)
+	}
+
+	vdb := &VspDatabase{
+		db:          db,
+		log:         log,
+		ticketCache: newTicketCache(ticketCacheSize)}
+
+	dbVersion, err := vdb.Version()
+	if err != nil {
+		vdb.Close(false)
+		return nil, fmt.Errorf("unable to get db version: %w", err)
+	}
+	if dbVersion != latestVersion {
+		vdb.Close(false)
+		return nil, fmt.Errorf("db version is %d, expected %d", dbVersion, latestVersion)
+	}
+
+	log.Infof("Opened database read-only (version=%d, file=%s)", dbVersion, dbFile)
+
+	return vdb, nil
+}
+
+// BackupFile returns the path of the backup file written by vspd for the
+// provided database file.
+func BackupFile(dbFile string) string {
+	return dbFile + "-backup"
+}
+
 // Close will close the database and, if requested, make a copy of the database
 // to the backup location.
 func (vdb *VspDatabase) Close(writeBackup bool) {
@@ -246,7 +329,7 @@ func (vdb *VspDatabase) Close(writeBackup bool) {
 	}
 
 	// Ensure the database backup file is up-to-date.
-	backupPath := dbPath + "-backup"
+	backupPath := BackupFile(dbPath)
 	tempPath := backupPath + "~"
 
 	backupMtx.Lock()
@@ -296,7 +379,12 @@ func (vdb *VspDatabase) KeyPair() (ed25519.PrivateKey, ed25519.PublicKey, error)
 
 		if seed == nil {
 			// should not happen
-			return fmt.Errorf("no private key found")
+			return ErrNoSigningKey
+		}
+
+		if len(seed) != ed25519.SeedSize {
+			return fmt.Errorf("signing key has invalid length %d, expected %d",
+				len(seed), ed25519.SeedSize)
 		}
 
 		return nil
@@ -316,6 +404,27 @@ func (vdb *VspDatabase) KeyPair() (ed25519.PrivateKey, ed25519.PublicKey, error)
 	return signKey, pubKey, err
 }
 
+// GenerateKeyPair generates a new keypair used to sign API responses and
+// stores it in the database. An error is returned if the database already
+// contains a signing key, because replacing it would invalidate all signatures
+// previously provided to clients.
+func (vdb *VspDatabase) GenerateKeyPair() error {
+	_, signKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	return vdb.db.Update(func(tx *bolt.Tx) error {
+		vspBkt := tx.Bucket(vspBktK)
+
+		if vspBkt.Get(privateKeyK) != nil {
+			return errors.New("database already contains a signing key")
+		}
+
+		return vspBkt.Put(privateKeyK, signKey.Seed())
+	})
+}
+
 // CookieSecret retrieves the generated cookie store secret key from the
 // database.
 func (vdb *VspDatabase) CookieSecret() ([]byte, error) {
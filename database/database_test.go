@@ -6,22 +6,26 @@ package database
 
 import (
 	"crypto/ed25519"
+	"errors"
 	"io"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
 
 	"github.com/decred/slog"
+	bolt "go.etcd.io/bbolt"
 )
 
 const (
 	testDb               = "test.db"
 	feeXPub              = "feexpub"
 	maxVoteChangeRecords = 3
+	ticketCacheSize      = 2
 
 	// addrCharset is a list of all valid DCR address characters.
 	addrCharset = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
@@ -70,21 +74,42 @@ func TestDatabase(t *testing.T) {
 
 	// All sub-tests to run.
 	tests := map[string]func(*testing.T){
-		"testCreateNew":         testCreateNew,
-		"testInsertNewTicket":   testInsertNewTicket,
-		"testGetTicketByHash":   testGetTicketByHash,
-		"testUpdateTicket":      testUpdateTicket,
-		"testTicketFeeExpired":  testTicketFeeExpired,
-		"testFilterTickets":     testFilterTickets,
-		"testCountTickets":      testCountTickets,
-		"testFeeXPub":           testFeeXPub,
-		"testRetireFeeXPub":     testRetireFeeXPub,
-		"testDeleteTicket":      testDeleteTicket,
-		"testVoteChangeRecords": testVoteChangeRecords,
-		"testHTTPBackup":        testHTTPBackup,
-		"testAltSignAddrData":   testAltSignAddrData,
-		"testInsertAltSignAddr": testInsertAltSignAddr,
-		"testDeleteAltSignAddr": testDeleteAltSignAddr,
+		"testCreateNew":                             testCreateNew,
+		"testGenerateKeyPair":                       testGenerateKeyPair,
+		"testInsertNewTicket":                       testInsertNewTicket,
+		"testGetTicketByHash":                       testGetTicketByHash,
+		"testUpdateTicket":                          testUpdateTicket,
+		"testTicketFeeExpired":                      testTicketFeeExpired,
+		"testFilterTickets":                         testFilterTickets,
+		"testCountTickets":                          testCountTickets,
+		"testCountVoteChoices":                      testCountVoteChoices,
+		"testGetPendingFees":                        testGetPendingFees,
+		"testGetExpiringFees":                       testGetExpiringFees,
+		"testGetReimportTickets":                    testGetReimportTickets,
+		"testCountActiveTicketsByCommitmentAddress": testCountActiveTicketsByCommitmentAddress,
+		"testCountReservedTickets":                  testCountReservedTickets,
+		"testCountFeeStatuses":                      testCountFeeStatuses,
+		"testCountRegistrationsByDay":               testCountRegistrationsByDay,
+		"testFeeAddressTickets":                     testFeeAddressTickets,
+		"testFeeXPub":                               testFeeXPub,
+		"testRetireFeeXPub":                         testRetireFeeXPub,
+		"testRetiredXPubTickets":                    testRetiredXPubTickets,
+		"testRecoverLastAddressIndexes":             testRecoverLastAddressIndexes,
+		"testFeesByXPub":                            testFeesByXPub,
+		"testOrphanedFeeAddresses":                  testOrphanedFeeAddresses,
+		"testFeeAddressIndex":                       testFeeAddressIndex,
+		"testCommitmentAddressIndex":                testCommitmentAddressIndex,
+		"testApprovers":                             testApprovers,
+		"testPendingAction":                         testPendingAction,
+		"testDeleteTicket":                          testDeleteTicket,
+		"testTicketCache":                           testTicketCache,
+		"testVoteChangeRecords":                     testVoteChangeRecords,
+		"testVoteChangeHistory":                     testVoteChangeHistory,
+		"testHTTPBackup":                            testHTTPBackup,
+		"testSnapshots":                             testSnapshots,
+		"testAltSignAddrData":                       testAltSignAddrData,
+		"testInsertAltSignAddr":                     testInsertAltSignAddr,
+		"testDeleteAltSignAddr":                     testDeleteAltSignAddr,
 	}
 
 	log := stdoutLogger()
@@ -98,7 +123,7 @@ func TestDatabase(t *testing.T) {
 		}
 
 		// Open the newly created database so it is ready to use.
-		db, err = Open(testDb, log, maxVoteChangeRecords)
+		db, err = Open(testDb, log, maxVoteChangeRecords, ticketCacheSize)
 		if err != nil {
 			t.Fatalf("error opening test database: %v", err)
 		}
@@ -137,6 +162,58 @@ func testCreateNew(t *testing.T) {
 	}
 }
 
+func testGenerateKeyPair(t *testing.T) {
+	// A new keypair must not replace an existing one.
+	err := db.GenerateKeyPair()
+	if err == nil {
+		t.Fatal("expected an error generating keypair when one already exists")
+	}
+
+	// Remove the keypair from the database.
+	err = db.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(vspBktK).Delete(privateKeyK)
+	})
+	if err != nil {
+		t.Fatalf("error deleting keypair: %v", err)
+	}
+
+	_, _, err = db.KeyPair()
+	if !errors.Is(err, ErrNoSigningKey) {
+		t.Fatalf("expected ErrNoSigningKey, got %v", err)
+	}
+
+	// Generate a new keypair and ensure it can be retrieved.
+	err = db.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("error generating keypair: %v", err)
+	}
+
+	priv, pub, err := db.KeyPair()
+	if err != nil {
+		t.Fatalf("error getting keypair: %v", err)
+	}
+
+	msg := []byte("msg")
+	sig := ed25519.Sign(priv, msg)
+	if !ed25519.Verify(pub, msg, sig) {
+		t.Fatalf("generated keypair could not be used to sign/verify a message")
+	}
+
+	// A keypair with an invalid length should return an error rather than
+	// being used.
+	err = db.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(vspBktK).Put(privateKeyK, []byte{1, 2, 3})
+	})
+	if err != nil {
+		t.Fatalf("error storing keypair: %v", err)
+	}
+
+	_, _, err = db.KeyPair()
+	if err == nil {
+		t.Fatal("expected an error for signing key with invalid length")
+	}
+}
+
 func testHTTPBackup(t *testing.T) {
 	// Capture the HTTP response written by the backup func.
 	rr := httptest.NewRecorder()
@@ -187,3 +264,47 @@ func testHTTPBackup(t *testing.T) {
 			cLength, len(body))
 	}
 }
+
+// TestOpenLocked ensures ErrLocked is returned when opening a database which is
+// already open, and that a read-only open succeeds once it is closed.
+func TestOpenLocked(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), testDb)
+
+	err := CreateNew(dbFile, feeXPub)
+	if err != nil {
+		t.Fatalf("error creating test database: %v", err)
+	}
+
+	vdb, err := Open(dbFile, slog.Disabled, maxVoteChangeRecords, ticketCacheSize)
+	if err != nil {
+		t.Fatalf("error opening test database: %v", err)
+	}
+
+	_, err = Open(dbFile, slog.Disabled, maxVoteChangeRecords, ticketCacheSize)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked from Open, got %v", err)
+	}
+
+	_, err = OpenReadOnly(dbFile, slog.Disabled, ticketCacheSize)
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked from OpenReadOnly, got %v", err)
+	}
+
+	vdb.Close(false)
+
+	vdb, err = OpenReadOnly(dbFile, slog.Disabled, ticketCacheSize)
+	if err != nil {
+		t.Fatalf("error opening test database read-only: %v", err)
+	}
+	defer vdb.Close(false)
+
+	_, err = vdb.FeeXPub()
+	if err != nil {
+		t.Fatalf("error reading read-only database: %v", err)
+	}
+
+	err = vdb.InsertNewTicket(exampleTicket())
+	if err == nil {
+		t.Fatal("expected error writing to read-only database")
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"bytes"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// feeAddrIndexKey returns the key of the entry in the fee address index which
+// records that address was issued to the ticket with the provided hash. Keys
+// are prefixed with the address so all tickets which an address was issued to
+// can be found with a single cursor seek.
+func feeAddrIndexKey(address, ticketHash string) []byte {
+	return []byte(address + "/" + ticketHash)
+}
+
+// indexFeeAddress adds an entry to the fee address index recording that
+// address was issued to the ticket with the provided hash.
+func indexFeeAddress(tx *bolt.Tx, address, ticketHash string) error {
+	if address == "" {
+		return nil
+	}
+
+	err := tx.Bucket(vspBktK).Bucket(feeAddrIndexBktK).Put(feeAddrIndexKey(address, ticketHash), nil)
+	if err != nil {
+		return fmt.Errorf("could not index fee address: %w", err)
+	}
+
+	return nil
+}
+
+// unindexFeeAddress removes the entry from the fee address index which records
+// that address was issued to the ticket with the provided hash.
+func unindexFeeAddress(tx *bolt.Tx, address, ticketHash string) error {
+	if address == "" {
+		return nil
+	}
+
+	err := tx.Bucket(vspBktK).Bucket(feeAddrIndexBktK).Delete(feeAddrIndexKey(address, ticketHash))
+	if err != nil {
+		return fmt.Errorf("could not remove fee address from index: %w", err)
+	}
+
+	return nil
+}
+
+// feeAddressInUse reports whether address has been issued to any ticket in the
+// database.
+func feeAddressInUse(tx *bolt.Tx, address string) bool {
+	prefix := []byte(address + "/")
+	k, _ := tx.Bucket(vspBktK).Bucket(feeAddrIndexBktK).Cursor().Seek(prefix)
+	return k != nil && bytes.HasPrefix(k, prefix)
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func testFeeAddressIndex(t *testing.T) {
+	inUse := func(address string) bool {
+		var found bool
+		err := db.db.View(func(tx *bolt.Tx) error {
+			found = feeAddressInUse(tx, address)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("error reading fee address index: %v", err)
+		}
+		return found
+	}
+
+	ticket := exampleTicket()
+	err := db.InsertNewTicket(ticket)
+	if err != nil {
+		t.Fatalf("error storing ticket in database: %v", err)
+	}
+
+	if !inUse(ticket.FeeAddress) {
+		t.Fatal("expected fee address of inserted ticket to be in use")
+	}
+
+	// A prefix of an address in use must not be reported as in use.
+	if inUse(ticket.FeeAddress[:len(ticket.FeeAddress)-1]) {
+		t.Fatal("expected prefix of fee address not to be in use")
+	}
+
+	// Changing the fee address of the ticket should update the index.
+	oldAddress := ticket.FeeAddress
+	ticket.FeeAddress = randString(35, addrCharset)
+	err = db.UpdateTicket(ticket)
+	if err != nil {
+		t.Fatalf("error updating ticket: %v", err)
+	}
+
+	if inUse(oldAddress) {
+		t.Fatal("expected previous fee address not to be in use")
+	}
+	if !inUse(ticket.FeeAddress) {
+		t.Fatal("expected new fee address to be in use")
+	}
+
+	// Deleting the ticket should remove its fee address from the index.
+	err = db.DeleteTicket(ticket)
+	if err != nil {
+		t.Fatalf("error deleting ticket: %v", err)
+	}
+
+	if inUse(ticket.FeeAddress) {
+		t.Fatal("expected fee address of deleted ticket not to be in use")
+	}
+}
@@ -66,7 +66,9 @@ func (vdb *VspDatabase) FeeXPub() (FeeXPub, error) {
 }
 
 // RetireXPub will mark the currently active xpub key as retired and insert the
-// provided pubkey as the currently active one.
+// provided pubkey as the currently active one. It must not be called while vspd
+// is running, because vspd caches the current xpub when it starts and would
+// continue deriving fee addresses from the retired key.
 func (vdb *VspDatabase) RetireXPub(xpub string) error {
 	// Ensure the new xpub has never been used before.
 	xpubs, err := vdb.AllXPubs()
@@ -142,16 +144,136 @@ func (vdb *VspDatabase) AllXPubs() (map[uint32]FeeXPub, error) {
 }
 
 // SetLastAddressIndex updates the last index used to derive a new fee address
-// from the fee xpub key.
-func (vdb *VspDatabase) SetLastAddressIndex(idx uint32) error {
+// from the fee xpub key. An error is returned if the key with the provided ID
+// is not the currently active key, which ensures new fee addresses are never
+// derived from a retired key.
+func (vdb *VspDatabase) SetLastAddressIndex(xpubID, idx uint32) error {
 	current, err := vdb.FeeXPub()
 	if err != nil {
 		return err
 	}
 
+	if current.ID != xpubID {
+		return fmt.Errorf("xpub with ID %d is not the active xpub (active ID %d)",
+			xpubID, current.ID)
+	}
+
 	current.LastUsedIdx = idx
 
 	return vdb.db.Update(func(tx *bolt.Tx) error {
 		return insertFeeXPub(tx, current)
 	})
 }
+
+// RecoverLastAddressIndexes scans the fee addresses of all tickets to find the
+// highest derivation index which has been used with each xpub key. Any xpub
+// with a stored last used index lower than this is updated, which prevents fee
+// addresses being reused after restoring a database backup which was slightly
+// out of date. The updated xpubs are returned, keyed by ID.
+func (vdb *VspDatabase) RecoverLastAddressIndexes() (map[uint32]FeeXPub, error) {
+	updated := make(map[uint32]FeeXPub)
+
+	err := vdb.db.Update(func(tx *bolt.Tx) error {
+		vspBkt := tx.Bucket(vspBktK)
+
+		xpubBkt := vspBkt.Bucket(xPubBktK)
+		if xpubBkt == nil {
+			return fmt.Errorf("%s bucket doesn't exist", string(xPubBktK))
+		}
+
+		// Find the highest index used by tickets for each xpub.
+		highest := make(map[uint32]uint32)
+		err := vspBkt.Bucket(ticketBktK).ForEach(func(k, _ []byte) error {
+			ticketBkt := vspBkt.Bucket(ticketBktK).Bucket(k)
+			id := bytesToUint32(ticketBkt.Get(feeAddressXPubIDK))
+			idx := bytesToUint32(ticketBkt.Get(feeAddressIndexK))
+			if current, ok := highest[id]; !ok || idx > current {
+				highest[id] = idx
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error iterating over %s bucket: %w", string(ticketBktK), err)
+		}
+
+		for id, idx := range highest {
+			xpubBytes := xpubBkt.Get(uint32ToBytes(id))
+			if xpubBytes == nil {
+				return fmt.Errorf("tickets reference unknown xpub with ID %d", id)
+			}
+
+			var xpub FeeXPub
+			err := json.Unmarshal(xpubBytes, &xpub)
+			if err != nil {
+				return fmt.Errorf("could not unmarshal xpub key: %w", err)
+			}
+
+			if xpub.LastUsedIdx >= idx {
+				continue
+			}
+
+			xpub.LastUsedIdx = idx
+			err = insertFeeXPub(tx, xpub)
+			if err != nil {
+				return err
+			}
+
+			updated[id] = xpub
+		}
+
+		return nil
+	})
+
+	return updated, err
+}
+
+// XPubFees is the total of the fees collected from tickets which were issued a
+// fee address derived from a single xpub key.
+type XPubFees struct {
+	// Tickets is the number of tickets with a confirmed fee tx.
+	Tickets int64
+	// Fees is the total amount in atoms paid by the confirmed fee txs,
+	// including any recorded surplus, less any recorded refunds.
+	Fees int64
+	// Refunds is the total amount in atoms refunded to the tickets.
+	Refunds int64
+}
+
+// FeesByXPub returns the fees collected from tickets with a confirmed fee tx,
+// keyed by the ID of the xpub which their fee address was derived from. Xpubs
+// which have not collected any fees are not included. This func iterates over
+// every ticket so should be used sparingly.
+func (vdb *VspDatabase) FeesByXPub() (map[uint32]XPubFees, error) {
+	fees := make(map[uint32]XPubFees)
+	err := vdb.db.View(func(tx *bolt.Tx) error {
+		ticketBkt := tx.Bucket(vspBktK).Bucket(ticketBktK)
+
+		return ticketBkt.ForEachBucket(func(k []byte) error {
+			tBkt := ticketBkt.Bucket(k)
+
+			if FeeStatus(tBkt.Get(feeTxStatusK)) != FeeConfirmed {
+				return nil
+			}
+
+			id := bytesToUint32(tBkt.Get(feeAddressXPubIDK))
+			xpubFees := fees[id]
+			xpubFees.Tickets++
+			xpubFees.Fees += bytesToInt64(tBkt.Get(feeAmountK))
+			// FeeSurplus was added without a database upgrade.
+			if surplusBytes := tBkt.Get(feeSurplusK); surplusBytes != nil {
+				xpubFees.Fees += bytesToInt64(surplusBytes)
+			}
+			// RefundAmount was also added without a database upgrade.
+			if refundBytes := tBkt.Get(refundAmountK); refundBytes != nil {
+				refund := bytesToInt64(refundBytes)
+				xpubFees.Fees -= refund
+				xpubFees.Refunds += refund
+			}
+			fees[id] = xpubFees
+
+			return nil
+		})
+	})
+
+	return fees, err
+}
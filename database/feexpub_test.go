@@ -5,6 +5,7 @@
 package database
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -32,7 +33,7 @@ func testFeeXPub(t *testing.T) {
 
 	// Update address index.
 	idx := uint32(99)
-	err = db.SetLastAddressIndex(idx)
+	err = db.SetLastAddressIndex(0, idx)
 	if err != nil {
 		t.Fatalf("error setting address index: %v", err)
 	}
@@ -61,7 +62,7 @@ func testFeeXPub(t *testing.T) {
 func testRetireFeeXPub(t *testing.T) {
 	// Increment the last used index to simulate some usage.
 	idx := uint32(99)
-	err := db.SetLastAddressIndex(idx)
+	err := db.SetLastAddressIndex(0, idx)
 	if err != nil {
 		t.Fatalf("error setting address index: %v", err)
 	}
@@ -114,4 +115,157 @@ func testRetireFeeXPub(t *testing.T) {
 	if xpubs[0].Retired == 0 {
 		t.Fatalf("old xpub retired field not set")
 	}
+
+	// The last used index of the retired xpub can no longer be updated, but
+	// the new xpub can be.
+	err = db.SetLastAddressIndex(0, idx+1)
+	if err == nil {
+		t.Fatal("expected an error setting address index of retired xpub")
+	}
+	err = db.SetLastAddressIndex(1, 1)
+	if err != nil {
+		t.Fatalf("error setting address index: %v", err)
+	}
+}
+
+func testRetiredXPubTickets(t *testing.T) {
+	// Insert a ticket with a fee address derived from the current xpub.
+	ticket := exampleTicket()
+	ticket.FeeAddressXPubID = 0
+	ticket.FeeTxStatus = NoFee
+	err := db.InsertNewTicket(ticket)
+	if err != nil {
+		t.Fatalf("error storing ticket in database: %v", err)
+	}
+
+	err = db.RetireXPub("feexpub2")
+	if err != nil {
+		t.Fatalf("retiring xpub failed: %v", err)
+	}
+
+	// A registration which was in-flight when the xpub was retired must still
+	// be able to complete, so the ticket must still reference its original
+	// fee address and xpub, and must still be updatable.
+	retrieved, found, err := db.GetTicketByHash(ticket.Hash)
+	if err != nil {
+		t.Fatalf("error retrieving ticket by ticket hash: %v", err)
+	}
+	if !found {
+		t.Fatal("expected found==true")
+	}
+	if retrieved.FeeAddress != ticket.FeeAddress || retrieved.FeeAddressXPubID != 0 {
+		t.Fatalf("ticket fee address changed after xpub retirement: %+v", retrieved)
+	}
+
+	retrieved.FeeTxStatus = FeeReceieved
+	err = db.UpdateTicket(retrieved)
+	if err != nil {
+		t.Fatalf("error updating ticket: %v", err)
+	}
+}
+
+func testRecoverLastAddressIndexes(t *testing.T) {
+	// Insert tickets using the current xpub, with an index higher than the
+	// stored last used index.
+	for _, idx := range []uint32{5, 12, 7} {
+		ticket := exampleTicket()
+		ticket.FeeAddressXPubID = 0
+		ticket.FeeAddressIndex = idx
+		err := db.InsertNewTicket(ticket)
+		if err != nil {
+			t.Fatalf("error storing ticket in database: %v", err)
+		}
+	}
+
+	updated, err := db.RecoverLastAddressIndexes()
+	if err != nil {
+		t.Fatalf("error recovering address indexes: %v", err)
+	}
+	if len(updated) != 1 || updated[0].LastUsedIdx != 12 {
+		t.Fatalf("expected xpub 0 to be updated to index 12, got %+v", updated)
+	}
+
+	retrievedXPub, err := db.FeeXPub()
+	if err != nil {
+		t.Fatalf("error getting fee xpub: %v", err)
+	}
+	if retrievedXPub.LastUsedIdx != 12 {
+		t.Fatalf("expected xpub last used 12, got %d", retrievedXPub.LastUsedIdx)
+	}
+
+	// Running again should not update anything.
+	updated, err = db.RecoverLastAddressIndexes()
+	if err != nil {
+		t.Fatalf("error recovering address indexes: %v", err)
+	}
+	if len(updated) != 0 {
+		t.Fatalf("expected no xpubs to be updated, got %+v", updated)
+	}
+
+	// An index which is already higher than any used by tickets should never
+	// be lowered.
+	err = db.SetLastAddressIndex(0, 20)
+	if err != nil {
+		t.Fatalf("error setting address index: %v", err)
+	}
+	updated, err = db.RecoverLastAddressIndexes()
+	if err != nil {
+		t.Fatalf("error recovering address indexes: %v", err)
+	}
+	if len(updated) != 0 {
+		t.Fatalf("expected no xpubs to be updated, got %+v", updated)
+	}
+
+	// Tickets referencing an unknown xpub should cause an error.
+	ticket := exampleTicket()
+	ticket.FeeAddressXPubID = 99
+	err = db.InsertNewTicket(ticket)
+	if err != nil {
+		t.Fatalf("error storing ticket in database: %v", err)
+	}
+	_, err = db.RecoverLastAddressIndexes()
+	if err == nil {
+		t.Fatal("expected an error for ticket with unknown xpub")
+	}
+}
+
+func testFeesByXPub(t *testing.T) {
+	insert := func(xpubID uint32, status FeeStatus, amount, surplus, refund int64) {
+		t.Helper()
+		ticket := exampleTicket()
+		ticket.FeeAddressXPubID = xpubID
+		ticket.FeeTxStatus = status
+		ticket.FeeAmount = amount
+		ticket.FeeSurplus = surplus
+		ticket.RefundAmount = refund
+		if refund == 0 {
+			ticket.RefundTxHash = ""
+		}
+		err := db.InsertNewTicket(ticket)
+		if err != nil {
+			t.Fatalf("error storing ticket in database: %v", err)
+		}
+	}
+
+	insert(0, FeeConfirmed, 1000, 0, 0)
+	insert(0, FeeConfirmed, 2000, 500, 0)
+	insert(1, FeeConfirmed, 3000, 0, 0)
+	// Refunds are excluded from the fees collected.
+	insert(1, FeeConfirmed, 6000, 0, 2500)
+	// Tickets without a confirmed fee tx have not paid any fees.
+	insert(0, FeeBroadcast, 4000, 0, 0)
+	insert(2, NoFee, 5000, 0, 0)
+
+	fees, err := db.FeesByXPub()
+	if err != nil {
+		t.Fatalf("error getting fees by xpub: %v", err)
+	}
+
+	expected := map[uint32]XPubFees{
+		0: {Tickets: 2, Fees: 3500},
+		1: {Tickets: 2, Fees: 6500, Refunds: 2500},
+	}
+	if !reflect.DeepEqual(fees, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, fees)
+	}
 }
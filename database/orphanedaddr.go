@@ -0,0 +1,153 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// OrphanedFeeAddress is serialized to json and stored in bbolt db. It describes
+// a fee address which was issued to a ticket that was never mined and never
+// received a fee payment.
+type OrphanedFeeAddress struct {
+	Address string `json:"addr"`
+	XPubID  uint32 `json:"xpubid"`
+	Index   uint32 `json:"idx"`
+	// Height is the height of the best block at the time the address was
+	// orphaned.
+	Height int64 `json:"height"`
+	// Verified is set once a chain scan has confirmed that the address has
+	// never received a payment. Only verified addresses are recycled.
+	Verified bool `json:"verified"`
+}
+
+// StoreOrphanedFeeAddress inserts or updates the provided orphaned fee address.
+func (vdb *VspDatabase) StoreOrphanedFeeAddress(addr OrphanedFeeAddress) error {
+	return vdb.db.Update(func(tx *bolt.Tx) error {
+		bkt, err := tx.Bucket(vspBktK).CreateBucketIfNotExists(orphanedAddrBktK)
+		if err != nil {
+			return fmt.Errorf("failed to get %s bucket: %w", string(orphanedAddrBktK), err)
+		}
+
+		addrBytes, err := json.Marshal(addr)
+		if err != nil {
+			return fmt.Errorf("could not marshal orphaned fee address: %w", err)
+		}
+
+		err = bkt.Put([]byte(addr.Address), addrBytes)
+		if err != nil {
+			return fmt.Errorf("could not store orphaned fee address: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// DeleteOrphanedFeeAddress removes the provided address from the database so it
+// will never be recycled. Does not error if the address is not present.
+func (vdb *VspDatabase) DeleteOrphanedFeeAddress(address string) error {
+	return vdb.db.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(vspBktK).Bucket(orphanedAddrBktK)
+		if bkt == nil {
+			return nil
+		}
+
+		return bkt.Delete([]byte(address))
+	})
+}
+
+// OrphanedFeeAddresses retrieves all orphaned fee addresses from the database.
+func (vdb *VspDatabase) OrphanedFeeAddresses() ([]OrphanedFeeAddress, error) {
+	var addrs []OrphanedFeeAddress
+
+	err := vdb.db.View(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket(vspBktK).Bucket(orphanedAddrBktK)
+		if bkt == nil {
+			return nil
+		}
+
+		return bkt.ForEach(func(_, v []byte) error {
+			var addr OrphanedFeeAddress
+			err := json.Unmarshal(v, &addr)
+			if err != nil {
+				return fmt.Errorf("could not unmarshal orphaned fee address: %w", err)
+			}
+
+			addrs = append(addrs, addr)
+			return nil
+		})
+	})
+
+	return addrs, err
+}
+
+// TakeRecycledFeeAddress removes a verified orphaned fee address derived from
+// the xpub with the provided ID from the database and returns it, so that it
+// can be issued to a new ticket. The final return value is false if no such
+// address is available. Orphaned addresses which the fee address index shows
+// are in use by any ticket in the database are discarded rather than returned.
+func (vdb *VspDatabase) TakeRecycledFeeAddress(xpubID uint32) (OrphanedFeeAddress, bool, error) {
+	var taken OrphanedFeeAddress
+	var found bool
+
+	err := vdb.db.Update(func(tx *bolt.Tx) error {
+		vspBkt := tx.Bucket(vspBktK)
+
+		bkt := vspBkt.Bucket(orphanedAddrBktK)
+		if bkt == nil {
+			return nil
+		}
+
+		// Keys cannot be deleted while iterating over a bucket, so record which
+		// need deleting and remove them afterwards.
+		var toDelete [][]byte
+		err := bkt.ForEach(func(k, v []byte) error {
+			if found {
+				return nil
+			}
+
+			if feeAddressInUse(tx, string(k)) {
+				toDelete = append(toDelete, bytes.Clone(k))
+				return nil
+			}
+
+			var addr OrphanedFeeAddress
+			err := json.Unmarshal(v, &addr)
+			if err != nil {
+				return fmt.Errorf("could not unmarshal orphaned fee address: %w", err)
+			}
+
+			if !addr.Verified || addr.XPubID != xpubID {
+				return nil
+			}
+
+			taken = addr
+			found = true
+			toDelete = append(toDelete, bytes.Clone(k))
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error iterating over %s bucket: %w", string(orphanedAddrBktK), err)
+		}
+
+		for _, k := range toDelete {
+			err = bkt.Delete(k)
+			if err != nil {
+				return fmt.Errorf("could not delete orphaned fee address: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return OrphanedFeeAddress{}, false, err
+	}
+
+	return taken, found, nil
+}
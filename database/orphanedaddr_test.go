@@ -0,0 +1,115 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"testing"
+)
+
+func testOrphanedFeeAddresses(t *testing.T) {
+	// Nothing should be returned before any addresses are stored.
+	_, found, err := db.TakeRecycledFeeAddress(1)
+	if err != nil {
+		t.Fatalf("error taking recycled fee address: %v", err)
+	}
+	if found {
+		t.Fatal("expected no recycled fee address from empty db")
+	}
+
+	unverified := OrphanedFeeAddress{
+		Address: randString(35, addrCharset),
+		XPubID:  1,
+		Index:   5,
+	}
+	otherXPub := OrphanedFeeAddress{
+		Address:  randString(35, addrCharset),
+		XPubID:   2,
+		Index:    6,
+		Verified: true,
+	}
+	inUse := OrphanedFeeAddress{
+		Address:  randString(35, addrCharset),
+		XPubID:   1,
+		Index:    7,
+		Verified: true,
+	}
+	for _, addr := range []OrphanedFeeAddress{unverified, otherXPub, inUse} {
+		err = db.StoreOrphanedFeeAddress(addr)
+		if err != nil {
+			t.Fatalf("error storing orphaned fee address: %v", err)
+		}
+	}
+
+	// Issue one of the addresses to a ticket.
+	ticket := exampleTicket()
+	ticket.FeeAddress = inUse.Address
+	err = db.InsertNewTicket(ticket)
+	if err != nil {
+		t.Fatalf("error storing ticket in database: %v", err)
+	}
+
+	// Unverified addresses, addresses from other xpubs and addresses in use by
+	// tickets must never be returned.
+	_, found, err = db.TakeRecycledFeeAddress(1)
+	if err != nil {
+		t.Fatalf("error taking recycled fee address: %v", err)
+	}
+	if found {
+		t.Fatal("expected no recycled fee address")
+	}
+
+	// The address in use by a ticket should have been discarded.
+	addrs, err := db.OrphanedFeeAddresses()
+	if err != nil {
+		t.Fatalf("error retrieving orphaned fee addresses: %v", err)
+	}
+	if len(addrs) != 2 {
+		t.Fatalf("expected 2 orphaned fee addresses, got %d", len(addrs))
+	}
+	for _, addr := range addrs {
+		if addr.Address == inUse.Address {
+			t.Fatal("expected address in use by ticket to be discarded")
+		}
+	}
+
+	// Once verified, the address can be taken exactly once.
+	unverified.Verified = true
+	err = db.StoreOrphanedFeeAddress(unverified)
+	if err != nil {
+		t.Fatalf("error storing orphaned fee address: %v", err)
+	}
+
+	taken, found, err := db.TakeRecycledFeeAddress(1)
+	if err != nil {
+		t.Fatalf("error taking recycled fee address: %v", err)
+	}
+	if !found {
+		t.Fatal("expected a recycled fee address")
+	}
+	if taken != unverified {
+		t.Fatalf("expected %+v, got %+v", unverified, taken)
+	}
+
+	_, found, err = db.TakeRecycledFeeAddress(1)
+	if err != nil {
+		t.Fatalf("error taking recycled fee address: %v", err)
+	}
+	if found {
+		t.Fatal("expected recycled fee address to only be returned once")
+	}
+
+	// Deleted addresses are no longer returned.
+	err = db.DeleteOrphanedFeeAddress(otherXPub.Address)
+	if err != nil {
+		t.Fatalf("error deleting orphaned fee address: %v", err)
+	}
+	addrs, err = db.OrphanedFeeAddresses()
+	if err != nil {
+		t.Fatalf("error retrieving orphaned fee addresses: %v", err)
+	}
+	if len(addrs) != 0 {
+		t.Fatalf("expected no orphaned fee addresses, got %d", len(addrs))
+	}
+}
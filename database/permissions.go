@@ -0,0 +1,42 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// permissiveBits are the permission bits which allow users other than the
+// owner to access a file or directory.
+const permissiveBits = 0077
+
+// CheckPermissions returns an error if the database file, or the directory
+// containing it, is accessible by users other than its owner. The database
+// contains the voting keys of every ticket, so it should only ever be readable
+// by the user running vspd. The check is skipped on Windows, where file
+// permissions are not represented by mode bits.
+func CheckPermissions(dbFile string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	for _, path := range []string{dbFile, filepath.Dir(dbFile)} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		mode := info.Mode().Perm()
+		if mode&permissiveBits != 0 {
+			return fmt.Errorf("%s has permissions %#o which allow access by other users, "+
+				"expected no group or other permissions", path, mode)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCheckPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not checked on windows")
+	}
+
+	tests := map[string]struct {
+		dirMode   os.FileMode
+		fileMode  os.FileMode
+		expectErr bool
+	}{
+		"owner only":          {dirMode: 0700, fileMode: 0600, expectErr: false},
+		"world readable file": {dirMode: 0700, fileMode: 0644, expectErr: true},
+		"group readable file": {dirMode: 0700, fileMode: 0640, expectErr: true},
+		"world readable dir":  {dirMode: 0755, fileMode: 0600, expectErr: true},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "data")
+			err := os.Mkdir(dir, 0700)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dbFile := filepath.Join(dir, "vspd.db")
+			err = os.WriteFile(dbFile, nil, 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Set modes explicitly so they are not affected by umask.
+			if err := os.Chmod(dbFile, test.fileMode); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chmod(dir, test.dirMode); err != nil {
+				t.Fatal(err)
+			}
+
+			err = CheckPermissions(dbFile)
+			if test.expectErr != (err != nil) {
+				t.Fatalf("expected error=%t, got %v", test.expectErr, err)
+			}
+		})
+	}
+
+	// A missing file should return an error.
+	err := CheckPermissions(filepath.Join(t.TempDir(), "missing.db"))
+	if err == nil {
+		t.Fatal("expected an error for missing database file")
+	}
+}
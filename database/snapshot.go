@@ -0,0 +1,91 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// snapshotPrefix is the file name prefix of database snapshots.
+	snapshotPrefix = "vspd.db-snapshot-"
+	// snapshotTimeFormat is the format of the time included in the file names
+	// of database snapshots. Snapshot file names sort in the order they were
+	// written.
+	snapshotTimeFormat = "20060102T150405Z"
+)
+
+// WriteSnapshot writes a consistent copy of the database to a new file in dir,
+// named with the time it was written, while the database is still open. The
+// path of the new snapshot is returned.
+func (vdb *VspDatabase) WriteSnapshot(dir string, now time.Time) (string, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", fmt.Errorf("os.MkdirAll: %w", err)
+	}
+
+	snapshotPath := filepath.Join(dir, snapshotPrefix+now.UTC().Format(snapshotTimeFormat))
+	tempPath := snapshotPath + "~"
+
+	// Write snapshot to temporary file so incomplete snapshots are never
+	// mistaken for complete ones.
+	err = vdb.db.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(tempPath, backupFileMode)
+	})
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return "", fmt.Errorf("tx.CopyFile: %w", err)
+	}
+
+	err = os.Rename(tempPath, snapshotPath)
+	if err != nil {
+		return "", fmt.Errorf("os.Rename: %w", err)
+	}
+
+	return snapshotPath, nil
+}
+
+// PruneSnapshots deletes the oldest database snapshots in dir so that no more
+// than keep snapshots remain. The paths of the deleted snapshots are returned.
+func PruneSnapshots(dir string, keep int) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadDir: %w", err)
+	}
+
+	var snapshots []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, snapshotPrefix) &&
+			!strings.HasSuffix(name, "~") {
+			snapshots = append(snapshots, name)
+		}
+	}
+
+	if len(snapshots) <= keep {
+		return nil, nil
+	}
+
+	sort.Strings(snapshots)
+
+	var pruned []string
+	for _, name := range snapshots[:len(snapshots)-keep] {
+		path := filepath.Join(dir, name)
+		err = os.Remove(path)
+		if err != nil {
+			return pruned, fmt.Errorf("os.Remove: %w", err)
+		}
+		pruned = append(pruned, path)
+	}
+
+	return pruned, nil
+}
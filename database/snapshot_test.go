@@ -0,0 +1,71 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testSnapshots(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "snapshots")
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// Write four snapshots an hour apart.
+	var written []string
+	for i := 0; i < 4; i++ {
+		path, err := db.WriteSnapshot(dir, start.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatalf("error writing snapshot: %v", err)
+		}
+		written = append(written, path)
+	}
+
+	if filepath.Base(written[0]) != "vspd.db-snapshot-20240102T030405Z" {
+		t.Fatalf("unexpected snapshot name %s", filepath.Base(written[0]))
+	}
+
+	// Snapshots should be valid databases.
+	snapshot, err := Open(written[0], stdoutLogger(), maxVoteChangeRecords, ticketCacheSize)
+	if err != nil {
+		t.Fatalf("error opening snapshot: %v", err)
+	}
+	snapshot.Close(false)
+
+	// Unrelated files should never be pruned.
+	other := filepath.Join(dir, "other")
+	if err = os.WriteFile(other, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	pruned, err := PruneSnapshots(dir, 2)
+	if err != nil {
+		t.Fatalf("error pruning snapshots: %v", err)
+	}
+	if len(pruned) != 2 || pruned[0] != written[0] || pruned[1] != written[1] {
+		t.Fatalf("expected oldest two snapshots to be pruned, got %v", pruned)
+	}
+
+	for i, path := range written {
+		_, err := os.Stat(path)
+		if exists := err == nil; exists != (i >= 2) {
+			t.Fatalf("snapshot %d: expected exists=%t, got %v", i, i >= 2, err)
+		}
+	}
+	if _, err = os.Stat(other); err != nil {
+		t.Fatalf("unrelated file was removed: %v", err)
+	}
+
+	// Nothing should be pruned when within the limit.
+	pruned, err = PruneSnapshots(dir, 2)
+	if err != nil {
+		t.Fatalf("error pruning snapshots: %v", err)
+	}
+	if len(pruned) != 0 {
+		t.Fatalf("expected no snapshots to be pruned, got %v", pruned)
+	}
+}
@@ -1,10 +1,11 @@
-// Copyright (c) 2020-2023 The Decred developers
+// Copyright (c) 2020-2024 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
 package database
 
 import (
+	"bytes"
 	"fmt"
 	"sort"
 	"time"
@@ -64,6 +65,16 @@ var (
 	feeTxHashK         = []byte("FeeTxHash")
 	feeTxStatusK       = []byte("FeeTxStatus")
 	outcomeK           = []byte("Outcome")
+	deferFeeBroadcastK = []byte("DeferFeeBroadcast")
+	priorityK          = []byte("Priority")
+	notesK             = []byte("Notes")
+	spendingTxHashK    = []byte("SpendingTxHash")
+	registeredK        = []byte("Registered")
+	feeSurplusK        = []byte("FeeSurplus")
+	refundTxHashK      = []byte("RefundTxHash")
+	refundAmountK      = []byte("RefundAmount")
+	reimportWalletsK   = []byte("ReimportWallets")
+	votingWalletK      = []byte("VotingWallet")
 )
 
 type Ticket struct {
@@ -92,12 +103,67 @@ type Ticket struct {
 	FeeTxHex  string
 	FeeTxHash string
 
+	// FeeSurplus is the amount in atoms by which the fee tx overpaid FeeAmount.
+	// It is only recorded if the VSP is configured to do so.
+	FeeSurplus int64
+
 	// FeeTxStatus indicates the current state of the fee transaction.
 	FeeTxStatus FeeStatus
 
+	// DeferFeeBroadcast is set in /payfee if the client requested that the fee
+	// tx is not broadcast until it is triggered by /broadcastfee.
+	DeferFeeBroadcast bool
+
+	// Priority is set in /feeaddress if the client paid the priority fee to
+	// have throttled or dropped fee txs broadcast without waiting for a new
+	// block.
+	Priority bool
+
+	// Notes is freeform text which can be set by the VSP operator via the
+	// admin page. It is never exposed to clients.
+	Notes string
+
 	// Outcome is set once a ticket is either voted or revoked. An empty outcome
 	// indicates that a ticket is still votable.
 	Outcome TicketOutcome
+
+	// SpendingTxHash is the hash of the vote or revocation which spent the
+	// ticket. It is set along with Outcome, but is empty for tickets which
+	// were spent before vspd started recording it.
+	SpendingTxHash string
+
+	// VotingWallet is the voting wallet which recorded the vote which spent
+	// the ticket. It is only set for voted tickets, and is empty if no online
+	// voting wallet had recorded the vote when it was detected.
+	VotingWallet string
+
+	// Registered is the unix time at which the ticket was registered via
+	// /feeaddress. It is zero for tickets which were registered before vspd
+	// started recording it.
+	Registered int64
+
+	// RefundTxHash and RefundAmount are set by the VSP operator via the admin
+	// page to record that some or all of the fee paid by the ticket has been
+	// refunded, eg. because it could not vote due to a fault of the VSP.
+	// Refunds are excluded from the fees collected by the VSP.
+	RefundTxHash string
+	RefundAmount int64
+
+	// ReimportWallets maps the voting wallets which the voting key of the
+	// ticket could not be imported into to the error returned by the most
+	// recent attempt, so the import can be retried.
+	ReimportWallets map[string]string
+}
+
+// Revoked reports whether the ticket has been revoked, ie. it was either
+// missed or expired.
+func (t *Ticket) Revoked() bool {
+	switch t.Outcome {
+	case Expired, Missed, Revoked:
+		return true
+	default:
+		return false
+	}
 }
 
 type TicketList []Ticket
@@ -125,6 +191,25 @@ func (t TicketList) SortByPurchaseHeight() {
 	})
 }
 
+// SortPriorityFirst moves priority tickets to the start of the list, otherwise
+// preserving the existing order.
+func (t TicketList) SortPriorityFirst() {
+	sort.SliceStable(t, func(i, j int) bool {
+		return t[i].Priority && !t[j].Priority
+	})
+}
+
+// Priority returns only the priority tickets in the list.
+func (t TicketList) Priority() TicketList {
+	var priority TicketList
+	for _, ticket := range t {
+		if ticket.Priority {
+			priority = append(priority, ticket)
+		}
+	}
+	return priority
+}
+
 func (t *Ticket) FeeExpired() bool {
 	now := time.Now()
 	return now.After(time.Unix(t.FeeExpiration, 0))
@@ -133,6 +218,8 @@ func (t *Ticket) FeeExpired() bool {
 // InsertNewTicket will insert the provided ticket into the database. Returns an
 // error if either the ticket hash or fee address already exist.
 func (vdb *VspDatabase) InsertNewTicket(ticket Ticket) error {
+	defer vdb.ticketCache.invalidate(ticket.Hash)
+
 	return vdb.db.Update(func(tx *bolt.Tx) error {
 		ticketBkt := tx.Bucket(vspBktK).Bucket(ticketBktK)
 
@@ -148,7 +235,12 @@ func (vdb *VspDatabase) InsertNewTicket(ticket Ticket) error {
 			return fmt.Errorf("putting ticket in bucket failed: %w", err)
 		}
 
-		return nil
+		err = indexFeeAddress(tx, ticket.FeeAddress, ticket.Hash)
+		if err != nil {
+			return err
+		}
+
+		return indexCommitmentAddress(tx, ticket.CommitmentAddress, ticket.Hash)
 	})
 }
 
@@ -195,15 +287,45 @@ func putTicketInBucket(bkt *bolt.Bucket, ticket Ticket) error {
 	if err = bkt.Put(feeExpirationK, int64ToBytes(ticket.FeeExpiration)); err != nil {
 		return err
 	}
+	if err = bkt.Put(feeSurplusK, int64ToBytes(ticket.FeeSurplus)); err != nil {
+		return err
+	}
+	if err = bkt.Put(registeredK, int64ToBytes(ticket.Registered)); err != nil {
+		return err
+	}
+	if err = bkt.Put(refundTxHashK, []byte(ticket.RefundTxHash)); err != nil {
+		return err
+	}
+	if err = bkt.Put(refundAmountK, int64ToBytes(ticket.RefundAmount)); err != nil {
+		return err
+	}
 	if err = bkt.Put(confirmedK, boolToBytes(ticket.Confirmed)); err != nil {
 		return err
 	}
+	if err = bkt.Put(deferFeeBroadcastK, boolToBytes(ticket.DeferFeeBroadcast)); err != nil {
+		return err
+	}
+	if err = bkt.Put(priorityK, boolToBytes(ticket.Priority)); err != nil {
+		return err
+	}
+	if err = bkt.Put(notesK, []byte(ticket.Notes)); err != nil {
+		return err
+	}
+	if err = bkt.Put(spendingTxHashK, []byte(ticket.SpendingTxHash)); err != nil {
+		return err
+	}
+	if err = bkt.Put(votingWalletK, []byte(ticket.VotingWallet)); err != nil {
+		return err
+	}
 	if err = bkt.Put(tSpendPolicyK, stringMapToBytes(ticket.TSpendPolicy)); err != nil {
 		return err
 	}
 	if err = bkt.Put(treasuryPolicyK, stringMapToBytes(ticket.TreasuryPolicy)); err != nil {
 		return err
 	}
+	if err = bkt.Put(reimportWalletsK, stringMapToBytes(ticket.ReimportWallets)); err != nil {
+		return err
+	}
 
 	return bkt.Put(voteChoicesK, stringMapToBytes(ticket.VoteChoices))
 }
@@ -219,6 +341,10 @@ func getTicketFromBkt(bkt *bolt.Bucket) (Ticket, error) {
 	ticket.FeeTxHash = string(bkt.Get(feeTxHashK))
 	ticket.FeeTxStatus = FeeStatus(bkt.Get(feeTxStatusK))
 	ticket.Outcome = TicketOutcome(bkt.Get(outcomeK))
+	ticket.Notes = string(bkt.Get(notesK))
+	ticket.SpendingTxHash = string(bkt.Get(spendingTxHashK))
+	ticket.VotingWallet = string(bkt.Get(votingWalletK))
+	ticket.RefundTxHash = string(bkt.Get(refundTxHashK))
 
 	ticket.PurchaseHeight = bytesToInt64(bkt.Get(purchaseHeightK))
 	ticket.FeeAddressXPubID = bytesToUint32(bkt.Get(feeAddressXPubIDK))
@@ -228,6 +354,32 @@ func getTicketFromBkt(bkt *bolt.Bucket) (Ticket, error) {
 
 	ticket.Confirmed = bytesToBool(bkt.Get(confirmedK))
 
+	// DeferFeeBroadcast was added without a database upgrade, so it is not
+	// present for tickets which have not been updated since.
+	if deferBytes := bkt.Get(deferFeeBroadcastK); deferBytes != nil {
+		ticket.DeferFeeBroadcast = bytesToBool(deferBytes)
+	}
+
+	// Priority was also added without a database upgrade.
+	if priorityBytes := bkt.Get(priorityK); priorityBytes != nil {
+		ticket.Priority = bytesToBool(priorityBytes)
+	}
+
+	// FeeSurplus was also added without a database upgrade.
+	if surplusBytes := bkt.Get(feeSurplusK); surplusBytes != nil {
+		ticket.FeeSurplus = bytesToInt64(surplusBytes)
+	}
+
+	// Registered was also added without a database upgrade.
+	if registeredBytes := bkt.Get(registeredK); registeredBytes != nil {
+		ticket.Registered = bytesToInt64(registeredBytes)
+	}
+
+	// RefundAmount was also added without a database upgrade.
+	if refundBytes := bkt.Get(refundAmountK); refundBytes != nil {
+		ticket.RefundAmount = bytesToInt64(refundBytes)
+	}
+
 	var err error
 	ticket.VoteChoices, err = bytesToStringMap(bkt.Get(voteChoicesK))
 	if err != nil {
@@ -244,13 +396,35 @@ func getTicketFromBkt(bkt *bolt.Bucket) (Ticket, error) {
 		return ticket, fmt.Errorf("unmarshal TreasuryPolicy err: %w", err)
 	}
 
+	// ReimportWallets was added without a database upgrade. An empty map is
+	// returned for tickets which do not have it.
+	ticket.ReimportWallets, err = bytesToStringMap(bkt.Get(reimportWalletsK))
+	if err != nil {
+		return ticket, fmt.Errorf("unmarshal ReimportWallets err: %w", err)
+	}
+
 	return ticket, nil
 }
 
 func (vdb *VspDatabase) DeleteTicket(ticket Ticket) error {
+	defer vdb.ticketCache.invalidate(ticket.Hash)
+
 	return vdb.db.Update(func(tx *bolt.Tx) error {
 		ticketBkt := tx.Bucket(vspBktK).Bucket(ticketBktK)
 
+		// Remove the addresses stored for the ticket from the indexes, which
+		// may differ from the addresses of the provided ticket.
+		if bkt := ticketBkt.Bucket([]byte(ticket.Hash)); bkt != nil {
+			err := unindexFeeAddress(tx, string(bkt.Get(feeAddressK)), ticket.Hash)
+			if err != nil {
+				return err
+			}
+			err = unindexCommitmentAddress(tx, string(bkt.Get(commitmentAddressK)), ticket.Hash)
+			if err != nil {
+				return err
+			}
+		}
+
 		err := ticketBkt.DeleteBucket([]byte(ticket.Hash))
 		if err != nil {
 			return fmt.Errorf("could not delete ticket: %w", err)
@@ -261,6 +435,8 @@ func (vdb *VspDatabase) DeleteTicket(ticket Ticket) error {
 }
 
 func (vdb *VspDatabase) UpdateTicket(ticket Ticket) error {
+	defer vdb.ticketCache.invalidate(ticket.Hash)
+
 	return vdb.db.Update(func(tx *bolt.Tx) error {
 		ticketBkt := tx.Bucket(vspBktK).Bucket(ticketBktK)
 
@@ -270,13 +446,40 @@ func (vdb *VspDatabase) UpdateTicket(ticket Ticket) error {
 			return fmt.Errorf("ticket does not exist with hash %s", ticket.Hash)
 		}
 
+		if oldAddress := string(bkt.Get(feeAddressK)); oldAddress != ticket.FeeAddress {
+			err := unindexFeeAddress(tx, oldAddress, ticket.Hash)
+			if err != nil {
+				return err
+			}
+			err = indexFeeAddress(tx, ticket.FeeAddress, ticket.Hash)
+			if err != nil {
+				return err
+			}
+		}
+
+		if oldAddress := string(bkt.Get(commitmentAddressK)); oldAddress != ticket.CommitmentAddress {
+			err := unindexCommitmentAddress(tx, oldAddress, ticket.Hash)
+			if err != nil {
+				return err
+			}
+			err = indexCommitmentAddress(tx, ticket.CommitmentAddress, ticket.Hash)
+			if err != nil {
+				return err
+			}
+		}
+
 		return putTicketInBucket(bkt, ticket)
 	})
 }
 
+// GetTicketByHash retrieves the ticket with the provided hash, reading from the
+// ticket cache where possible.
 func (vdb *VspDatabase) GetTicketByHash(ticketHash string) (Ticket, bool, error) {
-	var ticket Ticket
-	var found bool
+	ticket, found, generation := vdb.ticketCache.get(ticketHash)
+	if found {
+		return ticket, true, nil
+	}
+
 	err := vdb.db.View(func(tx *bolt.Tx) error {
 		ticketBkt := tx.Bucket(vspBktK).Bucket(ticketBktK).Bucket([]byte(ticketHash))
 
@@ -295,6 +498,10 @@ func (vdb *VspDatabase) GetTicketByHash(ticketHash string) (Ticket, bool, error)
 		return nil
 	})
 
+	if err == nil && found {
+		vdb.ticketCache.add(ticket, generation)
+	}
+
 	return ticket, found, err
 }
 
@@ -345,6 +552,231 @@ func (vdb *VspDatabase) CountTickets() (int64, int64, int64, int64, error) {
 	return voting, voted, expired, missed, err
 }
 
+// CountFeeStatuses returns the number of tickets without an outcome (ie. not
+// expired/voted/missed) keyed by fee status. This func iterates over every
+// ticket so should be used sparingly.
+func (vdb *VspDatabase) CountFeeStatuses() (map[FeeStatus]int64, error) {
+	counts := make(map[FeeStatus]int64)
+	err := vdb.db.View(func(tx *bolt.Tx) error {
+		ticketBkt := tx.Bucket(vspBktK).Bucket(ticketBktK)
+
+		return ticketBkt.ForEachBucket(func(k []byte) error {
+			tBkt := ticketBkt.Bucket(k)
+
+			if TicketOutcome(tBkt.Get(outcomeK)) == "" {
+				counts[FeeStatus(tBkt.Get(feeTxStatusK))]++
+			}
+
+			return nil
+		})
+	})
+
+	return counts, err
+}
+
+// DailyRegistrations is the number of tickets registered on a single day.
+type DailyRegistrations struct {
+	// Day is midnight UTC at the start of the day.
+	Day     time.Time
+	Tickets int64
+}
+
+// CountRegistrationsByDay returns the number of tickets registered on each UTC
+// day from the day of from to the day of to inclusive, ordered by day. Days on
+// which no tickets were registered are included with a count of zero. The
+// number of tickets which have no recorded registration time is also returned.
+// This func iterates over every ticket so should be used sparingly.
+func (vdb *VspDatabase) CountRegistrationsByDay(from, to time.Time) ([]DailyRegistrations, int64, error) {
+	const day = 24 * time.Hour
+	first := from.UTC().Truncate(day)
+	last := to.UTC().Truncate(day)
+	if last.Before(first) {
+		return nil, 0, fmt.Errorf("end day %s is before start day %s",
+			last.Format(time.DateOnly), first.Format(time.DateOnly))
+	}
+
+	numDays := int(last.Sub(first)/day) + 1
+	counts := make([]DailyRegistrations, numDays)
+	for i := range counts {
+		counts[i].Day = first.Add(time.Duration(i) * day)
+	}
+
+	var undated int64
+	err := vdb.db.View(func(tx *bolt.Tx) error {
+		ticketBkt := tx.Bucket(vspBktK).Bucket(ticketBktK)
+
+		return ticketBkt.ForEachBucket(func(k []byte) error {
+			registeredBytes := ticketBkt.Bucket(k).Get(registeredK)
+			if registeredBytes == nil || bytesToInt64(registeredBytes) == 0 {
+				undated++
+				return nil
+			}
+
+			registered := time.Unix(bytesToInt64(registeredBytes), 0).UTC()
+			if registered.Before(first) || !registered.Before(last.Add(day)) {
+				return nil
+			}
+			counts[int(registered.Sub(first)/day)].Tickets++
+
+			return nil
+		})
+	})
+
+	return counts, undated, err
+}
+
+// CountVoteChoices returns the number of currently voting tickets which have
+// set each choice for each agenda, keyed by agenda ID and then by choice ID.
+// Tickets which have not set a choice for an agenda are not included in the
+// counts for that agenda. This func iterates over every ticket so should be
+// used sparingly.
+func (vdb *VspDatabase) CountVoteChoices() (map[string]map[string]int64, error) {
+	counts := make(map[string]map[string]int64)
+	err := vdb.db.View(func(tx *bolt.Tx) error {
+		ticketBkt := tx.Bucket(vspBktK).Bucket(ticketBktK)
+
+		return ticketBkt.ForEachBucket(func(k []byte) error {
+			tBkt := ticketBkt.Bucket(k)
+
+			if FeeStatus(tBkt.Get(feeTxStatusK)) != FeeConfirmed ||
+				TicketOutcome(tBkt.Get(outcomeK)) != "" {
+				return nil
+			}
+
+			voteChoices, err := bytesToStringMap(tBkt.Get(voteChoicesK))
+			if err != nil {
+				return fmt.Errorf("could not unmarshal vote choices: %w", err)
+			}
+
+			for agenda, choice := range voteChoices {
+				if counts[agenda] == nil {
+					counts[agenda] = make(map[string]int64)
+				}
+				counts[agenda][choice]++
+			}
+
+			return nil
+		})
+	})
+
+	return counts, err
+}
+
+// CountActiveTicketsByCommitmentAddress returns the number of tickets with the
+// provided commitment address which do not yet have an outcome (ie. not
+// expired/voted/missed) and which have either had a fee tx received, or have
+// been issued a fee which has not expired as of the time now. Tickets are
+// found using the commitment address index.
+func (vdb *VspDatabase) CountActiveTicketsByCommitmentAddress(addr string, now time.Time) (int64, error) {
+	var count int64
+	err := vdb.db.View(func(tx *bolt.Tx) error {
+		vspBkt := tx.Bucket(vspBktK)
+		ticketBkt := vspBkt.Bucket(ticketBktK)
+		c := vspBkt.Bucket(commitmentAddrIndexBktK).Cursor()
+
+		prefix := []byte(addr + "/")
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			tBkt := ticketBkt.Bucket(k[len(prefix):])
+			if tBkt == nil {
+				return fmt.Errorf("commitment address index refers to unknown ticket %s", k[len(prefix):])
+			}
+
+			if TicketOutcome(tBkt.Get(outcomeK)) != "" {
+				continue
+			}
+
+			switch FeeStatus(tBkt.Get(feeTxStatusK)) {
+			case FeeReceieved, FeeBroadcast, FeeConfirmed:
+				count++
+			case NoFee:
+				if now.Before(time.Unix(bytesToInt64(tBkt.Get(feeExpirationK)), 0)) {
+					count++
+				}
+			}
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+// CountReservedTickets returns the number of tickets without an outcome which
+// are not yet voting but have reserved capacity at the VSP, either because a
+// fee tx has been received but is not yet confirmed, or because a fee address
+// was issued and its fee has not expired as of the time now. This func iterates
+// over every ticket so should be used sparingly.
+func (vdb *VspDatabase) CountReservedTickets(now time.Time) (int64, error) {
+	var count int64
+	err := vdb.db.View(func(tx *bolt.Tx) error {
+		ticketBkt := tx.Bucket(vspBktK).Bucket(ticketBktK)
+
+		return ticketBkt.ForEachBucket(func(k []byte) error {
+			tBkt := ticketBkt.Bucket(k)
+
+			if TicketOutcome(tBkt.Get(outcomeK)) != "" {
+				return nil
+			}
+
+			switch FeeStatus(tBkt.Get(feeTxStatusK)) {
+			case FeeReceieved, FeeBroadcast:
+				count++
+			case NoFee:
+				if now.Before(time.Unix(bytesToInt64(tBkt.Get(feeExpirationK)), 0)) {
+					count++
+				}
+			}
+
+			return nil
+		})
+	})
+
+	return count, err
+}
+
+// FeeAddressTicket identifies a ticket which a fee address was issued to, and
+// the hash of the fee tx received for the ticket, if any.
+type FeeAddressTicket struct {
+	TicketHash string
+	FeeTxHash  string
+}
+
+// FeeAddressTickets looks up the provided addresses in the fee address index
+// and returns each address which was issued as a fee address mapped to the
+// tickets which it was issued to. Each fee address should only ever be issued
+// to a single ticket.
+func (vdb *VspDatabase) FeeAddressTickets(addresses []string) (map[string][]FeeAddressTicket, error) {
+	addrs := make(map[string][]FeeAddressTicket)
+	err := vdb.db.View(func(tx *bolt.Tx) error {
+		vspBkt := tx.Bucket(vspBktK)
+		ticketBkt := vspBkt.Bucket(ticketBktK)
+		c := vspBkt.Bucket(feeAddrIndexBktK).Cursor()
+
+		for _, addr := range addresses {
+			if _, ok := addrs[addr]; ok {
+				continue
+			}
+
+			prefix := []byte(addr + "/")
+			for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+				tBkt := ticketBkt.Bucket(k[len(prefix):])
+				if tBkt == nil {
+					return fmt.Errorf("fee address index refers to unknown ticket %s", k[len(prefix):])
+				}
+
+				addrs[addr] = append(addrs[addr], FeeAddressTicket{
+					TicketHash: string(tBkt.Get(hashK)),
+					FeeTxHash:  string(tBkt.Get(feeTxHashK)),
+				})
+			}
+		}
+
+		return nil
+	})
+
+	return addrs, err
+}
+
 // GetUnconfirmedTickets returns tickets which are not yet confirmed.
 func (vdb *VspDatabase) GetUnconfirmedTickets() (TicketList, error) {
 	return vdb.filterTickets(func(t *bolt.Bucket) bool {
@@ -353,10 +785,25 @@ func (vdb *VspDatabase) GetUnconfirmedTickets() (TicketList, error) {
 }
 
 // GetPendingFees returns tickets which are confirmed and have a fee tx which is
-// not yet broadcast.
+// not yet broadcast. Tickets with a deferred fee broadcast are not included.
 func (vdb *VspDatabase) GetPendingFees() (TicketList, error) {
 	return vdb.filterTickets(func(t *bolt.Bucket) bool {
-		return bytesToBool(t.Get(confirmedK)) && FeeStatus(t.Get(feeTxStatusK)) == FeeReceieved
+		deferred := t.Get(deferFeeBroadcastK)
+		return bytesToBool(t.Get(confirmedK)) && FeeStatus(t.Get(feeTxStatusK)) == FeeReceieved &&
+			(deferred == nil || !bytesToBool(deferred))
+	})
+}
+
+// GetExpiringFees returns tickets without an outcome which have been issued a
+// fee that has not been paid, and which expires after now but no later than
+// deadline.
+func (vdb *VspDatabase) GetExpiringFees(now, deadline time.Time) (TicketList, error) {
+	return vdb.filterTickets(func(t *bolt.Bucket) bool {
+		if FeeStatus(t.Get(feeTxStatusK)) != NoFee || TicketOutcome(t.Get(outcomeK)) != "" {
+			return false
+		}
+		expiration := time.Unix(bytesToInt64(t.Get(feeExpirationK)), 0)
+		return expiration.After(now) && !expiration.After(deadline)
 	})
 }
 
@@ -368,6 +815,18 @@ func (vdb *VspDatabase) GetUnconfirmedFees() (TicketList, error) {
 	})
 }
 
+// GetReimportTickets returns tickets with no outcome whose voting key could not
+// be imported into one or more voting wallets.
+func (vdb *VspDatabase) GetReimportTickets() (TicketList, error) {
+	return vdb.filterTickets(func(t *bolt.Bucket) bool {
+		if TicketOutcome(t.Get(outcomeK)) != "" {
+			return false
+		}
+		wallets, err := bytesToStringMap(t.Get(reimportWalletsK))
+		return err == nil && len(wallets) > 0
+	})
+}
+
 // GetVotableTickets returns tickets with a confirmed fee tx and no outcome (ie.
 // not expired/voted/missed).
 func (vdb *VspDatabase) GetVotableTickets() (TicketList, error) {
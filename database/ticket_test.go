@@ -29,6 +29,11 @@ func exampleTicket() Ticket {
 		FeeTxHex:          randString(504, hexCharset),
 		FeeTxHash:         randString(64, hexCharset),
 		FeeTxStatus:       FeeBroadcast,
+		FeeSurplus:        2500,
+		Registered:        1700000000,
+		RefundTxHash:      randString(64, hexCharset),
+		RefundAmount:      5000,
+		ReimportWallets:   map[string]string{},
 	}
 }
 
@@ -131,6 +136,12 @@ func testUpdateTicket(t *testing.T) {
 	ticket.FeeExpiration = ticket.FeeExpiration + 1
 	ticket.VoteChoices = map[string]string{"New agenda": "New value"}
 	ticket.FeeAddressXPubID = 20
+	ticket.Priority = true
+	ticket.Notes = "Support case 123"
+	ticket.Outcome = Missed
+	ticket.SpendingTxHash = randString(64, hexCharset)
+	ticket.VotingWallet = "wallet1:9110"
+	ticket.ReimportWallets = map[string]string{"wallet1:9110": "wallet busy"}
 
 	err = db.UpdateTicket(ticket)
 	if err != nil {
@@ -327,3 +338,393 @@ func testCountTickets(t *testing.T) {
 
 	count("revoked", 1, 1, 2, 1)
 }
+
+func testCountVoteChoices(t *testing.T) {
+	insert := func(feeStatus FeeStatus, outcome TicketOutcome, voteChoices map[string]string) {
+		ticket := exampleTicket()
+		ticket.FeeTxStatus = feeStatus
+		ticket.Outcome = outcome
+		ticket.VoteChoices = voteChoices
+		err := db.InsertNewTicket(ticket)
+		if err != nil {
+			t.Fatalf("error storing ticket in database: %v", err)
+		}
+	}
+
+	// Only currently voting tickets should be counted.
+	insert(FeeConfirmed, "", map[string]string{"agenda1": "yes", "agenda2": "no"})
+	insert(FeeConfirmed, "", map[string]string{"agenda1": "yes"})
+	insert(FeeConfirmed, "", map[string]string{"agenda1": "no"})
+	insert(FeeConfirmed, "", nil)
+	insert(FeeReceieved, "", map[string]string{"agenda1": "no"})
+	insert(FeeConfirmed, Voted, map[string]string{"agenda1": "no"})
+
+	counts, err := db.CountVoteChoices()
+	if err != nil {
+		t.Fatalf("error counting vote choices: %v", err)
+	}
+
+	expected := map[string]map[string]int64{
+		"agenda1": {"yes": 2, "no": 1},
+		"agenda2": {"no": 1},
+	}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Fatalf("expected vote choice counts %v, got %v", expected, counts)
+	}
+}
+
+func testCountActiveTicketsByCommitmentAddress(t *testing.T) {
+	const addr = "Tsfkn6k9AoYgVZRV6ZzcgmuVSgCdJQt9JY2"
+
+	now := time.Now()
+
+	count := func(test string, expected int64) {
+		actual, err := db.CountActiveTicketsByCommitmentAddress(addr, now)
+		if err != nil {
+			t.Fatalf("error counting tickets: %v", err)
+		}
+		if actual != expected {
+			t.Fatalf("test %s: expected %d active tickets, got %d",
+				test, expected, actual)
+		}
+	}
+
+	insert := func(commitmentAddr string, status FeeStatus, expiration time.Time, outcome TicketOutcome) {
+		ticket := exampleTicket()
+		ticket.CommitmentAddress = commitmentAddr
+		ticket.FeeTxStatus = status
+		ticket.FeeExpiration = expiration.Unix()
+		ticket.Outcome = outcome
+		err := db.InsertNewTicket(ticket)
+		if err != nil {
+			t.Fatalf("error storing ticket in database: %v", err)
+		}
+	}
+
+	// Initial count should be zero.
+	count("empty db", 0)
+
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	// Tickets with a different commitment address should not be counted.
+	insert(randString(35, addrCharset), FeeConfirmed, past, "")
+	insert(randString(35, addrCharset), NoFee, future, "")
+	count("different address", 0)
+
+	// Tickets with no fee received and an expired fee should not be counted.
+	insert(addr, NoFee, past, "")
+	count("expired fee", 0)
+
+	// Tickets with no fee received and an unexpired fee should be counted.
+	insert(addr, NoFee, future, "")
+	count("unexpired fee", 1)
+
+	// Tickets with a fee received and no outcome should be counted.
+	insert(addr, FeeReceieved, past, "")
+	insert(addr, FeeBroadcast, past, "")
+	insert(addr, FeeConfirmed, past, "")
+	count("active", 4)
+
+	// Tickets with an outcome should not be counted.
+	insert(addr, FeeConfirmed, past, Voted)
+	insert(addr, FeeConfirmed, past, Expired)
+	insert(addr, NoFee, future, Missed)
+	count("outcome set", 4)
+}
+
+func testCountReservedTickets(t *testing.T) {
+	now := time.Now()
+
+	count := func(test string, expected int64) {
+		actual, err := db.CountReservedTickets(now)
+		if err != nil {
+			t.Fatalf("error counting tickets: %v", err)
+		}
+		if actual != expected {
+			t.Fatalf("test %s: expected %d reserved tickets, got %d",
+				test, expected, actual)
+		}
+	}
+
+	insert := func(status FeeStatus, expiration time.Time, outcome TicketOutcome) {
+		ticket := exampleTicket()
+		ticket.FeeTxStatus = status
+		ticket.FeeExpiration = expiration.Unix()
+		ticket.Outcome = outcome
+		err := db.InsertNewTicket(ticket)
+		if err != nil {
+			t.Fatalf("error storing ticket in database: %v", err)
+		}
+	}
+
+	// Initial count should be zero.
+	count("empty db", 0)
+
+	// Tickets with an expired fee and no fee tx should not be counted.
+	insert(NoFee, now.Add(-time.Minute), "")
+	count("expired fee", 0)
+
+	// Tickets with an unexpired fee should be counted.
+	insert(NoFee, now.Add(time.Minute), "")
+	count("unexpired fee", 1)
+
+	// Tickets with an unconfirmed fee tx should be counted regardless of fee
+	// expiration.
+	insert(FeeReceieved, now.Add(-time.Minute), "")
+	insert(FeeBroadcast, now.Add(-time.Minute), "")
+	count("unconfirmed fee tx", 3)
+
+	// Voting tickets and tickets with an outcome should not be counted.
+	insert(FeeConfirmed, now.Add(time.Minute), "")
+	insert(FeeConfirmed, now.Add(time.Minute), Voted)
+	insert(FeeError, now.Add(time.Minute), "")
+	insert(NoFee, now.Add(time.Minute), Expired)
+	count("not reserved", 3)
+}
+
+func testFeeAddressTickets(t *testing.T) {
+	ticket1 := exampleTicket()
+	ticket2 := exampleTicket()
+	for _, ticket := range []Ticket{ticket1, ticket2} {
+		err := db.InsertNewTicket(ticket)
+		if err != nil {
+			t.Fatalf("error storing ticket in database: %v", err)
+		}
+	}
+
+	// Issue the fee address of the first ticket to a third ticket.
+	reused := exampleTicket()
+	reused.FeeAddress = ticket1.FeeAddress
+	err := db.InsertNewTicket(reused)
+	if err != nil {
+		t.Fatalf("error storing ticket in database: %v", err)
+	}
+
+	unknown := randString(35, addrCharset)
+	addrs, err := db.FeeAddressTickets([]string{ticket1.FeeAddress, ticket2.FeeAddress, unknown})
+	if err != nil {
+		t.Fatalf("error retrieving fee address tickets: %v", err)
+	}
+
+	if len(addrs) != 2 {
+		t.Fatalf("expected 2 fee addresses, got %d", len(addrs))
+	}
+
+	if len(addrs[ticket1.FeeAddress]) != 2 {
+		t.Fatalf("expected 2 tickets for reused fee address, got %d", len(addrs[ticket1.FeeAddress]))
+	}
+
+	expected := []FeeAddressTicket{{TicketHash: ticket2.Hash, FeeTxHash: ticket2.FeeTxHash}}
+	if !reflect.DeepEqual(addrs[ticket2.FeeAddress], expected) {
+		t.Fatalf("expected %v for fee address %s, got %v",
+			expected, ticket2.FeeAddress, addrs[ticket2.FeeAddress])
+	}
+}
+
+func testGetPendingFees(t *testing.T) {
+	insert := func(confirmed bool, status FeeStatus, deferred bool) Ticket {
+		ticket := exampleTicket()
+		ticket.Confirmed = confirmed
+		ticket.FeeTxStatus = status
+		ticket.DeferFeeBroadcast = deferred
+		err := db.InsertNewTicket(ticket)
+		if err != nil {
+			t.Fatalf("error storing ticket in database: %v", err)
+		}
+		return ticket
+	}
+
+	pending := insert(true, FeeReceieved, false)
+	insert(false, FeeReceieved, false)
+	insert(true, FeeBroadcast, false)
+	insert(true, FeeReceieved, true)
+
+	retrieved, err := db.GetPendingFees()
+	if err != nil {
+		t.Fatalf("error retrieving pending fees: %v", err)
+	}
+	if len(retrieved) != 1 {
+		t.Fatalf("expected to find 1 ticket, found %d", len(retrieved))
+	}
+	if retrieved[0].Hash != pending.Hash {
+		t.Fatal("retrieved unexpected ticket")
+	}
+}
+
+func testGetReimportTickets(t *testing.T) {
+	insert := func(wallets map[string]string, outcome TicketOutcome) Ticket {
+		ticket := exampleTicket()
+		ticket.ReimportWallets = wallets
+		ticket.Outcome = outcome
+		err := db.InsertNewTicket(ticket)
+		if err != nil {
+			t.Fatalf("error storing ticket in database: %v", err)
+		}
+		return ticket
+	}
+
+	reimport := insert(map[string]string{"wallet1:9110": "wallet busy"}, "")
+	insert(map[string]string{}, "")
+	insert(nil, "")
+	insert(map[string]string{"wallet1:9110": "wallet busy"}, Voted)
+
+	retrieved, err := db.GetReimportTickets()
+	if err != nil {
+		t.Fatalf("error retrieving reimport tickets: %v", err)
+	}
+	if len(retrieved) != 1 {
+		t.Fatalf("expected to find 1 ticket, found %d", len(retrieved))
+	}
+	if retrieved[0].Hash != reimport.Hash {
+		t.Fatal("retrieved unexpected ticket")
+	}
+	if !reflect.DeepEqual(retrieved[0].ReimportWallets, reimport.ReimportWallets) {
+		t.Fatalf("expected reimport wallets %v, got %v",
+			reimport.ReimportWallets, retrieved[0].ReimportWallets)
+	}
+}
+
+func testGetExpiringFees(t *testing.T) {
+	now := time.Now()
+	deadline := now.Add(10 * time.Minute)
+
+	insert := func(status FeeStatus, expiration time.Time, outcome TicketOutcome) Ticket {
+		ticket := exampleTicket()
+		ticket.FeeTxStatus = status
+		ticket.FeeExpiration = expiration.Unix()
+		ticket.Outcome = outcome
+		err := db.InsertNewTicket(ticket)
+		if err != nil {
+			t.Fatalf("error storing ticket in database: %v", err)
+		}
+		return ticket
+	}
+
+	expiring := insert(NoFee, now.Add(5*time.Minute), "")
+	insert(NoFee, now.Add(-time.Minute), "")
+	insert(NoFee, now.Add(time.Hour), "")
+	insert(FeeReceieved, now.Add(5*time.Minute), "")
+	insert(NoFee, now.Add(5*time.Minute), Expired)
+
+	retrieved, err := db.GetExpiringFees(now, deadline)
+	if err != nil {
+		t.Fatalf("error retrieving expiring fees: %v", err)
+	}
+	if len(retrieved) != 1 {
+		t.Fatalf("expected to find 1 ticket, found %d", len(retrieved))
+	}
+	if retrieved[0].Hash != expiring.Hash {
+		t.Fatal("retrieved unexpected ticket")
+	}
+}
+
+func TestTicketListPriority(t *testing.T) {
+	list := TicketList{
+		{Hash: "a"},
+		{Hash: "b", Priority: true},
+		{Hash: "c"},
+		{Hash: "d", Priority: true},
+	}
+
+	priority := list.Priority()
+	if len(priority) != 2 || priority[0].Hash != "b" || priority[1].Hash != "d" {
+		t.Fatalf("unexpected priority tickets: %v", priority)
+	}
+
+	list.SortPriorityFirst()
+	var order string
+	for _, ticket := range list {
+		order += ticket.Hash
+	}
+	if order != "bdac" {
+		t.Fatalf("expected order bdac, got %s", order)
+	}
+}
+
+func testCountRegistrationsByDay(t *testing.T) {
+	day1 := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	day3 := day1.AddDate(0, 0, 2)
+
+	insert := func(registered int64) {
+		ticket := exampleTicket()
+		ticket.Registered = registered
+		err := db.InsertNewTicket(ticket)
+		if err != nil {
+			t.Fatalf("error storing ticket in database: %v", err)
+		}
+	}
+
+	insert(day1.Unix())
+	insert(day1.Add(23*time.Hour + 59*time.Minute).Unix())
+	insert(day3.Add(time.Hour).Unix())
+
+	// Tickets outside of the range should not be counted.
+	insert(day1.Add(-time.Second).Unix())
+	insert(day3.AddDate(0, 0, 1).Unix())
+
+	// Tickets without a registration time are counted separately.
+	insert(0)
+
+	expected := []DailyRegistrations{
+		{Day: day1, Tickets: 2},
+		{Day: day2, Tickets: 0},
+		{Day: day3, Tickets: 1},
+	}
+
+	// Times within the first and last days select the whole days.
+	actual, undated, err := db.CountRegistrationsByDay(day1.Add(time.Hour), day3.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("error counting registrations: %v", err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+	if undated != 1 {
+		t.Fatalf("expected 1 undated ticket, got %d", undated)
+	}
+
+	// End before start should error.
+	_, _, err = db.CountRegistrationsByDay(day2, day1)
+	if err == nil {
+		t.Fatal("expected error when end day is before start day")
+	}
+}
+
+func testCountFeeStatuses(t *testing.T) {
+	insert := func(status FeeStatus, outcome TicketOutcome) {
+		ticket := exampleTicket()
+		ticket.FeeTxStatus = status
+		ticket.Outcome = outcome
+		err := db.InsertNewTicket(ticket)
+		if err != nil {
+			t.Fatalf("error storing ticket in database: %v", err)
+		}
+	}
+
+	insert(NoFee, "")
+	insert(FeeReceieved, "")
+	insert(FeeConfirmed, "")
+	insert(FeeConfirmed, "")
+	insert(FeeError, "")
+
+	// Tickets with an outcome should not be counted.
+	insert(FeeConfirmed, Voted)
+	insert(FeeConfirmed, Expired)
+
+	expected := map[FeeStatus]int64{
+		NoFee:        1,
+		FeeReceieved: 1,
+		FeeConfirmed: 2,
+		FeeError:     1,
+	}
+
+	actual, err := db.CountFeeStatuses()
+	if err != nil {
+		t.Fatalf("error counting fee statuses: %v", err)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
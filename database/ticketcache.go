@@ -0,0 +1,109 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"container/list"
+	"sync"
+)
+
+// ticketCache is a concurrency safe LRU cache of recently accessed tickets. A
+// ticket cache with a size of zero is disabled and never stores anything.
+type ticketCache struct {
+	mtx     sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	lru     *list.List
+
+	// generation is incremented every time a ticket is invalidated. It allows
+	// readers to detect a write which happened while they were reading from
+	// the database, so stale tickets are never added to the cache.
+	generation uint64
+}
+
+func newTicketCache(size int) *ticketCache {
+	return &ticketCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// get returns a copy of the cached ticket with the provided hash, and reports
+// whether it was found. It also returns the current generation of the cache,
+// which should be passed to add if the ticket is subsequently read from the
+// database.
+func (tc *ticketCache) get(hash string) (Ticket, bool, uint64) {
+	tc.mtx.Lock()
+	defer tc.mtx.Unlock()
+
+	elem, ok := tc.entries[hash]
+	if !ok {
+		return Ticket{}, false, tc.generation
+	}
+
+	tc.lru.MoveToFront(elem)
+	return copyTicket(elem.Value.(Ticket)), true, tc.generation
+}
+
+// add inserts a copy of the provided ticket into the cache, evicting the least
+// recently used ticket if the cache is full. The ticket is not added if any
+// ticket has been invalidated since generation was returned by get.
+func (tc *ticketCache) add(ticket Ticket, generation uint64) {
+	tc.mtx.Lock()
+	defer tc.mtx.Unlock()
+
+	if tc.size == 0 || generation != tc.generation {
+		return
+	}
+
+	if elem, ok := tc.entries[ticket.Hash]; ok {
+		elem.Value = copyTicket(ticket)
+		tc.lru.MoveToFront(elem)
+		return
+	}
+
+	if tc.lru.Len() >= tc.size {
+		oldest := tc.lru.Back()
+		tc.lru.Remove(oldest)
+		delete(tc.entries, oldest.Value.(Ticket).Hash)
+	}
+
+	tc.entries[ticket.Hash] = tc.lru.PushFront(copyTicket(ticket))
+}
+
+// invalidate removes the ticket with the provided hash from the cache. It must
+// be called whenever a ticket is written to the database.
+func (tc *ticketCache) invalidate(hash string) {
+	tc.mtx.Lock()
+	defer tc.mtx.Unlock()
+
+	tc.generation++
+
+	if elem, ok := tc.entries[hash]; ok {
+		tc.lru.Remove(elem)
+		delete(tc.entries, hash)
+	}
+}
+
+// copyTicket returns a copy of the provided ticket which does not share any
+// maps with the original, so callers cannot modify cached tickets.
+func copyTicket(ticket Ticket) Ticket {
+	ticket.VoteChoices = copyStringMap(ticket.VoteChoices)
+	ticket.TSpendPolicy = copyStringMap(ticket.TSpendPolicy)
+	ticket.TreasuryPolicy = copyStringMap(ticket.TreasuryPolicy)
+	return ticket
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testTicketCache(t *testing.T) {
+	// Insert a ticket into the database and read it to populate the cache.
+	ticket := exampleTicket()
+	err := db.InsertNewTicket(ticket)
+	if err != nil {
+		t.Fatalf("error storing ticket in database: %v", err)
+	}
+
+	_, found, err := db.GetTicketByHash(ticket.Hash)
+	if err != nil {
+		t.Fatalf("error retrieving ticket by ticket hash: %v", err)
+	}
+	if !found {
+		t.Fatal("expected found==true")
+	}
+
+	if _, cached, _ := db.ticketCache.get(ticket.Hash); !cached {
+		t.Fatal("expected ticket to be cached")
+	}
+
+	// Modifying a retrieved ticket must not modify the cached copy.
+	retrieved, _, _ := db.GetTicketByHash(ticket.Hash)
+	retrieved.VoteChoices["AgendaID"] = "no"
+	retrieved, _, _ = db.GetTicketByHash(ticket.Hash)
+	if !reflect.DeepEqual(retrieved, ticket) {
+		t.Fatal("cached ticket was modified by caller")
+	}
+
+	// Updating the ticket should invalidate the cache, so the new values are
+	// returned immediately.
+	ticket.FeeTxStatus = FeeConfirmed
+	ticket.VoteChoices = map[string]string{"AgendaID": "no"}
+	err = db.UpdateTicket(ticket)
+	if err != nil {
+		t.Fatalf("error updating ticket: %v", err)
+	}
+
+	retrieved, _, err = db.GetTicketByHash(ticket.Hash)
+	if err != nil {
+		t.Fatalf("error retrieving ticket by ticket hash: %v", err)
+	}
+	if !reflect.DeepEqual(retrieved, ticket) {
+		t.Fatal("retrieved stale ticket after update")
+	}
+
+	// Deleting the ticket should invalidate the cache.
+	err = db.DeleteTicket(ticket)
+	if err != nil {
+		t.Fatalf("error deleting ticket: %v", err)
+	}
+
+	_, found, err = db.GetTicketByHash(ticket.Hash)
+	if err != nil {
+		t.Fatalf("error retrieving ticket by ticket hash: %v", err)
+	}
+	if found {
+		t.Fatal("expected found==false after delete")
+	}
+
+	// Reading more tickets than the cache size should evict the least recently
+	// used ticket.
+	tickets := make([]Ticket, ticketCacheSize+1)
+	for i := range tickets {
+		tickets[i] = exampleTicket()
+		err = db.InsertNewTicket(tickets[i])
+		if err != nil {
+			t.Fatalf("error storing ticket in database: %v", err)
+		}
+		_, _, err = db.GetTicketByHash(tickets[i].Hash)
+		if err != nil {
+			t.Fatalf("error retrieving ticket by ticket hash: %v", err)
+		}
+	}
+
+	if _, cached, _ := db.ticketCache.get(tickets[0].Hash); cached {
+		t.Fatal("expected least recently used ticket to be evicted")
+	}
+	for _, ticket := range tickets[1:] {
+		if _, cached, _ := db.ticketCache.get(ticket.Hash); !cached {
+			t.Fatalf("expected ticket %s to be cached", ticket.Hash)
+		}
+	}
+
+	// A ticket read before a concurrent write must not be added to the cache.
+	_, _, generation := db.ticketCache.get(tickets[0].Hash)
+	db.ticketCache.invalidate(tickets[1].Hash)
+	db.ticketCache.add(tickets[0], generation)
+	if _, cached, _ := db.ticketCache.get(tickets[0].Hash); cached {
+		t.Fatal("expected ticket read before invalidation not to be cached")
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"fmt"
+
+	"github.com/decred/slog"
+	bolt "go.etcd.io/bbolt"
+)
+
+func commitmentAddrIndexUpgrade(db *bolt.DB, log slog.Logger) error {
+	log.Infof("Upgrading database to version %d", commitmentAddrIndexVersion)
+
+	// Run the upgrade in a single database transaction so it can be safely
+	// rolled back if an error is encountered.
+	err := db.Update(func(tx *bolt.Tx) error {
+		vspBkt := tx.Bucket(vspBktK)
+		ticketBkt := vspBkt.Bucket(ticketBktK)
+
+		// Create commitment address index bucket.
+		_, err := vspBkt.CreateBucket(commitmentAddrIndexBktK)
+		if err != nil {
+			return fmt.Errorf("failed to create %s bucket: %w", commitmentAddrIndexBktK, err)
+		}
+
+		// Index the commitment address of every existing ticket.
+		err = ticketBkt.ForEachBucket(func(k []byte) error {
+			tBkt := ticketBkt.Bucket(k)
+			return indexCommitmentAddress(tx, string(tBkt.Get(commitmentAddressK)), string(k))
+		})
+		if err != nil {
+			return fmt.Errorf("error iterating over %s bucket: %w", string(ticketBktK), err)
+		}
+
+		// Update database version.
+		err = vspBkt.Put(versionK, uint32ToBytes(commitmentAddrIndexVersion))
+		if err != nil {
+			return fmt.Errorf("failed to update db version: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Info("Upgrade completed")
+	return nil
+}
@@ -37,10 +37,20 @@ const (
 	// keys as well as the current key.
 	xPubBucketVersion = 5
 
+	// feeAddrIndexVersion adds a bucket which indexes the fee addresses issued
+	// to tickets, so checking whether an address is in use no longer requires
+	// iterating over every ticket.
+	feeAddrIndexVersion = 6
+
+	// commitmentAddrIndexVersion adds a bucket which indexes the commitment
+	// addresses of tickets, so counting the tickets of an address no longer
+	// requires iterating over every ticket.
+	commitmentAddrIndexVersion = 7
+
 	// latestVersion is the latest version of the database that is understood by
 	// vspd. Databases with recorded versions higher than this will fail to open
 	// (meaning any upgrades prevent reverting to older software).
-	latestVersion = xPubBucketVersion
+	latestVersion = commitmentAddrIndexVersion
 )
 
 // upgrades maps between old database versions and the upgrade function to
@@ -50,6 +60,8 @@ var upgrades = []func(tx *bolt.DB, log slog.Logger) error{
 	removeOldFeeTxVersion: ticketBucketUpgrade,
 	ticketBucketVersion:   altSignAddrUpgrade,
 	altSignAddrVersion:    xPubBucketUpgrade,
+	xPubBucketVersion:     feeAddrIndexUpgrade,
+	feeAddrIndexVersion:   commitmentAddrIndexUpgrade,
 }
 
 // v1Ticket has the json tags required to unmarshal tickets stored in the
@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"sort"
 
 	bolt "go.etcd.io/bbolt"
 )
@@ -120,3 +121,27 @@ func (vdb *VspDatabase) GetVoteChanges(ticketHash string) (map[uint32]VoteChange
 
 	return records, err
 }
+
+// GetVoteChangeHistory retrieves all of the stored vote change records for the
+// provided ticket hash, ordered from oldest to newest.
+func (vdb *VspDatabase) GetVoteChangeHistory(ticketHash string) ([]VoteChangeRecord, error) {
+	records, err := vdb.GetVoteChanges(ticketHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Records are keyed by a serially increasing integer, so sorting by key
+	// gives the order in which they were inserted.
+	keys := make([]uint32, 0, len(records))
+	for k := range records {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	history := make([]VoteChangeRecord, len(keys))
+	for i, k := range keys {
+		history[i] = records[k]
+	}
+
+	return history, nil
+}
@@ -1,10 +1,11 @@
-// Copyright (c) 2020 The Decred developers
+// Copyright (c) 2020-2024 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
 package database
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 )
@@ -61,3 +62,44 @@ func testVoteChangeRecords(t *testing.T) {
 		t.Fatalf("oldest vote change record should have been deleted")
 	}
 }
+
+func testVoteChangeHistory(t *testing.T) {
+	const hash = "MyHistoryHash"
+
+	// History for a ticket with no records should be empty.
+	history, err := db.GetVoteChangeHistory(hash)
+	if err != nil {
+		t.Fatalf("error retrieving vote change history: %v", err)
+	}
+	if len(history) != 0 {
+		t.Fatalf("expected empty history, got %d records", len(history))
+	}
+
+	// Insert more records than the limit, each with a distinct request.
+	total := maxVoteChangeRecords + 2
+	for i := 0; i < total; i++ {
+		record := exampleRecord()
+		record.Request = fmt.Sprintf("Request %d", i)
+		err = db.SaveVoteChange(hash, record)
+		if err != nil {
+			t.Fatalf("error storing vote change record in database: %v", err)
+		}
+	}
+
+	history, err = db.GetVoteChangeHistory(hash)
+	if err != nil {
+		t.Fatalf("error retrieving vote change history: %v", err)
+	}
+	if len(history) != maxVoteChangeRecords {
+		t.Fatalf("expected %d records, got %d", maxVoteChangeRecords, len(history))
+	}
+
+	// Oldest records should have been deleted and the remainder should be
+	// ordered oldest first.
+	for i, record := range history {
+		expected := fmt.Sprintf("Request %d", i+total-maxVoteChangeRecords)
+		if record.Request != expected {
+			t.Fatalf("record %d: expected request %q, got %q", i, expected, record.Request)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package alert sends email notifications to VSP operators when critical
+// events are detected.
+package alert
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/decred/slog"
+)
+
+// Kind identifies a category of alert. Alerts are rate-limited per kind so a
+// persistent problem does not result in a flood of emails.
+type Kind string
+
+const (
+	WalletOffline   Kind = "voting wallet offline"
+	DcrdUnreachable Kind = "dcrd unreachable"
+	FeeErrors       Kind = "fee errors"
+	MissedVote      Kind = "missed vote"
+	FeeAddressReuse Kind = "fee address reuse"
+	KeyImportFailed Kind = "voting key import failed"
+	WalletLagging   Kind = "voting wallet lagging"
+)
+
+// Config contains the SMTP settings used to send alert emails.
+type Config struct {
+	// Host is the host:port of the SMTP server. Alerts are disabled if empty.
+	Host string
+	// User and Pass are used for SMTP authentication. Authentication is not
+	// attempted if User is empty.
+	User string
+	Pass string
+	// From is the address alert emails are sent from.
+	From string
+	// To is the list of addresses alert emails are sent to.
+	To []string
+	// Interval is the minimum time between two alerts of the same kind.
+	Interval time.Duration
+	// Designation is included in email subjects to identify the VSP.
+	Designation string
+}
+
+// Alerter sends rate-limited alert emails. A nil or disabled Alerter is safe
+// to use and does nothing.
+type Alerter struct {
+	cfg Config
+	log slog.Logger
+
+	mtx      sync.Mutex
+	lastSent map[Kind]time.Time
+
+	// send delivers a single email. It is a field so it can be replaced in
+	// tests.
+	send func(subject, body string) error
+}
+
+// New returns an Alerter configured with the provided SMTP settings.
+func New(cfg Config, log slog.Logger) *Alerter {
+	a := &Alerter{
+		cfg:      cfg,
+		log:      log,
+		lastSent: make(map[Kind]time.Time),
+	}
+	a.send = a.sendMail
+	return a
+}
+
+// Enabled reports whether alert emails will be sent.
+func (a *Alerter) Enabled() bool {
+	return a != nil && a.cfg.Host != ""
+}
+
+// Alert sends an email for the provided kind of event, unless an alert of the
+// same kind was sent within the configured interval. Emails are sent in the
+// background so callers are never blocked by a slow SMTP server.
+func (a *Alerter) Alert(kind Kind, format string, args ...any) {
+	if !a.Enabled() {
+		return
+	}
+
+	if !a.allow(kind, time.Now()) {
+		a.log.Debugf("Suppressing %s alert, rate limit reached", kind)
+		return
+	}
+
+	subject := fmt.Sprintf("[%s] Alert: %s", a.cfg.Designation, kind)
+	body := fmt.Sprintf(format, args...)
+
+	go func() {
+		err := a.send(subject, body)
+		if err != nil {
+			a.log.Errorf("Failed to send %s alert email: %v", kind, err)
+			return
+		}
+		a.log.Infof("Sent %s alert email", kind)
+	}()
+}
+
+// allow reports whether an alert of the provided kind may be sent at time now,
+// and if so records now as the time it was sent.
+func (a *Alerter) allow(kind Kind, now time.Time) bool {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	last, ok := a.lastSent[kind]
+	if ok && now.Sub(last) < a.cfg.Interval {
+		return false
+	}
+
+	a.lastSent[kind] = now
+	return true
+}
+
+// sendMail sends an email with the provided subject and body to all configured
+// recipients.
+func (a *Alerter) sendMail(subject, body string) error {
+	var auth smtp.Auth
+	if a.cfg.User != "" {
+		host, _, err := net.SplitHostPort(a.cfg.Host)
+		if err != nil {
+			return fmt.Errorf("invalid smtp host: %w", err)
+		}
+		auth = smtp.PlainAuth("", a.cfg.User, a.cfg.Pass, host)
+	}
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nDate: %s\r\nSubject: %s\r\n\r\n%s\r\n",
+		a.cfg.From, strings.Join(a.cfg.To, ", "), time.Now().Format(time.RFC1123Z),
+		subject, body)
+
+	return smtp.SendMail(a.cfg.Host, auth, a.cfg.From, a.cfg.To, []byte(msg))
+}
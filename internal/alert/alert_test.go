@@ -0,0 +1,73 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/slog"
+)
+
+func TestAllow(t *testing.T) {
+	a := New(Config{Host: "localhost:25", Interval: time.Hour}, slog.Disabled)
+
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		kind   Kind
+		time   time.Time
+		expect bool
+	}{{
+		name:   "first alert",
+		kind:   WalletOffline,
+		time:   now,
+		expect: true,
+	}, {
+		name:   "same kind within interval",
+		kind:   WalletOffline,
+		time:   now.Add(time.Minute),
+		expect: false,
+	}, {
+		name:   "different kind within interval",
+		kind:   MissedVote,
+		time:   now.Add(time.Minute),
+		expect: true,
+	}, {
+		name:   "same kind after interval",
+		kind:   WalletOffline,
+		time:   now.Add(time.Hour),
+		expect: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual := a.allow(test.kind, test.time)
+			if actual != test.expect {
+				t.Fatalf("expected allow=%t, got %t", test.expect, actual)
+			}
+		})
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	var nilAlerter *Alerter
+	if nilAlerter.Enabled() {
+		t.Fatal("expected nil alerter to be disabled")
+	}
+
+	a := New(Config{}, slog.Disabled)
+	if a.Enabled() {
+		t.Fatal("expected alerter with no smtp host to be disabled")
+	}
+
+	a.send = func(_, _ string) error {
+		t.Fatal("disabled alerter should not send email")
+		return nil
+	}
+	a.Alert(MissedVote, "test")
+	nilAlerter.Alert(MissedVote, "test")
+}
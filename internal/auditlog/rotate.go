@@ -0,0 +1,280 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package auditlog provides a writer for on-disk audit logs which rotates the
+// log file based on size, compresses rotated files, and deletes rotated files
+// based on count and age so audit logs can never fill the disk.
+package auditlog
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// logFileMode is the file mode for audit log files.
+	logFileMode = 0600
+
+	// rotatedSuffix is appended to the names of compressed rotated files.
+	rotatedSuffix = ".gz"
+
+	// timeFormat is used to name rotated files. It sorts lexicographically in
+	// chronological order.
+	timeFormat = "20060102T150405.000000000"
+)
+
+// Config defines the lifecycle of an audit log.
+type Config struct {
+	// Path is the path of the active log file. Rotated files are written to
+	// the same directory.
+	Path string
+	// MaxSize is the size in bytes at which the active log file is rotated.
+	MaxSize int64
+	// MaxFiles is the maximum number of rotated files to keep. Zero keeps an
+	// unlimited number of files.
+	MaxFiles int
+	// MaxAge is the maximum age of rotated files to keep. Zero keeps files
+	// regardless of their age.
+	MaxAge time.Duration
+}
+
+// Rotator is an io.WriteCloser which writes to an audit log file and manages
+// its rotation and retention. It is safe for concurrent use.
+type Rotator struct {
+	cfg Config
+
+	mtx    sync.Mutex
+	file   *os.File
+	size   int64
+	closed bool
+
+	// now returns the current time. It is a field so it can be replaced in
+	// tests.
+	now func() time.Time
+}
+
+// New opens the audit log file described by cfg, creating it if necessary,
+// and returns a Rotator which writes to it.
+func New(cfg Config) (*Rotator, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("audit log path is not set")
+	}
+	if cfg.MaxSize <= 0 {
+		return nil, errors.New("audit log max size must be greater than 0")
+	}
+	if cfg.MaxFiles < 0 || cfg.MaxAge < 0 {
+		return nil, errors.New("audit log max files and max age cannot be negative")
+	}
+
+	r := &Rotator{cfg: cfg, now: time.Now}
+
+	err := r.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// open opens the active log file for appending.
+func (r *Rotator) open() error {
+	err := os.MkdirAll(filepath.Dir(r.cfg.Path), 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	f, err := os.OpenFile(r.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, logFileMode)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+// Write writes p to the active log file, first rotating the file if writing p
+// would cause it to exceed the maximum size.
+func (r *Rotator) Write(p []byte) (int, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+
+	// The active log file is not open if a previous rotation failed part way
+	// through, so try to open it again rather than failing every write.
+	if r.file == nil {
+		err := r.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if r.size > 0 && r.size+int64(len(p)) > r.cfg.MaxSize {
+		err := r.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the active log file.
+func (r *Rotator) Close() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	if r.file == nil {
+		return nil
+	}
+
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// rotate compresses the active log file into a rotated file, opens a new empty
+// active log file, and deletes any rotated files which should not be kept. If
+// rotation fails after the active log file is closed, it is reopened by the
+// next write. The mutex must be held.
+func (r *Rotator) rotate() error {
+	err := r.file.Close()
+	r.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close audit log: %w", err)
+	}
+
+	rotatedPath := r.cfg.Path + "." + r.now().UTC().Format(timeFormat) + rotatedSuffix
+	err = compressFile(r.cfg.Path, rotatedPath)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(r.cfg.Path)
+	if err != nil {
+		return fmt.Errorf("failed to remove rotated audit log: %w", err)
+	}
+
+	err = r.open()
+	if err != nil {
+		return err
+	}
+
+	return r.prune()
+}
+
+// prune deletes rotated files which exceed the maximum count or age.
+func (r *Rotator) prune() error {
+	rotated, err := r.rotatedFiles()
+	if err != nil {
+		return err
+	}
+
+	// Files are sorted oldest first.
+	remove := make(map[string]struct{})
+	if r.cfg.MaxFiles > 0 && len(rotated) > r.cfg.MaxFiles {
+		for _, path := range rotated[:len(rotated)-r.cfg.MaxFiles] {
+			remove[path] = struct{}{}
+		}
+	}
+
+	if r.cfg.MaxAge > 0 {
+		cutoff := r.now().Add(-r.cfg.MaxAge)
+		for _, path := range rotated {
+			info, err := os.Stat(path)
+			if err != nil {
+				return fmt.Errorf("failed to stat rotated audit log: %w", err)
+			}
+			if info.ModTime().Before(cutoff) {
+				remove[path] = struct{}{}
+			}
+		}
+	}
+
+	for path := range remove {
+		err := os.Remove(path)
+		if err != nil {
+			return fmt.Errorf("failed to remove old audit log: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// rotatedFiles returns the paths of all rotated files of the log, sorted from
+// oldest to newest.
+func (r *Rotator) rotatedFiles() ([]string, error) {
+	dir := filepath.Dir(r.cfg.Path)
+	prefix := filepath.Base(r.cfg.Path) + "."
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log directory: %w", err)
+	}
+
+	var rotated []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && strings.HasPrefix(name, prefix) &&
+			strings.HasSuffix(name, rotatedSuffix) {
+			rotated = append(rotated, filepath.Join(dir, name))
+		}
+	}
+
+	sort.Strings(rotated)
+	return rotated, nil
+}
+
+// compressFile writes a gzip compressed copy of the file at src to dst.
+func compressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log for compression: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, logFileMode)
+	if err != nil {
+		return fmt.Errorf("failed to create rotated audit log: %w", err)
+	}
+
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("failed to compress audit log: %w", err)
+	}
+
+	return nil
+}
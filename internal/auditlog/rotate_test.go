@@ -0,0 +1,201 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package auditlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestRotator returns a Rotator writing to a temporary directory, with a
+// fake clock which advances one second every time it is read.
+func newTestRotator(t *testing.T, cfg Config) *Rotator {
+	t.Helper()
+
+	cfg.Path = filepath.Join(t.TempDir(), "audit.log")
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create rotator: %v", err)
+	}
+	t.Cleanup(func() { r.Close() })
+
+	now := time.Now()
+	r.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	return r
+}
+
+func write(t *testing.T, r *Rotator, data string) {
+	t.Helper()
+	if _, err := r.Write([]byte(data)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+}
+
+func TestRotateOnSize(t *testing.T) {
+	r := newTestRotator(t, Config{MaxSize: 10})
+
+	write(t, r, "0123456789")
+	write(t, r, "abcde")
+
+	rotated, err := r.rotatedFiles()
+	if err != nil {
+		t.Fatalf("failed to list rotated files: %v", err)
+	}
+	if len(rotated) != 1 {
+		t.Fatalf("expected 1 rotated file, got %d", len(rotated))
+	}
+
+	// The rotated file should contain the compressed original contents.
+	f, err := os.Open(rotated[0])
+	if err != nil {
+		t.Fatalf("failed to open rotated file: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("rotated file is not gzip compressed: %v", err)
+	}
+	contents, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("failed to decompress rotated file: %v", err)
+	}
+	if !bytes.Equal(contents, []byte("0123456789")) {
+		t.Fatalf("unexpected rotated file contents %q", contents)
+	}
+
+	// The active file should only contain the latest write.
+	active, err := os.ReadFile(r.cfg.Path)
+	if err != nil {
+		t.Fatalf("failed to read active file: %v", err)
+	}
+	if !bytes.Equal(active, []byte("abcde")) {
+		t.Fatalf("unexpected active file contents %q", active)
+	}
+}
+
+func TestPruneMaxFiles(t *testing.T) {
+	r := newTestRotator(t, Config{MaxSize: 1, MaxFiles: 2})
+
+	for i := 0; i < 5; i++ {
+		write(t, r, "x")
+	}
+
+	rotated, err := r.rotatedFiles()
+	if err != nil {
+		t.Fatalf("failed to list rotated files: %v", err)
+	}
+	if len(rotated) != 2 {
+		t.Fatalf("expected 2 rotated files, got %d", len(rotated))
+	}
+}
+
+func TestPruneMaxAge(t *testing.T) {
+	r := newTestRotator(t, Config{MaxSize: 1, MaxAge: time.Hour})
+
+	write(t, r, "x")
+	write(t, r, "x")
+
+	rotated, err := r.rotatedFiles()
+	if err != nil {
+		t.Fatalf("failed to list rotated files: %v", err)
+	}
+	if len(rotated) != 1 {
+		t.Fatalf("expected 1 rotated file, got %d", len(rotated))
+	}
+
+	// Age the existing rotated file beyond the limit, then rotate again.
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(rotated[0], old, old); err != nil {
+		t.Fatalf("failed to change file times: %v", err)
+	}
+	write(t, r, "x")
+
+	remaining, err := r.rotatedFiles()
+	if err != nil {
+		t.Fatalf("failed to list rotated files: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0] == rotated[0] {
+		t.Fatalf("expected only the newest rotated file to remain, got %v", remaining)
+	}
+}
+
+// TestRotateFailureRecovers ensures writes succeed again after a rotation fails
+// part way through, rather than the log remaining closed.
+func TestRotateFailureRecovers(t *testing.T) {
+	r := newTestRotator(t, Config{MaxSize: 10})
+
+	// Block the next rotation by creating a file with the name the rotated file
+	// will be given.
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	blocker := r.cfg.Path + "." + now.UTC().Format(timeFormat) + rotatedSuffix
+	if err := os.WriteFile(blocker, nil, logFileMode); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	write(t, r, "0123456789")
+	if _, err := r.Write([]byte("abcde")); err == nil {
+		t.Fatal("expected rotation to fail")
+	}
+
+	if err := os.Remove(blocker); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	write(t, r, "abcde")
+
+	rotated, err := r.rotatedFiles()
+	if err != nil {
+		t.Fatalf("failed to list rotated files: %v", err)
+	}
+	if len(rotated) != 1 {
+		t.Fatalf("expected 1 rotated file, got %d", len(rotated))
+	}
+	active, err := os.ReadFile(r.cfg.Path)
+	if err != nil {
+		t.Fatalf("failed to read active file: %v", err)
+	}
+	if string(active) != "abcde" {
+		t.Fatalf("expected active file to contain %q, got %q", "abcde", active)
+	}
+}
+
+// TestWriteAfterClose ensures writes fail once the rotator is closed.
+func TestWriteAfterClose(t *testing.T) {
+	r := newTestRotator(t, Config{MaxSize: 10})
+	if err := r.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if _, err := r.Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("expected os.ErrClosed, got %v", err)
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]Config{
+		"no path":           {MaxSize: 1},
+		"zero max size":     {Path: filepath.Join(dir, "a.log")},
+		"negative max file": {Path: filepath.Join(dir, "b.log"), MaxSize: 1, MaxFiles: -1},
+		"negative max age":  {Path: filepath.Join(dir, "c.log"), MaxSize: 1, MaxAge: -time.Second},
+	}
+
+	for testName, cfg := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if _, err := New(cfg); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package broadcast provides implementations for sending fee transactions to
+// the Decred network.
+package broadcast
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/decred/vspd/rpc"
+)
+
+// Broadcaster sends a serialized transaction to the Decred network.
+type Broadcaster interface {
+	// Broadcast sends the provided hex encoded transaction to the network. It
+	// returns the hash of the transaction as reported by the network, or an
+	// empty string if the hash is not known.
+	Broadcast(txHex string) (string, error)
+}
+
+// Dcrd broadcasts transactions using the sendrawtransaction RPC of the dcrd
+// instance which vspd is connected to.
+type Dcrd struct {
+	dcrd rpc.DcrdConnect
+}
+
+// NewDcrd returns a Broadcaster which uses the provided dcrd connection.
+func NewDcrd(dcrd rpc.DcrdConnect) *Dcrd {
+	return &Dcrd{dcrd: dcrd}
+}
+
+func (d *Dcrd) Broadcast(txHex string) (string, error) {
+	dcrdClient, _, err := d.dcrd.Client()
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := dcrdClient.SendRawTransaction(txHex)
+	if err != nil && isThrottleError(err) {
+		return "", fmt.Errorf("%w: %v", ErrThrottled, err)
+	}
+	return hash, err
+}
+
+// HTTP broadcasts transactions by sending them to an external relay service.
+// The hex encoded transaction is sent as the body of a POST request, and any
+// 2xx response status is considered a success. The service does not report the
+// hash of the transaction.
+type HTTP struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTP returns a Broadcaster which POSTs transactions to the provided URL,
+// waiting at most timeout for each request to complete.
+func NewHTTP(url string, timeout time.Duration) *HTTP {
+	return &HTTP{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// maxErrorBody is the maximum number of bytes read from an error response so
+// it can be included in the returned error.
+const maxErrorBody = 512
+
+func (h *HTTP) Broadcast(txHex string) (string, error) {
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodPost, h.url,
+		strings.NewReader(txHex))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("broadcast request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		err := fmt.Errorf("broadcast service responded with status %d: %s",
+			resp.StatusCode, strings.TrimSpace(string(body)))
+
+		// The service is overloaded rather than refusing the transaction.
+		if resp.StatusCode == http.StatusTooManyRequests ||
+			resp.StatusCode == http.StatusServiceUnavailable {
+			return "", fmt.Errorf("%w: %v", ErrThrottled, err)
+		}
+		return "", err
+	}
+
+	return "", nil
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package broadcast
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPBroadcast(t *testing.T) {
+	const txHex = "0100000001abcdef"
+
+	tests := map[string]struct {
+		status    int
+		expectErr bool
+	}{
+		"success":      {status: http.StatusOK, expectErr: false},
+		"accepted":     {status: http.StatusAccepted, expectErr: false},
+		"bad request":  {status: http.StatusBadRequest, expectErr: true},
+		"server error": {status: http.StatusInternalServerError, expectErr: true},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			var received string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("expected POST request, got %s", r.Method)
+				}
+				body, _ := io.ReadAll(r.Body)
+				received = string(body)
+				w.WriteHeader(test.status)
+				_, _ = w.Write([]byte("response message"))
+			}))
+			defer server.Close()
+
+			_, err := NewHTTP(server.URL, time.Second).Broadcast(txHex)
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if !strings.Contains(err.Error(), "response message") {
+					t.Fatalf("expected error to include response body, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if received != txHex {
+				t.Fatalf("expected service to receive %q, got %q", txHex, received)
+			}
+		})
+	}
+}
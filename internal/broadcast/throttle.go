@@ -0,0 +1,97 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package broadcast
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrThrottled indicates a transaction was not accepted because the network is
+// temporarily refusing transactions, for example because a mempool rate limit
+// has been reached, rather than because the transaction is invalid. The
+// broadcast should be retried later.
+var ErrThrottled = errors.New("broadcast throttled")
+
+// throttleErrors are substrings of dcrd errors which indicate a transaction was
+// refused because of the current load on the mempool.
+var throttleErrors = []string{
+	"rejected by the rate limiter",
+	"mempool is full",
+}
+
+// isThrottleError reports whether the provided dcrd error indicates that the
+// transaction was refused because of the current load on the mempool.
+func isThrottleError(err error) bool {
+	for _, s := range throttleErrors {
+		if strings.Contains(err.Error(), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Throttled wraps a Broadcaster and backs off when the network reports that it
+// is temporarily refusing transactions. While backing off, broadcasts fail
+// immediately with ErrThrottled without being sent. The backoff period starts
+// at a minimum delay and doubles each time the network refuses a transaction,
+// up to a maximum delay, and is reset as soon as a transaction is accepted.
+type Throttled struct {
+	Broadcaster
+	minDelay time.Duration
+	maxDelay time.Duration
+
+	mtx   sync.Mutex
+	delay time.Duration
+	until time.Time
+	now   func() time.Time
+}
+
+// NewThrottled returns a Broadcaster which sends transactions using b, backing
+// off for between minDelay and maxDelay when the network refuses them.
+func NewThrottled(b Broadcaster, minDelay, maxDelay time.Duration) *Throttled {
+	return &Throttled{
+		Broadcaster: b,
+		minDelay:    minDelay,
+		maxDelay:    maxDelay,
+		now:         time.Now,
+	}
+}
+
+func (t *Throttled) Broadcast(txHex string) (string, error) {
+	t.mtx.Lock()
+	if t.now().Before(t.until) {
+		until := t.until
+		t.mtx.Unlock()
+		return "", fmt.Errorf("%w: backing off until %s", ErrThrottled,
+			until.Format(time.RFC3339))
+	}
+	t.mtx.Unlock()
+
+	hash, err := t.Broadcaster.Broadcast(txHex)
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	switch {
+	case errors.Is(err, ErrThrottled):
+		t.delay *= 2
+		if t.delay < t.minDelay {
+			t.delay = t.minDelay
+		}
+		if t.delay > t.maxDelay {
+			t.delay = t.maxDelay
+		}
+		t.until = t.now().Add(t.delay)
+	case err == nil:
+		t.delay = 0
+		t.until = time.Time{}
+	}
+
+	return hash, err
+}
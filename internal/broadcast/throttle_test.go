@@ -0,0 +1,81 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package broadcast
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type fakeBroadcaster struct {
+	err   error
+	calls int
+}
+
+func (f *fakeBroadcaster) Broadcast(string) (string, error) {
+	f.calls++
+	return "", f.err
+}
+
+func TestThrottled(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	fake := &fakeBroadcaster{}
+	throttled := NewThrottled(fake, time.Minute, 3*time.Minute)
+	throttled.now = func() time.Time { return now }
+
+	broadcast := func() error {
+		_, err := throttled.Broadcast("00")
+		return err
+	}
+
+	// Broadcasts are sent while the network accepts them.
+	if err := broadcast(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A refused broadcast starts a backoff period of the minimum delay, during
+	// which broadcasts are not sent.
+	fake.err = fmt.Errorf("%w: rate limited", ErrThrottled)
+	if err := broadcast(); !errors.Is(err, ErrThrottled) {
+		t.Fatalf("expected ErrThrottled, got %v", err)
+	}
+	fake.err = nil
+	now = now.Add(time.Minute - time.Second)
+	if err := broadcast(); !errors.Is(err, ErrThrottled) {
+		t.Fatalf("expected ErrThrottled while backing off, got %v", err)
+	}
+	if fake.calls != 2 {
+		t.Fatalf("expected 2 broadcasts to be sent, got %d", fake.calls)
+	}
+
+	// Each refusal doubles the delay, up to the maximum.
+	fake.err = fmt.Errorf("%w: rate limited", ErrThrottled)
+	for _, expectDelay := range []time.Duration{2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		now = throttled.until
+		_ = broadcast()
+		if delay := throttled.until.Sub(now); delay != expectDelay {
+			t.Fatalf("expected backoff of %v, got %v", expectDelay, delay)
+		}
+	}
+
+	// The backoff is reset once a broadcast is accepted.
+	now = throttled.until
+	fake.err = nil
+	if err := broadcast(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if throttled.delay != 0 {
+		t.Fatalf("expected backoff to be reset, got %v", throttled.delay)
+	}
+
+	// Other errors do not cause broadcasts to back off.
+	fake.err = errors.New("invalid transaction")
+	_ = broadcast()
+	if !throttled.until.IsZero() {
+		t.Fatal("expected no backoff for other errors")
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayout is the layout of dates which can be used in fee schedule entries.
+const dateLayout = "2006-01-02"
+
+// FeeScheduleEntry is a scheduled change to the fee percentage charged by the
+// VSP. It takes effect once the best block reaches Height, or if Height is
+// zero, once the current time reaches Time.
+type FeeScheduleEntry struct {
+	Height     int64
+	Time       time.Time
+	Percentage float64
+}
+
+// active reports whether the entry has taken effect at the provided block
+// height and time.
+func (e FeeScheduleEntry) active(height int64, now time.Time) bool {
+	if e.Height != 0 {
+		return height >= e.Height
+	}
+	return !now.Before(e.Time)
+}
+
+// FeeSchedule is a list of scheduled changes to the fee percentage charged by
+// the VSP, in the order they were configured.
+type FeeSchedule []FeeScheduleEntry
+
+// ParseFeeSchedule parses a comma separated list of fee schedule entries. Each
+// entry is in the form <height>:<percentage> or <date>:<percentage>, where date
+// is either YYYY-MM-DD, which is interpreted as midnight UTC, or an RFC 3339
+// timestamp.
+func ParseFeeSchedule(s string) (FeeSchedule, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var schedule FeeSchedule
+	for _, entryStr := range strings.Split(s, ",") {
+		entryStr = strings.TrimSpace(entryStr)
+
+		// Split on the last colon because RFC 3339 timestamps contain colons.
+		idx := strings.LastIndex(entryStr, ":")
+		if idx == -1 {
+			return nil, fmt.Errorf("fee schedule entry %q should be in the form "+
+				"<height or date>:<percentage>", entryStr)
+		}
+		when, pctStr := entryStr[:idx], entryStr[idx+1:]
+
+		var entry FeeScheduleEntry
+
+		pct, err := strconv.ParseFloat(pctStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("fee schedule entry %q has invalid percentage: %w",
+				entryStr, err)
+		}
+		entry.Percentage = pct
+
+		if height, err := strconv.ParseInt(when, 10, 64); err == nil {
+			if height <= 0 {
+				return nil, fmt.Errorf("fee schedule entry %q has invalid height", entryStr)
+			}
+			entry.Height = height
+		} else if t, err := time.Parse(dateLayout, when); err == nil {
+			entry.Time = t
+		} else if t, err := time.Parse(time.RFC3339, when); err == nil {
+			entry.Time = t
+		} else {
+			return nil, fmt.Errorf("fee schedule entry %q should begin with a block "+
+				"height, a YYYY-MM-DD date or an RFC 3339 timestamp", entryStr)
+		}
+
+		schedule = append(schedule, entry)
+	}
+
+	return schedule, nil
+}
+
+// Percentage returns the fee percentage which is in effect at the provided
+// block height and time. If more than one entry has taken effect, the entry
+// which was configured last is used. The base percentage is returned if no
+// entries have taken effect.
+func (s FeeSchedule) Percentage(base float64, height int64, now time.Time) float64 {
+	pct := base
+	for _, entry := range s {
+		if entry.active(height, now) {
+			pct = entry.Percentage
+		}
+	}
+	return pct
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFeeSchedule(t *testing.T) {
+	tests := map[string]struct {
+		input     string
+		expect    FeeSchedule
+		expectErr bool
+	}{
+		"empty": {
+			input:  "",
+			expect: nil,
+		},
+		"height": {
+			input:  "900000:2.5",
+			expect: FeeSchedule{{Height: 900000, Percentage: 2.5}},
+		},
+		"date": {
+			input:  "2024-06-01:1",
+			expect: FeeSchedule{{Time: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), Percentage: 1}},
+		},
+		"timestamp": {
+			input:  "2024-06-01T12:30:00Z:1",
+			expect: FeeSchedule{{Time: time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC), Percentage: 1}},
+		},
+		"multiple": {
+			input: "900000:2.5, 2024-06-01:1",
+			expect: FeeSchedule{
+				{Height: 900000, Percentage: 2.5},
+				{Time: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), Percentage: 1},
+			},
+		},
+		"missing percentage": {
+			input:     "900000",
+			expectErr: true,
+		},
+		"invalid percentage": {
+			input:     "900000:abc",
+			expectErr: true,
+		},
+		"invalid height": {
+			input:     "-5:2",
+			expectErr: true,
+		},
+		"invalid date": {
+			input:     "June 1st:2",
+			expectErr: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual, err := ParseFeeSchedule(test.input)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error=%t, got %v", test.expectErr, err)
+			}
+			if !reflect.DeepEqual(actual, test.expect) {
+				t.Fatalf("expected %v, got %v", test.expect, actual)
+			}
+		})
+	}
+}
+
+func TestFeeSchedulePercentage(t *testing.T) {
+	changeTime := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	schedule := FeeSchedule{
+		{Height: 1000, Percentage: 2},
+		{Time: changeTime, Percentage: 1},
+	}
+
+	tests := map[string]struct {
+		height int64
+		now    time.Time
+		expect float64
+	}{
+		"no entries active": {
+			height: 999,
+			now:    changeTime.Add(-time.Second),
+			expect: 3,
+		},
+		"height entry active": {
+			height: 1000,
+			now:    changeTime.Add(-time.Second),
+			expect: 2,
+		},
+		"time entry active": {
+			height: 999,
+			now:    changeTime,
+			expect: 1,
+		},
+		"later configured entry wins": {
+			height: 1000,
+			now:    changeTime,
+			expect: 1,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := schedule.Percentage(3, test.height, test.now)
+			if actual != test.expect {
+				t.Fatalf("expected %v, got %v", test.expect, actual)
+			}
+		})
+	}
+}
@@ -6,8 +6,12 @@ package config
 
 import (
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/dcrd/dcrutil/v4"
+	"github.com/decred/dcrd/txscript/v4/stdaddr"
 )
 
 type Network struct {
@@ -28,6 +32,13 @@ type Network struct {
 	// DCP0012Height is the activation height of DCP-0012 change PoW/PoS subsidy
 	// split R2 agenda on this network.
 	DCP0012Height int64
+	// DefaultVSPFee is the fee percentage charged on this network when no fee
+	// percentage is configured.
+	DefaultVSPFee float64
+	// MaxPlausibleFee is the highest fee percentage which is expected to be
+	// charged on this network. vspd will log a warning at startup if any
+	// configured fee percentage is higher.
+	MaxPlausibleFee float64
 }
 
 var MainNet = Network{
@@ -44,7 +55,9 @@ var MainNet = Network{
 	DCP0010Height: 657280,
 	// DCP0012Height on mainnet is block
 	// 071683030010299ab13f139df59dc98d637957b766e47f8da6dd5ac762f1e8c7.
-	DCP0012Height: 794368,
+	DCP0012Height:   794368,
+	DefaultVSPFee:   3.0,
+	MaxPlausibleFee: 10.0,
 }
 
 var TestNet3 = Network{
@@ -62,6 +75,9 @@ var TestNet3 = Network{
 	// DCP0012Height on testnet3 is block
 	// c7da7b548a2a9463dc97adb48433c4ffff18c3873f7e2ae99338a990dae039f0.
 	DCP0012Height: 1170048,
+	// Fees on test networks have no real value, so any fee is plausible.
+	DefaultVSPFee:   1.0,
+	MaxPlausibleFee: 100.0,
 }
 
 var SimNet = Network{
@@ -76,6 +92,9 @@ var SimNet = Network{
 	DCP0010Height: 1,
 	// DCP0012Height on simnet is 1 because the agenda will always be active.
 	DCP0012Height: 1,
+	// Fees on test networks have no real value, so any fee is plausible.
+	DefaultVSPFee:   1.0,
+	MaxPlausibleFee: 100.0,
 }
 
 func NetworkFromName(name string) (*Network, error) {
@@ -91,6 +110,42 @@ func NetworkFromName(name string) (*Network, error) {
 	}
 }
 
+// networks is every network supported by vspd.
+var networks = []*Network{&MainNet, &TestNet3, &SimNet}
+
+// IsName reports whether name identifies this network, either by the name of
+// its chain params, eg. testnet3, or by the name used to select it in the vspd
+// config, eg. testnet.
+func (n *Network) IsName(name string) bool {
+	if name == n.Name {
+		return true
+	}
+	named, err := NetworkFromName(name)
+	return err == nil && named == n
+}
+
+// WIFNetwork returns the supported network which the WIF encoded private key
+// is for, or nil if the key is not valid for any supported network.
+func WIFNetwork(wif string) *Network {
+	for _, n := range networks {
+		if _, err := dcrutil.DecodeWIF(wif, n.PrivateKeyID); err == nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// AddressNetwork returns the supported network which the address is for, or
+// nil if the address is not valid for any supported network.
+func AddressNetwork(addr string) *Network {
+	for _, n := range networks {
+		if _, err := stdaddr.DecodeAddress(addr, n); err == nil {
+			return n
+		}
+	}
+	return nil
+}
+
 // DCP5Active returns true if the DCP-0005 block header commitments agenda is
 // active on this network at the provided height, otherwise false.
 func (n *Network) DCP5Active(height int64) bool {
@@ -120,3 +175,31 @@ func (n *Network) CurrentVoteVersion() uint32 {
 	}
 	return latestVersion
 }
+
+// VoteVersions returns the vote versions with agendas which tickets may still
+// be voting on at the provided time, most recent first. The current vote
+// version is always included. Older versions are included while any of their
+// agendas have not yet expired, because during the transition to a new vote
+// version tickets may continue to vote on the agendas of the previous one.
+func (n *Network) VoteVersions(now time.Time) []uint32 {
+	current := n.CurrentVoteVersion()
+	versions := []uint32{current}
+
+	for version, deployments := range n.Deployments {
+		if version == current {
+			continue
+		}
+		for _, deployment := range deployments {
+			if deployment.ExpireTime > uint64(now.Unix()) {
+				versions = append(versions, version)
+				break
+			}
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] > versions[j]
+	})
+
+	return versions
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec"
+	"github.com/decred/dcrd/dcrutil/v4"
+	"github.com/decred/dcrd/txscript/v4/stdaddr"
+)
+
+func TestIsName(t *testing.T) {
+	tests := map[string]struct {
+		network *Network
+		name    string
+		expect  bool
+	}{
+		"mainnet":                {&MainNet, "mainnet", true},
+		"testnet params name":    {&TestNet3, "testnet3", true},
+		"testnet config name":    {&TestNet3, "testnet", true},
+		"simnet":                 {&SimNet, "simnet", true},
+		"mainnet is not testnet": {&MainNet, "testnet", false},
+		"testnet is not mainnet": {&TestNet3, "mainnet", false},
+		"unknown network":        {&MainNet, "regnet", false},
+		"empty name":             {&MainNet, "", false},
+		"case sensitive":         {&MainNet, "MainNet", false},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := test.network.IsName(test.name)
+			if actual != test.expect {
+				t.Fatalf("expected %t, got %t", test.expect, actual)
+			}
+		})
+	}
+}
+
+func TestWIFAndAddressNetwork(t *testing.T) {
+	privKey := bytes.Repeat([]byte{1}, 32)
+	pkHash := bytes.Repeat([]byte{2}, 20)
+
+	for _, network := range networks {
+		t.Run(network.Name, func(t *testing.T) {
+			wif, err := dcrutil.NewWIF(privKey, network.PrivateKeyID, dcrec.STEcdsaSecp256k1)
+			if err != nil {
+				t.Fatalf("failed to create WIF: %v", err)
+			}
+			if actual := WIFNetwork(wif.String()); actual != network {
+				t.Fatalf("expected WIF network %s, got %v", network.Name, actual)
+			}
+
+			addr, err := stdaddr.NewAddressPubKeyHashEcdsaSecp256k1V0(pkHash, network)
+			if err != nil {
+				t.Fatalf("failed to create address: %v", err)
+			}
+			if actual := AddressNetwork(addr.String()); actual != network {
+				t.Fatalf("expected address network %s, got %v", network.Name, actual)
+			}
+		})
+	}
+
+	if WIFNetwork("not a wif") != nil {
+		t.Fatal("expected no network for invalid WIF")
+	}
+	if AddressNetwork("not an address") != nil {
+		t.Fatal("expected no network for invalid address")
+	}
+}
+
+func TestVoteVersions(t *testing.T) {
+	// Mainnet vote version 9 agendas expire on 2023-09-16, and vote version 10
+	// is the current version.
+	tests := map[string]struct {
+		now    time.Time
+		expect []uint32
+	}{
+		"previous version not expired": {
+			now:    time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC),
+			expect: []uint32{10, 9},
+		},
+		"previous version expired": {
+			now:    time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC),
+			expect: []uint32{10},
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := MainNet.VoteVersions(test.now)
+			if !reflect.DeepEqual(actual, test.expect) {
+				t.Fatalf("expected vote versions %v, got %v", test.expect, actual)
+			}
+		})
+	}
+}
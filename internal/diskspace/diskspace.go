@@ -0,0 +1,13 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package diskspace reports the free disk space of the filesystem containing a
+// path.
+package diskspace
+
+import "errors"
+
+// ErrUnsupported is returned by Free on platforms where free disk space cannot
+// be determined.
+var ErrUnsupported = errors.New("free disk space cannot be determined on this platform")
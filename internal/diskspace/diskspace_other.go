@@ -0,0 +1,14 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+//go:build !(darwin || dragonfly || freebsd || linux)
+
+package diskspace
+
+// Free returns the number of bytes available to unprivileged users on the
+// filesystem containing path. It always returns ErrUnsupported on this
+// platform.
+func Free(path string) (uint64, error) {
+	return 0, ErrUnsupported
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package diskspace
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestFree(t *testing.T) {
+	dir := t.TempDir()
+
+	free, err := Free(dir)
+	if errors.Is(err, ErrUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if free == 0 {
+		t.Fatal("expected free disk space to be non-zero")
+	}
+
+	_, err = Free(filepath.Join(dir, "missing"))
+	if err == nil {
+		t.Fatal("expected error for path which does not exist")
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package events publishes ticket lifecycle events to a NATS message queue so
+// they can be consumed by external systems.
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/decred/slog"
+)
+
+// Kind identifies a ticket lifecycle transition.
+type Kind string
+
+const (
+	TicketRegistered Kind = "registered"
+	FeeExpiring      Kind = "feeexpiring"
+	FeeBroadcast     Kind = "feebroadcast"
+	FeeConfirmed     Kind = "feeconfirmed"
+	TicketVoted      Kind = "voted"
+	TicketMissed     Kind = "missed"
+	TicketExpired    Kind = "expired"
+)
+
+const (
+	// queueSize is the number of events which can be waiting to be published.
+	// Events are dropped if the queue is full, eg. because the message queue
+	// has been unreachable for a long time.
+	queueSize = 1000
+	// reconnectInterval is the time to wait before reconnecting after the
+	// connection to the message queue fails.
+	reconnectInterval = 5 * time.Second
+	// dialTimeout is the maximum time to wait while connecting.
+	dialTimeout = 10 * time.Second
+)
+
+// Event is serialized to json and published to the message queue.
+type Event struct {
+	Kind       Kind   `json:"event"`
+	TicketHash string `json:"tickethash"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+// Publisher publishes events to a NATS server. Events are queued and published
+// asynchronously so callers are never blocked by the message queue. A nil
+// Publisher is safe to use and does nothing.
+type Publisher struct {
+	addr    string
+	user    string
+	pass    string
+	subject string
+	log     slog.Logger
+	queue   chan Event
+}
+
+// ParseURL ensures the provided NATS server URL is valid.
+func ParseURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "nats" {
+		return nil, fmt.Errorf("unsupported scheme %q, expected nats", u.Scheme)
+	}
+	if u.Port() == "" {
+		return nil, errors.New("url must include a port")
+	}
+	return u, nil
+}
+
+// New returns a Publisher which publishes events to subject on the NATS server
+// at rawURL. Credentials may be included in the URL.
+func New(rawURL, subject string, log slog.Logger) (*Publisher, error) {
+	u, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return nil, fmt.Errorf("invalid subject %q", subject)
+	}
+
+	pass, _ := u.User.Password()
+	return &Publisher{
+		addr:    u.Host,
+		user:    u.User.Username(),
+		pass:    pass,
+		subject: subject,
+		log:     log,
+		queue:   make(chan Event, queueSize),
+	}, nil
+}
+
+// Publish queues an event for the ticket with the provided hash.
+func (p *Publisher) Publish(kind Kind, ticketHash string) {
+	if p == nil {
+		return
+	}
+
+	event := Event{
+		Kind:       kind,
+		TicketHash: ticketHash,
+		Timestamp:  time.Now().Unix(),
+	}
+
+	select {
+	case p.queue <- event:
+	default:
+		p.log.Warnf("Event queue is full, dropping %s event (ticketHash=%s)", kind, ticketHash)
+	}
+}
+
+// Run publishes queued events until the context is canceled, reconnecting to
+// the NATS server whenever the connection fails.
+func (p *Publisher) Run(ctx context.Context) {
+	// pending holds an event which could not be published because the
+	// connection failed, so it can be retried once reconnected.
+	var pending *Event
+
+	for {
+		conn, err := p.connect(ctx)
+		if err == nil {
+			p.log.Infof("Connected to NATS server %s", p.addr)
+			pending, err = p.serve(ctx, conn, pending)
+			conn.Close()
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		p.log.Errorf("NATS server %s error: %v", p.addr, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectInterval):
+		}
+	}
+}
+
+// connect dials the NATS server and performs the protocol handshake.
+func (p *Publisher) connect(ctx context.Context) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// The server sends INFO as soon as a client connects.
+	_ = conn.SetReadDeadline(time.Now().Add(dialTimeout))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read server info: %w", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected message from server: %q", strings.TrimSpace(line))
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	connectOpts := struct {
+		Verbose  bool   `json:"verbose"`
+		Pedantic bool   `json:"pedantic"`
+		Name     string `json:"name"`
+		Lang     string `json:"lang"`
+		Protocol int    `json:"protocol"`
+		User     string `json:"user,omitempty"`
+		Pass     string `json:"pass,omitempty"`
+	}{
+		Name:     "vspd",
+		Lang:     "go",
+		Protocol: 1,
+		User:     p.user,
+		Pass:     p.pass,
+	}
+	opts, err := json.Marshal(connectOpts)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	_, err = fmt.Fprintf(conn, "CONNECT %s\r\n", opts)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// serve publishes events over an established connection and answers pings
+// from the server, until the connection fails or the context is canceled. An
+// event which was being published when the connection failed is returned.
+func (p *Publisher) serve(ctx context.Context, conn net.Conn, pending *Event) (*Event, error) {
+	// Writes are made both when publishing and when answering pings.
+	var writeMtx sync.Mutex
+	write := func(format string, a ...any) error {
+		writeMtx.Lock()
+		defer writeMtx.Unlock()
+		_, err := fmt.Fprintf(conn, format, a...)
+		return err
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				readErr <- err
+				return
+			}
+
+			line = strings.TrimSpace(line)
+			switch {
+			case line == "PING":
+				if err := write("PONG\r\n"); err != nil {
+					readErr <- err
+					return
+				}
+			case strings.HasPrefix(line, "-ERR"):
+				readErr <- fmt.Errorf("server error: %s", strings.TrimPrefix(line, "-ERR "))
+				return
+			}
+		}
+	}()
+
+	publish := func(event Event) error {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		return write("PUB %s %d\r\n%s\r\n", p.subject, len(payload), payload)
+	}
+
+	if pending != nil {
+		if err := publish(*pending); err != nil {
+			return pending, err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case err := <-readErr:
+			return nil, err
+		case event := <-p.queue:
+			if err := publish(event); err != nil {
+				return &event, err
+			}
+		}
+	}
+}
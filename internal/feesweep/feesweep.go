@@ -0,0 +1,160 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package feesweep periodically sweeps confirmed fee payments received by the
+// VSP to an address controlled by the operator.
+package feesweep
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/decred/dcrd/dcrutil/v4"
+	"github.com/decred/dcrd/txscript/v4/stdaddr"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/slog"
+	"github.com/decred/vspd/rpc"
+)
+
+// Config contains the settings used to sweep fees.
+type Config struct {
+	// Account is the name of the fee wallet account which holds the private
+	// keys of the fee xpub.
+	Account string
+	// Destination is the address which fees are swept to.
+	Destination stdaddr.Address
+	// MinBalance is the minimum spendable balance required before a sweep is
+	// attempted, to avoid paying tx fees to sweep tiny amounts.
+	MinBalance dcrutil.Amount
+	// Confirmations is the number of confirmations a fee payment requires
+	// before it is swept.
+	Confirmations int32
+	// Interval is the time period between two sweep attempts.
+	Interval time.Duration
+}
+
+// feeWallet is the subset of rpc.FeeWalletRPC used to sweep fees.
+type feeWallet interface {
+	SpendableBalance(account string, minConf int32) (dcrutil.Amount, error)
+	SweepAccount(account, destAddr string, minConf uint32) (string, error)
+	SignRawTransaction(txHex string) (string, error)
+	SendRawTransaction(txHex string) (string, error)
+}
+
+// Sweeper periodically sweeps the spendable balance of the fee wallet account
+// to the configured destination address.
+type Sweeper struct {
+	cfg    Config
+	wallet func() (feeWallet, error)
+	log    slog.Logger
+}
+
+// New returns a Sweeper which sweeps fees using the provided fee wallet.
+func New(cfg Config, wallet *rpc.FeeWalletConnect, log slog.Logger) *Sweeper {
+	return &Sweeper{
+		cfg: cfg,
+		wallet: func() (feeWallet, error) {
+			return wallet.Client()
+		},
+		log: log,
+	}
+}
+
+// Run attempts a sweep every interval until the context is canceled.
+func (s *Sweeper) Run(ctx context.Context) {
+	s.log.Infof("Sweeping fees from account %q to %s every %v once the spendable "+
+		"balance reaches %v", s.cfg.Account, s.cfg.Destination, s.cfg.Interval, s.cfg.MinBalance)
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := s.sweep()
+			if err != nil {
+				s.log.Errorf("Failed to sweep fees: %v", err)
+			}
+		}
+	}
+}
+
+// sweep sends the spendable balance of the fee account to the destination
+// address if it has reached the minimum balance. The unsigned tx created by
+// the wallet is checked to ensure it pays only the destination address before
+// it is signed and broadcast.
+func (s *Sweeper) sweep() error {
+	wallet, err := s.wallet()
+	if err != nil {
+		return err
+	}
+
+	balance, err := wallet.SpendableBalance(s.cfg.Account, s.cfg.Confirmations)
+	if err != nil {
+		return fmt.Errorf("SpendableBalance error: %w", err)
+	}
+
+	if balance < s.cfg.MinBalance {
+		s.log.Debugf("Not sweeping fees, spendable balance %v is below minimum %v",
+			balance, s.cfg.MinBalance)
+		return nil
+	}
+
+	unsignedHex, err := wallet.SweepAccount(s.cfg.Account, s.cfg.Destination.String(),
+		uint32(s.cfg.Confirmations))
+	if err != nil {
+		return fmt.Errorf("SweepAccount error: %w", err)
+	}
+
+	amount, err := s.checkSweepTx(unsignedHex)
+	if err != nil {
+		return err
+	}
+
+	signedHex, err := wallet.SignRawTransaction(unsignedHex)
+	if err != nil {
+		return fmt.Errorf("SignRawTransaction error: %w", err)
+	}
+
+	txHash, err := wallet.SendRawTransaction(signedHex)
+	if err != nil {
+		return fmt.Errorf("SendRawTransaction error: %w", err)
+	}
+
+	s.log.Infof("Swept %v of fees to %s (txHash=%s)", amount, s.cfg.Destination, txHash)
+
+	return nil
+}
+
+// checkSweepTx ensures the provided tx has exactly one output, and that it
+// pays the destination address. The value of the output is returned.
+func (s *Sweeper) checkSweepTx(txHex string) (dcrutil.Amount, error) {
+	txBytes, err := hex.DecodeString(txHex)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode sweep tx: %w", err)
+	}
+
+	var tx wire.MsgTx
+	err = tx.FromBytes(txBytes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to deserialize sweep tx: %w", err)
+	}
+
+	if len(tx.TxOut) != 1 {
+		return 0, fmt.Errorf("sweep tx has %d outputs, expected 1", len(tx.TxOut))
+	}
+
+	version, script := s.cfg.Destination.PaymentScript()
+	out := tx.TxOut[0]
+	if out.Version != version || !bytes.Equal(out.PkScript, script) {
+		return 0, fmt.Errorf("sweep tx does not pay destination address %s", s.cfg.Destination)
+	}
+
+	return dcrutil.Amount(out.Value), nil
+}
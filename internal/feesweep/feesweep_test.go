@@ -0,0 +1,143 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package feesweep
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/dcrd/dcrutil/v4"
+	"github.com/decred/dcrd/txscript/v4/stdaddr"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/slog"
+)
+
+type fakeWallet struct {
+	balance dcrutil.Amount
+	sweepTx string
+	signed  bool
+	sentHex string
+	sweptTo string
+}
+
+func (f *fakeWallet) SpendableBalance(string, int32) (dcrutil.Amount, error) {
+	return f.balance, nil
+}
+
+func (f *fakeWallet) SweepAccount(_, destAddr string, _ uint32) (string, error) {
+	f.sweptTo = destAddr
+	return f.sweepTx, nil
+}
+
+func (f *fakeWallet) SignRawTransaction(txHex string) (string, error) {
+	f.signed = true
+	return txHex, nil
+}
+
+func (f *fakeWallet) SendRawTransaction(txHex string) (string, error) {
+	f.sentHex = txHex
+	return "txhash", nil
+}
+
+func txPaying(t *testing.T, addrs ...stdaddr.Address) string {
+	t.Helper()
+
+	tx := wire.NewMsgTx()
+	tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, 0, wire.TxTreeRegular), 0, nil))
+	for _, addr := range addrs {
+		version, script := addr.PaymentScript()
+		tx.AddTxOut(&wire.TxOut{Value: 1e8, Version: version, PkScript: script})
+	}
+
+	var buf bytes.Buffer
+	err := tx.Serialize(&buf)
+	if err != nil {
+		t.Fatalf("failed to serialize tx: %v", err)
+	}
+	return hex.EncodeToString(buf.Bytes())
+}
+
+func TestSweep(t *testing.T) {
+	params := chaincfg.TestNet3Params()
+	dest, err := stdaddr.NewAddressPubKeyHashEcdsaSecp256k1V0(bytes.Repeat([]byte{1}, 20), params)
+	if err != nil {
+		t.Fatalf("failed to create address: %v", err)
+	}
+	other, err := stdaddr.NewAddressPubKeyHashEcdsaSecp256k1V0(bytes.Repeat([]byte{2}, 20), params)
+	if err != nil {
+		t.Fatalf("failed to create address: %v", err)
+	}
+
+	cfg := Config{
+		Account:       "fees",
+		Destination:   dest,
+		MinBalance:    dcrutil.Amount(1e8),
+		Confirmations: 6,
+		Interval:      time.Hour,
+	}
+
+	tests := map[string]struct {
+		balance   dcrutil.Amount
+		sweepTx   string
+		expectErr bool
+		expectTx  bool
+	}{
+		"below minimum balance": {
+			balance: 1e8 - 1,
+			sweepTx: txPaying(t, dest),
+		},
+		"valid sweep": {
+			balance:  1e8,
+			sweepTx:  txPaying(t, dest),
+			expectTx: true,
+		},
+		"pays other address": {
+			balance:   1e8,
+			sweepTx:   txPaying(t, other),
+			expectErr: true,
+		},
+		"multiple outputs": {
+			balance:   1e8,
+			sweepTx:   txPaying(t, dest, other),
+			expectErr: true,
+		},
+		"invalid tx": {
+			balance:   1e8,
+			sweepTx:   "zz",
+			expectErr: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			wallet := &fakeWallet{balance: test.balance, sweepTx: test.sweepTx}
+			s := &Sweeper{
+				cfg:    cfg,
+				wallet: func() (feeWallet, error) { return wallet, nil },
+				log:    slog.Disabled,
+			}
+
+			err := s.sweep()
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error=%t, got %v", test.expectErr, err)
+			}
+
+			if test.expectTx {
+				if wallet.sweptTo != dest.String() {
+					t.Fatalf("expected sweep to %s, got %q", dest, wallet.sweptTo)
+				}
+				if wallet.sentHex != test.sweepTx {
+					t.Fatal("expected sweep tx to be broadcast")
+				}
+			} else if wallet.signed || wallet.sentHex != "" {
+				t.Fatal("expected sweep tx not to be signed or broadcast")
+			}
+		})
+	}
+}
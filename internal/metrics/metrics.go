@@ -0,0 +1,88 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package metrics collects counters and gauges describing the operation of
+// vspd so they can be exported to external monitoring systems.
+package metrics
+
+import (
+	"sync"
+)
+
+// Registry holds the current value of every metric. Metrics are created the
+// first time they are updated. A nil Registry is safe to use and does nothing.
+type Registry struct {
+	mtx      sync.Mutex
+	counters map[string]int64
+	gauges   map[string]int64
+}
+
+// Snapshot is a copy of the values held by a Registry at a point in time.
+type Snapshot struct {
+	// Counters are cumulative totals which only ever increase.
+	Counters map[string]int64
+	// Gauges are values which can go up or down, set to their latest value.
+	Gauges map[string]int64
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		counters: make(map[string]int64),
+		gauges:   make(map[string]int64),
+	}
+}
+
+// IncCounter adds one to the named counter.
+func (r *Registry) IncCounter(name string) {
+	r.AddCounter(name, 1)
+}
+
+// AddCounter adds delta to the named counter.
+func (r *Registry) AddCounter(name string, delta int64) {
+	if r == nil {
+		return
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.counters[name] += delta
+}
+
+// SetGauge sets the named gauge to value.
+func (r *Registry) SetGauge(name string, value int64) {
+	if r == nil {
+		return
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.gauges[name] = value
+}
+
+// Snapshot returns a copy of the current value of every metric.
+func (r *Registry) Snapshot() Snapshot {
+	s := Snapshot{
+		Counters: make(map[string]int64),
+		Gauges:   make(map[string]int64),
+	}
+
+	if r == nil {
+		return s
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	for name, value := range r.counters {
+		s.Counters[name] = value
+	}
+	for name, value := range r.gauges {
+		s.Gauges[name] = value
+	}
+
+	return s
+}
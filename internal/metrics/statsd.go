@@ -0,0 +1,129 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/decred/slog"
+)
+
+// maxPacketSize is the maximum size of a single StatsD UDP packet. Larger
+// packets risk being fragmented or dropped on common network links.
+const maxPacketSize = 1432
+
+// StatsD periodically pushes the metrics held by a Registry to a StatsD server
+// over UDP.
+type StatsD struct {
+	addr     string
+	prefix   string
+	interval time.Duration
+	registry *Registry
+	log      slog.Logger
+
+	// lastCounters holds counter values as of the previous push. StatsD
+	// counters are incremented by each value received, so only the change
+	// since the previous push is sent.
+	lastCounters map[string]int64
+}
+
+// NewStatsD returns a StatsD which pushes metrics from registry to the server
+// at addr every interval. The names of all metrics are prefixed with prefix
+// followed by a dot, unless prefix is empty.
+func NewStatsD(addr, prefix string, interval time.Duration, registry *Registry,
+	log slog.Logger) *StatsD {
+	if prefix != "" {
+		prefix += "."
+	}
+	return &StatsD{
+		addr:         addr,
+		prefix:       prefix,
+		interval:     interval,
+		registry:     registry,
+		log:          log,
+		lastCounters: make(map[string]int64),
+	}
+}
+
+// Run pushes metrics until the context is canceled.
+func (s *StatsD) Run(ctx context.Context) {
+	conn, err := net.Dial("udp", s.addr)
+	if err != nil {
+		s.log.Errorf("Failed to connect to StatsD server %s: %v", s.addr, err)
+		return
+	}
+	defer conn.Close()
+
+	s.log.Infof("Pushing metrics to StatsD server %s every %v", s.addr, s.interval)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, packet := range s.packets(s.registry.Snapshot()) {
+				_, err := conn.Write(packet)
+				if err != nil {
+					s.log.Warnf("Failed to push metrics to StatsD server %s: %v", s.addr, err)
+					break
+				}
+			}
+		}
+	}
+}
+
+// packets encodes a snapshot into StatsD packets, each containing as many
+// newline separated metrics as fit within maxPacketSize. Counters which have
+// not changed since the previous call are omitted.
+func (s *StatsD) packets(snap Snapshot) [][]byte {
+	var lines []string
+
+	for _, name := range sortedNames(snap.Counters) {
+		delta := snap.Counters[name] - s.lastCounters[name]
+		if delta == 0 {
+			continue
+		}
+		s.lastCounters[name] = snap.Counters[name]
+		lines = append(lines, fmt.Sprintf("%s%s:%d|c", s.prefix, name, delta))
+	}
+
+	for _, name := range sortedNames(snap.Gauges) {
+		lines = append(lines, fmt.Sprintf("%s%s:%d|g", s.prefix, name, snap.Gauges[name]))
+	}
+
+	var packets [][]byte
+	var packet []byte
+	for _, line := range lines {
+		if len(packet) > 0 && len(packet)+1+len(line) > maxPacketSize {
+			packets = append(packets, packet)
+			packet = nil
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	if len(packet) > 0 {
+		packets = append(packets, packet)
+	}
+
+	return packets
+}
+
+func sortedNames(m map[string]int64) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
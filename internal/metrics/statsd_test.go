@@ -0,0 +1,72 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/slog"
+)
+
+func TestStatsDPackets(t *testing.T) {
+	registry := NewRegistry()
+	s := NewStatsD("", "vspd", time.Second, registry, slog.Disabled)
+
+	registry.AddCounter("requests", 3)
+	registry.SetGauge("wallets.online", 2)
+
+	expected := [][]byte{[]byte("vspd.requests:3|c\nvspd.wallets.online:2|g")}
+	actual := s.packets(registry.Snapshot())
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+
+	// Only the change in counters since the previous push should be sent,
+	// and unchanged counters omitted entirely.
+	registry.IncCounter("requests")
+	registry.IncCounter("errors")
+	expected = [][]byte{[]byte("vspd.errors:1|c\nvspd.requests:1|c\nvspd.wallets.online:2|g")}
+	actual = s.packets(registry.Snapshot())
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+
+	expected = [][]byte{[]byte("vspd.wallets.online:2|g")}
+	actual = s.packets(registry.Snapshot())
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestStatsDPacketSize(t *testing.T) {
+	registry := NewRegistry()
+	s := NewStatsD("", "vspd", time.Second, registry, slog.Disabled)
+
+	const numGauges = 200
+	for i := 0; i < numGauges; i++ {
+		registry.SetGauge(fmt.Sprintf("gauge%03d", i), int64(i))
+	}
+
+	packets := s.packets(registry.Snapshot())
+	if len(packets) < 2 {
+		t.Fatalf("expected metrics to be split over multiple packets, got %d", len(packets))
+	}
+
+	var lines int
+	for _, packet := range packets {
+		if len(packet) > maxPacketSize {
+			t.Fatalf("packet size %d exceeds maximum %d", len(packet), maxPacketSize)
+		}
+		lines += len(strings.Split(string(packet), "\n"))
+	}
+
+	if lines != numGauges {
+		t.Fatalf("expected %d metrics, got %d", numGauges, lines)
+	}
+}
@@ -1,5 +1,5 @@
 // Copyright (c) 2013-2014 The btcsuite developers
-// Copyright (c) 2021-2023 The Decred developers
+// Copyright (c) 2021-2024 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
@@ -17,14 +17,55 @@ import (
 // shutdown. This may be modified during init depending on the platform.
 var interruptSignals = []os.Signal{os.Interrupt}
 
+// reloadSignals defines the signals which request a config reload rather than
+// a shutdown when reloading is enabled. This may be modified during init
+// depending on the platform.
+var reloadSignals []os.Signal
+
 // ShutdownListener listens for OS Signals such as SIGINT (Ctrl+C) and shutdown
 // requests from requestShutdown. It returns a context that is canceled when
 // either signal is received.
 func ShutdownListener(log slog.Logger) context.Context {
+	return listen(log, interruptSignals)
+}
+
+// ReloadListener behaves like ShutdownListener, except that reload signals such
+// as SIGHUP do not cause a shutdown. Instead they are sent on the returned
+// channel. Reload signals received while a previous one is still waiting to be
+// handled are dropped.
+func ReloadListener(log slog.Logger) (context.Context, <-chan os.Signal) {
+	reload := make(chan os.Signal, 1)
+
+	// signal.Notify relays every signal if none are specified, so only call it
+	// on platforms which have reload signals.
+	if len(reloadSignals) > 0 {
+		signal.Notify(reload, reloadSignals...)
+	}
+
+	var shutdownSignals []os.Signal
+	for _, sig := range interruptSignals {
+		isReload := false
+		for _, reloadSig := range reloadSignals {
+			if sig == reloadSig {
+				isReload = true
+				break
+			}
+		}
+		if !isReload {
+			shutdownSignals = append(shutdownSignals, sig)
+		}
+	}
+
+	return listen(log, shutdownSignals), reload
+}
+
+// listen returns a context that is canceled when any of the provided signals
+// is received.
+func listen(log slog.Logger, signals []os.Signal) context.Context {
 	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
 		interruptChannel := make(chan os.Signal, 1)
-		signal.Notify(interruptChannel, interruptSignals...)
+		signal.Notify(interruptChannel, signals...)
 
 		// Listen for the initial shutdown signal.
 		sig := <-interruptChannel
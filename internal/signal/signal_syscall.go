@@ -1,5 +1,5 @@
 // Copyright (c) 2016 The btcsuite developers
-// Copyright (c) 2021-2023 The Decred developers
+// Copyright (c) 2021-2024 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
@@ -13,4 +13,5 @@ import (
 
 func init() {
 	interruptSignals = append(interruptSignals, syscall.SIGTERM, syscall.SIGHUP)
+	reloadSignals = append(reloadSignals, syscall.SIGHUP)
 }
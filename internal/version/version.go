@@ -1,4 +1,4 @@
-// Copyright (c) 2020 The Decred developers
+// Copyright (c) 2020-2024 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
@@ -28,6 +28,17 @@ const (
 // It must only contain characters from the semantic version alphabet.
 var preRelease = "pre"
 
+// commit and buildDate contain the git commit hash and the date the binary was
+// built. They are variables so they can be modified at link time (e.g.
+// `-ldflags "-X github.com/decred/vspd/internal/version.commit=abc123
+// -X github.com/decred/vspd/internal/version.buildDate=2024-01-02T15:04:05Z"`).
+// If commit is not set at link time, the VCS revision embedded by the Go
+// toolchain will be used instead.
+var (
+	commit    = ""
+	buildDate = ""
+)
+
 func IsPreRelease() bool {
 	return preRelease != ""
 }
@@ -61,7 +72,34 @@ func String() string {
 	return version
 }
 
+// Commit returns the full git commit hash of the source used to build the
+// binary, or an empty string if it is not known.
+func Commit() string {
+	if commit != "" {
+		return normalizeVerString(commit)
+	}
+	return vcsRevision()
+}
+
+// BuildDate returns the date the binary was built, or an empty string if it was
+// not set at link time.
+func BuildDate() string {
+	return buildDate
+}
+
+// vcsCommitID returns the abbreviated commit hash which is appended to the
+// version string as build metadata.
 func vcsCommitID() string {
+	revision := Commit()
+	if len(revision) > 9 {
+		revision = revision[:9]
+	}
+	return revision
+}
+
+// vcsRevision returns the git revision embedded in the binary by the Go
+// toolchain, or an empty string if it is not available.
+func vcsRevision() string {
 	bi, ok := debug.ReadBuildInfo()
 	if !ok {
 		return ""
@@ -78,9 +116,6 @@ func vcsCommitID() string {
 	if vcs == "" {
 		return ""
 	}
-	if vcs == "git" && len(revision) > 9 {
-		revision = revision[:9]
-	}
 	return revision
 }
 
@@ -120,6 +120,8 @@ func (v *Vspd) checkRevoked(ctx context.Context) error {
 			fixedExpired++
 		}
 
+		spentTicket.dbTicket.SpendingTxHash = spentTicket.spendingTx.TxHash().String()
+
 		err = v.db.UpdateTicket(spentTicket.dbTicket)
 		if err != nil {
 			v.log.Errorf("Could not update status of ticket %s: %v", hash, err)
@@ -0,0 +1,146 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package vspd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/decred/dcrd/txscript/v4/stdscript"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/internal/alert"
+	"github.com/decred/vspd/rpc"
+)
+
+// checkFeeAddressReuse checks the outputs of every block mined since the
+// previous check for payments to fee addresses which have been issued to more
+// than one ticket, or which were made by a transaction other than the fee tx of
+// the ticket the address was issued to. Either indicates a serious problem such
+// as a bug in fee address derivation, so an alert is sent.
+//
+// Payments mined before vspd started are not detected. Blocks are scanned by
+// height, so blocks which are reorged out after being scanned are not
+// rescanned.
+func (v *Vspd) checkFeeAddressReuse(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
+	const funcName = "checkFeeAddressReuse"
+
+	bestHeight, err := dcrdClient.GetBlockCount()
+	if err != nil {
+		v.log.Errorf("%s: dcrd.GetBlockCount error: %v", funcName, err)
+		return
+	}
+
+	// Start scanning from the current best block if no scan has been
+	// performed since vspd started.
+	if v.lastReuseCheckBlock == 0 {
+		v.lastReuseCheckBlock = bestHeight
+		return
+	}
+
+	for height := v.lastReuseCheckBlock + 1; height <= bestHeight; height++ {
+		// Exit early if context has been canceled.
+		if ctx.Err() != nil {
+			return
+		}
+
+		hash, err := dcrdClient.GetBlockHash(height)
+		if err != nil {
+			v.log.Errorf("%s: dcrd.GetBlockHash error (height=%d): %v", funcName, height, err)
+			return
+		}
+
+		block, err := dcrdClient.GetBlock(hash)
+		if err != nil {
+			v.log.Errorf("%s: dcrd.GetBlock error (height=%d): %v", funcName, height, err)
+			return
+		}
+
+		txs := make([]*wire.MsgTx, 0, len(block.Transactions)+len(block.STransactions))
+		txs = append(txs, block.Transactions...)
+		txs = append(txs, block.STransactions...)
+
+		// Look up only the addresses paid by the block in the database.
+		feeAddrs, err := v.db.FeeAddressTickets(v.paidAddresses(txs))
+		if err != nil {
+			v.log.Errorf("%s: db.FeeAddressTickets error (height=%d): %v", funcName, height, err)
+			return
+		}
+
+		for addr, tickets := range feeAddrs {
+			if len(tickets) > 1 {
+				hashes := make([]string, len(tickets))
+				for i, ticket := range tickets {
+					hashes[i] = ticket.TicketHash
+				}
+				v.log.Errorf("Fee address %s has been issued to %d tickets (ticketHashes=%s, height=%d)",
+					addr, len(tickets), strings.Join(hashes, ","), height)
+				v.alerter.Alert(alert.FeeAddressReuse, "Fee address %s has been issued to "+
+					"multiple tickets: %s", addr, strings.Join(hashes, ", "))
+			}
+		}
+
+		for _, tx := range txs {
+			for addr, ticketHash := range v.unexpectedFeePayments(tx, feeAddrs) {
+				txHash := tx.TxHash().String()
+				v.log.Errorf("Fee address %s received an unexpected payment (ticketHash=%s, "+
+					"txHash=%s, height=%d)", addr, ticketHash, txHash, height)
+				v.alerter.Alert(alert.FeeAddressReuse, "Fee address %s of ticket %s "+
+					"received an unexpected payment in tx %s at height %d",
+					addr, ticketHash, txHash, height)
+			}
+		}
+
+		v.lastReuseCheckBlock = height
+	}
+}
+
+// paidAddresses returns every address paid by the outputs of txs.
+func (v *Vspd) paidAddresses(txs []*wire.MsgTx) []string {
+	seen := make(map[string]struct{})
+	var paid []string
+	for _, tx := range txs {
+		for _, txOut := range tx.TxOut {
+			_, addrs := stdscript.ExtractAddrs(txOut.Version, txOut.PkScript, v.network)
+			for _, addr := range addrs {
+				if _, ok := seen[addr.String()]; ok {
+					continue
+				}
+				seen[addr.String()] = struct{}{}
+				paid = append(paid, addr.String())
+			}
+		}
+	}
+
+	return paid
+}
+
+// unexpectedFeePayments returns the fee addresses paid by tx which were issued
+// to a ticket with a different fee tx, mapped to the hash of the ticket. Fee
+// addresses of tickets which have not yet received a fee tx are ignored, as the
+// client may have broadcast the fee tx before providing it to the VSP.
+func (v *Vspd) unexpectedFeePayments(tx *wire.MsgTx,
+	feeAddrs map[string][]database.FeeAddressTicket) map[string]string {
+
+	var unexpected map[string]string
+	txHash := tx.TxHash().String()
+
+	for _, txOut := range tx.TxOut {
+		_, addrs := stdscript.ExtractAddrs(txOut.Version, txOut.PkScript, v.network)
+		for _, addr := range addrs {
+			for _, ticket := range feeAddrs[addr.String()] {
+				if ticket.FeeTxHash == "" || ticket.FeeTxHash == txHash {
+					continue
+				}
+				if unexpected == nil {
+					unexpected = make(map[string]string)
+				}
+				unexpected[addr.String()] = ticket.TicketHash
+			}
+		}
+	}
+
+	return unexpected
+}
@@ -0,0 +1,213 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package vspd
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/internal/alert"
+	"github.com/decred/vspd/rpc"
+)
+
+// maxKeyImportInterval is the maximum number of blocks between retries of a
+// failed voting key import. The interval starts at one block and doubles with
+// each failed retry until it reaches this maximum.
+const maxKeyImportInterval = 64
+
+// importBackoff tracks the retries of voting key imports for a ticket. It is
+// only held in memory, so imports are retried on the first block after vspd
+// restarts.
+type importBackoff struct {
+	// failures is the number of retries which have failed.
+	failures int
+	// wait is the number of blocks to skip before the next retry.
+	wait int
+}
+
+// markForReimport records on the ticket that its voting key could not be
+// imported into a voting wallet, so the import is retried by retryImports on
+// later blocks. Tickets are only marked if key import retries are enabled.
+func (v *Vspd) markForReimport(ticket *database.Ticket, wallet string, importErr error) {
+	const funcName = "markForReimport"
+
+	if v.cfg.KeyImportRetries == 0 {
+		return
+	}
+
+	if ticket.ReimportWallets == nil {
+		ticket.ReimportWallets = make(map[string]string)
+	}
+	ticket.ReimportWallets[wallet] = importErr.Error()
+
+	err := v.db.UpdateTicket(*ticket)
+	if err != nil {
+		v.log.Errorf("%s: db.UpdateTicket error, failed to mark ticket for re-import (ticketHash=%s): %v",
+			funcName, ticket.Hash, err)
+		return
+	}
+
+	// Skip the retry in the current block so the first retry happens on the
+	// next one.
+	if v.reimportBackoff[ticket.Hash] == nil {
+		v.reimportBackoff[ticket.Hash] = &importBackoff{wait: 1}
+	}
+}
+
+// retryImports attempts to add every ticket marked by markForReimport to the
+// voting wallets which it could not previously be added to. Retries are made
+// with an increasing number of blocks between them, and an alert is sent once
+// KeyImportRetries retries of a ticket have failed. A retry fails if any of the
+// wallets cannot be connected to. Wallets which are no longer configured are
+// not retried, and an alert is sent instead.
+func (v *Vspd) retryImports(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
+	const funcName = "retryImports"
+
+	if v.cfg.KeyImportRetries == 0 {
+		return
+	}
+
+	tickets, err := v.db.GetReimportTickets()
+	if err != nil {
+		v.log.Errorf("%s: db.GetReimportTickets error: %v", funcName, err)
+		return
+	}
+
+	// Forget the backoff of tickets which no longer need to be re-imported,
+	// eg. because they have voted.
+	marked := make(map[string]struct{}, len(tickets))
+	for _, ticket := range tickets {
+		marked[ticket.Hash] = struct{}{}
+	}
+	for ticketHash := range v.reimportBackoff {
+		if _, ok := marked[ticketHash]; !ok {
+			delete(v.reimportBackoff, ticketHash)
+		}
+	}
+
+	// Only retry tickets which are not waiting for a later block.
+	var due database.TicketList
+	for _, ticket := range tickets {
+		backoff := v.reimportBackoff[ticket.Hash]
+		if backoff == nil {
+			backoff = &importBackoff{}
+			v.reimportBackoff[ticket.Hash] = backoff
+		}
+		if backoff.wait > 0 {
+			backoff.wait--
+			continue
+		}
+		due = append(due, ticket)
+	}
+
+	if len(due) == 0 {
+		return
+	}
+
+	walletClients, failedConnections := v.wallets.Clients()
+	v.alertWalletsOffline(failedConnections)
+	if len(walletClients) == 0 {
+		v.log.Errorf("%s: Could not connect to any wallets", funcName)
+	}
+
+	configured := make(map[string]struct{})
+	for _, addr := range v.wallets.Addrs() {
+		configured[addr] = struct{}{}
+	}
+
+	for _, ticket := range due {
+		// Exit early if context has been canceled.
+		if ctx.Err() != nil {
+			return
+		}
+
+		// Stop retrying imports into wallets which are no longer configured,
+		// and tell the operator that the voting key never reached them.
+		var removed []string
+		for wallet := range ticket.ReimportWallets {
+			if _, ok := configured[wallet]; !ok {
+				removed = append(removed, wallet)
+				delete(ticket.ReimportWallets, wallet)
+			}
+		}
+		if len(removed) > 0 {
+			sort.Strings(removed)
+			v.log.Warnf("%s: Voting key was never imported into %s which are no longer "+
+				"configured, no longer retrying (wallets=%s, ticketHash=%s)", funcName,
+				pluralize(len(removed), "voting wallet"), strings.Join(removed, ", "), ticket.Hash)
+			v.alerter.Alert(alert.KeyImportFailed, "Voting key of ticket %s was never imported "+
+				"into %s which are no longer configured: %s", ticket.Hash,
+				pluralize(len(removed), "voting wallet"), strings.Join(removed, ", "))
+		}
+
+		// Wallets which are not connected count as failed retries, so a wallet
+		// which never reconnects is eventually alerted on.
+		var connected []*rpc.WalletRPC
+		for _, walletClient := range walletClients {
+			if _, ok := ticket.ReimportWallets[walletClient.String()]; ok {
+				connected = append(connected, walletClient)
+			}
+		}
+		failed := len(connected) < len(ticket.ReimportWallets)
+
+		if len(connected) > 0 {
+			rawTicket, err := dcrdClient.GetRawTransaction(ticket.Hash)
+			if err != nil {
+				v.log.Errorf("%s: dcrd.GetRawTransaction for ticket failed (ticketHash=%s): %v",
+					funcName, ticket.Hash, err)
+				continue
+			}
+
+			for _, walletClient := range connected {
+				err = walletClient.AddTicketForVoting(ticket.VotingWIF, rawTicket.BlockHash, rawTicket.Hex)
+				if err != nil {
+					v.log.Errorf("%s: dcrwallet.AddTicketForVoting error (wallet=%s, ticketHash=%s): %v",
+						funcName, walletClient.String(), ticket.Hash, err)
+					ticket.ReimportWallets[walletClient.String()] = err.Error()
+					failed = true
+					continue
+				}
+
+				v.setTicketPolicies(walletClient, ticket)
+				delete(ticket.ReimportWallets, walletClient.String())
+				v.log.Infof("Ticket re-imported to voting wallet %s (ticketHash=%s)",
+					walletClient.String(), ticket.Hash)
+			}
+		}
+
+		if len(connected) > 0 || len(removed) > 0 {
+			err = v.db.UpdateTicket(ticket)
+			if err != nil {
+				v.log.Errorf("%s: db.UpdateTicket error, failed to update re-import wallets (ticketHash=%s): %v",
+					funcName, ticket.Hash, err)
+			}
+		}
+
+		if len(ticket.ReimportWallets) == 0 {
+			delete(v.reimportBackoff, ticket.Hash)
+			continue
+		}
+
+		if !failed {
+			continue
+		}
+
+		backoff := v.reimportBackoff[ticket.Hash]
+		backoff.failures++
+		interval := 1
+		for i := 0; i < backoff.failures && interval < maxKeyImportInterval; i++ {
+			interval *= 2
+		}
+		backoff.wait = interval - 1
+
+		if backoff.failures == v.cfg.KeyImportRetries {
+			v.alerter.Alert(alert.KeyImportFailed, "Failed to import voting key of ticket %s "+
+				"into %s after %d retries", ticket.Hash,
+				pluralize(len(ticket.ReimportWallets), "voting wallet"), backoff.failures)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package vspd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/decred/dcrd/txscript/v4/stdaddr"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/rpc"
+)
+
+// orphanFeeAddress records the fee address of a ticket which was never mined so
+// that it can be reissued once it is confirmed to have never received a
+// payment. Fee addresses of tickets which ever had a fee tx are never recorded.
+func (v *Vspd) orphanFeeAddress(dcrdClient *rpc.DcrdRPC, ticket database.Ticket) {
+	const funcName = "orphanFeeAddress"
+
+	if ticket.FeeAddress == "" || ticket.FeeTxStatus != database.NoFee ||
+		ticket.FeeTxHash != "" || ticket.FeeTxHex != "" {
+		return
+	}
+
+	height, err := dcrdClient.GetBlockCount()
+	if err != nil {
+		v.log.Errorf("%s: dcrd.GetBlockCount error (ticketHash=%s): %v",
+			funcName, ticket.Hash, err)
+		return
+	}
+
+	err = v.db.StoreOrphanedFeeAddress(database.OrphanedFeeAddress{
+		Address: ticket.FeeAddress,
+		XPubID:  ticket.FeeAddressXPubID,
+		Index:   ticket.FeeAddressIndex,
+		Height:  height,
+	})
+	if err != nil {
+		v.log.Errorf("%s: db.StoreOrphanedFeeAddress error (ticketHash=%s): %v",
+			funcName, ticket.Hash, err)
+		return
+	}
+
+	v.log.Debugf("Recorded orphaned fee address %s (ticketHash=%s)",
+		ticket.FeeAddress, ticket.Hash)
+}
+
+// verifyOrphanedFeeAddresses scans the chain for payments to any orphaned fee
+// addresses which have not yet been verified. Addresses which have received a
+// payment are discarded so they are never reissued, and all others are marked
+// as verified.
+//
+// A ticket can only remain in the mempool until the end of the stake difficulty
+// window it was purchased in, so its fee address cannot have been issued more
+// than two windows before the ticket was removed from the database. Addresses
+// are only verified once a further window has passed, which gives late payments
+// a chance to be mined before the scan.
+func (v *Vspd) verifyOrphanedFeeAddresses(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
+	const funcName = "verifyOrphanedFeeAddresses"
+
+	orphaned, err := v.db.OrphanedFeeAddresses()
+	if err != nil {
+		v.log.Errorf("%s: db.OrphanedFeeAddresses error: %v", funcName, err)
+		return
+	}
+
+	if len(orphaned) == 0 {
+		return
+	}
+
+	bestHeight, err := dcrdClient.GetBlockCount()
+	if err != nil {
+		v.log.Errorf("%s: dcrd.GetBlockCount error: %v", funcName, err)
+		return
+	}
+
+	window := v.network.StakeDiffWindowSize
+
+	for _, addr := range orphaned {
+		// Exit early if context has been canceled.
+		if ctx.Err() != nil {
+			return
+		}
+
+		if addr.Verified || bestHeight < addr.Height+window {
+			continue
+		}
+
+		startHeight := addr.Height - 2*window
+		if startHeight < 1 {
+			startHeight = 1
+		}
+
+		paid, err := v.addressPaid(ctx, dcrdClient, addr.Address, startHeight, bestHeight)
+		if err != nil {
+			v.log.Errorf("%s: addressPaid error (address=%s): %v", funcName, addr.Address, err)
+			continue
+		}
+
+		if paid {
+			v.log.Warnf("Orphaned fee address %s has received a payment and will not be reissued",
+				addr.Address)
+			err = v.db.DeleteOrphanedFeeAddress(addr.Address)
+			if err != nil {
+				v.log.Errorf("%s: db.DeleteOrphanedFeeAddress error (address=%s): %v",
+					funcName, addr.Address, err)
+			}
+			continue
+		}
+
+		addr.Verified = true
+		err = v.db.StoreOrphanedFeeAddress(addr)
+		if err != nil {
+			v.log.Errorf("%s: db.StoreOrphanedFeeAddress error (address=%s): %v",
+				funcName, addr.Address, err)
+			continue
+		}
+
+		v.log.Infof("Orphaned fee address %s verified unpaid and can be reissued", addr.Address)
+	}
+}
+
+// addressPaid reports whether any transaction mined in the mainchain blocks
+// between startHeight and endHeight (inclusive) pays to the provided address.
+// Block filters are used to avoid fetching blocks which cannot contain a
+// payment.
+func (v *Vspd) addressPaid(ctx context.Context, dcrdClient *rpc.DcrdRPC, address string,
+	startHeight, endHeight int64) (bool, error) {
+
+	parsedAddr, err := stdaddr.DecodeAddress(address, v.network)
+	if err != nil {
+		return false, err
+	}
+	_, script := parsedAddr.PaymentScript()
+
+	for iHeight := startHeight; iHeight <= endHeight; iHeight++ {
+		// Exit early if context has been canceled.
+		if ctx.Err() != nil {
+			return false, context.Canceled
+		}
+
+		iHash, err := dcrdClient.GetBlockHash(iHeight)
+		if err != nil {
+			return false, err
+		}
+
+		iHeader, err := dcrdClient.GetBlockHeader(iHash)
+		if err != nil {
+			return false, err
+		}
+
+		verifyProof := v.network.DCP5Active(iHeight)
+		key, filter, err := dcrdClient.GetCFilterV2(iHeader, verifyProof)
+		if err != nil {
+			return false, err
+		}
+
+		if !filter.Match(key, script) {
+			continue
+		}
+
+		// Filter match means the address is likely paid in this block. Get the
+		// full block to confirm.
+		iBlock, err := dcrdClient.GetBlock(iHash)
+		if err != nil {
+			return false, fmt.Errorf("dcrd.GetBlock error: %w", err)
+		}
+
+		for _, txs := range [][]*wire.MsgTx{iBlock.Transactions, iBlock.STransactions} {
+			for _, tx := range txs {
+				if txPaysScript(tx, script) {
+					return true, nil
+				}
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// txPaysScript returns true if any output of the passed tx has the provided
+// payment script.
+func txPaysScript(tx *wire.MsgTx, script []byte) bool {
+	for _, txOut := range tx.TxOut {
+		if bytes.Equal(txOut.PkScript, script) {
+			return true
+		}
+	}
+	return false
+}
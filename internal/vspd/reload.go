@@ -0,0 +1,61 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package vspd
+
+import (
+	"reflect"
+)
+
+// reloadableOptions contains the long names of the config options which can be
+// changed by reloading the config while vspd is running. All other options
+// only take effect when vspd is restarted.
+var reloadableOptions = map[string]struct{}{
+	"vspclosed":    {},
+	"vspclosedmsg": {},
+	"vspfee":       {},
+}
+
+// ConfigChange describes a config option which has a different value in a
+// reloaded config.
+type ConfigChange struct {
+	// Option is the long name of the option.
+	Option string
+	// Old and New are the previous and reloaded values of the option. Secrets
+	// are redacted.
+	Old, New any
+	// Reloadable is true if the change can be applied without restarting.
+	Reloadable bool
+}
+
+// ConfigChanges returns every option which has a different value in newCfg
+// than in cfg, in the order they are declared.
+func (cfg *Config) ConfigChanges(newCfg *Config) []ConfigChange {
+	oldRedacted, newRedacted := cfg.Redacted(), newCfg.Redacted()
+	oldVal, newVal := reflect.ValueOf(*cfg), reflect.ValueOf(*newCfg)
+	oldShown, newShown := reflect.ValueOf(oldRedacted), reflect.ValueOf(newRedacted)
+
+	var changes []ConfigChange
+	t := oldVal.Type()
+	for i := 0; i < t.NumField(); i++ {
+		option := t.Field(i).Tag.Get("long")
+		if option == "" {
+			continue
+		}
+		before, after := oldVal.Field(i).Interface(), newVal.Field(i).Interface()
+		if reflect.DeepEqual(before, after) {
+			continue
+		}
+
+		_, reloadable := reloadableOptions[option]
+		changes = append(changes, ConfigChange{
+			Option:     option,
+			Old:        oldShown.Field(i).Interface(),
+			New:        newShown.Field(i).Interface(),
+			Reloadable: reloadable,
+		})
+	}
+
+	return changes
+}
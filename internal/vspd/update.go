@@ -1,4 +1,4 @@
-// Copyright (c) 2020-2023 The Decred developers
+// Copyright (c) 2020-2024 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
@@ -10,6 +10,9 @@ import (
 	"strings"
 
 	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/internal/alert"
+	"github.com/decred/vspd/internal/broadcast"
+	"github.com/decred/vspd/internal/events"
 	"github.com/decred/vspd/rpc"
 	"github.com/jrick/wsrpc/v2"
 )
@@ -26,31 +29,113 @@ func (v *Vspd) update(ctx context.Context) {
 		return
 	}
 
-	// Step 1/4: Update the database with any tickets which now have 6+
+	// Step 1/6: Update the database with any tickets which now have 6+
 	// confirmations.
 	v.updateUnconfirmed(ctx, dcrdClient)
 	if ctx.Err() != nil {
 		return
 	}
 
-	// Step 2/4: Broadcast fee tx for tickets which are confirmed.
-	v.broadcastFees(ctx, dcrdClient)
+	// Step 2/6: Broadcast fee tx for tickets which are confirmed.
+	v.broadcastFees(ctx, false)
 	if ctx.Err() != nil {
 		return
 	}
 
-	// Step 3/4: Add tickets with confirmed fees to voting wallets.
+	// Step 3/6: Add tickets with confirmed fees to voting wallets, and retry
+	// adding tickets which previously could not be added.
 	v.addToWallets(ctx, dcrdClient)
 	if ctx.Err() != nil {
 		return
 	}
+	v.retryImports(ctx, dcrdClient)
+	if ctx.Err() != nil {
+		return
+	}
 
-	// Step 4/4: Set ticket outcome in database if any tickets are
+	// Step 4/6: Set ticket outcome in database if any tickets are
 	// voted/revoked.
 	v.setOutcomes(ctx, dcrdClient)
 	if ctx.Err() != nil {
 		return
 	}
+
+	// Step 5/6: Verify orphaned fee addresses have never been paid so they
+	// can be reissued.
+	if v.cfg.RecycleFeeAddresses {
+		v.verifyOrphanedFeeAddresses(ctx, dcrdClient)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+
+	// Step 6/6: Check for fee addresses which have been reused.
+	if v.cfg.CheckFeeAddrReuse {
+		v.checkFeeAddressReuse(ctx, dcrdClient)
+	}
+}
+
+// updatePriority gives tickets which have paid for priority processing faster
+// handling of their fee transactions than waiting for a new block. Pending fee
+// txs which could not be broadcast, for example because broadcasts were
+// throttled, are retried, and broadcast fee txs which dcrd no longer knows
+// about, for example because they were evicted from its mempool, are broadcast
+// again so they can still be mined in the next block.
+func (v *Vspd) updatePriority(ctx context.Context) {
+	const funcName = "updatePriority"
+
+	dcrdClient, _, err := v.dcrd.Client()
+	if err != nil {
+		v.log.Errorf("%s: %v", funcName, err)
+		return
+	}
+
+	v.broadcastFees(ctx, true)
+	if ctx.Err() != nil {
+		return
+	}
+
+	v.rebroadcastDroppedFees(ctx, dcrdClient)
+}
+
+// rebroadcastDroppedFees broadcasts the fee txs of priority tickets again if
+// they have been broadcast but are neither mined nor in the mempool of dcrd.
+func (v *Vspd) rebroadcastDroppedFees(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
+	const funcName = "rebroadcastDroppedFees"
+
+	unconfirmedFees, err := v.db.GetUnconfirmedFees()
+	if err != nil {
+		v.log.Errorf("%s: db.GetUnconfirmedFees error: %v", funcName, err)
+		return
+	}
+
+	for _, ticket := range unconfirmedFees.Priority() {
+		// Exit early if context has been canceled.
+		if ctx.Err() != nil {
+			return
+		}
+
+		_, err := dcrdClient.GetRawTransaction(ticket.FeeTxHash)
+		if err == nil {
+			continue
+		}
+		var e *wsrpc.Error
+		if !errors.As(err, &e) || e.Code != rpc.ErrNoTxInfo {
+			v.log.Errorf("%s: dcrd.GetRawTransaction for fee tx failed (feeTxHash=%s, ticketHash=%s): %v",
+				funcName, ticket.FeeTxHash, ticket.Hash, err)
+			continue
+		}
+
+		_, err = v.broadcaster.Broadcast(ticket.FeeTxHex)
+		if err != nil {
+			v.log.Warnf("%s: Rebroadcast of dropped fee tx failed (ticketHash=%s, feeHash=%s): %v",
+				funcName, ticket.Hash, ticket.FeeTxHash, err)
+			continue
+		}
+
+		v.log.Infof("Dropped fee tx rebroadcast for priority ticket (ticketHash=%s, feeHash=%s)",
+			ticket.Hash, ticket.FeeTxHash)
+	}
 }
 
 func (v *Vspd) updateUnconfirmed(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
@@ -83,6 +168,8 @@ func (v *Vspd) updateUnconfirmed(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
 				if err != nil {
 					v.log.Errorf("%s: db.DeleteTicket error (ticketHash=%s): %v",
 						funcName, ticket.Hash, err)
+				} else if v.cfg.RecycleFeeAddresses {
+					v.orphanFeeAddress(dcrdClient, ticket)
 				}
 
 				// This will not error if an alternate signing address does not
@@ -115,7 +202,10 @@ func (v *Vspd) updateUnconfirmed(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
 	}
 }
 
-func (v *Vspd) broadcastFees(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
+// broadcastFees broadcasts fee transactions which are pending, with the fees
+// of priority tickets being broadcast first. If priorityOnly is true, only the
+// fees of priority tickets are broadcast.
+func (v *Vspd) broadcastFees(ctx context.Context, priorityOnly bool) {
 	const funcName = "broadcastFees"
 
 	pending, err := v.db.GetPendingFees()
@@ -124,18 +214,50 @@ func (v *Vspd) broadcastFees(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
 		return
 	}
 
-	for _, ticket := range pending {
+	if priorityOnly {
+		pending = pending.Priority()
+	} else {
+		pending.SortPriorityFirst()
+	}
+
+	var failed int
+	defer func() {
+		if failed >= feeErrorAlertThreshold {
+			v.alerter.Alert(alert.FeeErrors, "%d fee transactions failed to broadcast", failed)
+		}
+	}()
+
+	for i, ticket := range pending {
 		// Exit early if context has been canceled.
 		if ctx.Err() != nil {
 			return
 		}
 
-		err = dcrdClient.SendRawTransaction(ticket.FeeTxHex)
+		acceptedHash, err := v.broadcaster.Broadcast(ticket.FeeTxHex)
+		if errors.Is(err, broadcast.ErrThrottled) {
+			// The network is temporarily refusing transactions. Leave this and
+			// all remaining fee txs pending so they are retried later, rather
+			// than marking them as failed.
+			v.log.Warnf("%s: Fee tx broadcasts throttled, %d fee txs remain pending: %v",
+				funcName, len(pending)-i, err)
+			return
+		}
 		if err != nil {
-			v.log.Errorf("%s: dcrd.SendRawTransaction for fee tx failed (ticketHash=%s): %v",
+			v.log.Errorf("%s: Broadcast of fee tx failed (ticketHash=%s): %v",
 				funcName, ticket.Hash, err)
+			failed++
 			ticket.FeeTxStatus = database.FeeError
 		} else {
+			// Ensure the stored hash matches the hash accepted by the network,
+			// otherwise the fee tx will not be found when checking whether it
+			// has confirmed.
+			if acceptedHash != "" && acceptedHash != ticket.FeeTxHash {
+				v.log.Warnf("%s: Broadcast fee tx hash does not match stored hash, updating "+
+					"(ticketHash=%s, storedHash=%s, acceptedHash=%s)",
+					funcName, ticket.Hash, ticket.FeeTxHash, acceptedHash)
+				ticket.FeeTxHash = acceptedHash
+			}
+
 			v.log.Infof("Fee tx broadcast for ticket (ticketHash=%s, feeHash=%s)",
 				ticket.Hash, ticket.FeeTxHash)
 			ticket.FeeTxStatus = database.FeeBroadcast
@@ -145,10 +267,18 @@ func (v *Vspd) broadcastFees(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
 		if err != nil {
 			v.log.Errorf("%s: db.UpdateTicket error, failed to set fee tx as broadcast (ticketHash=%s): %v",
 				funcName, ticket.Hash, err)
+			continue
+		}
+
+		if ticket.FeeTxStatus == database.FeeBroadcast {
+			v.events.Publish(events.FeeBroadcast, ticket.Hash)
 		}
 	}
 }
 
+// addToWallets checks whether broadcast fee transactions are confirmed, and
+// adds their tickets to the voting wallets if so. Priority tickets are checked
+// first.
 func (v *Vspd) addToWallets(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
 	const funcName = "addToWallets"
 
@@ -158,7 +288,10 @@ func (v *Vspd) addToWallets(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
 		return
 	}
 
+	unconfirmedFees.SortPriorityFirst()
+
 	walletClients, failedConnections := v.wallets.Clients()
+	v.alertWalletsOffline(failedConnections)
 	if len(walletClients) == 0 {
 		v.log.Errorf("%s: Could not connect to any wallets", funcName)
 		return
@@ -190,7 +323,7 @@ func (v *Vspd) addToWallets(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
 
 		// If fee is confirmed, update the database and add ticket to voting
 		// wallets.
-		if feeTx.Confirmations >= requiredConfs {
+		if feeTx.Confirmations >= v.cfg.FeeConfirmations {
 			// We no longer need the hex once the tx is confirmed on-chain.
 			ticket.FeeTxHex = ""
 			ticket.FeeTxStatus = database.FeeConfirmed
@@ -201,6 +334,7 @@ func (v *Vspd) addToWallets(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
 				continue
 			}
 			v.log.Infof("Fee tx confirmed (ticketHash=%s)", ticket.Hash)
+			v.events.Publish(events.FeeConfirmed, ticket.Hash)
 
 			// Add ticket to the voting wallet.
 
@@ -218,47 +352,12 @@ func (v *Vspd) addToWallets(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
 				if err != nil {
 					v.log.Errorf("%s: dcrwallet.AddTicketForVoting error (wallet=%s, ticketHash=%s): %v",
 						funcName, walletClient.String(), ticket.Hash, err)
+					v.markForReimport(&ticket, walletClient.String(), err)
 					continue
 				}
 				added++
 
-				// Set consensus vote choices on voting wallets.
-				for agenda, choice := range ticket.VoteChoices {
-					err = walletClient.SetVoteChoice(agenda, choice, ticket.Hash)
-					if err != nil {
-						if strings.Contains(err.Error(), "no agenda with ID") {
-							v.log.Warnf("%s: Removing invalid agenda from ticket vote choices (ticketHash=%s, agenda=%s)",
-								funcName, ticket.Hash, agenda)
-							delete(ticket.VoteChoices, agenda)
-							err = v.db.UpdateTicket(ticket)
-							if err != nil {
-								v.log.Errorf("%s: db.UpdateTicket error, failed to remove invalid agenda (ticketHash=%s): %v",
-									funcName, ticket.Hash, err)
-							}
-						} else {
-							v.log.Errorf("%s: dcrwallet.SetVoteChoice error (wallet=%s, ticketHash=%s): %v",
-								funcName, walletClient.String(), ticket.Hash, err)
-						}
-					}
-				}
-
-				// Set tspend policy on voting wallets.
-				for tspend, policy := range ticket.TSpendPolicy {
-					err = walletClient.SetTSpendPolicy(tspend, policy, ticket.Hash)
-					if err != nil {
-						v.log.Errorf("%s: dcrwallet.SetTSpendPolicy failed (wallet=%s, ticketHash=%s): %v",
-							funcName, walletClient.String(), ticket.Hash, err)
-					}
-				}
-
-				// Set treasury policy on voting wallets.
-				for key, policy := range ticket.TreasuryPolicy {
-					err = walletClient.SetTreasuryPolicy(key, policy, ticket.Hash)
-					if err != nil {
-						v.log.Errorf("%s: dcrwallet.SetTreasuryPolicy failed (wallet=%s, ticketHash=%s): %v",
-							funcName, walletClient.String(), ticket.Hash, err)
-					}
-				}
+				v.setTicketPolicies(walletClient, ticket)
 			}
 
 			v.log.Infof("Ticket added to %s (ticketHash=%s)",
@@ -268,6 +367,52 @@ func (v *Vspd) addToWallets(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
 	}
 }
 
+// setTicketPolicies sets the consensus vote choices, tspend policies and
+// treasury policies of a ticket on a voting wallet which it has been added to.
+// Vote choices for agendas which the wallet does not recognize are removed
+// from the ticket.
+func (v *Vspd) setTicketPolicies(walletClient *rpc.WalletRPC, ticket database.Ticket) {
+	const funcName = "setTicketPolicies"
+
+	// Set consensus vote choices on voting wallets.
+	for agenda, choice := range ticket.VoteChoices {
+		err := walletClient.SetVoteChoice(agenda, choice, ticket.Hash)
+		if err != nil {
+			if strings.Contains(err.Error(), "no agenda with ID") {
+				v.log.Warnf("%s: Removing invalid agenda from ticket vote choices (ticketHash=%s, agenda=%s)",
+					funcName, ticket.Hash, agenda)
+				delete(ticket.VoteChoices, agenda)
+				err = v.db.UpdateTicket(ticket)
+				if err != nil {
+					v.log.Errorf("%s: db.UpdateTicket error, failed to remove invalid agenda (ticketHash=%s): %v",
+						funcName, ticket.Hash, err)
+				}
+			} else {
+				v.log.Errorf("%s: dcrwallet.SetVoteChoice error (wallet=%s, ticketHash=%s): %v",
+					funcName, walletClient.String(), ticket.Hash, err)
+			}
+		}
+	}
+
+	// Set tspend policy on voting wallets.
+	for tspend, policy := range ticket.TSpendPolicy {
+		err := walletClient.SetTSpendPolicy(tspend, policy, ticket.Hash)
+		if err != nil {
+			v.log.Errorf("%s: dcrwallet.SetTSpendPolicy failed (wallet=%s, ticketHash=%s): %v",
+				funcName, walletClient.String(), ticket.Hash, err)
+		}
+	}
+
+	// Set treasury policy on voting wallets.
+	for key, policy := range ticket.TreasuryPolicy {
+		err := walletClient.SetTreasuryPolicy(key, policy, ticket.Hash)
+		if err != nil {
+			v.log.Errorf("%s: dcrwallet.SetTreasuryPolicy failed (wallet=%s, ticketHash=%s): %v",
+				funcName, walletClient.String(), ticket.Hash, err)
+		}
+	}
+}
+
 func (v *Vspd) setOutcomes(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
 	const funcName = "setOutcomes"
 
@@ -305,6 +450,8 @@ func (v *Vspd) setOutcomes(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
 
 	v.lastScannedBlock = endHeight
 
+	votingWallets, offlineWallets := v.votingWallets(spent)
+
 	for _, spentTicket := range spent {
 		// Exit early if context has been canceled.
 		if ctx.Err() != nil {
@@ -312,10 +459,12 @@ func (v *Vspd) setOutcomes(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
 		}
 
 		dbTicket := spentTicket.dbTicket
+		dbTicket.SpendingTxHash = spentTicket.spendingTx.TxHash().String()
 
 		switch {
 		case spentTicket.voted():
 			dbTicket.Outcome = database.Voted
+			dbTicket.VotingWallet = votingWallets[dbTicket.Hash]
 		case spentTicket.missed():
 			dbTicket.Outcome = database.Missed
 		default:
@@ -331,5 +480,86 @@ func (v *Vspd) setOutcomes(ctx context.Context, dcrdClient *rpc.DcrdRPC) {
 
 		v.log.Infof("Ticket %s at height %d (ticketHash=%s)",
 			dbTicket.Outcome, spentTicket.heightSpent, dbTicket.Hash)
+
+		switch dbTicket.Outcome {
+		case database.Voted:
+			v.events.Publish(events.TicketVoted, dbTicket.Hash)
+			v.checkDegradedVote(dbTicket, offlineWallets)
+		case database.Missed:
+			v.events.Publish(events.TicketMissed, dbTicket.Hash)
+			v.metrics.IncCounter("votes.missed")
+			v.alerter.Alert(alert.MissedVote, "Ticket missed its vote at height %d "+
+				"(offline=%v, ticketHash=%s)", spentTicket.heightSpent, offlineWallets, dbTicket.Hash)
+		case database.Expired:
+			v.events.Publish(events.TicketExpired, dbTicket.Hash)
+		}
 	}
 }
+
+// votingWallets returns a map of the hashes of voted tickets in spent to the
+// first voting wallet which recorded their vote, along with the voting wallets
+// which could not be queried. Votes are only recorded by wallets which were
+// online and held the voting key of the ticket, so the wallet recorded for a
+// vote is one which was able to cast it. Wallets are not queried if no tickets
+// were voted.
+func (v *Vspd) votingWallets(spent []spentTicket) (map[string]string, []string) {
+	const funcName = "votingWallets"
+
+	var startHeight int64
+	var voted []database.Ticket
+	for _, spentTicket := range spent {
+		if !spentTicket.voted() {
+			continue
+		}
+		if len(voted) == 0 || spentTicket.dbTicket.PurchaseHeight < startHeight {
+			startHeight = spentTicket.dbTicket.PurchaseHeight
+		}
+		voted = append(voted, spentTicket.dbTicket)
+	}
+
+	if len(voted) == 0 {
+		return nil, nil
+	}
+
+	walletClients, offline := v.wallets.Clients()
+
+	votingWallets := make(map[string]string, len(voted))
+	for _, walletClient := range walletClients {
+		walletTickets, err := walletClient.TicketInfo(startHeight)
+		if err != nil {
+			v.log.Errorf("%s: dcrwallet.TicketInfo failed (startHeight=%d, wallet=%s): %v",
+				funcName, startHeight, walletClient.String(), err)
+			offline = append(offline, walletClient.String())
+			continue
+		}
+
+		for _, spentTicket := range spent {
+			ticketHash := spentTicket.dbTicket.Hash
+			if _, ok := votingWallets[ticketHash]; ok {
+				continue
+			}
+
+			walletTicket, ok := walletTickets[ticketHash]
+			if ok && walletTicket.Vote == spentTicket.spendingTx.TxHash().String() {
+				votingWallets[ticketHash] = walletClient.String()
+			}
+		}
+	}
+
+	return votingWallets, offline
+}
+
+// checkDegradedVote determines whether voting was degraded when a ticket voted,
+// ie. whether at least v.cfg.DegradedWallets voting wallets were offline. Votes
+// cast in a degraded state are counted and logged along with the voting wallet
+// which recorded the vote.
+func (v *Vspd) checkDegradedVote(ticket database.Ticket, offline []string) {
+	if len(offline) < v.cfg.DegradedWallets {
+		return
+	}
+
+	v.metrics.IncCounter("votes.degraded")
+
+	v.log.Warnf("Ticket voted while voting was degraded (votingWallet=%s, offline=%v, ticketHash=%s)",
+		ticket.VotingWallet, offline, ticket.Hash)
+}
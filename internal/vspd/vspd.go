@@ -1,4 +1,4 @@
-// Copyright (c) 2020-2023 The Decred developers
+// Copyright (c) 2020-2024 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
@@ -7,18 +7,23 @@ package vspd
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/decred/dcrd/wire"
 	"github.com/decred/slog"
 	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/internal/alert"
+	"github.com/decred/vspd/internal/broadcast"
 	"github.com/decred/vspd/internal/config"
+	"github.com/decred/vspd/internal/events"
+	"github.com/decred/vspd/internal/metrics"
 	"github.com/decred/vspd/rpc"
 )
 
 const (
 	// requiredConfs is the number of confirmations required to consider a
-	// ticket purchase or a fee transaction to be final.
+	// ticket purchase to be final.
 	requiredConfs = 6
 
 	// consistencyInterval is the time period between wallet consistency checks.
@@ -26,33 +31,124 @@ const (
 
 	// dcrdInterval is the time period between dcrd connection checks.
 	dcrdInterval = time.Second * 15
+
+	// dcrdAlertDelay is how long dcrd must be unreachable before an alert is
+	// sent.
+	dcrdAlertDelay = 5 * time.Minute
+
+	// priorityInterval is the time period between fee broadcast checks for
+	// tickets which have paid for priority processing.
+	priorityInterval = time.Minute
+
+	// expiryInterval is the time period between checks for unpaid fees which
+	// are about to expire.
+	expiryInterval = time.Minute
+
+	// walletLagInterval is the time period between checks of how far voting
+	// wallets are behind dcrd.
+	walletLagInterval = time.Minute
+
+	// feeErrorAlertThreshold is the number of fee transactions which must
+	// fail in a single update before an alert is sent.
+	feeErrorAlertThreshold = 3
 )
 
+// Settings configures the behavior of the background processes run by Vspd.
+type Settings struct {
+	// FeeConfirmations is the number of confirmations required to consider a
+	// fee transaction to be confirmed.
+	FeeConfirmations int64
+
+	// DegradedWallets is the number of offline voting wallets at which voting
+	// is considered to be degraded.
+	DegradedWallets int
+
+	// RecycleFeeAddresses is true if fee addresses of tickets which were never
+	// mined should be recorded so they can be reissued.
+	RecycleFeeAddresses bool
+
+	// PriorityEnabled is true if the fees of priority tickets should be
+	// processed every priorityInterval, in addition to every block.
+	PriorityEnabled bool
+
+	// CheckFeeAddrReuse is true if new blocks should be checked for payments
+	// which indicate that a fee address has been reused.
+	CheckFeeAddrReuse bool
+
+	// FeeExpiryNotice is how long before an unpaid fee expires that a
+	// FeeExpiring event is published for its ticket. Zero disables the events.
+	FeeExpiryNotice time.Duration
+
+	// KeyImportRetries is the number of times adding a ticket to a voting
+	// wallet is retried if importing its voting key fails before an alert is
+	// sent. Zero disables retries.
+	KeyImportRetries int
+
+	// WalletLagThreshold is the number of blocks a voting wallet may be
+	// behind dcrd before it is reported as lagging. Zero disables reporting.
+	WalletLagThreshold int64
+}
+
 type Vspd struct {
+	cfg     Settings
 	network *config.Network
 	log     slog.Logger
 	db      *database.VspDatabase
 	dcrd    rpc.DcrdConnect
 	wallets rpc.WalletConnect
+	alerter *alert.Alerter
+	events  *events.Publisher
+	metrics *metrics.Registry
+
+	// broadcaster is used to send fee transactions to the network.
+	broadcaster broadcast.Broadcaster
 
 	blockNotifChan chan *wire.BlockHeader
 
+	// expiryNotified maps the hashes of tickets which have had a FeeExpiring
+	// event published to the fee expiration the event was published for, so
+	// each fee is only notified once.
+	expiryNotified map[string]int64
+
+	// reimportBackoff maps the hashes of tickets which are marked for voting
+	// key re-import to the state of their retries.
+	reimportBackoff map[string]*importBackoff
+
+	// dcrdUnreachableSince is the time at which dcrd was first found to be
+	// unreachable, or zero if dcrd is currently reachable.
+	dcrdUnreachableSince time.Time
+
 	// lastScannedBlock is the height of the most recent block which has been
 	// scanned for spent tickets.
 	lastScannedBlock int64
+
+	// lastReuseCheckBlock is the height of the most recent block which has
+	// been scanned for unexpected payments to fee addresses.
+	lastReuseCheckBlock int64
 }
 
-func New(network *config.Network, log slog.Logger, db *database.VspDatabase,
-	dcrd rpc.DcrdConnect, wallets rpc.WalletConnect, blockNotifChan chan *wire.BlockHeader) *Vspd {
+func New(cfg Settings, network *config.Network, log slog.Logger, db *database.VspDatabase,
+	dcrd rpc.DcrdConnect, wallets rpc.WalletConnect, broadcaster broadcast.Broadcaster,
+	blockNotifChan chan *wire.BlockHeader, alerter *alert.Alerter, events *events.Publisher,
+	registry *metrics.Registry) *Vspd {
 
 	v := &Vspd{
+		cfg:     cfg,
 		network: network,
 		log:     log,
 		db:      db,
 		dcrd:    dcrd,
 		wallets: wallets,
+		alerter: alerter,
+		events:  events,
+		metrics: registry,
+
+		broadcaster: broadcaster,
 
 		blockNotifChan: blockNotifChan,
+
+		expiryNotified:  make(map[string]int64),
+		reimportBackoff: make(map[string]*importBackoff),
 	}
 
 	return v
@@ -100,6 +196,24 @@ func (v *Vspd) Run(ctx context.Context) {
 	defer consistencyTicker.Stop()
 	dcrdTicker := time.NewTicker(dcrdInterval)
 	defer dcrdTicker.Stop()
+	walletLagTicker := time.NewTicker(walletLagInterval)
+	defer walletLagTicker.Stop()
+
+	// A nil channel is never ready, so priority processing only runs if it
+	// is enabled.
+	var priorityTick <-chan time.Time
+	if v.cfg.PriorityEnabled {
+		priorityTicker := time.NewTicker(priorityInterval)
+		defer priorityTicker.Stop()
+		priorityTick = priorityTicker.C
+	}
+
+	var expiryTick <-chan time.Time
+	if v.cfg.FeeExpiryNotice > 0 {
+		expiryTicker := time.NewTicker(expiryInterval)
+		defer expiryTicker.Stop()
+		expiryTick = expiryTicker.C
+	}
 
 	for {
 		select {
@@ -110,9 +224,20 @@ func (v *Vspd) Run(ctx context.Context) {
 		// Ensure dcrd client is connected so notifications are received.
 		case <-dcrdTicker.C:
 			_, _, err := v.dcrd.Client()
-			if err != nil {
-				v.log.Error(err)
-			}
+			v.checkDcrdReachable(err)
+
+		// Check whether any voting wallets are falling behind dcrd.
+		case <-walletLagTicker.C:
+			v.checkWalletLag()
+
+		// Retry and rebroadcast fee txs of priority tickets without waiting for
+		// a new block.
+		case <-priorityTick:
+			v.updatePriority(ctx)
+
+		// Notify clients of unpaid fees which are about to expire.
+		case <-expiryTick:
+			v.notifyExpiringFees(time.Now())
 
 		// Run the update function every time a block connected notification is
 		// received from dcrd.
@@ -126,3 +251,67 @@ func (v *Vspd) Run(ctx context.Context) {
 		}
 	}
 }
+
+// notifyExpiringFees publishes a FeeExpiring event for every ticket with an
+// unpaid fee which expires within FeeExpiryNotice of now. Each fee is only
+// notified once, but a ticket which is issued a new fee is notified again.
+func (v *Vspd) notifyExpiringFees(now time.Time) {
+	const funcName = "notifyExpiringFees"
+
+	expiring, err := v.db.GetExpiringFees(now, now.Add(v.cfg.FeeExpiryNotice))
+	if err != nil {
+		v.log.Errorf("%s: db.GetExpiringFees error: %v", funcName, err)
+		return
+	}
+
+	// Forget fees which have since expired.
+	for ticketHash, expiration := range v.expiryNotified {
+		if expiration <= now.Unix() {
+			delete(v.expiryNotified, ticketHash)
+		}
+	}
+
+	for _, ticket := range expiring {
+		if v.expiryNotified[ticket.Hash] == ticket.FeeExpiration {
+			continue
+		}
+		v.expiryNotified[ticket.Hash] = ticket.FeeExpiration
+
+		v.log.Debugf("%s: Fee expiring soon (ticketHash=%s, expiration=%s)", funcName,
+			ticket.Hash, time.Unix(ticket.FeeExpiration, 0).Format(time.RFC3339))
+		v.events.Publish(events.FeeExpiring, ticket.Hash)
+	}
+}
+
+// checkDcrdReachable tracks how long dcrd has been unreachable, and sends an
+// alert if it has been unreachable for longer than dcrdAlertDelay. err is the
+// result of the most recent attempt to connect to dcrd.
+func (v *Vspd) checkDcrdReachable(err error) {
+	if err == nil {
+		v.dcrdUnreachableSince = time.Time{}
+		return
+	}
+
+	v.log.Error(err)
+
+	if v.dcrdUnreachableSince.IsZero() {
+		v.dcrdUnreachableSince = time.Now()
+		return
+	}
+
+	if since := time.Since(v.dcrdUnreachableSince); since >= dcrdAlertDelay {
+		v.alerter.Alert(alert.DcrdUnreachable, "dcrd has been unreachable for %v: %v",
+			since.Round(time.Second), err)
+	}
+}
+
+// alertWalletsOffline sends an alert if any voting wallets could not be
+// connected to.
+func (v *Vspd) alertWalletsOffline(failedConnections []string) {
+	if len(failedConnections) == 0 {
+		return
+	}
+
+	v.alerter.Alert(alert.WalletOffline, "Failed to connect to %d voting wallet(s): %s",
+		len(failedConnections), strings.Join(failedConnections, ", "))
+}
@@ -24,6 +24,7 @@ func (v *Vspd) checkWalletConsistency(ctx context.Context) {
 	}
 
 	walletClients, failedConnections := v.wallets.Clients()
+	v.alertWalletsOffline(failedConnections)
 	if len(walletClients) == 0 {
 		v.log.Errorf("%s: Could not connect to any wallets", funcName)
 		return
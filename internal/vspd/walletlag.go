@@ -0,0 +1,72 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package vspd
+
+import (
+	"strings"
+
+	"github.com/decred/vspd/internal/alert"
+)
+
+// walletLag returns the number of blocks which a wallet with best block height
+// walletHeight is behind a dcrd with best block height dcrdHeight. Wallets
+// which are ahead of dcrd are not considered to be lagging.
+func walletLag(dcrdHeight, walletHeight int64) int64 {
+	if walletHeight >= dcrdHeight {
+		return 0
+	}
+	return dcrdHeight - walletHeight
+}
+
+// checkWalletLag compares the best block height of every voting wallet with
+// the best block height of dcrd, and records the greatest lag in the
+// wallets.maxlag gauge. A warning is logged and an alert is sent for any wallet
+// which is more than WalletLagThreshold blocks behind dcrd.
+func (v *Vspd) checkWalletLag() {
+	const funcName = "checkWalletLag"
+
+	dcrdClient, _, err := v.dcrd.Client()
+	if err != nil {
+		v.log.Errorf("%s: %v", funcName, err)
+		return
+	}
+
+	dcrdHeight, err := dcrdClient.GetBlockCount()
+	if err != nil {
+		v.log.Errorf("%s: dcrd.GetBlockCount error: %v", funcName, err)
+		return
+	}
+
+	walletClients, _ := v.wallets.Clients()
+
+	var maxLag int64
+	var lagging []string
+	for _, walletClient := range walletClients {
+		walletHeight, err := walletClient.GetBestBlockHeight()
+		if err != nil {
+			v.log.Errorf("%s: dcrwallet.GetBestBlockHeight error (wallet=%s): %v",
+				funcName, walletClient.String(), err)
+			continue
+		}
+
+		lag := walletLag(dcrdHeight, walletHeight)
+		if lag > maxLag {
+			maxLag = lag
+		}
+
+		if v.cfg.WalletLagThreshold > 0 && lag > v.cfg.WalletLagThreshold {
+			v.log.Warnf("%s: Voting wallet is %d blocks behind dcrd (wallet=%s, walletHeight=%d, dcrdHeight=%d)",
+				funcName, lag, walletClient.String(), walletHeight, dcrdHeight)
+			lagging = append(lagging, walletClient.String())
+		}
+	}
+
+	v.metrics.SetGauge("wallets.maxlag", maxLag)
+
+	if len(lagging) > 0 {
+		v.alerter.Alert(alert.WalletLagging, "%d voting wallet(s) are more than %d blocks behind dcrd: %s",
+			len(lagging), v.cfg.WalletLagThreshold, strings.Join(lagging, ", "))
+	}
+}
@@ -6,8 +6,15 @@ package webapi
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrutil/v4"
 	"github.com/decred/vspd/database"
 	"github.com/decred/vspd/rpc"
 	"github.com/gin-gonic/gin"
@@ -26,6 +33,7 @@ type walletStatus struct {
 	Voting          bool   `json:"voting"`
 	BestBlockError  bool   `json:"bestblockerror"`
 	BestBlockHeight int64  `json:"bestblockheight"`
+	BlockLag        int64  `json:"blocklag"`
 }
 
 // dcrdStatus describes the current status of the local instance of dcrd used by
@@ -38,6 +46,10 @@ type dcrdStatus struct {
 	BestBlockHeight uint32 `json:"bestblockheight"`
 }
 
+// maxTicketNotesLength is the maximum length of the notes which an operator can
+// attach to a ticket.
+const maxTicketNotesLength = 2000
+
 type searchResult struct {
 	Hash            string
 	Found           bool
@@ -46,16 +58,19 @@ type searchResult struct {
 	AltSignAddrData *database.AltSignAddrData
 	VoteChanges     map[uint32]database.VoteChangeRecord
 	MaxVoteChanges  int
+	MaxNotesLength  int
 }
 
 func (w *WebAPI) dcrdStatus(c *gin.Context) dcrdStatus {
+	log := w.requestLog(c)
+
 	hostname := c.MustGet(dcrdHostKey).(string)
 	status := dcrdStatus{Host: hostname}
 
 	dcrdClient := c.MustGet(dcrdKey).(*rpc.DcrdRPC)
 	dcrdErr := c.MustGet(dcrdErrorKey)
 	if dcrdErr != nil {
-		w.log.Errorf("%v", dcrdErr.(error))
+		log.Errorf("%v", dcrdErr.(error))
 		return status
 	}
 
@@ -63,7 +78,7 @@ func (w *WebAPI) dcrdStatus(c *gin.Context) dcrdStatus {
 
 	bestBlock, err := dcrdClient.GetBlockCount()
 	if err != nil {
-		w.log.Errorf("Could not get dcrd block count: %v", err)
+		log.Errorf("Could not get dcrd block count: %v", err)
 		status.BestBlockError = true
 		return status
 	}
@@ -74,6 +89,8 @@ func (w *WebAPI) dcrdStatus(c *gin.Context) dcrdStatus {
 }
 
 func (w *WebAPI) walletStatus(c *gin.Context) map[string]walletStatus {
+	log := w.requestLog(c)
+
 	walletClients := c.MustGet(walletsKey).([]*rpc.WalletRPC)
 	failedWalletClients := c.MustGet(failedWalletsKey).([]string)
 
@@ -83,7 +100,7 @@ func (w *WebAPI) walletStatus(c *gin.Context) map[string]walletStatus {
 
 		walletInfo, err := v.WalletInfo()
 		if err != nil {
-			w.log.Errorf("dcrwallet.WalletInfo error (wallet=%s): %v", v.String(), err)
+			log.Errorf("dcrwallet.WalletInfo error (wallet=%s): %v", v.String(), err)
 			ws.InfoError = true
 		} else {
 			ws.DaemonConnected = walletInfo.DaemonConnected
@@ -94,7 +111,7 @@ func (w *WebAPI) walletStatus(c *gin.Context) map[string]walletStatus {
 
 		height, err := v.GetBestBlockHeight()
 		if err != nil {
-			w.log.Errorf("dcrwallet.GetBestBlockHeight error (wallet=%s): %v", v.String(), err)
+			log.Errorf("dcrwallet.GetBestBlockHeight error (wallet=%s): %v", v.String(), err)
 			ws.BestBlockError = true
 		} else {
 			ws.BestBlockHeight = height
@@ -115,6 +132,20 @@ func (w *WebAPI) statusJSON(c *gin.Context) {
 	httpStatus := http.StatusOK
 
 	wallets := w.walletStatus(c)
+	dcrd := w.dcrdStatus(c)
+
+	// Determine how far each voting wallet is behind dcrd.
+	if dcrd.Connected && !dcrd.BestBlockError {
+		for host, wallet := range wallets {
+			if !wallet.Connected || wallet.BestBlockError {
+				continue
+			}
+			if lag := int64(dcrd.BestBlockHeight) - wallet.BestBlockHeight; lag > 0 {
+				wallet.BlockLag = lag
+				wallets[host] = wallet
+			}
+		}
+	}
 
 	// Respond with HTTP status 500 if any voting wallets have issues.
 	for _, wallet := range wallets {
@@ -123,32 +154,105 @@ func (w *WebAPI) statusJSON(c *gin.Context) {
 			!wallet.Connected ||
 			!wallet.DaemonConnected ||
 			!wallet.Voting ||
-			!wallet.Unlocked {
+			!wallet.Unlocked ||
+			(w.cfg.WalletLagThreshold > 0 && wallet.BlockLag > w.cfg.WalletLagThreshold) {
 			httpStatus = http.StatusInternalServerError
 			break
 		}
 	}
 
-	dcrd := w.dcrdStatus(c)
-
 	// Respond with HTTP status 500 if dcrd has issues.
 	if !dcrd.Connected || dcrd.BestBlockError {
 		httpStatus = http.StatusInternalServerError
 	}
 
+	// Respond with HTTP status 503 while requests are being rejected because
+	// startup has not completed.
+	ready := w.isReady()
+	if !ready && w.cfg.RejectUntilReady && httpStatus == http.StatusOK {
+		httpStatus = http.StatusServiceUnavailable
+	}
+
 	c.AbortWithStatusJSON(httpStatus, gin.H{
 		"wallets": wallets,
 		"dcrd":    dcrd,
+		"ready":   ready,
+	})
+}
+
+// defaultStatsDays and maxStatsDays are the default and maximum number of days
+// of registration history returned by /admin/stats.
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 366
+)
+
+// dailyRegistrations is the number of tickets registered on a single day, as
+// returned by /admin/stats.
+type dailyRegistrations struct {
+	Day     string `json:"day"`
+	Tickets int64  `json:"tickets"`
+}
+
+// statsJSON is the handler for "GET /admin/stats". It returns a JSON object
+// with the ticket counts and per-agenda vote choice tallies from the cache,
+// and the number of tickets registered on each of the last days days, which
+// defaults to defaultStatsDays. It only reads data, so it is available to the
+// read-only account as well as the admin account.
+func (w *WebAPI) statsJSON(c *gin.Context) {
+	log := w.requestLog(c)
+
+	cacheData := c.MustGet(cacheKey).(cacheData)
+
+	days := defaultStatsDays
+	if param := c.Query("days"); param != "" {
+		var err error
+		days, err = strconv.Atoi(param)
+		if err != nil || days < 1 || days > maxStatsDays {
+			c.String(http.StatusBadRequest, "days must be between 1 and %d", maxStatsDays)
+			return
+		}
+	}
+
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, 1-days)
+	counts, undated, err := w.db.CountRegistrationsByDay(from, to)
+	if err != nil {
+		log.Errorf("db.CountRegistrationsByDay error: %v", err)
+		c.String(http.StatusInternalServerError, "Error getting registrations from db")
+		return
+	}
+
+	registrations := make([]dailyRegistrations, 0, len(counts))
+	for _, count := range counts {
+		registrations = append(registrations, dailyRegistrations{
+			Day:     count.Day.Format(time.DateOnly),
+			Tickets: count.Tickets,
+		})
+	}
+
+	c.AbortWithStatusJSON(http.StatusOK, gin.H{
+		"lastupdated":    cacheData.LastUpdated,
+		"voting":         cacheData.Voting,
+		"reserved":       cacheData.Reserved,
+		"voted":          cacheData.Voted,
+		"expired":        cacheData.Expired,
+		"missed":         cacheData.Missed,
+		"votechoices":    cacheData.VoteChoiceCounts,
+		"registrations":  registrations,
+		"undatedtickets": undated,
 	})
 }
 
 // adminPage is the handler for "GET /admin".
 func (w *WebAPI) adminPage(c *gin.Context) {
+	log := w.requestLog(c)
+
 	cacheData := c.MustGet(cacheKey).(cacheData)
 
 	missed, err := w.db.GetMissedTickets()
 	if err != nil {
-		w.log.Errorf("db.GetMissedTickets error: %v", err)
+		log.Errorf("db.GetMissedTickets error: %v", err)
 		c.String(http.StatusInternalServerError, "Error getting missed tickets from db")
 		return
 	}
@@ -157,14 +261,14 @@ func (w *WebAPI) adminPage(c *gin.Context) {
 
 	xpubs, err := w.db.AllXPubs()
 	if err != nil {
-		w.log.Errorf("db.AllXPubs error: %v", err)
+		log.Errorf("db.AllXPubs error: %v", err)
 		c.String(http.StatusInternalServerError, "Error getting xpubs from db")
 		return
 	}
 
 	c.HTML(http.StatusOK, "admin.html", gin.H{
 		"WebApiCache":   cacheData,
-		"WebApiCfg":     w.cfg,
+		"WebApiCfg":     w.config(),
 		"WalletStatus":  w.walletStatus(c),
 		"DcrdStatus":    w.dcrdStatus(c),
 		"MissedTickets": missed,
@@ -175,27 +279,29 @@ func (w *WebAPI) adminPage(c *gin.Context) {
 // ticketSearch is the handler for "POST /admin/ticket". The hash param will be
 // used to retrieve a ticket from the database.
 func (w *WebAPI) ticketSearch(c *gin.Context) {
+	log := w.requestLog(c)
+
 	cacheData := c.MustGet(cacheKey).(cacheData)
 
 	hash := c.PostForm("hash")
 
 	ticket, found, err := w.db.GetTicketByHash(hash)
 	if err != nil {
-		w.log.Errorf("db.GetTicketByHash error (ticketHash=%s): %v", hash, err)
+		log.Errorf("db.GetTicketByHash error (ticketHash=%s): %v", hash, err)
 		c.String(http.StatusInternalServerError, "Error getting ticket from db")
 		return
 	}
 
 	voteChanges, err := w.db.GetVoteChanges(hash)
 	if err != nil {
-		w.log.Errorf("db.GetVoteChanges error (ticketHash=%s): %v", hash, err)
+		log.Errorf("db.GetVoteChanges error (ticketHash=%s): %v", hash, err)
 		c.String(http.StatusInternalServerError, "Error getting vote changes from db")
 		return
 	}
 
 	altSignAddrData, err := w.db.AltSignAddrData(hash)
 	if err != nil {
-		w.log.Errorf("db.AltSignAddrData error (ticketHash=%s): %v", hash, err)
+		log.Errorf("db.AltSignAddrData error (ticketHash=%s): %v", hash, err)
 		c.String(http.StatusInternalServerError, "Error getting alt sig from db")
 		return
 	}
@@ -208,21 +314,21 @@ func (w *WebAPI) ticketSearch(c *gin.Context) {
 		dcrdClient := c.MustGet(dcrdKey).(*rpc.DcrdRPC)
 		dcrdErr := c.MustGet(dcrdErrorKey)
 		if dcrdErr != nil {
-			w.log.Errorf("%v", dcrdErr.(error))
+			log.Errorf("%v", dcrdErr.(error))
 			c.String(http.StatusInternalServerError, "Could not get dcrd client")
 			return
 		}
 
 		resp, err := dcrdClient.DecodeRawTransaction(ticket.FeeTxHex)
 		if err != nil {
-			w.log.Errorf("dcrd.DecodeRawTransaction error: %w", err)
+			log.Errorf("dcrd.DecodeRawTransaction error: %w", err)
 			c.String(http.StatusInternalServerError, "Error decoding fee transaction")
 			return
 		}
 
 		decoded, err := json.Marshal(resp)
 		if err != nil {
-			w.log.Errorf("Unmarshal fee tx error: %w", err)
+			log.Errorf("Unmarshal fee tx error: %w", err)
 			c.String(http.StatusInternalServerError, "Error unmarshalling fee tx")
 			return
 		}
@@ -232,7 +338,7 @@ func (w *WebAPI) ticketSearch(c *gin.Context) {
 
 	missed, err := w.db.GetMissedTickets()
 	if err != nil {
-		w.log.Errorf("db.GetMissedTickets error: %v", err)
+		log.Errorf("db.GetMissedTickets error: %v", err)
 		c.String(http.StatusInternalServerError, "Error getting missed tickets from db")
 		return
 	}
@@ -241,7 +347,7 @@ func (w *WebAPI) ticketSearch(c *gin.Context) {
 
 	xpubs, err := w.db.AllXPubs()
 	if err != nil {
-		w.log.Errorf("db.AllXPubs error: %v", err)
+		log.Errorf("db.AllXPubs error: %v", err)
 		c.String(http.StatusInternalServerError, "Error getting xpubs from db")
 		return
 	}
@@ -255,9 +361,10 @@ func (w *WebAPI) ticketSearch(c *gin.Context) {
 			AltSignAddrData: altSignAddrData,
 			VoteChanges:     voteChanges,
 			MaxVoteChanges:  w.cfg.MaxVoteChangeRecords,
+			MaxNotesLength:  maxTicketNotesLength,
 		},
 		"WebApiCache":   cacheData,
-		"WebApiCfg":     w.cfg,
+		"WebApiCfg":     w.config(),
 		"WalletStatus":  w.walletStatus(c),
 		"DcrdStatus":    w.dcrdStatus(c),
 		"MissedTickets": missed,
@@ -265,18 +372,131 @@ func (w *WebAPI) ticketSearch(c *gin.Context) {
 	})
 }
 
+// ticketNotes is the handler for "POST /admin/ticket/notes". The notes param
+// replaces the notes of the ticket identified by the hash param, and the ticket
+// is then displayed in the same way as ticketSearch.
+func (w *WebAPI) ticketNotes(c *gin.Context) {
+	log := w.requestLog(c)
+
+	hash := c.PostForm("hash")
+	notes := strings.TrimSpace(c.PostForm("notes"))
+
+	if len(notes) > maxTicketNotesLength {
+		c.String(http.StatusBadRequest, "Notes cannot be longer than %d characters",
+			maxTicketNotesLength)
+		return
+	}
+
+	ticket, found, err := w.db.GetTicketByHash(hash)
+	if err != nil {
+		log.Errorf("db.GetTicketByHash error (ticketHash=%s): %v", hash, err)
+		c.String(http.StatusInternalServerError, "Error getting ticket from db")
+		return
+	}
+
+	if found {
+		ticket.Notes = notes
+		err = w.db.UpdateTicket(ticket)
+		if err != nil {
+			log.Errorf("db.UpdateTicket error, failed to update notes (ticketHash=%s): %v", hash, err)
+			c.String(http.StatusInternalServerError, "Error updating ticket notes")
+			return
+		}
+
+		log.Infof("Ticket notes updated (ticketHash=%s)", hash)
+	}
+
+	w.ticketSearch(c)
+}
+
+// parseRefund validates the refund tx hash and amount in DCR which an operator
+// has provided for ticket, and returns the amount in atoms. The refund cannot be
+// more than the fee paid by the ticket.
+func parseRefund(ticket database.Ticket, txHash, amount string) (int64, error) {
+	if len(txHash) != chainhash.MaxHashStringSize {
+		return 0, fmt.Errorf("refund tx hash must be %d characters", chainhash.MaxHashStringSize)
+	}
+	_, err := chainhash.NewHashFromStr(txHash)
+	if err != nil {
+		return 0, fmt.Errorf("invalid refund tx hash: %w", err)
+	}
+
+	if ticket.FeeTxStatus != database.FeeConfirmed {
+		return 0, errors.New("ticket does not have a confirmed fee tx")
+	}
+
+	dcr, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return 0, errors.New("invalid refund amount")
+	}
+	atoms, err := dcrutil.NewAmount(dcr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid refund amount: %w", err)
+	}
+	if atoms <= 0 {
+		return 0, errors.New("refund amount must be greater than zero")
+	}
+	if paid := dcrutil.Amount(ticket.FeeAmount + ticket.FeeSurplus); atoms > paid {
+		return 0, fmt.Errorf("refund amount cannot be more than the fee paid (%v)", paid)
+	}
+
+	return int64(atoms), nil
+}
+
+// ticketRefund is the handler for "POST /admin/ticket/refund". It records that
+// the fee of the ticket identified by the hash param has been refunded by the
+// transaction and amount in the refundtxhash and refundamount params. The
+// ticket is then displayed in the same way as ticketSearch.
+func (w *WebAPI) ticketRefund(c *gin.Context) {
+	log := w.requestLog(c)
+
+	hash := c.PostForm("hash")
+	refundTxHash := strings.TrimSpace(c.PostForm("refundtxhash"))
+
+	ticket, found, err := w.db.GetTicketByHash(hash)
+	if err != nil {
+		log.Errorf("db.GetTicketByHash error (ticketHash=%s): %v", hash, err)
+		c.String(http.StatusInternalServerError, "Error getting ticket from db")
+		return
+	}
+
+	if found {
+		refundAmount, err := parseRefund(ticket, refundTxHash, strings.TrimSpace(c.PostForm("refundamount")))
+		if err != nil {
+			c.String(http.StatusBadRequest, "Cannot record refund: %v", err)
+			return
+		}
+
+		ticket.RefundTxHash = refundTxHash
+		ticket.RefundAmount = refundAmount
+		err = w.db.UpdateTicket(ticket)
+		if err != nil {
+			log.Errorf("db.UpdateTicket error, failed to record refund (ticketHash=%s): %v", hash, err)
+			c.String(http.StatusInternalServerError, "Error recording ticket refund")
+			return
+		}
+
+		log.Infof("Ticket refund recorded (ticketHash=%s, refundTxHash=%s, refundAmount=%v)",
+			hash, refundTxHash, dcrutil.Amount(refundAmount))
+	}
+
+	w.ticketSearch(c)
+}
+
 // adminLogin is the handler for "POST /admin". If a valid password is provided,
 // the current session will be authenticated as an admin.
 func (w *WebAPI) adminLogin(c *gin.Context) {
+	log := w.requestLog(c)
+
 	cacheData := c.MustGet(cacheKey).(cacheData)
 
 	password := c.PostForm("password")
 
 	if password != w.cfg.AdminPass {
-		w.log.Warnf("Failed login attempt from %s", c.ClientIP())
+		log.Warnf("Failed login attempt from %s", c.ClientIP())
 		c.HTML(http.StatusUnauthorized, "login.html", gin.H{
 			"WebApiCache":    cacheData,
-			"WebApiCfg":      w.cfg,
+			"WebApiCfg":      w.config(),
 			"FailedLoginMsg": "Incorrect password",
 		})
 		return
@@ -294,9 +514,11 @@ func (w *WebAPI) adminLogout(c *gin.Context) {
 // downloadDatabaseBackup is the handler for "GET /backup". A binary
 // representation of the whole database is generated and returned to the client.
 func (w *WebAPI) downloadDatabaseBackup(c *gin.Context) {
+	log := w.requestLog(c)
+
 	err := w.db.BackupDB(c.Writer)
 	if err != nil {
-		w.log.Errorf("Error backing up database: %v", err)
+		log.Errorf("Error backing up database: %v", err)
 		// Don't write any http body here because Content-Length has already
 		// been set in db.BackupDB. Status is enough to indicate an error.
 		c.Status(http.StatusInternalServerError)
@@ -306,11 +528,13 @@ func (w *WebAPI) downloadDatabaseBackup(c *gin.Context) {
 // setAdminStatus stores the authentication status of the current session and
 // redirects the client to GET /admin.
 func (w *WebAPI) setAdminStatus(admin any, c *gin.Context) {
+	log := w.requestLog(c)
+
 	session := c.MustGet(sessionKey).(*sessions.Session)
 	session.Values["admin"] = admin
 	err := session.Save(c.Request, c.Writer)
 	if err != nil {
-		w.log.Errorf("Error saving session: %v", err)
+		log.Errorf("Error saving session: %v", err)
 		c.String(http.StatusInternalServerError, "Error saving session")
 		return
 	}
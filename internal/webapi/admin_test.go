@@ -0,0 +1,181 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/vspd/database"
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseRefund(t *testing.T) {
+	validHash := strings.Repeat("ab", 32)
+
+	// The ticket paid a fee of 0.1 DCR plus a surplus of 0.05 DCR.
+	confirmed := database.Ticket{
+		FeeAmount:   10000000,
+		FeeSurplus:  5000000,
+		FeeTxStatus: database.FeeConfirmed,
+	}
+	broadcast := confirmed
+	broadcast.FeeTxStatus = database.FeeBroadcast
+
+	tests := map[string]struct {
+		ticket      database.Ticket
+		txHash      string
+		amount      string
+		expectAtoms int64
+		expectErr   bool
+	}{
+		"partial refund": {
+			ticket:      confirmed,
+			txHash:      validHash,
+			amount:      "0.05",
+			expectAtoms: 5000000,
+		},
+		"full refund including surplus": {
+			ticket:      confirmed,
+			txHash:      validHash,
+			amount:      "0.15",
+			expectAtoms: 15000000,
+		},
+		"more than fee paid": {
+			ticket:    confirmed,
+			txHash:    validHash,
+			amount:    "0.15000001",
+			expectErr: true,
+		},
+		"zero amount": {
+			ticket:    confirmed,
+			txHash:    validHash,
+			amount:    "0",
+			expectErr: true,
+		},
+		"negative amount": {
+			ticket:    confirmed,
+			txHash:    validHash,
+			amount:    "-0.01",
+			expectErr: true,
+		},
+		"invalid amount": {
+			ticket:    confirmed,
+			txHash:    validHash,
+			amount:    "lots",
+			expectErr: true,
+		},
+		"short tx hash": {
+			ticket:    confirmed,
+			txHash:    "abcd",
+			amount:    "0.05",
+			expectErr: true,
+		},
+		"non hex tx hash": {
+			ticket:    confirmed,
+			txHash:    strings.Repeat("zz", 32),
+			amount:    "0.05",
+			expectErr: true,
+		},
+		"fee not confirmed": {
+			ticket:    broadcast,
+			txHash:    validHash,
+			amount:    "0.05",
+			expectErr: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			atoms, err := parseRefund(test.ticket, test.txHash, test.amount)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error=%t, got %v", test.expectErr, err)
+			}
+			if atoms != test.expectAtoms {
+				t.Fatalf("expected %d atoms, got %d", test.expectAtoms, atoms)
+			}
+		})
+	}
+}
+
+// TestStatsJSON ensures /admin/stats returns cached ticket counts and vote
+// choice tallies along with the requested days of registration history.
+func TestStatsJSON(t *testing.T) {
+	w := &WebAPI{
+		db:  api.db,
+		log: api.log,
+	}
+
+	data := cacheData{
+		Voting:           3,
+		Voted:            2,
+		VoteChoiceCounts: map[string]map[string]int64{"agenda": {"yes": 3}},
+	}
+
+	tests := map[string]struct {
+		query        string
+		expectStatus int
+		expectDays   int
+	}{
+		"default days": {expectStatus: http.StatusOK, expectDays: defaultStatsDays},
+		"one day":      {query: "?days=1", expectStatus: http.StatusOK, expectDays: 1},
+		"zero days":    {query: "?days=0", expectStatus: http.StatusBadRequest},
+		"too many":     {query: "?days=367", expectStatus: http.StatusBadRequest},
+		"not a number": {query: "?days=lots", expectStatus: http.StatusBadRequest},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			_, r := gin.CreateTestContext(rec)
+			r.GET("/admin/stats", func(c *gin.Context) {
+				c.Set(cacheKey, data)
+				w.statsJSON(c)
+			})
+
+			req, err := http.NewRequest(http.MethodGet, "/admin/stats"+test.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != test.expectStatus {
+				t.Fatalf("expected status %d, got %d", test.expectStatus, rec.Code)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var stats struct {
+				Voting        int64                       `json:"voting"`
+				Voted         int64                       `json:"voted"`
+				VoteChoices   map[string]map[string]int64 `json:"votechoices"`
+				Registrations []dailyRegistrations        `json:"registrations"`
+			}
+			err = json.Unmarshal(rec.Body.Bytes(), &stats)
+			if err != nil {
+				t.Fatalf("could not unmarshal stats: %v", err)
+			}
+			if stats.Voting != data.Voting || stats.Voted != data.Voted {
+				t.Fatalf("unexpected ticket counts: %+v", stats)
+			}
+			if stats.VoteChoices["agenda"]["yes"] != 3 {
+				t.Fatalf("unexpected vote choices: %v", stats.VoteChoices)
+			}
+			if len(stats.Registrations) != test.expectDays {
+				t.Fatalf("expected %d days of registrations, got %d",
+					test.expectDays, len(stats.Registrations))
+			}
+			today := time.Now().UTC().Format(time.DateOnly)
+			if last := stats.Registrations[len(stats.Registrations)-1].Day; last != today {
+				t.Fatalf("expected last day %s, got %s", today, last)
+			}
+		})
+	}
+}
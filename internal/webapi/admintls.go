@@ -0,0 +1,59 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminTLSConfig returns a TLS config which serves the certificate and key in
+// certFile and keyFile, and requires every client to present a certificate
+// signed by the CA in caFile.
+func adminTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load admin TLS keypair: %w", err)
+	}
+
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read admin client CA: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("admin client CA file contains no certificates")
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// requireClientCert middleware rejects requests which were not made with a
+// client certificate signed by the admin client CA, if one is configured. Only
+// connections to the admin TLS listener can provide a verified certificate, so
+// admin endpoints cannot be reached via the main listener.
+func (w *WebAPI) requireClientCert(c *gin.Context) {
+	if w.cfg.AdminClientCA == "" {
+		return
+	}
+
+	if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 {
+		w.requestLog(c).Warnf("Admin request without client certificate from %s", c.ClientIP())
+		c.String(http.StatusForbidden, "Admin access requires a client certificate")
+		c.Abort()
+		return
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testCert is a certificate and private key created for tests.
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// newTestCert creates a certificate signed by parent, or a self-signed CA
+// certificate if parent is nil.
+func newTestCert(t *testing.T, parent *testCert, serial int64) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "vspd test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// writeFiles writes the certificate and key of c as PEM files in dir and
+// returns their paths.
+func (c *testCert) writeFiles(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, name+".cert")
+	keyFile := filepath.Join(dir, name+".key")
+	err = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return certFile, keyFile
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+// TestAdminTLS ensures the admin TLS listener only accepts connections from
+// clients with a certificate signed by the admin client CA.
+func TestAdminTLS(t *testing.T) {
+	dir := t.TempDir()
+
+	ca := newTestCert(t, nil, 1)
+	server := newTestCert(t, ca, 2)
+	client := newTestCert(t, ca, 3)
+	otherCA := newTestCert(t, nil, 4)
+	otherClient := newTestCert(t, otherCA, 5)
+
+	caFile, _ := ca.writeFiles(t, dir, "ca")
+	certFile, keyFile := server.writeFiles(t, dir, "server")
+
+	tlsCfg, err := adminTLSConfig(certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", tlsCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &WebAPI{
+		log: api.log,
+		cfg: Config{AdminClientCA: caFile},
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/status", w.requireClientCert, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	srv := &http.Server{Handler: router, ReadHeaderTimeout: time.Second}
+	go func() { _ = srv.Serve(listener) }()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	tests := map[string]struct {
+		clientCerts []tls.Certificate
+		expectOK    bool
+	}{
+		"signed by client CA": {
+			clientCerts: []tls.Certificate{client.tlsCertificate()},
+			expectOK:    true,
+		},
+		"signed by other CA": {
+			clientCerts: []tls.Certificate{otherClient.tlsCertificate()},
+		},
+		"no client certificate": {},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			httpClient := &http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig: &tls.Config{
+						RootCAs:      roots,
+						Certificates: test.clientCerts,
+						MinVersion:   tls.VersionTLS12,
+					},
+				},
+				Timeout: 5 * time.Second,
+			}
+			defer httpClient.CloseIdleConnections()
+
+			resp, err := httpClient.Get("https://" + listener.Addr().String() + "/admin/status")
+			if test.expectOK {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+				}
+				return
+			}
+			if err == nil {
+				resp.Body.Close()
+				t.Fatal("expected connection to be rejected")
+			}
+		})
+	}
+
+	// Requests which do not arrive via the admin TLS listener are rejected.
+	req, err := http.NewRequest(http.MethodGet, "/admin/status", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d without TLS, got %d", http.StatusForbidden, rec.Code)
+	}
+}
+
+func TestAdminTLSConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	ca := newTestCert(t, nil, 1)
+	server := newTestCert(t, ca, 2)
+	caFile, _ := ca.writeFiles(t, dir, "ca")
+	certFile, keyFile := server.writeFiles(t, dir, "server")
+
+	emptyCAFile := filepath.Join(dir, "empty.cert")
+	err := os.WriteFile(emptyCAFile, []byte("not a certificate"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		certFile, keyFile, caFile string
+	}{
+		"missing cert":     {filepath.Join(dir, "missing"), keyFile, caFile},
+		"missing key":      {certFile, filepath.Join(dir, "missing"), caFile},
+		"mismatched key":   {certFile, caFile, caFile},
+		"missing CA":       {certFile, keyFile, filepath.Join(dir, "missing")},
+		"CA without certs": {certFile, keyFile, emptyCAFile},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, err := adminTLSConfig(test.certFile, test.keyFile, test.caFile)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
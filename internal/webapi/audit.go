@@ -0,0 +1,90 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redactedFields are the fields of API requests whose values are never written
+// to the audit log because they are secret.
+var redactedFields = []string{"votingkey"}
+
+// auditRecord is written to the audit log for every API request.
+type auditRecord struct {
+	Time       string          `json:"time"`
+	RequestID  string          `json:"requestid"`
+	Method     string          `json:"method"`
+	Path       string          `json:"path"`
+	ClientIP   string          `json:"clientip"`
+	Status     int             `json:"status"`
+	DurationMS int64           `json:"durationms"`
+	TicketHash string          `json:"tickethash,omitempty"`
+	Request    json.RawMessage `json:"request,omitempty"`
+}
+
+// auditRequest middleware writes a record of every request, including its body
+// with any secret fields redacted, to the audit log once it has been handled.
+// It does nothing if the audit log is not enabled by config.
+func (w *WebAPI) auditRequest(c *gin.Context) {
+	if w.cfg.AuditLog == nil {
+		return
+	}
+
+	start := time.Now()
+	c.Next()
+
+	record := auditRecord{
+		Time:       start.UTC().Format(time.RFC3339Nano),
+		RequestID:  c.GetString(requestIDKey),
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		ClientIP:   c.ClientIP(),
+		Status:     c.Writer.Status(),
+		DurationMS: time.Since(start).Milliseconds(),
+		TicketHash: c.GetString(ticketHashKey),
+	}
+	if reqBytes, ok := c.Get(requestBytesKey); ok {
+		record.Request = redactRequest(reqBytes.([]byte))
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		w.log.Errorf("Failed to encode audit record (requestID=%s): %v", record.RequestID, err)
+		return
+	}
+
+	_, err = w.cfg.AuditLog.Write(append(line, '\n'))
+	if err != nil {
+		w.log.Errorf("Failed to write audit record (requestID=%s): %v", record.RequestID, err)
+	}
+}
+
+// redactRequest returns the JSON object in reqBytes with the values of any
+// redactedFields replaced. Nil is returned if reqBytes is not a JSON object, so
+// malformed requests are recorded without their body.
+func redactRequest(reqBytes []byte) json.RawMessage {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(reqBytes, &fields)
+	if err != nil || fields == nil {
+		return nil
+	}
+
+	for _, field := range redactedFields {
+		if _, ok := fields[field]; ok {
+			fields[field] = json.RawMessage(`"redacted"`)
+		}
+	}
+
+	redacted, err := json.Marshal(fields)
+	if err != nil {
+		return nil
+	}
+
+	return redacted
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestAuditRequest ensures a record of each request is written to the audit
+// log, with secret fields of the request body redacted.
+func TestAuditRequest(t *testing.T) {
+	var auditLog bytes.Buffer
+	w := &WebAPI{
+		log: api.log,
+		cfg: Config{AuditLog: &auditLog},
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/payfee", w.withRequestID, w.auditRequest, func(c *gin.Context) {
+		reqBytes, err := drainAndReplaceBody(c.Request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Set(requestBytesKey, reqBytes)
+		c.Set(ticketHashKey, "hash")
+		c.Status(http.StatusTeapot)
+	})
+
+	body := `{"tickethash":"hash","votingkey":"secret","feetx":"tx"}`
+	req, err := http.NewRequest(http.MethodPost, "/payfee", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if strings.Contains(auditLog.String(), "secret") {
+		t.Fatalf("audit log contains voting key: %s", auditLog.String())
+	}
+
+	var record auditRecord
+	err = json.Unmarshal(auditLog.Bytes(), &record)
+	if err != nil {
+		t.Fatalf("failed to decode audit record: %v", err)
+	}
+	if record.RequestID != rec.Header().Get(requestIDHeader) {
+		t.Fatalf("expected request ID %q, got %q", rec.Header().Get(requestIDHeader), record.RequestID)
+	}
+	if record.Method != http.MethodPost || record.Path != "/payfee" {
+		t.Fatalf("unexpected request %s %s", record.Method, record.Path)
+	}
+	if record.Status != http.StatusTeapot {
+		t.Fatalf("expected status %d, got %d", http.StatusTeapot, record.Status)
+	}
+	if record.TicketHash != "hash" {
+		t.Fatalf("expected ticket hash %q, got %q", "hash", record.TicketHash)
+	}
+
+	var request map[string]string
+	err = json.Unmarshal(record.Request, &request)
+	if err != nil {
+		t.Fatalf("failed to decode audited request: %v", err)
+	}
+	expected := map[string]string{"tickethash": "hash", "votingkey": "redacted", "feetx": "tx"}
+	for k, v := range expected {
+		if request[k] != v {
+			t.Fatalf("expected %s=%q, got %q", k, v, request[k])
+		}
+	}
+}
+
+func TestRedactRequest(t *testing.T) {
+	tests := map[string]struct {
+		reqBytes string
+		expect   string
+	}{
+		"voting key redacted": {
+			reqBytes: `{"votingkey":"secret"}`,
+			expect:   `{"votingkey":"redacted"}`,
+		},
+		"no secret fields": {
+			reqBytes: `{"tickethash":"hash"}`,
+			expect:   `{"tickethash":"hash"}`,
+		},
+		"not an object": {
+			reqBytes: `["secret"]`,
+		},
+		"malformed": {
+			reqBytes: `{"votingkey":`,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := redactRequest([]byte(test.reqBytes))
+			if string(actual) != test.expect {
+				t.Fatalf("expected %q, got %q", test.expect, actual)
+			}
+		})
+	}
+}
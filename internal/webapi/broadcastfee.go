@@ -0,0 +1,109 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"time"
+
+	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/internal/events"
+	"github.com/decred/vspd/rpc"
+	"github.com/decred/vspd/types/v3"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// broadcastFee is the handler for "POST /api/v3/broadcastfee". It triggers the
+// broadcast of a fee tx which was deferred when it was received by /payfee.
+func (w *WebAPI) broadcastFee(c *gin.Context) {
+	const funcName = "broadcastFee"
+	log := w.requestLog(c)
+
+	// Get values which have been added to context by middleware.
+	ticket := c.MustGet(ticketKey).(database.Ticket)
+	knownTicket := c.MustGet(knownTicketKey).(bool)
+	dcrdClient := c.MustGet(dcrdKey).(*rpc.DcrdRPC)
+	dcrdErr := c.MustGet(dcrdErrorKey)
+	if dcrdErr != nil {
+		log.Errorf("%s: %v", funcName, dcrdErr.(error))
+		w.sendError(types.ErrInternalError, c)
+		return
+	}
+	reqBytes := c.MustGet(requestBytesKey).([]byte)
+
+	if !knownTicket {
+		log.Warnf("%s: Unknown ticket (clientIP=%s)", funcName, c.ClientIP())
+		w.sendError(types.ErrUnknownTicket, c)
+		return
+	}
+
+	var request types.BroadcastFeeRequest
+	if err := binding.JSON.BindBody(reqBytes, &request); err != nil {
+		log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
+		w.sendErrorWithMsg(err.Error(), types.ErrBadRequest, c)
+		return
+	}
+
+	if ticket.FeeTxStatus == database.NoFee {
+		log.Warnf("%s: No fee tx for ticket (clientIP=%s, ticketHash=%s)",
+			funcName, c.ClientIP(), ticket.Hash)
+		w.sendError(types.ErrFeeNotReceived, c)
+		return
+	}
+
+	if ticket.FeeTxStatus != database.FeeReceieved || !ticket.DeferFeeBroadcast {
+		log.Warnf("%s: Fee tx broadcast is not deferred (clientIP=%s, ticketHash=%s, feeTxStatus=%s)",
+			funcName, c.ClientIP(), ticket.Hash, ticket.FeeTxStatus)
+		w.sendErrorWithMsg("fee tx broadcast is not deferred", types.ErrBadRequest, c)
+		return
+	}
+
+	ticket.DeferFeeBroadcast = false
+
+	switch {
+	case !ticket.Confirmed:
+		// The fee tx cannot be broadcast until the ticket is confirmed. Clearing
+		// the deferral allows vspd to broadcast it as soon as that happens.
+		err := w.db.UpdateTicket(ticket)
+		if err != nil {
+			log.Errorf("%s: db.UpdateTicket error, failed to clear fee tx deferral (ticketHash=%s): %v",
+				funcName, ticket.Hash, err)
+			w.sendError(types.ErrInternalError, c)
+			return
+		}
+
+		log.Debugf("%s: Fee tx will be broadcast once ticket is confirmed (ticketHash=%s)",
+			funcName, ticket.Hash)
+
+	default:
+		// The client may have broadcast the fee tx itself, in which case there
+		// is no need to broadcast it again.
+		if _, err := dcrdClient.GetRawTransaction(ticket.FeeTxHash); err == nil {
+			ticket.FeeTxStatus = database.FeeBroadcast
+			err = w.db.UpdateTicket(ticket)
+			if err != nil {
+				log.Errorf("%s: db.UpdateTicket error, failed to set fee tx as broadcast (ticketHash=%s): %v",
+					funcName, ticket.Hash, err)
+				w.sendError(types.ErrInternalError, c)
+				return
+			}
+
+			w.events.Publish(events.FeeBroadcast, ticket.Hash)
+
+			log.Debugf("%s: Fee tx already broadcast by client (ticketHash=%s, feeHash=%s)",
+				funcName, ticket.Hash, ticket.FeeTxHash)
+			break
+		}
+
+		if !w.sendFeeTx(funcName, ticket, c) {
+			return
+		}
+	}
+
+	w.sendJSONResponse(types.BroadcastFeeResponse{
+		Timestamp: time.Now().Unix(),
+		Request:   reqBytes,
+	}, c)
+}
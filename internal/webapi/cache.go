@@ -1,4 +1,4 @@
-// Copyright (c) 2020-2023 The Decred developers
+// Copyright (c) 2020-2024 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
@@ -6,11 +6,14 @@ package webapi
 
 import (
 	"errors"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/decred/slog"
 	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/internal/config"
+	"github.com/decred/vspd/internal/metrics"
 	"github.com/decred/vspd/rpc"
 	"github.com/dustin/go-humanize"
 )
@@ -27,6 +30,12 @@ type cache struct {
 	db      *database.VspDatabase
 	dcrd    rpc.DcrdConnect
 	wallets rpc.WalletConnect
+	metrics *metrics.Registry
+
+	// vspFee and feeSchedule are used to determine the fee percentage which
+	// is currently in effect.
+	vspFee      float64
+	feeSchedule config.FeeSchedule
 }
 
 type cacheData struct {
@@ -34,19 +43,40 @@ type cacheData struct {
 	// the first time.
 	Initialized bool
 
-	UpdateTime          string
-	PubKey              string
-	DatabaseSize        string
-	Voting              int64
+	// LastUpdated is the unix time at which the cache was last successfully
+	// updated. The cached values are not changed if an update fails, so they
+	// may be stale.
+	LastUpdated int64
+
+	UpdateTime   string
+	PubKey       string
+	DatabaseSize string
+	Voting       int64
+	// Reserved is the number of tickets which are not yet voting but have
+	// reserved capacity, either with an unexpired fee or an unconfirmed fee tx.
+	Reserved            int64
 	Voted               int64
 	Expired             int64
 	Missed              int64
 	VotingWalletsOnline int64
 	TotalVotingWallets  int64
 	BlockHeight         uint32
-	NetworkProportion   float32
-	ExpiredProportion   float32
-	MissedProportion    float32
+	// Synced is true if dcrd reported that it had completed its initial sync
+	// of the chain.
+	Synced bool
+	// FeePercentage is the fee percentage in effect at BlockHeight, which may
+	// differ from the configured vspfee if a scheduled change has taken
+	// effect.
+	FeePercentage     float64
+	NetworkProportion float32
+	ExpiredProportion float32
+	MissedProportion  float32
+	// VoteChoiceCounts is the number of voting tickets which have set each
+	// choice, keyed by agenda ID and then choice ID.
+	VoteChoiceCounts map[string]map[string]int64
+	// WalletVoteVersions are the distinct vote versions reported by the voting
+	// wallets which could be reached, most recent first.
+	WalletVoteVersions []uint32
 }
 
 func (c *cache) initialized() bool {
@@ -64,19 +94,31 @@ func (c *cache) getData() cacheData {
 }
 
 // newCache creates a new cache and initializes it with static values.
-func newCache(signPubKey string, log slog.Logger, db *database.VspDatabase,
-	dcrd rpc.DcrdConnect, wallets rpc.WalletConnect) *cache {
+func newCache(signPubKey string, vspFee float64, feeSchedule config.FeeSchedule,
+	log slog.Logger, db *database.VspDatabase, dcrd rpc.DcrdConnect,
+	wallets rpc.WalletConnect, registry *metrics.Registry) *cache {
 	return &cache{
 		data: cacheData{
 			PubKey: signPubKey,
 		},
-		log:     log,
-		db:      db,
-		dcrd:    dcrd,
-		wallets: wallets,
+		log:         log,
+		db:          db,
+		dcrd:        dcrd,
+		wallets:     wallets,
+		metrics:     registry,
+		vspFee:      vspFee,
+		feeSchedule: feeSchedule,
 	}
 }
 
+// setVSPFee replaces the base fee percentage which the cached fee percentage is
+// determined from. The new fee is reflected by the next update.
+func (c *cache) setVSPFee(vspFee float64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.vspFee = vspFee
+}
+
 // update will use the provided database and RPC connections to update the
 // dynamic values in the cache.
 func (c *cache) update() error {
@@ -91,6 +133,24 @@ func (c *cache) update() error {
 		return err
 	}
 
+	// Get latest count of tickets which have reserved capacity.
+	reserved, err := c.db.CountReservedTickets(time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Get latest vote choices of voting tickets.
+	voteChoiceCounts, err := c.db.CountVoteChoices()
+	if err != nil {
+		return err
+	}
+
+	// Get latest fee statuses of tickets without an outcome.
+	feeStatusCounts, err := c.db.CountFeeStatuses()
+	if err != nil {
+		return err
+	}
+
 	// Get latest best block height.
 	dcrdClient, _, err := c.dcrd.Client()
 	if err != nil {
@@ -106,6 +166,11 @@ func (c *cache) update() error {
 		return errors.New("dcr node reports a network ticket pool size of zero")
 	}
 
+	chainInfo, err := dcrdClient.GetBlockchainInfo()
+	if err != nil {
+		return err
+	}
+
 	clients, failedConnections := c.wallets.Clients()
 	if len(clients) == 0 {
 		c.log.Error("Could not connect to any wallets")
@@ -114,31 +179,76 @@ func (c *cache) update() error {
 			len(failedConnections), len(clients))
 	}
 
+	// Voting wallets can only set vote choices for the agendas of the vote
+	// version they are running.
+	versionSet := make(map[uint32]struct{})
+	for _, client := range clients {
+		walletInfo, err := client.WalletInfo()
+		if err != nil {
+			c.log.Errorf("dcrwallet.WalletInfo error (wallet=%s): %v", client.String(), err)
+			continue
+		}
+		versionSet[walletInfo.VoteVersion] = struct{}{}
+	}
+	walletVoteVersions := make([]uint32, 0, len(versionSet))
+	for version := range versionSet {
+		walletVoteVersions = append(walletVoteVersions, version)
+	}
+	sort.Slice(walletVoteVersions, func(i, j int) bool {
+		return walletVoteVersions[i] > walletVoteVersions[j]
+	})
+
+	c.updateMetrics(voting, reserved, feeStatusCounts, len(clients), len(failedConnections))
+
 	c.mtx.Lock()
 	defer c.mtx.Unlock()
 
 	c.data.Initialized = true
-	c.data.UpdateTime = dateTime(time.Now().Unix())
+	now := time.Now()
+	c.data.UpdateTime = dateTime(now.Unix())
+	c.data.LastUpdated = now.Unix()
 	c.data.DatabaseSize = humanize.Bytes(dbSize)
 	c.data.Voting = voting
+	c.data.Reserved = reserved
 	c.data.Voted = voted
 	c.data.TotalVotingWallets = int64(len(clients) + len(failedConnections))
 	c.data.VotingWalletsOnline = int64(len(clients))
 	c.data.Expired = expired
 	c.data.Missed = missed
+	c.data.VoteChoiceCounts = voteChoiceCounts
+	c.data.WalletVoteVersions = walletVoteVersions
 	c.data.BlockHeight = bestBlock.Height
+	c.data.Synced = !chainInfo.InitialBlockDownload
+	c.data.FeePercentage = c.feeSchedule.Percentage(c.vspFee, int64(bestBlock.Height), now)
 	c.data.NetworkProportion = float32(voting) / float32(bestBlock.PoolSize)
 
+	c.data.ExpiredProportion, c.data.MissedProportion = outcomeProportions(voted, expired, missed)
+
+	return nil
+}
+
+// updateMetrics sets gauges for ticket and wallet counts retrieved by update.
+func (c *cache) updateMetrics(voting, reserved int64, feeStatusCounts map[database.FeeStatus]int64,
+	walletsOnline, walletsOffline int) {
+	c.metrics.SetGauge("tickets.voting", voting)
+	c.metrics.SetGauge("tickets.reserved", reserved)
+	for _, status := range []database.FeeStatus{database.NoFee, database.FeeReceieved,
+		database.FeeBroadcast, database.FeeConfirmed, database.FeeError} {
+		c.metrics.SetGauge("tickets.feestatus."+string(status), feeStatusCounts[status])
+	}
+	c.metrics.SetGauge("wallets.online", int64(walletsOnline))
+	c.metrics.SetGauge("wallets.total", int64(walletsOnline+walletsOffline))
+}
+
+// outcomeProportions returns the proportions of expired and missed tickets out
+// of all tickets which have an outcome.
+func outcomeProportions(voted, expired, missed int64) (float32, float32) {
 	total := voted + expired + missed
 
 	// Prevent dividing by zero when pool has no voted/expired/missed tickets.
 	if total == 0 {
-		c.data.ExpiredProportion = 0
-		c.data.MissedProportion = 0
-	} else {
-		c.data.ExpiredProportion = float32(expired) / float32(total)
-		c.data.MissedProportion = float32(missed) / float32(total)
+		return 0, 0
 	}
 
-	return nil
+	return float32(expired) / float32(total), float32(missed) / float32(total)
 }
@@ -0,0 +1,49 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"testing"
+)
+
+// TestOutcomeProportions ensures expired and missed proportions are calculated
+// from the total of all ticket outcomes, and are zero when there are none.
+func TestOutcomeProportions(t *testing.T) {
+	tests := map[string]struct {
+		voted, expired, missed int64
+		expectExpired          float32
+		expectMissed           float32
+	}{
+		"no outcomes": {},
+		"all voted": {
+			voted: 10,
+		},
+		"mixed": {
+			voted:         6,
+			expired:       3,
+			missed:        1,
+			expectExpired: 0.3,
+			expectMissed:  0.1,
+		},
+		"none voted": {
+			expired:       1,
+			missed:        1,
+			expectExpired: 0.5,
+			expectMissed:  0.5,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			expired, missed := outcomeProportions(test.voted, test.expired, test.missed)
+			if expired != test.expectExpired {
+				t.Fatalf("expected expired proportion %v, got %v", test.expectExpired, expired)
+			}
+			if missed != test.expectMissed {
+				t.Fatalf("expected missed proportion %v, got %v", test.expectMissed, missed)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"context"
+	"time"
+
+	"github.com/decred/vspd/internal/diskspace"
+	"github.com/decred/vspd/types/v3"
+	"github.com/dustin/go-humanize"
+	"github.com/gin-gonic/gin"
+)
+
+// diskSpaceInterval is the time period between checks of the free disk space
+// of the database directory.
+const diskSpaceInterval = time.Minute
+
+// checkDiskSpace logs a warning if the free disk space of the database
+// directory is below the configured threshold. If configured, maintenance mode
+// is entered while free disk space is low and left once it has recovered.
+func (w *WebAPI) checkDiskSpace() {
+	free, err := diskspace.Free(w.cfg.DiskSpaceDir)
+	if err != nil {
+		w.log.Errorf("Could not determine free disk space of %s: %v", w.cfg.DiskSpaceDir, err)
+		return
+	}
+
+	low := free < w.cfg.LowDiskSpace
+	if low {
+		w.log.Warnf("Free disk space of %s is %s, below the threshold of %s",
+			w.cfg.DiskSpaceDir, humanize.IBytes(free), humanize.IBytes(w.cfg.LowDiskSpace))
+	}
+
+	if !w.cfg.LowDiskMaintenance {
+		return
+	}
+
+	if w.maintenance.Swap(low) != low {
+		if low {
+			w.log.Warnf("Entering maintenance mode due to low disk space, requests " +
+				"which write to the database will be rejected")
+		} else {
+			w.log.Infof("Free disk space has recovered, leaving maintenance mode")
+		}
+	}
+}
+
+// monitorDiskSpace runs checkDiskSpace every diskSpaceInterval until the
+// context is canceled.
+func (w *WebAPI) monitorDiskSpace(ctx context.Context) {
+	ticker := time.NewTicker(diskSpaceInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.checkDiskSpace()
+		}
+	}
+}
+
+// notInMaintenance middleware rejects requests while the VSP is in
+// maintenance mode. It is used on endpoints which write to the database.
+func (w *WebAPI) notInMaintenance(c *gin.Context) {
+	if w.maintenance.Load() {
+		w.sendError(types.ErrMaintenance, c)
+		return
+	}
+}
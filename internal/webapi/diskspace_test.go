@@ -0,0 +1,70 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/decred/vspd/internal/diskspace"
+	"github.com/decred/vspd/types/v3"
+	"github.com/gin-gonic/gin"
+)
+
+// TestLowDiskMaintenance ensures maintenance mode is entered while free disk
+// space is below the threshold, that requests are rejected in maintenance
+// mode, and that maintenance mode is left once disk space recovers.
+func TestLowDiskMaintenance(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := diskspace.Free(dir); errors.Is(err, diskspace.ErrUnsupported) {
+		t.Skip(err)
+	}
+
+	w := &WebAPI{
+		log:         api.log,
+		signPrivKey: api.signPrivKey,
+		cfg: Config{
+			DiskSpaceDir:       dir,
+			LowDiskSpace:       math.MaxUint64,
+			LowDiskMaintenance: true,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/payfee", w.notInMaintenance, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func() int {
+		req, err := http.NewRequest(http.MethodPost, "/payfee", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// No disk can have the maximum possible free space.
+	w.checkDiskSpace()
+	if !w.maintenance.Load() {
+		t.Fatal("expected maintenance mode with low disk space")
+	}
+	if code := request(); code != types.ErrMaintenance.HTTPStatus() {
+		t.Fatalf("expected status %d in maintenance mode, got %d",
+			types.ErrMaintenance.HTTPStatus(), code)
+	}
+
+	w.cfg.LowDiskSpace = 1
+	w.checkDiskSpace()
+	if w.maintenance.Load() {
+		t.Fatal("expected maintenance mode to end when disk space recovers")
+	}
+	if code := request(); code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+}
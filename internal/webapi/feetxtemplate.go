@@ -0,0 +1,146 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/txscript/v4/stdaddr"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/internal/config"
+	"github.com/decred/vspd/types/v3"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// feeTxTemplate is the handler for "POST /api/v3/feetxtemplate". It returns an
+// unsigned fee tx which spends the inputs provided by the client, pays the
+// exact fee amount to the fee address of the ticket, and includes any change
+// outputs requested by the client. The client only needs to sign the tx before
+// sending it to /payfee.
+func (w *WebAPI) feeTxTemplate(c *gin.Context) {
+	const funcName = "feeTxTemplate"
+	log := w.requestLog(c)
+
+	// Get values which have been added to context by middleware.
+	ticket := c.MustGet(ticketKey).(database.Ticket)
+	knownTicket := c.MustGet(knownTicketKey).(bool)
+	reqBytes := c.MustGet(requestBytesKey).([]byte)
+
+	if !knownTicket {
+		log.Warnf("%s: Unknown ticket (clientIP=%s)", funcName, c.ClientIP())
+		w.sendError(types.ErrUnknownTicket, c)
+		return
+	}
+
+	var request types.FeeTxTemplateRequest
+	if err := binding.JSON.BindBody(reqBytes, &request); err != nil {
+		log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
+		w.sendErrorWithMsg(err.Error(), types.ErrBadRequest, c)
+		return
+	}
+
+	if ticket.FeeTxStatus != database.NoFee {
+		log.Warnf("%s: Fee tx already received (clientIP=%s, ticketHash=%s)",
+			funcName, c.ClientIP(), ticket.Hash)
+		w.sendError(types.ErrFeeAlreadyReceived, c)
+		return
+	}
+
+	// A template for an expired fee would be rejected by /payfee, so the client
+	// must request a new fee from /feeaddress first.
+	if ticket.FeeExpired() {
+		log.Warnf("%s: Expired fee (clientIP=%s, ticketHash=%s)",
+			funcName, c.ClientIP(), ticket.Hash)
+		w.sendError(types.ErrFeeExpired, c)
+		return
+	}
+
+	feeTx, err := buildFeeTxTemplate(ticket.FeeAddress, ticket.FeeAmount,
+		request.Inputs, request.Change, w.cfg.Network)
+	if err != nil {
+		log.Warnf("%s: Failed to build fee tx template (clientIP=%s, ticketHash=%s): %v",
+			funcName, c.ClientIP(), ticket.Hash, err)
+		w.sendErrorWithMsg(err.Error(), types.ErrBadRequest, c)
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(feeTx.SerializeSize())
+	err = feeTx.Serialize(&buf)
+	if err != nil {
+		log.Errorf("%s: Failed to serialize fee tx template (ticketHash=%s): %v",
+			funcName, ticket.Hash, err)
+		w.sendError(types.ErrInternalError, c)
+		return
+	}
+
+	w.sendJSONResponse(types.FeeTxTemplateResponse{
+		Timestamp:  time.Now().Unix(),
+		FeeTx:      hex.EncodeToString(buf.Bytes()),
+		FeeAddress: ticket.FeeAddress,
+		FeeAmount:  ticket.FeeAmount,
+		Expiration: ticket.FeeExpiration,
+		Request:    reqBytes,
+	}, c)
+}
+
+// buildFeeTxTemplate returns an unsigned tx which spends the provided inputs,
+// pays feeAmount to feeAddress, and pays each of the provided change outputs.
+func buildFeeTxTemplate(feeAddress string, feeAmount int64, inputs []types.FeeTxInput,
+	change []types.FeeTxOutput, network *config.Network) (*wire.MsgTx, error) {
+
+	if len(inputs) == 0 {
+		return nil, errors.New("no inputs provided")
+	}
+
+	tx := wire.NewMsgTx()
+
+	seen := make(map[wire.OutPoint]struct{}, len(inputs))
+	for i, input := range inputs {
+		hash, err := chainhash.NewHashFromStr(input.TxHash)
+		if err != nil {
+			return nil, fmt.Errorf("input %d has invalid tx hash: %w", i, err)
+		}
+		if input.Tree != wire.TxTreeRegular && input.Tree != wire.TxTreeStake {
+			return nil, fmt.Errorf("input %d has invalid tree %d", i, input.Tree)
+		}
+
+		outpoint := wire.NewOutPoint(hash, input.Index, input.Tree)
+		if _, ok := seen[*outpoint]; ok {
+			return nil, fmt.Errorf("input %d is a duplicate", i)
+		}
+		seen[*outpoint] = struct{}{}
+
+		tx.AddTxIn(wire.NewTxIn(outpoint, wire.NullValueIn, nil))
+	}
+
+	feeAddr, err := stdaddr.DecodeAddress(feeAddress, network)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode fee address: %w", err)
+	}
+	scriptVer, script := feeAddr.PaymentScript()
+	tx.AddTxOut(&wire.TxOut{Value: feeAmount, Version: scriptVer, PkScript: script})
+
+	for i, output := range change {
+		addr, err := stdaddr.DecodeAddress(output.Address, network)
+		if err != nil {
+			return nil, fmt.Errorf("change output %d has invalid address: %w", i, err)
+		}
+		if output.Amount <= 0 {
+			return nil, fmt.Errorf("change output %d has invalid amount %d", i, output.Amount)
+		}
+		scriptVer, script := addr.PaymentScript()
+		tx.AddTxOut(&wire.TxOut{Value: output.Amount, Version: scriptVer, PkScript: script})
+	}
+
+	return tx, nil
+}
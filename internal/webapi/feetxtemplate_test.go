@@ -0,0 +1,107 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/dcrutil/v4"
+	"github.com/decred/dcrd/txscript/v4/stdaddr"
+	"github.com/decred/vspd/internal/config"
+	"github.com/decred/vspd/types/v3"
+)
+
+func TestBuildFeeTxTemplate(t *testing.T) {
+	network := &config.MainNet
+
+	newAddr := func() stdaddr.Address {
+		addr, err := stdaddr.NewAddressPubKeyHashEcdsaSecp256k1V0(randBytes(20), network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return addr
+	}
+	feeAddr := newAddr()
+	changeAddr := newAddr()
+
+	const feeAmount = 1000
+	validInput := types.FeeTxInput{TxHash: randString(64, hexCharset), Index: 1}
+
+	tests := map[string]struct {
+		inputs    []types.FeeTxInput
+		change    []types.FeeTxOutput
+		expectErr bool
+	}{
+		"no inputs": {
+			inputs:    nil,
+			expectErr: true,
+		},
+		"invalid input hash": {
+			inputs:    []types.FeeTxInput{{TxHash: "not a hash"}},
+			expectErr: true,
+		},
+		"invalid input tree": {
+			inputs:    []types.FeeTxInput{{TxHash: validInput.TxHash, Tree: 2}},
+			expectErr: true,
+		},
+		"duplicate input": {
+			inputs:    []types.FeeTxInput{validInput, validInput},
+			expectErr: true,
+		},
+		"invalid change address": {
+			inputs:    []types.FeeTxInput{validInput},
+			change:    []types.FeeTxOutput{{Address: "Tsfkn6k9AoYgVZRV6ZzcgmuVSgCdJQt9JY2", Amount: 1}},
+			expectErr: true,
+		},
+		"invalid change amount": {
+			inputs:    []types.FeeTxInput{validInput},
+			change:    []types.FeeTxOutput{{Address: changeAddr.String(), Amount: -1}},
+			expectErr: true,
+		},
+		"no change": {
+			inputs:    []types.FeeTxInput{validInput},
+			expectErr: false,
+		},
+		"with change": {
+			inputs: []types.FeeTxInput{
+				validInput,
+				{TxHash: randString(64, hexCharset), Index: 0, Tree: 1},
+			},
+			change:    []types.FeeTxOutput{{Address: changeAddr.String(), Amount: 5000}},
+			expectErr: false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			tx, err := buildFeeTxTemplate(feeAddr.String(), feeAmount, test.inputs,
+				test.change, network)
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(tx.TxIn) != len(test.inputs) {
+				t.Fatalf("expected %d inputs, got %d", len(test.inputs), len(tx.TxIn))
+			}
+			if len(tx.TxOut) != len(test.change)+1 {
+				t.Fatalf("expected %d outputs, got %d", len(test.change)+1, len(tx.TxOut))
+			}
+
+			// The template must be accepted as a fee payment by /payfee.
+			scriptVer, script := feeAddr.PaymentScript()
+			paid, found := findFeePayment(tx, scriptVer, script)
+			if !found || paid != dcrutil.Amount(feeAmount) {
+				t.Fatalf("expected fee payment of %d, got found=%t paid=%d",
+					feeAmount, found, paid)
+			}
+		})
+	}
+}
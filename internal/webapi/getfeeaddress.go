@@ -5,12 +5,19 @@
 package webapi
 
 import (
+	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
 	"decred.org/dcrwallet/v4/wallet/txrules"
+	"github.com/decred/dcrd/blockchain/stake/v5"
 	"github.com/decred/dcrd/dcrutil/v4"
+	"github.com/decred/dcrd/txscript/v4/stdscript"
+	"github.com/decred/dcrd/wire"
 	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/internal/config"
+	"github.com/decred/vspd/internal/events"
 	"github.com/decred/vspd/rpc"
 	"github.com/decred/vspd/types/v3"
 	"github.com/gin-gonic/gin"
@@ -23,17 +30,31 @@ var addrMtx sync.Mutex
 // getNewFeeAddress gets a new address from the address generator, and updates
 // the last used address index in the database. In order to maintain consistency
 // between the internal counter of address generator and the database, this func
-// uses a mutex to ensure it is not run concurrently.
+// uses a mutex to ensure it is not run concurrently. If fee address recycling is
+// enabled, a verified orphaned address from the active xpub is reissued in
+// preference to deriving a new one.
 func (w *WebAPI) getNewFeeAddress() (string, uint32, error) {
 	addrMtx.Lock()
 	defer addrMtx.Unlock()
 
+	if w.cfg.RecycleFeeAddresses {
+		recycled, found, err := w.db.TakeRecycledFeeAddress(w.addrGen.xPubID())
+		if err != nil {
+			return "", 0, err
+		}
+		if found {
+			w.log.Debugf("Reissuing orphaned fee address %s (index %d)",
+				recycled.Address, recycled.Index)
+			return recycled.Address, recycled.Index, nil
+		}
+	}
+
 	addr, idx, err := w.addrGen.nextAddress()
 	if err != nil {
 		return "", 0, err
 	}
 
-	err = w.db.SetLastAddressIndex(idx)
+	err = w.db.SetLastAddressIndex(w.addrGen.xPubID(), idx)
 	if err != nil {
 		return "", 0, err
 	}
@@ -41,14 +62,105 @@ func (w *WebAPI) getNewFeeAddress() (string, uint32, error) {
 	return addr, idx, nil
 }
 
+// registerMtx protects registerNewTicket.
+var registerMtx sync.Mutex
+
+// registerNewTicket issues a fee address to the provided ticket and inserts it
+// into the database, unless the ticket has already been inserted, for example
+// by a concurrent request for the same ticket. The registered ticket is
+// returned, along with true if it was inserted by this call. If the ticket was
+// already registered, the existing ticket is returned and no fee address is
+// issued, so each ticket is only ever issued a single fee address.
+func (w *WebAPI) registerNewTicket(ticket database.Ticket) (database.Ticket, bool, error) {
+	registerMtx.Lock()
+	defer registerMtx.Unlock()
+
+	existing, found, err := w.db.GetTicketByHash(ticket.Hash)
+	if err != nil {
+		return database.Ticket{}, false, fmt.Errorf("db.GetTicketByHash error: %w", err)
+	}
+	if found {
+		return existing, false, nil
+	}
+
+	ticket.FeeAddress, ticket.FeeAddressIndex, err = w.getNewFeeAddress()
+	if err != nil {
+		return database.Ticket{}, false, fmt.Errorf("getNewFeeAddress error: %w", err)
+	}
+	ticket.FeeAddressXPubID = w.addrGen.xPubID()
+
+	err = w.db.InsertNewTicket(ticket)
+	if err != nil {
+		return database.Ticket{}, false, fmt.Errorf("db.InsertNewTicket error: %w", err)
+	}
+
+	return ticket, true, nil
+}
+
+// reissueRetiredFeeAddress issues a new fee address from the active xpub to the
+// ticket with the provided hash if its current fee address was derived from a
+// retired xpub. The fee amount and expiration of the ticket are not changed.
+// The ticket is returned, along with true if a new fee address was issued.
+func (w *WebAPI) reissueRetiredFeeAddress(ticketHash string) (database.Ticket, bool, error) {
+	registerMtx.Lock()
+	defer registerMtx.Unlock()
+
+	// Read the ticket again while holding the mutex in case a concurrent
+	// request has already reissued its fee address.
+	ticket, found, err := w.db.GetTicketByHash(ticketHash)
+	if err != nil {
+		return database.Ticket{}, false, fmt.Errorf("db.GetTicketByHash error: %w", err)
+	}
+	if !found {
+		return database.Ticket{}, false, fmt.Errorf("ticket %s not found", ticketHash)
+	}
+	if ticket.FeeAddressXPubID == w.addrGen.xPubID() {
+		return ticket, false, nil
+	}
+
+	ticket.FeeAddress, ticket.FeeAddressIndex, err = w.getNewFeeAddress()
+	if err != nil {
+		return database.Ticket{}, false, fmt.Errorf("getNewFeeAddress error: %w", err)
+	}
+	ticket.FeeAddressXPubID = w.addrGen.xPubID()
+
+	err = w.db.UpdateTicket(ticket)
+	if err != nil {
+		return database.Ticket{}, false, fmt.Errorf("db.UpdateTicket error: %w", err)
+	}
+
+	return ticket, true, nil
+}
+
 // getCurrentFee returns the minimum fee amount a client should pay in order to
-// register a ticket with the VSP at the current block height.
-func (w *WebAPI) getCurrentFee(dcrdClient *rpc.DcrdRPC) (dcrutil.Amount, error) {
+// register a ticket with the VSP at the current block height. The fee percentage
+// follows the fee schedule, and tickets which request priority processing are
+// additionally charged the priority fee percentage on top of it.
+func (w *WebAPI) getCurrentFee(dcrdClient *rpc.DcrdRPC, priority bool) (dcrutil.Amount, error) {
 	bestBlock, err := dcrdClient.GetBestBlockHeader()
 	if err != nil {
 		return 0, err
 	}
 
+	vspFee := w.cfg.FeeSchedule.Percentage(w.config().VSPFee, int64(bestBlock.Height), time.Now())
+	if priority {
+		vspFee += w.cfg.PriorityFee
+	}
+
+	return calcFee(bestBlock, vspFee, w.cfg.NominalFee, w.cfg.Network), nil
+}
+
+// calcFee returns the fee amount a client should pay in order to register a
+// ticket with the VSP at the height of the provided block. VSPs which do not
+// charge a fee percentage still require the nominal fee to be paid so that
+// clients have a valid fee tx to provide.
+func calcFee(bestBlock *wire.BlockHeader, vspFee float64, nominalFee dcrutil.Amount,
+	network *config.Network) dcrutil.Amount {
+
+	if vspFee == 0 {
+		return nominalFee
+	}
+
 	sDiff := dcrutil.Amount(bestBlock.SBits)
 
 	// Using a hard-coded amount for relay fee is acceptable here because this
@@ -57,19 +169,18 @@ func (w *WebAPI) getCurrentFee(dcrdClient *rpc.DcrdRPC) (dcrutil.Amount, error)
 	const defaultMinRelayTxFee = dcrutil.Amount(1e4)
 
 	height := int64(bestBlock.Height)
-	isDCP0010Active := w.cfg.Network.DCP10Active(height)
-	isDCP0012Active := w.cfg.Network.DCP12Active(height)
+	isDCP0010Active := network.DCP10Active(height)
+	isDCP0012Active := network.DCP12Active(height)
 
-	fee := txrules.StakePoolTicketFee(sDiff, defaultMinRelayTxFee, int32(bestBlock.Height),
-		w.cfg.VSPFee, w.cfg.Network.Params, isDCP0010Active, isDCP0012Active)
-
-	return fee, nil
+	return txrules.StakePoolTicketFee(sDiff, defaultMinRelayTxFee, int32(bestBlock.Height),
+		vspFee, network.Params, isDCP0010Active, isDCP0012Active)
 }
 
 // feeAddress is the handler for "POST /api/v3/feeaddress".
 func (w *WebAPI) feeAddress(c *gin.Context) {
 
 	const funcName = "feeAddress"
+	log := w.requestLog(c)
 
 	// Get values which have been added to context by middleware.
 	ticket := c.MustGet(ticketKey).(database.Ticket)
@@ -78,7 +189,7 @@ func (w *WebAPI) feeAddress(c *gin.Context) {
 	dcrdClient := c.MustGet(dcrdKey).(*rpc.DcrdRPC)
 	dcrdErr := c.MustGet(dcrdErrorKey)
 	if dcrdErr != nil {
-		w.log.Errorf("%s: %v", funcName, dcrdErr.(error))
+		log.Errorf("%s: %v", funcName, dcrdErr.(error))
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
@@ -86,19 +197,26 @@ func (w *WebAPI) feeAddress(c *gin.Context) {
 
 	var request types.FeeAddressRequest
 	if err := binding.JSON.BindBody(reqBytes, &request); err != nil {
-		w.log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
+		log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
 		w.sendErrorWithMsg(err.Error(), types.ErrBadRequest, c)
 		return
 	}
 
 	ticketHash := request.TicketHash
 
+	if request.Priority && w.cfg.PriorityFee == 0 {
+		log.Warnf("%s: Priority processing requested but not offered (clientIP=%s, ticketHash=%s)",
+			funcName, c.ClientIP(), ticketHash)
+		w.sendErrorWithMsg("priority processing is not offered by this VSP", types.ErrBadRequest, c)
+		return
+	}
+
 	// Respond early if we already have the fee tx for this ticket.
 	if knownTicket &&
 		(ticket.FeeTxStatus == database.FeeReceieved ||
 			ticket.FeeTxStatus == database.FeeBroadcast ||
 			ticket.FeeTxStatus == database.FeeConfirmed) {
-		w.log.Warnf("%s: Fee tx already received (clientIP=%s, ticketHash=%s)",
+		log.Warnf("%s: Fee tx already received (clientIP=%s, ticketHash=%s)",
 			funcName, c.ClientIP(), ticket.Hash)
 		w.sendError(types.ErrFeeAlreadyReceived, c)
 		return
@@ -107,7 +225,7 @@ func (w *WebAPI) feeAddress(c *gin.Context) {
 	// Get ticket details.
 	rawTicket, err := dcrdClient.GetRawTransaction(ticketHash)
 	if err != nil {
-		w.log.Errorf("%s: dcrd.GetRawTransaction for ticket failed (ticketHash=%s): %v", funcName, ticketHash, err)
+		log.Errorf("%s: dcrd.GetRawTransaction for ticket failed (ticketHash=%s): %v", funcName, ticketHash, err)
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
@@ -115,12 +233,12 @@ func (w *WebAPI) feeAddress(c *gin.Context) {
 	// Ensure this ticket is eligible to vote at some point in the future.
 	canVote, err := canTicketVote(rawTicket, dcrdClient, w.cfg.Network)
 	if err != nil {
-		w.log.Errorf("%s: canTicketVote error (ticketHash=%s): %v", funcName, ticketHash, err)
+		log.Errorf("%s: canTicketVote error (ticketHash=%s): %v", funcName, ticketHash, err)
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
 	if !canVote {
-		w.log.Warnf("%s: Unvotable ticket (clientIP=%s, ticketHash=%s)",
+		log.Warnf("%s: Unvotable ticket (clientIP=%s, ticketHash=%s)",
 			funcName, c.ClientIP(), ticketHash)
 		w.sendError(types.ErrTicketCannotVote, c)
 		return
@@ -129,34 +247,83 @@ func (w *WebAPI) feeAddress(c *gin.Context) {
 	// VSP already knows this ticket and has already issued it a fee address.
 	if knownTicket {
 
-		// If the expiry period has passed we need to issue a new fee.
+		// Replace fee addresses derived from a retired xpub if configured to,
+		// so the fee is paid to an address derived from the active xpub.
+		if w.cfg.ReissueRetiredFeeAddrs && ticket.FeeAddressXPubID != w.addrGen.xPubID() {
+			retiredAddress := ticket.FeeAddress
+			var reissued bool
+			ticket, reissued, err = w.reissueRetiredFeeAddress(ticket.Hash)
+			if err != nil {
+				log.Errorf("%s: reissueRetiredFeeAddress error (ticketHash=%s): %v",
+					funcName, ticketHash, err)
+				w.sendError(types.ErrInternalError, c)
+				return
+			}
+			if reissued {
+				log.Infof("%s: Reissued fee address derived from retired xpub (retiredFeeAddress=%s, "+
+					"feeAddress=%s, ticketHash=%s)", funcName, retiredAddress, ticket.FeeAddress, ticket.Hash)
+			}
+		}
+
+		// If the expiry period has passed we need to issue a new fee. The
+		// ticket no longer holds a reservation, so it can only be renewed if
+		// the VSP has capacity. A new fee is also issued if the current fee
+		// expires too soon for the client to reasonably pay it, or if the
+		// client has changed whether it is requesting priority processing.
 		now := time.Now()
-		if ticket.FeeExpired() {
-			newFee, err := w.getCurrentFee(dcrdClient)
+		if feeExpiresSoon(ticket.FeeExpiration, now, w.cfg.FeeQuoteMinValidity) ||
+			ticket.Priority != request.Priority {
+			if ticket.FeeExpired() && w.atCapacity() {
+				log.Warnf("%s: VSP is at capacity, cannot renew expired fee (clientIP=%s, ticketHash=%s)",
+					funcName, c.ClientIP(), ticket.Hash)
+				w.sendErrorWithMsg(vspAtCapacityMsg, types.ErrVspClosed, c)
+				return
+			}
+
+			newFee, err := w.getCurrentFee(dcrdClient, request.Priority)
 			if err != nil {
-				w.log.Errorf("%s: getCurrentFee error (ticketHash=%s): %v", funcName, ticket.Hash, err)
+				log.Errorf("%s: getCurrentFee error (ticketHash=%s): %v", funcName, ticket.Hash, err)
 				w.sendError(types.ErrInternalError, c)
 				return
 			}
-			ticket.FeeExpiration = now.Add(feeAddressExpiration).Unix()
+
+			// The new fee may be higher than the fee which was checked
+			// when the ticket was registered.
+			if w.cfg.TicketFeeLimitCheck != feeLimitCheckOff {
+				ticketTx, err := decodeTransaction(rawTicket.Hex)
+				if err != nil {
+					log.Errorf("%s: Failed to decode ticket hex (ticketHash=%s): %v",
+						funcName, ticket.Hash, err)
+					w.sendError(types.ErrInternalError, c)
+					return
+				}
+				if !w.checkTicketFeeLimit(c, ticketTx, newFee) {
+					return
+				}
+			}
+
+			ticket.FeeExpiration = now.Add(w.cfg.FeeReservationTimeout).Unix()
 			ticket.FeeAmount = int64(newFee)
+			ticket.Priority = request.Priority
 
 			err = w.db.UpdateTicket(ticket)
 			if err != nil {
-				w.log.Errorf("%s: db.UpdateTicket error, failed to update fee expiry (ticketHash=%s): %v",
+				log.Errorf("%s: db.UpdateTicket error, failed to update fee expiry (ticketHash=%s): %v",
 					funcName, ticket.Hash, err)
 				w.sendError(types.ErrInternalError, c)
 				return
 			}
-			w.log.Debugf("%s: Expired fee updated (newFeeAmt=%s, ticketHash=%s)",
-				funcName, newFee, ticket.Hash)
+			log.Debugf("%s: Fee updated (newFeeAmt=%s, priority=%t, ticketHash=%s)",
+				funcName, newFee, ticket.Priority, ticket.Hash)
 		}
 		w.sendJSONResponse(types.FeeAddressResponse{
-			Timestamp:  now.Unix(),
-			Request:    reqBytes,
-			FeeAddress: ticket.FeeAddress,
-			FeeAmount:  ticket.FeeAmount,
-			Expiration: ticket.FeeExpiration,
+			Timestamp:      now.Unix(),
+			Request:        reqBytes,
+			FeeAddress:     ticket.FeeAddress,
+			FeeAmount:      ticket.FeeAmount,
+			Expiration:     ticket.FeeExpiration,
+			DeadlineHeight: w.feeDeadline(),
+			PaymentURI:     paymentURI(ticket.FeeAddress, dcrutil.Amount(ticket.FeeAmount)),
 		}, c)
 
 		return
@@ -165,22 +332,91 @@ func (w *WebAPI) feeAddress(c *gin.Context) {
 	// Beyond this point we are processing a new ticket which the VSP has not
 	// seen before.
 
-	fee, err := w.getCurrentFee(dcrdClient)
+	// Ensure the VSP has capacity for another ticket.
+	if w.atCapacity() {
+		log.Warnf("%s: VSP is at capacity (clientIP=%s, ticketHash=%s)",
+			funcName, c.ClientIP(), ticketHash)
+		w.sendErrorWithMsg(vspAtCapacityMsg, types.ErrVspClosed, c)
+		return
+	}
+
+	// Ensure the commitment address has not reached the limit of active
+	// tickets it is allowed to register. Tickets which have been issued a fee
+	// which has not yet expired are counted, so unpaid tickets cannot be used
+	// to exceed the limit.
+	if w.cfg.MaxTicketsPerAddress > 0 {
+		count, err := w.db.CountActiveTicketsByCommitmentAddress(commitmentAddress, time.Now())
+		if err != nil {
+			log.Errorf("%s: db.CountActiveTicketsByCommitmentAddress error (ticketHash=%s): %v",
+				funcName, ticketHash, err)
+			w.sendError(types.ErrInternalError, c)
+			return
+		}
+		if count >= int64(w.cfg.MaxTicketsPerAddress) {
+			log.Warnf("%s: Commitment address has too many active tickets (clientIP=%s, "+
+				"ticketHash=%s, commitmentAddress=%s, count=%d)",
+				funcName, c.ClientIP(), ticketHash, commitmentAddress, count)
+			w.sendError(types.ErrTooManyTickets, c)
+			return
+		}
+	}
+
+	ticketTx, err := decodeTransaction(rawTicket.Hex)
 	if err != nil {
-		w.log.Errorf("%s: getCurrentFee error (ticketHash=%s): %v", funcName, ticketHash, err)
+		log.Errorf("%s: Failed to decode ticket hex (ticketHash=%s): %v",
+			funcName, ticketHash, err)
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
 
-	newAddress, newAddressIdx, err := w.getNewFeeAddress()
+	submission, scriptType, err := stakeSubmission(ticketTx)
 	if err != nil {
-		w.log.Errorf("%s: getNewFeeAddress error (ticketHash=%s): %v", funcName, ticketHash, err)
+		log.Warnf("%s: Invalid ticket (clientIP=%s, ticketHash=%s): %v",
+			funcName, c.ClientIP(), ticketHash, err)
+		w.sendErrorWithMsg(err.Error(), types.ErrInvalidTicket, c)
+		return
+	}
+
+	// Voting rights held by a script hash cannot be exercised with a single
+	// voting key, so reject the ticket before it is issued a fee address.
+	if scriptType == stdscript.STStakeSubmissionScriptHash {
+		log.Warnf("%s: Ticket voting rights held by script hash (clientIP=%s, ticketHash=%s)",
+			funcName, c.ClientIP(), ticketHash)
+		w.sendErrorWithMsg(scriptHashTicketMsg, types.ErrInvalidTicket, c)
+		return
+	}
+
+	// Ensure the ticket is accepted by the admission policy of the VSP.
+	err = w.checkTicketPolicy(ticketTx)
+	if err != nil {
+		log.Warnf("%s: Ticket rejected by policy (clientIP=%s, ticketHash=%s): %v",
+			funcName, c.ClientIP(), ticketHash, err)
+		w.sendErrorWithMsg(err.Error(), types.ErrTicketRejectedByPolicy, c)
+		return
+	}
+
+	// Ensure the ticket price is within the range accepted by the VSP.
+	price := dcrutil.Amount(submission.Value)
+	if !ticketPriceInRange(price, w.cfg.MinTicketPrice, w.cfg.MaxTicketPrice) {
+		log.Warnf("%s: Ticket price out of range (clientIP=%s, ticketHash=%s, price=%v)",
+			funcName, c.ClientIP(), ticketHash, price)
+		w.sendError(types.ErrTicketPriceOutOfRange, c)
+		return
+	}
+
+	fee, err := w.getCurrentFee(dcrdClient, request.Priority)
+	if err != nil {
+		log.Errorf("%s: getCurrentFee error (ticketHash=%s): %v", funcName, ticketHash, err)
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
 
+	if !w.checkTicketFeeLimit(c, ticketTx, fee) {
+		return
+	}
+
 	now := time.Now()
-	expire := now.Add(feeAddressExpiration).Unix()
+	expire := now.Add(w.cfg.FeeReservationTimeout).Unix()
 
 	// Only set purchase height if the ticket already has 6 confs, otherwise its
 	// purchase height may change due to reorgs.
@@ -191,35 +427,125 @@ func (w *WebAPI) feeAddress(c *gin.Context) {
 		purchaseHeight = rawTicket.BlockHeight
 	}
 
-	dbTicket := database.Ticket{
+	dbTicket, inserted, err := w.registerNewTicket(database.Ticket{
 		Hash:              ticketHash,
 		PurchaseHeight:    purchaseHeight,
 		CommitmentAddress: commitmentAddress,
-		FeeAddressIndex:   newAddressIdx,
-		FeeAddressXPubID:  w.addrGen.xPubID(),
-		FeeAddress:        newAddress,
 		Confirmed:         confirmed,
 		FeeAmount:         int64(fee),
 		FeeExpiration:     expire,
 		FeeTxStatus:       database.NoFee,
-	}
-
-	err = w.db.InsertNewTicket(dbTicket)
+		Priority:          request.Priority,
+		Registered:        now.Unix(),
+	})
 	if err != nil {
-		w.log.Errorf("%s: db.InsertNewTicket failed (ticketHash=%s): %v", funcName, ticketHash, err)
+		log.Errorf("%s: registerNewTicket failed (ticketHash=%s): %v", funcName, ticketHash, err)
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
 
-	w.log.Debugf("%s: Fee address created for new ticket: (tktConfirmed=%t, feeAddrIdx=%d, "+
-		"feeAddr=%s, feeAmt=%s, ticketHash=%s)",
-		funcName, confirmed, newAddressIdx, newAddress, fee, ticketHash)
+	if inserted {
+		w.events.Publish(events.TicketRegistered, ticketHash)
+
+		log.Debugf("%s: Fee address created for new ticket: (tktConfirmed=%t, feeAddrIdx=%d, "+
+			"feeAddr=%s, feeAmt=%s, priority=%t, ticketHash=%s)",
+			funcName, confirmed, dbTicket.FeeAddressIndex, dbTicket.FeeAddress, fee,
+			request.Priority, ticketHash)
+	} else {
+		// A concurrent request registered the ticket first, so return the
+		// fee address which it was issued.
+		log.Debugf("%s: Ticket registered by concurrent request, returning existing fee "+
+			"address (feeAddr=%s, ticketHash=%s)", funcName, dbTicket.FeeAddress, ticketHash)
+	}
 
 	w.sendJSONResponse(types.FeeAddressResponse{
-		Timestamp:  now.Unix(),
-		Request:    reqBytes,
-		FeeAddress: newAddress,
-		FeeAmount:  int64(fee),
-		Expiration: expire,
+		Timestamp:      now.Unix(),
+		Request:        reqBytes,
+		FeeAddress:     dbTicket.FeeAddress,
+		FeeAmount:      dbTicket.FeeAmount,
+		Expiration:     dbTicket.FeeExpiration,
+		DeadlineHeight: w.feeDeadline(),
+		PaymentURI:     paymentURI(dbTicket.FeeAddress, dcrutil.Amount(dbTicket.FeeAmount)),
 	}, c)
 }
+
+// feeExpiresSoon reports whether a fee which expires at the unix timestamp
+// expiration has less than minValidity remaining at time now, including fees
+// which have already expired.
+func feeExpiresSoon(expiration int64, now time.Time, minValidity time.Duration) bool {
+	return time.Unix(expiration, 0).Before(now.Add(minValidity))
+}
+
+// paymentURI returns a URI which instructs wallets to pay amount to address.
+func paymentURI(address string, amount dcrutil.Amount) string {
+	return fmt.Sprintf("decred:%s?amount=%s", address,
+		strconv.FormatFloat(amount.ToCoin(), 'f', -1, 64))
+}
+
+// checkTicketFeeLimit compares the fee limits declared in the commitment of a
+// ticket with fee, the fee being issued to the ticket. A limit lower than the
+// fee indicates the client expected a different arrangement with the VSP, and
+// is either logged or rejected depending on config. False is returned if the
+// request has been rejected and an error response has already been sent.
+func (w *WebAPI) checkTicketFeeLimit(c *gin.Context, ticketTx *wire.MsgTx, fee dcrutil.Amount) bool {
+	const funcName = "checkTicketFeeLimit"
+	log := w.requestLog(c)
+
+	if w.cfg.TicketFeeLimitCheck == feeLimitCheckOff {
+		return true
+	}
+
+	limit, limited := ticketFeeLimit(ticketTx)
+	if !limited || limit >= fee {
+		return true
+	}
+
+	ticketHash := ticketTx.TxHash().String()
+	if w.cfg.TicketFeeLimitCheck == feeLimitCheckReject {
+		log.Warnf("%s: Ticket fee limit lower than VSP fee (clientIP=%s, ticketHash=%s, "+
+			"feeLimit=%v, feeAmt=%v)", funcName, c.ClientIP(), ticketHash, limit, fee)
+		w.sendError(types.ErrTicketFeeLimitTooLow, c)
+		return false
+	}
+
+	log.Warnf("%s: Ticket fee limit lower than VSP fee, accepting anyway (clientIP=%s, "+
+		"ticketHash=%s, feeLimit=%v, feeAmt=%v)", funcName, c.ClientIP(), ticketHash, limit, fee)
+	return true
+}
+
+// ticketFeeLimit returns the lowest fee limit declared by the commitment
+// outputs of a ticket, considering the limits for both votes and revocations.
+// The second return value is false if the ticket does not declare any limit.
+func ticketFeeLimit(ticketTx *wire.MsgTx) (dcrutil.Amount, bool) {
+	_, _, _, _, spendRules, spendLimits := stake.TxSStxStakeOutputInfo(ticketTx)
+
+	var limit dcrutil.Amount
+	var limited bool
+	for i, rules := range spendRules {
+		for j, hasLimit := range rules {
+			if !hasLimit {
+				continue
+			}
+			// Limits are encoded as a power of two number of atoms.
+			l := dcrutil.Amount(int64(1) << spendLimits[i][j])
+			if !limited || l < limit {
+				limit = l
+				limited = true
+			}
+		}
+	}
+
+	return limit, limited
+}
+
+// ticketPriceInRange reports whether the provided ticket price is within the
+// range set by min and max. A limit of zero means no limit is applied.
+func ticketPriceInRange(price, min, max dcrutil.Amount) bool {
+	if min > 0 && price < min {
+		return false
+	}
+	if max > 0 && price > max {
+		return false
+	}
+	return true
+}
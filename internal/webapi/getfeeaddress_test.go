@@ -0,0 +1,551 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrutil/v4"
+	"github.com/decred/dcrd/hdkeychain/v3"
+	dcrdtypes "github.com/decred/dcrd/rpc/jsonrpc/types/v4"
+	"github.com/decred/dcrd/txscript/v4"
+	"github.com/decred/dcrd/txscript/v4/stdaddr"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/internal/config"
+	"github.com/decred/vspd/rpc"
+	"github.com/decred/vspd/types/v3"
+	"github.com/gin-gonic/gin"
+)
+
+// TestCalcFee ensures the fee amount is calculated from the fee percentage, and
+// that VSPs with a zero fee percentage request the nominal fee amount instead.
+func TestCalcFee(t *testing.T) {
+	network := &config.MainNet
+	bestBlock := &wire.BlockHeader{
+		Height: 850000,
+		SBits:  int64(200 * dcrutil.AtomsPerCoin),
+	}
+	const nominalFee = dcrutil.Amount(1e4)
+
+	tests := map[string]struct {
+		vspFee  float64
+		wantFee func(dcrutil.Amount) bool
+	}{
+		"zero fee percentage": {
+			vspFee:  0,
+			wantFee: func(fee dcrutil.Amount) bool { return fee == nominalFee },
+		},
+		"non-zero fee percentage": {
+			vspFee:  1.0,
+			wantFee: func(fee dcrutil.Amount) bool { return fee > nominalFee },
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			fee := calcFee(bestBlock, test.vspFee, nominalFee, network)
+			if !test.wantFee(fee) {
+				t.Fatalf("unexpected fee amount %s for fee percentage %.2f",
+					fee, test.vspFee)
+			}
+		})
+	}
+
+	// A higher fee percentage should always result in a higher fee.
+	low := calcFee(bestBlock, 0.5, nominalFee, network)
+	high := calcFee(bestBlock, 5, nominalFee, network)
+	if low >= high {
+		t.Fatalf("expected fee for 0.5%% (%s) to be less than fee for 5%% (%s)",
+			low, high)
+	}
+}
+
+// TestTicketPriceInRange ensures ticket prices are checked against the
+// configured limits, and that a limit of zero is not applied.
+func TestTicketPriceInRange(t *testing.T) {
+	const dcr = dcrutil.Amount(dcrutil.AtomsPerCoin)
+
+	tests := map[string]struct {
+		price, min, max dcrutil.Amount
+		expect          bool
+	}{
+		"no limits":        {price: 100 * dcr, expect: true},
+		"above minimum":    {price: 100 * dcr, min: 50 * dcr, expect: true},
+		"equal to minimum": {price: 50 * dcr, min: 50 * dcr, expect: true},
+		"below minimum":    {price: 49 * dcr, min: 50 * dcr, expect: false},
+		"below maximum":    {price: 100 * dcr, max: 200 * dcr, expect: true},
+		"equal to maximum": {price: 200 * dcr, max: 200 * dcr, expect: true},
+		"above maximum":    {price: 201 * dcr, max: 200 * dcr, expect: false},
+		"within range":     {price: 100 * dcr, min: 50 * dcr, max: 200 * dcr, expect: true},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := ticketPriceInRange(test.price, test.min, test.max)
+			if actual != test.expect {
+				t.Fatalf("expected %t, got %t", test.expect, actual)
+			}
+		})
+	}
+}
+
+// activateNewXPub retires the current fee xpub in the database of w in favor of
+// a randomly generated one, and initializes the address generator of w for the
+// new xpub, as happens when vspd is restarted after using retirexpub.
+func activateNewXPub(t *testing.T, w *WebAPI) {
+	t.Helper()
+
+	seed := randBytes(hdkeychain.RecommendedSeedLen)
+	master, err := hdkeychain.NewMaster(seed, w.cfg.Network.Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = w.db.RetireXPub(master.Neuter().String())
+	if err != nil {
+		t.Fatalf("retiring xpub failed: %v", err)
+	}
+	active, err := w.db.FeeXPub()
+	if err != nil {
+		t.Fatalf("error getting fee xpub: %v", err)
+	}
+	w.addrGen, err = newAddressGenerator(active, w.cfg.Network.Params, w.log)
+	if err != nil {
+		t.Fatalf("failed to initialize address generator: %v", err)
+	}
+}
+
+// TestGetNewFeeAddressRetiredXPub ensures new fee addresses are only issued
+// from the currently active xpub, and not from an xpub which has been retired.
+func TestGetNewFeeAddressRetiredXPub(t *testing.T) {
+	activateNewXPub(t, api)
+
+	_, idx, err := api.getNewFeeAddress()
+	if err != nil {
+		t.Fatalf("error getting fee address from active xpub: %v", err)
+	}
+	active, err := api.db.FeeXPub()
+	if err != nil {
+		t.Fatalf("error getting fee xpub: %v", err)
+	}
+	if active.LastUsedIdx != idx {
+		t.Fatalf("expected last used index %d, got %d", idx, active.LastUsedIdx)
+	}
+
+	// Retire the xpub used by the address generator. It should no longer be
+	// possible to issue fee addresses from it.
+	seed := randBytes(hdkeychain.RecommendedSeedLen)
+	master, err := hdkeychain.NewMaster(seed, api.cfg.Network.Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = api.db.RetireXPub(master.Neuter().String())
+	if err != nil {
+		t.Fatalf("retiring xpub failed: %v", err)
+	}
+	_, _, err = api.getNewFeeAddress()
+	if err == nil {
+		t.Fatal("expected an error getting fee address from retired xpub")
+	}
+}
+
+// TestRegisterNewTicketConcurrent ensures concurrent registrations of the same
+// ticket issue a single fee address, which is returned to every caller.
+func TestRegisterNewTicketConcurrent(t *testing.T) {
+	active, err := api.db.FeeXPub()
+	if err != nil {
+		t.Fatalf("error getting fee xpub: %v", err)
+	}
+	api.addrGen, err = newAddressGenerator(active, api.cfg.Network.Params, api.log)
+	if err != nil {
+		t.Fatalf("failed to initialize address generator: %v", err)
+	}
+
+	ticketHash := hex.EncodeToString(randBytes(32))
+
+	const numRequests = 10
+	var wg sync.WaitGroup
+	tickets := make([]database.Ticket, numRequests)
+	inserted := make([]bool, numRequests)
+	errs := make([]error, numRequests)
+	for i := 0; i < numRequests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tickets[i], inserted[i], errs[i] = api.registerNewTicket(database.Ticket{
+				Hash:        ticketHash,
+				FeeAmount:   1000,
+				FeeTxStatus: database.NoFee,
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	numInserted := 0
+	for i := 0; i < numRequests; i++ {
+		if errs[i] != nil {
+			t.Fatalf("registerNewTicket failed: %v", errs[i])
+		}
+		if inserted[i] {
+			numInserted++
+		}
+		if tickets[i].FeeAddress != tickets[0].FeeAddress {
+			t.Fatalf("expected fee address %s, got %s", tickets[0].FeeAddress, tickets[i].FeeAddress)
+		}
+	}
+	if numInserted != 1 {
+		t.Fatalf("expected ticket to be inserted once, got %d", numInserted)
+	}
+
+	// Only one fee address should have been derived.
+	updated, err := api.db.FeeXPub()
+	if err != nil {
+		t.Fatalf("error getting fee xpub: %v", err)
+	}
+	if updated.LastUsedIdx != tickets[0].FeeAddressIndex {
+		t.Fatalf("expected last used index %d, got %d", tickets[0].FeeAddressIndex, updated.LastUsedIdx)
+	}
+
+	stored, found, err := api.db.GetTicketByHash(ticketHash)
+	if err != nil || !found {
+		t.Fatalf("expected ticket to be stored, found=%t, err=%v", found, err)
+	}
+	if stored.FeeAddress != tickets[0].FeeAddress {
+		t.Fatalf("expected stored fee address %s, got %s", tickets[0].FeeAddress, stored.FeeAddress)
+	}
+}
+
+// TestFeeExpiresSoon ensures fees are considered to expire soon if they have
+// less than the minimum validity remaining, including fees which have already
+// expired.
+func TestFeeExpiresSoon(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	const minValidity = 10 * time.Minute
+
+	tests := map[string]struct {
+		expiration time.Time
+		expect     bool
+	}{
+		"already expired": {
+			expiration: now.Add(-time.Second),
+			expect:     true,
+		},
+		"less than minimum remaining": {
+			expiration: now.Add(minValidity - time.Second),
+			expect:     true,
+		},
+		"exactly minimum remaining": {
+			expiration: now.Add(minValidity),
+			expect:     false,
+		},
+		"more than minimum remaining": {
+			expiration: now.Add(time.Hour),
+			expect:     false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := feeExpiresSoon(test.expiration.Unix(), now, minValidity)
+			if actual != test.expect {
+				t.Fatalf("expected %t, got %t", test.expect, actual)
+			}
+		})
+	}
+}
+
+// TestPaymentURI ensures payment URIs encode the fee amount in coins without
+// losing precision.
+func TestPaymentURI(t *testing.T) {
+	const addr = "Tsfkn6k9AoYgVZRV6ZzcgmuVSgCdJQt9JY2"
+
+	tests := map[string]struct {
+		amount dcrutil.Amount
+		expect string
+	}{
+		"whole coins": {
+			amount: 2e8,
+			expect: "decred:" + addr + "?amount=2",
+		},
+		"fractional coins": {
+			amount: 100000,
+			expect: "decred:" + addr + "?amount=0.001",
+		},
+		"single atom": {
+			amount: 1,
+			expect: "decred:" + addr + "?amount=0.00000001",
+		},
+		"mixed": {
+			amount: 123456789,
+			expect: "decred:" + addr + "?amount=1.23456789",
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := paymentURI(addr, test.amount)
+			if actual != test.expect {
+				t.Fatalf("expected %q, got %q", test.expect, actual)
+			}
+		})
+	}
+}
+
+// TestTicketFeeLimit ensures the lowest fee limit declared by ticket commitment
+// outputs is found.
+func TestTicketFeeLimit(t *testing.T) {
+	// commitmentScript returns a ticket commitment output script with the
+	// provided encoded fee limits.
+	commitmentScript := func(limits uint16) []byte {
+		script := make([]byte, 32)
+		script[0] = txscript.OP_RETURN
+		script[1] = txscript.OP_DATA_30
+		binary.LittleEndian.PutUint16(script[30:], limits)
+		return script
+	}
+
+	ticket := func(limits ...uint16) *wire.MsgTx {
+		tx := wire.NewMsgTx()
+		tx.AddTxOut(wire.NewTxOut(0, nil))
+		for _, l := range limits {
+			tx.AddTxOut(wire.NewTxOut(0, commitmentScript(l)))
+			tx.AddTxOut(wire.NewTxOut(0, nil))
+		}
+		return tx
+	}
+
+	tests := map[string]struct {
+		ticket        *wire.MsgTx
+		expectLimit   dcrutil.Amount
+		expectLimited bool
+	}{
+		"no limits": {
+			ticket: ticket(0x0018),
+		},
+		"revocation limit": {
+			ticket:        ticket(0x5800),
+			expectLimit:   1 << 24,
+			expectLimited: true,
+		},
+		"vote and revocation limits": {
+			ticket:        ticket(0x5840 | 0x0010),
+			expectLimit:   1 << 16,
+			expectLimited: true,
+		},
+		"lowest of multiple commitments": {
+			ticket:        ticket(0x5800, 0x0054),
+			expectLimit:   1 << 20,
+			expectLimited: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			limit, limited := ticketFeeLimit(test.ticket)
+			if limited != test.expectLimited {
+				t.Fatalf("expected limited=%t, got %t", test.expectLimited, limited)
+			}
+			if limit != test.expectLimit {
+				t.Fatalf("expected limit %v, got %v", test.expectLimit, limit)
+			}
+		})
+	}
+}
+
+// TestCheckTicketFeeLimit ensures fees issued to tickets which exceed the fee
+// limit declared in the ticket commitment are only rejected when configured.
+func TestCheckTicketFeeLimit(t *testing.T) {
+	// Create a ticket with a revocation fee limit of 1<<24 atoms.
+	script := make([]byte, 32)
+	script[0] = txscript.OP_RETURN
+	script[1] = txscript.OP_DATA_30
+	binary.LittleEndian.PutUint16(script[30:], 0x5800)
+	ticketTx := wire.NewMsgTx()
+	ticketTx.AddTxOut(wire.NewTxOut(0, nil))
+	ticketTx.AddTxOut(wire.NewTxOut(0, script))
+	ticketTx.AddTxOut(wire.NewTxOut(0, nil))
+
+	tests := map[string]struct {
+		check          string
+		fee            dcrutil.Amount
+		expectAccepted bool
+	}{
+		"check off":          {check: feeLimitCheckOff, fee: 1 << 25, expectAccepted: true},
+		"warn":               {check: feeLimitCheckWarn, fee: 1 << 25, expectAccepted: true},
+		"reject":             {check: feeLimitCheckReject, fee: 1 << 25, expectAccepted: false},
+		"reject, fee equal":  {check: feeLimitCheckReject, fee: 1 << 24, expectAccepted: true},
+		"reject, fee within": {check: feeLimitCheckReject, fee: 1 << 23, expectAccepted: true},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			w := &WebAPI{
+				cfg:         Config{TicketFeeLimitCheck: test.check},
+				signPrivKey: api.signPrivKey,
+				log:         api.log,
+			}
+
+			rec := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(rec)
+			c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+
+			accepted := w.checkTicketFeeLimit(c, ticketTx, test.fee)
+			if accepted != test.expectAccepted {
+				t.Fatalf("expected accepted=%t, got %t", test.expectAccepted, accepted)
+			}
+			if accepted {
+				return
+			}
+
+			var apiError types.ErrorResponse
+			err := json.Unmarshal(rec.Body.Bytes(), &apiError)
+			if err != nil {
+				t.Fatalf("could not unmarshal error response: %v", err)
+			}
+			if apiError.Code != types.ErrTicketFeeLimitTooLow {
+				t.Fatalf("expected error code %d, got %d", types.ErrTicketFeeLimitTooLow, apiError.Code)
+			}
+		})
+	}
+}
+
+// TestFeeDeadline ensures the fee deadline is only provided when a deadline
+// window is configured and the current block height is known.
+func TestFeeDeadline(t *testing.T) {
+	tests := map[string]struct {
+		blocks      int64
+		data        cacheData
+		expectBlock int64
+	}{
+		"no window configured": {
+			blocks:      0,
+			data:        cacheData{Initialized: true, BlockHeight: 1000},
+			expectBlock: 0,
+		},
+		"cache not initialized": {
+			blocks:      12,
+			data:        cacheData{BlockHeight: 1000},
+			expectBlock: 0,
+		},
+		"window added to current height": {
+			blocks:      12,
+			data:        cacheData{Initialized: true, BlockHeight: 1000},
+			expectBlock: 1012,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			w := &WebAPI{
+				cfg:   Config{FeeDeadlineBlocks: test.blocks},
+				cache: &cache{data: test.data},
+			}
+			actual := w.feeDeadline()
+			if actual != test.expectBlock {
+				t.Fatalf("expected deadline %d, got %d", test.expectBlock, actual)
+			}
+		})
+	}
+}
+
+// TestFeeAddressScriptHashTicket ensures tickets whose voting rights are held
+// by a script hash are rejected before they are issued a fee address.
+func TestFeeAddressScriptHashTicket(t *testing.T) {
+	network := api.cfg.Network
+
+	scriptAddr, err := stdaddr.NewAddressScriptHashV0FromHash(randBytes(20), network)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var prevHash chainhash.Hash
+	copy(prevHash[:], randBytes(chainhash.HashSize))
+	ticketTx := wire.NewMsgTx()
+	ticketTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevHash, 0, wire.TxTreeRegular), 1e8, nil))
+	scriptVer, script := scriptAddr.VotingRightsScript()
+	ticketTx.AddTxOut(&wire.TxOut{Value: 1e8, Version: scriptVer, PkScript: script})
+	scriptVer, script = scriptAddr.RewardCommitmentScript(1e8, 0, 0)
+	ticketTx.AddTxOut(&wire.TxOut{Version: scriptVer, PkScript: script})
+	scriptVer, script = scriptAddr.StakeChangeScript()
+	ticketTx.AddTxOut(&wire.TxOut{Version: scriptVer, PkScript: script})
+	ticketBytes, err := ticketTx.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticketHex := hex.EncodeToString(ticketBytes)
+	ticketHash := ticketTx.TxHash().String()
+
+	dcrd := &rpc.DcrdRPC{Caller: &testDcrd{rawTx: dcrdtypes.TxRawResult{
+		Txid:          ticketHash,
+		Hex:           ticketHex,
+		Confirmations: 1,
+	}}}
+
+	w := &WebAPI{
+		cfg:         Config{Network: network},
+		signPrivKey: api.signPrivKey,
+		db:          api.db,
+		log:         api.log,
+		cache:       &cache{},
+	}
+
+	reqBytes, err := json.Marshal(types.FeeAddressRequest{
+		Timestamp:  time.Now().Unix(),
+		TicketHash: ticketHash,
+		TicketHex:  ticketHex,
+		ParentHex:  ticketHex,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	_, r := gin.CreateTestContext(rec)
+	r.POST("/", func(c *gin.Context) {
+		c.Set(ticketKey, database.Ticket{Hash: ticketHash})
+		c.Set(knownTicketKey, false)
+		c.Set(commitmentAddressKey, scriptAddr.String())
+		c.Set(dcrdKey, dcrd)
+		c.Set(dcrdErrorKey, nil)
+		c.Set(requestBytesKey, reqBytes)
+		w.feeAddress(c)
+	})
+
+	req, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected http status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+	var apiError types.ErrorResponse
+	err = json.Unmarshal(rec.Body.Bytes(), &apiError)
+	if err != nil {
+		t.Fatalf("could not unmarshal error response: %v", err)
+	}
+	if apiError.Code != types.ErrInvalidTicket || apiError.Message != scriptHashTicketMsg {
+		t.Fatalf("expected error code %d with message %q, got %d with message %q",
+			types.ErrInvalidTicket, scriptHashTicketMsg, apiError.Code, apiError.Message)
+	}
+
+	_, found, err := api.db.GetTicketByHash(ticketHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Fatal("expected ticket not to be registered")
+	}
+}
@@ -1,67 +1,128 @@
-// Copyright (c) 2021-2023 The Decred developers
+// Copyright (c) 2021-2024 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
 package webapi
 
 import (
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/decred/dcrd/blockchain/stake/v5"
 	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 	"github.com/decred/dcrd/dcrutil/v4"
 	dcrdtypes "github.com/decred/dcrd/rpc/jsonrpc/types/v4"
+	"github.com/decred/dcrd/txscript/v4/stdaddr"
+	"github.com/decred/dcrd/txscript/v4/stdscript"
 	"github.com/decred/dcrd/wire"
 	"github.com/decred/vspd/database"
 	"github.com/decred/vspd/internal/config"
+	"github.com/gin-gonic/gin"
 )
 
 // validConsensusVoteChoices returns an error if provided vote choices are not
-// valid for the most recent consensus agendas.
-func validConsensusVoteChoices(network *config.Network, voteVersion uint32, voteChoices map[string]string) error {
+// valid for the consensus agendas of the provided vote versions. If an agenda
+// appears in more than one version, a choice is valid if it exists in any of
+// them.
+func validConsensusVoteChoices(network *config.Network, voteVersions []uint32, voteChoices map[string]string) error {
 
 agendaLoop:
 	for agenda, choice := range voteChoices {
 		// Does the agenda exist?
-		for _, v := range network.Deployments[voteVersion] {
-			if v.Vote.Id == agenda {
-				// Agenda exists - does the vote choice exist?
-				for _, c := range v.Vote.Choices {
-					if c.Id == choice {
-						// Valid agenda and choice combo! Check the next one...
-						continue agendaLoop
+		agendaFound := false
+		for _, voteVersion := range voteVersions {
+			for _, v := range network.Deployments[voteVersion] {
+				if v.Vote.Id == agenda {
+					// Agenda exists - does the vote choice exist?
+					agendaFound = true
+					for _, c := range v.Vote.Choices {
+						if c.Id == choice {
+							// Valid agenda and choice combo! Check the next one...
+							continue agendaLoop
+						}
 					}
 				}
-				return fmt.Errorf("choice %q not found for agenda %q", choice, agenda)
 			}
-
 		}
-		return fmt.Errorf("agenda %q not found for vote version %d", agenda, voteVersion)
+		if agendaFound {
+			return fmt.Errorf("choice %q not found for agenda %q", choice, agenda)
+		}
+		return fmt.Errorf("agenda %q not found for vote versions %v", agenda, voteVersions)
 	}
 
 	return nil
 }
 
-func validTreasuryPolicy(policy map[string]string) error {
+// settableVoteVersions returns the vote versions whose agendas vote choices can
+// currently be accepted for. dcrwallet only sets vote choices for the agendas of
+// the vote version it is running, so these are the versions reported by the
+// voting wallets which still have unexpired agendas at the provided time. The
+// current vote version is returned if no voting wallet version is known.
+func settableVoteVersions(network *config.Network, walletVersions []uint32, now time.Time) []uint32 {
+	var versions []uint32
+	for _, version := range network.VoteVersions(now) {
+		for _, walletVersion := range walletVersions {
+			if version == walletVersion {
+				versions = append(versions, version)
+				break
+			}
+		}
+	}
+
+	if len(versions) == 0 {
+		return []uint32{network.CurrentVoteVersion()}
+	}
+
+	return versions
+}
+
+// voteVersions returns the vote versions whose agendas vote choices can
+// currently be accepted for, using the vote versions of the voting wallets
+// recorded in the cache.
+func (w *WebAPI) voteVersions() []uint32 {
+	return settableVoteVersions(w.cfg.Network, w.cache.getData().WalletVoteVersions, time.Now())
+}
+
+// validTreasuryPolicy checks that every key of policy is a hex encoded
+// compressed public key and every choice is a valid policy option. A copy of
+// policy is returned with every key in canonical lowercase hex encoding, so
+// equivalent keys with different encodings are merged into a single entry.
+// Equivalent keys which are assigned different choices return an error.
+func validTreasuryPolicy(policy map[string]string) (map[string]string, error) {
+	if policy == nil {
+		return nil, nil
+	}
+
+	normalized := make(map[string]string, len(policy))
 	for key, choice := range policy {
 		pikey, err := hex.DecodeString(key)
 		if err != nil {
-			return fmt.Errorf("error decoding treasury key %q: %w", key, err)
+			return nil, fmt.Errorf("error decoding treasury key %q: %w", key, err)
 		}
 		if len(pikey) != secp256k1.PubKeyBytesLenCompressed {
-			return fmt.Errorf("treasury key %q is not 33 bytes", key)
+			return nil, fmt.Errorf("treasury key %q is not 33 bytes", key)
 		}
 
 		err = validPolicyOption(choice)
 		if err != nil {
-			return err
+			return nil, err
 		}
+
+		canonical := hex.EncodeToString(pikey)
+		if existing, ok := normalized[canonical]; ok && existing != choice {
+			return nil, fmt.Errorf("treasury key %s is assigned conflicting policies %q and %q",
+				canonical, existing, choice)
+		}
+		normalized[canonical] = choice
 	}
 
-	return nil
+	return normalized, nil
 }
 
 func validTSpendPolicy(policy map[string]string) error {
@@ -96,10 +157,65 @@ func validPolicyOption(policy string) error {
 	}
 }
 
+// The headers which clients use to provide request signatures.
+const (
+	clientSignatureHeader = "VSP-Client-Signature"
+	votingSignatureHeader = "VSP-Voting-Signature"
+)
+
+// compactSigSize is the size of the compact signatures created by the Decred
+// message signing used for request signatures.
+const compactSigSize = 65
+
+// checkSignatureEncoding returns an error if signature is not the base64
+// encoding of a compact signature. It does not verify the signature.
+func checkSignatureEncoding(signature string) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.New("not valid base64")
+	}
+	if len(sig) != compactSigSize {
+		return fmt.Errorf("decoded signature is %d bytes, expected %d", len(sig), compactSigSize)
+	}
+	return nil
+}
+
+// errUnsupportedSigType is returned when a signature is checked against an
+// address of a type which cannot be used to sign messages.
+var errUnsupportedSigType = errors.New("address type does not support message signing")
+
+// verifyMessage returns an error if signature is not a valid Decred signed
+// message signature of message created by the key of address.
+//
+// Decred message signatures are secp256k1 ECDSA compact signatures from which
+// the signing public key can be recovered, so only addresses which commit to a
+// secp256k1 public key can be verified. Ed25519 and Schnorr addresses, and
+// script hash addresses (eg. multisig ticket commitments), return an error
+// wrapping errUnsupportedSigType. Verification itself is performed by
+// dcrutil.VerifyMessage.
+func verifyMessage(address, signature, message string, network *config.Network) error {
+	addr, err := stdaddr.DecodeAddress(address, network)
+	if err != nil {
+		return fmt.Errorf("failed to decode address: %w", err)
+	}
+
+	switch a := addr.(type) {
+	case *stdaddr.AddressPubKeyHashEcdsaSecp256k1V0:
+	case *stdaddr.AddressPubKeyEcdsaSecp256k1V0:
+		// dcrutil.VerifyMessage only accepts pay-to-pubkey-hash addresses, so
+		// verify against the equivalent address of the compressed public key.
+		address = a.AddressPubKeyHash().String()
+	default:
+		return fmt.Errorf("%w: %T", errUnsupportedSigType, addr)
+	}
+
+	return dcrutil.VerifyMessage(address, signature, message, network)
+}
+
 func validateSignature(hash, commitmentAddress, signature, message string,
 	db *database.VspDatabase, network *config.Network) error {
 
-	firstErr := dcrutil.VerifyMessage(commitmentAddress, signature, message, network)
+	firstErr := verifyMessage(commitmentAddress, signature, message, network)
 	if firstErr != nil {
 		// Don't return an error straight away if sig validation fails -
 		// first check if we have an alternate sign address for this ticket.
@@ -108,10 +224,16 @@ func validateSignature(hash, commitmentAddress, signature, message string,
 			return fmt.Errorf("db.AltSignAddrData failed: %w", err)
 		}
 
-		// If we have no alternate sign address, or if validating with the
-		// alt sign addr fails, return an error to the client.
-		if altSigData == nil ||
-			dcrutil.VerifyMessage(altSigData.AltSignAddr, signature, message, network) != nil {
+		// If we have no alternate sign address, return the reason validation
+		// with the commitment address failed, so clients can be told when
+		// their commitment address cannot sign messages.
+		if altSigData == nil {
+			return firstErr
+		}
+
+		// If validating with the alt sign addr also fails, return an error to
+		// the client.
+		if verifyMessage(altSigData.AltSignAddr, signature, message, network) != nil {
 			return fmt.Errorf("bad signature")
 		}
 
@@ -119,6 +241,92 @@ func validateSignature(hash, commitmentAddress, signature, message string,
 	return nil
 }
 
+// validateVotingKeySignature returns an error if signature is not a valid
+// signature of message created with the private key encoded in votingWIF.
+func validateVotingKeySignature(votingWIF, signature, message string,
+	network *config.Network) error {
+
+	wif, err := dcrutil.DecodeWIF(votingWIF, network.PrivateKeyID)
+	if err != nil {
+		return fmt.Errorf("failed to decode voting key: %w", err)
+	}
+
+	pkHash := stdaddr.Hash160(wif.PubKey())
+	addr, err := stdaddr.NewAddressPubKeyHashEcdsaSecp256k1V0(pkHash, network)
+	if err != nil {
+		return fmt.Errorf("failed to derive voting key address: %w", err)
+	}
+
+	if verifyMessage(addr.String(), signature, message, network) != nil {
+		return errors.New("bad signature")
+	}
+
+	return nil
+}
+
+// withinClockSkew reports whether the unix timestamp ts is no more than maxSkew
+// before or after now. A maxSkew of zero permits any timestamp.
+func withinClockSkew(ts int64, now time.Time, maxSkew time.Duration) bool {
+	if maxSkew == 0 {
+		return true
+	}
+	skew := now.Sub(time.Unix(ts, 0))
+	return skew <= maxSkew && skew >= -maxSkew
+}
+
+// voteChangeWait returns how much longer a client must wait before the vote
+// choices of a ticket last changed at the unix timestamp last can be changed
+// again, or zero if they can be changed now. A minInterval of zero never
+// requires a wait.
+func voteChangeWait(last int64, now time.Time, minInterval time.Duration) time.Duration {
+	if minInterval == 0 {
+		return 0
+	}
+	wait := time.Unix(last, 0).Add(minInterval).Sub(now)
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// validUntil returns the unix timestamp until which a response created at now
+// should be considered fresh, or zero if validity is zero.
+func validUntil(now time.Time, validity time.Duration) int64 {
+	if validity == 0 {
+		return 0
+	}
+	return now.Add(validity).Unix()
+}
+
+// statsStale reports whether cached stats which were last updated at the unix
+// timestamp updated are older than maxAge. Stats are never considered stale if
+// maxAge is zero.
+func statsStale(updated int64, now time.Time, maxAge time.Duration) bool {
+	if maxAge == 0 {
+		return false
+	}
+	return now.Sub(time.Unix(updated, 0)) > maxAge
+}
+
+// validateEndpoints returns an error if any of the endpoints named by the config
+// option is not the name of an API route.
+func validateEndpoints(option string, endpoints []string, routes gin.RoutesInfo) error {
+	known := make(map[string]struct{})
+	for _, route := range routes {
+		if strings.HasPrefix(route.Path, "/api/v3/") {
+			known[path.Base(route.Path)] = struct{}{}
+		}
+	}
+
+	for _, endpoint := range endpoints {
+		if _, ok := known[endpoint]; !ok {
+			return fmt.Errorf("unknown endpoint %q in %s", endpoint, option)
+		}
+	}
+
+	return nil
+}
+
 func decodeTransaction(txHex string) (*wire.MsgTx, error) {
 	msgHex, err := hex.DecodeString(txHex)
 	if err != nil {
@@ -142,6 +350,26 @@ func isValidTicket(tx *wire.MsgTx) error {
 	return nil
 }
 
+// stakeSubmission returns the stake submission output of a ticket, which holds
+// its voting rights, along with the type of its script. Consensus rules require
+// it to be the first output, but the script type is checked so that tickets
+// which do not hold their voting rights with a pubkey hash can be identified.
+// An error is returned rather than reading any other output if the first
+// output is missing or is not a stake submission.
+func stakeSubmission(tx *wire.MsgTx) (*wire.TxOut, stdscript.ScriptType, error) {
+	if len(tx.TxOut) == 0 {
+		return nil, stdscript.STNonStandard, errors.New("ticket has no outputs")
+	}
+
+	txOut := tx.TxOut[0]
+	scriptType := stdscript.DetermineScriptType(txOut.Version, txOut.PkScript)
+	switch scriptType {
+	case stdscript.STStakeSubmissionPubKeyHash, stdscript.STStakeSubmissionScriptHash:
+		return txOut, scriptType, nil
+	}
+	return nil, stdscript.STNonStandard, errors.New("ticket has no stake submission output")
+}
+
 // validateTicketHash ensures the provided ticket hash is a valid ticket hash.
 // A ticket hash should be 64 chars (MaxHashStringSize) and should parse into
 // a chainhash.Hash without error.
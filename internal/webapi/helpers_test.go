@@ -1,13 +1,29 @@
-// Copyright (c) 2020-2023 The Decred developers
+// Copyright (c) 2020-2024 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
 package webapi
 
 import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/dcrd/dcrec"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/decred/dcrd/dcrutil/v4"
+	"github.com/decred/dcrd/txscript/v4"
+	"github.com/decred/dcrd/txscript/v4/stdaddr"
+	"github.com/decred/dcrd/txscript/v4/stdscript"
+	"github.com/decred/dcrd/wire"
 	"github.com/decred/vspd/internal/config"
+	"github.com/gin-gonic/gin"
 )
 
 func TestIsValidVoteChoices(t *testing.T) {
@@ -46,7 +62,7 @@ func TestIsValidVoteChoices(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		err := validConsensusVoteChoices(&network, voteVersion, test.voteChoices)
+		err := validConsensusVoteChoices(&network, []uint32{voteVersion}, test.voteChoices)
 		if (err == nil) != test.valid {
 			t.Fatalf("isValidVoteChoices failed for votechoices '%v': %v",
 				test.voteChoices, err)
@@ -54,6 +70,158 @@ func TestIsValidVoteChoices(t *testing.T) {
 	}
 }
 
+// TestVoteChoicesMultipleVersions ensures choices are accepted for agendas from
+// any of the provided vote versions, but not for agendas of other versions.
+func TestVoteChoicesMultipleVersions(t *testing.T) {
+	network := config.MainNet
+
+	// Mainnet vote version 9 contains the changesubsidysplit agenda, and vote
+	// version 10 contains the blake3pow agenda.
+	tests := map[string]struct {
+		voteVersions []uint32
+		voteChoices  map[string]string
+		valid        bool
+	}{
+		"current version agenda": {
+			voteVersions: []uint32{10},
+			voteChoices:  map[string]string{"blake3pow": "yes"},
+			valid:        true,
+		},
+		"previous version agenda not included": {
+			voteVersions: []uint32{10},
+			voteChoices:  map[string]string{"changesubsidysplit": "yes"},
+			valid:        false,
+		},
+		"previous version agenda included": {
+			voteVersions: []uint32{10, 9},
+			voteChoices:  map[string]string{"blake3pow": "no", "changesubsidysplit": "yes"},
+			valid:        true,
+		},
+		"invalid choice for previous version agenda": {
+			voteVersions: []uint32{10, 9},
+			voteChoices:  map[string]string{"changesubsidysplit": "1234"},
+			valid:        false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			err := validConsensusVoteChoices(&network, test.voteVersions, test.voteChoices)
+			if (err == nil) != test.valid {
+				t.Fatalf("expected valid=%t, got %v", test.valid, err)
+			}
+		})
+	}
+}
+
+// TestVoteChoicesAgendaInMultipleVersions ensures a choice for an agenda which
+// appears in more than one vote version is accepted if it is valid for any of
+// those versions.
+func TestVoteChoicesAgendaInMultipleVersions(t *testing.T) {
+	agenda := func(choices ...string) chaincfg.ConsensusDeployment {
+		vote := chaincfg.Vote{Id: "testagenda"}
+		for _, choice := range choices {
+			vote.Choices = append(vote.Choices, chaincfg.Choice{Id: choice})
+		}
+		return chaincfg.ConsensusDeployment{Vote: vote}
+	}
+
+	network := config.Network{Params: &chaincfg.Params{
+		Deployments: map[uint32][]chaincfg.ConsensusDeployment{
+			1: {agenda("abstain", "no")},
+			2: {agenda("abstain", "no", "yes")},
+		},
+	}}
+
+	tests := map[string]struct {
+		voteVersions []uint32
+		choice       string
+		valid        bool
+	}{
+		"choice in both versions": {
+			voteVersions: []uint32{1, 2},
+			choice:       "no",
+			valid:        true,
+		},
+		"choice only in later version": {
+			voteVersions: []uint32{1, 2},
+			choice:       "yes",
+			valid:        true,
+		},
+		"choice only in excluded version": {
+			voteVersions: []uint32{1},
+			choice:       "yes",
+			valid:        false,
+		},
+		"choice in neither version": {
+			voteVersions: []uint32{1, 2},
+			choice:       "1234",
+			valid:        false,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			voteChoices := map[string]string{"testagenda": test.choice}
+			err := validConsensusVoteChoices(&network, test.voteVersions, voteChoices)
+			if (err == nil) != test.valid {
+				t.Fatalf("expected valid=%t, got %v", test.valid, err)
+			}
+		})
+	}
+}
+
+// TestSettableVoteVersions ensures vote choices are only accepted for the
+// unexpired agendas of vote versions which the voting wallets are running.
+func TestSettableVoteVersions(t *testing.T) {
+	network := config.MainNet
+
+	// Mainnet vote version 9 agendas expire on 2023-09-16, and vote version 10
+	// is the current version.
+	beforeExpiry := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	afterExpiry := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		walletVersions []uint32
+		now            time.Time
+		expect         []uint32
+	}{
+		"wallets running current version": {
+			walletVersions: []uint32{10},
+			now:            beforeExpiry,
+			expect:         []uint32{10},
+		},
+		"wallets running previous version": {
+			walletVersions: []uint32{9},
+			now:            beforeExpiry,
+			expect:         []uint32{9},
+		},
+		"wallets running both versions": {
+			walletVersions: []uint32{10, 9},
+			now:            beforeExpiry,
+			expect:         []uint32{10, 9},
+		},
+		"previous version expired": {
+			walletVersions: []uint32{10, 9},
+			now:            afterExpiry,
+			expect:         []uint32{10},
+		},
+		"no wallet versions known": {
+			now:    beforeExpiry,
+			expect: []uint32{10},
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := settableVoteVersions(&network, test.walletVersions, test.now)
+			if !reflect.DeepEqual(actual, test.expect) {
+				t.Fatalf("expected vote versions %v, got %v", test.expect, actual)
+			}
+		})
+	}
+}
+
 func TestIsValidTSpendPolicy(t *testing.T) {
 
 	// A valid tspend hash is 32 bytes (64 characters).
@@ -136,10 +304,458 @@ func TestIsValidTreasuryPolicy(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		err := validTreasuryPolicy(test.treasuryPolicy)
+		_, err := validTreasuryPolicy(test.treasuryPolicy)
 		if (err == nil) != test.valid {
 			t.Fatalf("validTreasuryPolicy failed for policy '%v': %v",
 				test.treasuryPolicy, err)
 		}
 	}
 }
+
+func TestNormalizeTreasuryPolicy(t *testing.T) {
+	const key = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	const upperKey = "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"
+	const mixedKey = "aAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaAaA"
+	const otherKey = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+
+	tests := map[string]struct {
+		policy    map[string]string
+		expect    map[string]string
+		expectErr bool
+	}{
+		"nil policy": {
+			policy: nil,
+			expect: nil,
+		},
+		"canonical key unchanged": {
+			policy: map[string]string{key: "yes"},
+			expect: map[string]string{key: "yes"},
+		},
+		"uppercase key lowercased": {
+			policy: map[string]string{upperKey: "no", otherKey: "yes"},
+			expect: map[string]string{key: "no", otherKey: "yes"},
+		},
+		"equivalent keys deduplicated": {
+			policy: map[string]string{key: "abstain", upperKey: "abstain", mixedKey: "abstain"},
+			expect: map[string]string{key: "abstain"},
+		},
+		"equivalent keys with conflicting choices": {
+			policy:    map[string]string{key: "yes", upperKey: "no"},
+			expectErr: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual, err := validTreasuryPolicy(test.policy)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error=%t, got %v", test.expectErr, err)
+			}
+			if !reflect.DeepEqual(actual, test.expect) {
+				t.Fatalf("expected policy %v, got %v", test.expect, actual)
+			}
+		})
+	}
+}
+
+// signMessage returns a base64 encoded signature of message created with
+// privKey, in the format expected by dcrutil.VerifyMessage.
+func signMessage(t *testing.T, privKey *secp256k1.PrivateKey, message string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	err := wire.WriteVarString(&buf, 0, "Decred Signed Message:\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = wire.WriteVarString(&buf, 0, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig := ecdsa.SignCompact(privKey, chainhash.HashB(buf.Bytes()), true)
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+func TestValidateVotingKeySignature(t *testing.T) {
+	network := &config.MainNet
+	message := `{"tickethash":"abc"}`
+
+	newWIF := func() (*secp256k1.PrivateKey, string) {
+		privKey, err := secp256k1.GeneratePrivateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		wif, err := dcrutil.NewWIF(privKey.Serialize(), network.PrivateKeyID, dcrec.STEcdsaSecp256k1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return privKey, wif.String()
+	}
+
+	votingKey, votingWIF := newWIF()
+	otherKey, _ := newWIF()
+
+	tests := []struct {
+		name      string
+		votingWIF string
+		signature string
+		expectErr bool
+	}{{
+		name:      "signed by voting key",
+		votingWIF: votingWIF,
+		signature: signMessage(t, votingKey, message),
+		expectErr: false,
+	}, {
+		name:      "signed by other key",
+		votingWIF: votingWIF,
+		signature: signMessage(t, otherKey, message),
+		expectErr: true,
+	}, {
+		name:      "signature of different message",
+		votingWIF: votingWIF,
+		signature: signMessage(t, votingKey, "different"),
+		expectErr: true,
+	}, {
+		name:      "invalid voting key",
+		votingWIF: "not a wif",
+		signature: signMessage(t, votingKey, message),
+		expectErr: true,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateVotingKeySignature(test.votingWIF, test.signature, message, network)
+			if test.expectErr != (err != nil) {
+				t.Fatalf("expected error=%t, got %v", test.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyMessage(t *testing.T) {
+	network := &config.MainNet
+	message := `{"tickethash":"abc"}`
+
+	privKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKey := privKey.PubKey()
+	pkHash := stdaddr.Hash160(pubKey.SerializeCompressed())
+
+	mustAddr := func(addr stdaddr.Address, err error) string {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return addr.String()
+	}
+
+	p2pkh := mustAddr(stdaddr.NewAddressPubKeyHashEcdsaSecp256k1V0(pkHash, network))
+	uncompressedP2PKH := mustAddr(stdaddr.NewAddressPubKeyHashEcdsaSecp256k1V0(
+		stdaddr.Hash160(pubKey.SerializeUncompressed()), network))
+	p2pk := mustAddr(stdaddr.NewAddressPubKeyEcdsaSecp256k1V0(pubKey, network))
+	ed25519 := mustAddr(stdaddr.NewAddressPubKeyHashEd25519V0(pkHash, network))
+	schnorr := mustAddr(stdaddr.NewAddressPubKeyHashSchnorrSecp256k1V0(pkHash, network))
+	p2sh := mustAddr(stdaddr.NewAddressScriptHashV0([]byte{txscript.OP_TRUE}, network))
+
+	var buf bytes.Buffer
+	_ = wire.WriteVarString(&buf, 0, "Decred Signed Message:\n")
+	_ = wire.WriteVarString(&buf, 0, message)
+	uncompressedSig := base64.StdEncoding.EncodeToString(
+		ecdsa.SignCompact(privKey, chainhash.HashB(buf.Bytes()), false))
+
+	sig := signMessage(t, privKey, message)
+
+	tests := map[string]struct {
+		address           string
+		signature         string
+		expectErr         bool
+		expectUnsupported bool
+	}{
+		"p2pkh":                   {address: p2pkh, signature: sig},
+		"p2pkh uncompressed key":  {address: uncompressedP2PKH, signature: uncompressedSig},
+		"p2pkh compression wrong": {address: p2pkh, signature: uncompressedSig, expectErr: true},
+		"p2pkh other key":         {address: p2pkh, signature: signMessage(t, otherKey, message), expectErr: true},
+		"p2pkh other message":     {address: p2pkh, signature: signMessage(t, privKey, "other"), expectErr: true},
+		"p2pk":                    {address: p2pk, signature: sig},
+		"p2pk other key":          {address: p2pk, signature: signMessage(t, otherKey, message), expectErr: true},
+		"ed25519":                 {address: ed25519, signature: sig, expectErr: true, expectUnsupported: true},
+		"schnorr":                 {address: schnorr, signature: sig, expectErr: true, expectUnsupported: true},
+		"script hash":             {address: p2sh, signature: sig, expectErr: true, expectUnsupported: true},
+		"invalid address":         {address: "not an address", signature: sig, expectErr: true},
+		"invalid signature":       {address: p2pkh, signature: "abc", expectErr: true},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			err := verifyMessage(test.address, test.signature, message, network)
+			if test.expectErr != (err != nil) {
+				t.Fatalf("expected error=%t, got %v", test.expectErr, err)
+			}
+			if test.expectUnsupported != errors.Is(err, errUnsupportedSigType) {
+				t.Fatalf("expected unsupported=%t, got %v", test.expectUnsupported, err)
+			}
+		})
+	}
+}
+
+func TestValidUntil(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := map[string]struct {
+		validity time.Duration
+		expect   int64
+	}{
+		"disabled":   {validity: 0, expect: 0},
+		"one minute": {validity: time.Minute, expect: 1700000060},
+		"one hour":   {validity: time.Hour, expect: 1700003600},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := validUntil(now, test.validity)
+			if actual != test.expect {
+				t.Fatalf("expected %d, got %d", test.expect, actual)
+			}
+		})
+	}
+}
+
+func TestStatsStale(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := map[string]struct {
+		updated int64
+		maxAge  time.Duration
+		expect  bool
+	}{
+		"disabled":          {updated: 0, maxAge: 0, expect: false},
+		"fresh":             {updated: 1700000000 - 60, maxAge: 5 * time.Minute, expect: false},
+		"exactly max age":   {updated: 1700000000 - 300, maxAge: 5 * time.Minute, expect: false},
+		"stale":             {updated: 1700000000 - 301, maxAge: 5 * time.Minute, expect: true},
+		"never initialized": {updated: 0, maxAge: 5 * time.Minute, expect: true},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := statsStale(test.updated, now, test.maxAge)
+			if actual != test.expect {
+				t.Fatalf("expected %t, got %t", test.expect, actual)
+			}
+		})
+	}
+}
+
+func TestValidateEndpoints(t *testing.T) {
+	routes := gin.RoutesInfo{
+		{Method: "GET", Path: "/api/v3/vspinfo"},
+		{Method: "POST", Path: "/api/v3/setvotechoices"},
+		{Method: "GET", Path: "/admin"},
+	}
+
+	tests := map[string]struct {
+		endpoints []string
+		expectErr bool
+	}{
+		"no endpoints":       {endpoints: nil, expectErr: false},
+		"known endpoints":    {endpoints: []string{"vspinfo", "setvotechoices"}, expectErr: false},
+		"unknown endpoint":   {endpoints: []string{"setvotechoice"}, expectErr: true},
+		"non-api route":      {endpoints: []string{"admin"}, expectErr: true},
+		"one unknown of two": {endpoints: []string{"vspinfo", "payfee"}, expectErr: true},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			err := validateEndpoints("disableendpoints", test.endpoints, routes)
+			if test.expectErr != (err != nil) {
+				t.Fatalf("expected error=%t, got %v", test.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestWithinClockSkew(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := map[string]struct {
+		ts      int64
+		maxSkew time.Duration
+		expect  bool
+	}{
+		"disabled":         {ts: 0, maxSkew: 0, expect: true},
+		"exact time":       {ts: 1700000000, maxSkew: time.Minute, expect: true},
+		"behind in window": {ts: 1700000000 - 60, maxSkew: time.Minute, expect: true},
+		"ahead in window":  {ts: 1700000000 + 60, maxSkew: time.Minute, expect: true},
+		"too far behind":   {ts: 1700000000 - 61, maxSkew: time.Minute, expect: false},
+		"too far ahead":    {ts: 1700000000 + 61, maxSkew: time.Minute, expect: false},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := withinClockSkew(test.ts, now, test.maxSkew)
+			if actual != test.expect {
+				t.Fatalf("expected %t, got %t", test.expect, actual)
+			}
+		})
+	}
+}
+
+func TestVoteChangeWait(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := map[string]struct {
+		last        int64
+		minInterval time.Duration
+		expect      time.Duration
+	}{
+		"disabled":          {last: 1700000000, minInterval: 0, expect: 0},
+		"no previous":       {last: 0, minInterval: time.Hour, expect: 0},
+		"interval elapsed":  {last: 1700000000 - 3600, minInterval: time.Hour, expect: 0},
+		"interval exceeded": {last: 1700000000 - 3601, minInterval: time.Hour, expect: 0},
+		"too soon":          {last: 1700000000 - 600, minInterval: time.Hour, expect: 50 * time.Minute},
+		"changed just now":  {last: 1700000000, minInterval: time.Hour, expect: time.Hour},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := voteChangeWait(test.last, now, test.minInterval)
+			if actual != test.expect {
+				t.Fatalf("expected %v, got %v", test.expect, actual)
+			}
+		})
+	}
+}
+
+func TestCheckSignatureEncoding(t *testing.T) {
+	tests := map[string]struct {
+		signature string
+		expectErr bool
+	}{
+		"valid": {
+			signature: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, compactSigSize)),
+		},
+		"not base64": {
+			signature: "not a signature!",
+			expectErr: true,
+		},
+		"too short": {
+			signature: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, compactSigSize-1)),
+			expectErr: true,
+		},
+		"too long": {
+			signature: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, compactSigSize+1)),
+			expectErr: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			err := checkSignatureEncoding(test.signature)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error=%t, got %v", test.expectErr, err)
+			}
+		})
+	}
+}
+
+// TestStakeSubmission ensures the stake submission output of a ticket is found
+// and its script type identified for each kind of voting rights script.
+// TestIsValidTicketMalformed ensures malformed ticket transactions are rejected
+// rather than causing a panic.
+func TestIsValidTicketMalformed(t *testing.T) {
+	noOutputs := wire.NewMsgTx()
+	noOutputs.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, 0, wire.TxTreeRegular), 0, nil))
+
+	oneOutput := noOutputs.Copy()
+	oneOutput.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN}))
+
+	for testName, tx := range map[string]*wire.MsgTx{
+		"empty":      wire.NewMsgTx(),
+		"no outputs": noOutputs,
+		"one output": oneOutput,
+	} {
+		t.Run(testName, func(t *testing.T) {
+			if err := isValidTicket(tx); err == nil {
+				t.Fatal("expected error for malformed ticket")
+			}
+		})
+	}
+}
+
+func TestStakeSubmission(t *testing.T) {
+	params := chaincfg.MainNetParams()
+
+	pkhAddr, err := stdaddr.NewAddressPubKeyHashEcdsaSecp256k1V0(make([]byte, 20), params)
+	if err != nil {
+		t.Fatalf("error creating pubkey hash address: %v", err)
+	}
+	shAddr, err := stdaddr.NewAddressScriptHashV0FromHash(make([]byte, 20), params)
+	if err != nil {
+		t.Fatalf("error creating script hash address: %v", err)
+	}
+
+	ticket := func(addr stdaddr.StakeAddress) *wire.MsgTx {
+		tx := wire.NewMsgTx()
+		if addr != nil {
+			ver, script := addr.VotingRightsScript()
+			tx.AddTxOut(&wire.TxOut{Value: 1e8, Version: ver, PkScript: script})
+		}
+		tx.AddTxOut(wire.NewTxOut(0, []byte{txscript.OP_RETURN}))
+		return tx
+	}
+
+	tests := map[string]struct {
+		ticket     *wire.MsgTx
+		expectType stdscript.ScriptType
+		expectErr  bool
+	}{
+		"pubkey hash": {
+			ticket:     ticket(pkhAddr),
+			expectType: stdscript.STStakeSubmissionPubKeyHash,
+		},
+		"script hash": {
+			ticket:     ticket(shAddr),
+			expectType: stdscript.STStakeSubmissionScriptHash,
+		},
+		"no stake submission": {
+			ticket:    ticket(nil),
+			expectErr: true,
+		},
+		"no outputs": {
+			ticket:    wire.NewMsgTx(),
+			expectErr: true,
+		},
+		"stake submission not first output": {
+			ticket: func() *wire.MsgTx {
+				tx := ticket(pkhAddr)
+				tx.TxOut[0], tx.TxOut[1] = tx.TxOut[1], tx.TxOut[0]
+				return tx
+			}(),
+			expectErr: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			txOut, scriptType, err := stakeSubmission(test.ticket)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error=%t, got %v", test.expectErr, err)
+			}
+			if test.expectErr {
+				return
+			}
+			if scriptType != test.expectType {
+				t.Fatalf("expected script type %v, got %v", test.expectType, scriptType)
+			}
+			if txOut != test.ticket.TxOut[0] {
+				t.Fatal("expected first output to be returned")
+			}
+		})
+	}
+}
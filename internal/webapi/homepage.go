@@ -15,6 +15,6 @@ func (w *WebAPI) homepage(c *gin.Context) {
 
 	c.HTML(http.StatusOK, "homepage.html", gin.H{
 		"WebApiCache": cacheData,
-		"WebApiCfg":   w.cfg,
+		"WebApiCfg":   w.config(),
 	})
 }
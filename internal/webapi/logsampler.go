@@ -0,0 +1,55 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"fmt"
+	"sync"
+)
+
+// logSampler limits the volume of low severity request logs by only allowing
+// one in every rate messages with the same format string to be logged. A nil
+// logSampler allows every message.
+type logSampler struct {
+	rate   uint64
+	mtx    sync.Mutex
+	counts map[string]uint64
+}
+
+// newLogSampler returns a logSampler which allows one in every rate messages.
+// Nil is returned if rate is less than 2 because sampling would have no effect.
+func newLogSampler(rate int) *logSampler {
+	if rate < 2 {
+		return nil
+	}
+	return &logSampler{
+		rate:   uint64(rate),
+		counts: make(map[string]uint64),
+	}
+}
+
+// sample reports whether a message with the provided format string should be
+// logged. The first message with each format string is always logged. If the
+// message should be logged, the returned suffix notes how many messages with
+// the same format string were not logged since the previous one.
+func (s *logSampler) sample(format string) (bool, string) {
+	if s == nil {
+		return true, ""
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	count := s.counts[format]
+	s.counts[format] = count + 1
+
+	if count%s.rate != 0 {
+		return false, ""
+	}
+	if count == 0 {
+		return true, ""
+	}
+	return true, fmt.Sprintf(" (%d similar messages not logged)", s.rate-1)
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import "testing"
+
+// TestLogSampler ensures one in every rate messages with the same format string
+// is logged, counting each format string separately.
+func TestLogSampler(t *testing.T) {
+	if newLogSampler(1) != nil {
+		t.Fatal("expected nil sampler for rate 1")
+	}
+
+	// A nil sampler allows every message.
+	var nilSampler *logSampler
+	for i := 0; i < 3; i++ {
+		if ok, _ := nilSampler.sample("a"); !ok {
+			t.Fatal("expected nil sampler to allow message")
+		}
+	}
+
+	s := newLogSampler(3)
+
+	expected := []bool{true, false, false, true, false, false, true}
+	for i, expect := range expected {
+		ok, suffix := s.sample("a")
+		if ok != expect {
+			t.Fatalf("message %d: expected logged=%t, got %t", i, expect, ok)
+		}
+		if i == 0 && suffix != "" {
+			t.Fatalf("expected no suffix for first message, got %q", suffix)
+		}
+		if i > 0 && ok && suffix != " (2 similar messages not logged)" {
+			t.Fatalf("message %d: unexpected suffix %q", i, suffix)
+		}
+	}
+
+	// A different format string is counted separately.
+	if ok, _ := s.sample("b"); !ok {
+		t.Fatal("expected first message with new format to be logged")
+	}
+}
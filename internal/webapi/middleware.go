@@ -6,9 +6,12 @@ package webapi
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"path"
 	"strings"
 	"sync"
 	"time"
@@ -60,29 +63,40 @@ func rateLimit(limit rate.Limit, limitExceeded gin.HandlerFunc) gin.HandlerFunc
 	}
 }
 
+// countRequest middleware counts every request received by the webserver, and
+// the status code of the response sent for each.
+func (w *WebAPI) countRequest(c *gin.Context) {
+	c.Next()
+
+	w.metrics.IncCounter("requests.total")
+	w.metrics.IncCounter(fmt.Sprintf("requests.status.%d", c.Writer.Status()))
+}
+
 // withSession middleware adds a gorilla session to the request context for
 // downstream handlers to make use of. Sessions are used by admin pages to
 // maintain authentication status.
 func (w *WebAPI) withSession(store *sessions.CookieStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		log := w.requestLog(c)
+
 		session, err := store.Get(c.Request, "vspd-session")
 		if err != nil {
 			// "value is not valid" occurs if the cookie secret changes. This is
 			// common during development (eg. when using the test harness) but
 			// it should not occur in production.
 			if strings.Contains(err.Error(), invalidCookieErr) {
-				w.log.Warn("Cookie secret has changed. Generating new session.")
+				log.Warn("Cookie secret has changed. Generating new session.")
 
 				// Persist the newly generated session.
 				err = store.Save(c.Request, c.Writer, session)
 				if err != nil {
-					w.log.Errorf("Error saving session: %v", err)
+					log.Errorf("Error saving session: %v", err)
 					c.String(http.StatusInternalServerError, "Error saving session")
 					c.Abort()
 					return
 				}
 			} else {
-				w.log.Errorf("Session error: %v", err)
+				log.Errorf("Session error: %v", err)
 				c.String(http.StatusInternalServerError, "Error getting session")
 				c.Abort()
 				return
@@ -97,11 +111,13 @@ func (w *WebAPI) withSession(store *sessions.CookieStore) gin.HandlerFunc {
 // has been initialized with data, otherwise it will return a 500 Internal
 // Server Error.
 func (w *WebAPI) requireWebCache(c *gin.Context) {
+	log := w.requestLog(c)
+
 	if !w.cache.initialized() {
 		// Try to initialize it now.
 		err := w.cache.update()
 		if err != nil {
-			w.log.Errorf("Failed to initialize cache: %v", err)
+			log.Errorf("Failed to initialize cache: %v", err)
 			c.String(http.StatusInternalServerError, "Cache is not initialized")
 			c.Abort()
 			return
@@ -121,18 +137,46 @@ func (w *WebAPI) requireAdmin(c *gin.Context) {
 	if admin == nil {
 		c.HTML(http.StatusUnauthorized, "login.html", gin.H{
 			"WebApiCache": cacheData,
-			"WebApiCfg":   w.cfg,
+			"WebApiCfg":   w.config(),
 		})
 		c.Abort()
 		return
 	}
 }
 
+// The usernames accepted by the Basic HTTP Auth on the read-only admin
+// endpoints.
+const (
+	adminUser    = "admin"
+	readOnlyUser = "readonly"
+)
+
+// readOnlyAccounts returns the Basic HTTP Auth accounts which are permitted to
+// access the read-only admin endpoints, /admin/status and /admin/stats. The
+// admin account is always permitted, and a read-only account is added if a
+// read-only password is configured. The read-only password cannot be used to
+// log in to the admin page, so it does not grant access to ticket search,
+// ticket notes, refunds or database backups.
+func (w *WebAPI) readOnlyAccounts() gin.Accounts {
+	accounts := gin.Accounts{
+		adminUser: w.cfg.AdminPass,
+	}
+	if w.cfg.ReadOnlyPass != "" {
+		accounts[readOnlyUser] = w.cfg.ReadOnlyPass
+	}
+	return accounts
+}
+
 // withDcrdClient middleware adds a dcrd client to the request context for
 // downstream handlers to make use of.
 func (w *WebAPI) withDcrdClient(dcrd rpc.DcrdConnect) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		client, hostname, err := dcrd.Client()
+		// Bound all calls made by the client to the request deadline, if one
+		// has been set.
+		if _, ok := c.Request.Context().Deadline(); ok && client != nil {
+			client = client.WithContext(c.Request.Context())
+		}
 		// Don't handle the error here, add it to the context and let downstream
 		// handlers decide what to do with it.
 		c.Set(dcrdKey, client)
@@ -146,11 +190,20 @@ func (w *WebAPI) withDcrdClient(dcrd rpc.DcrdConnect) gin.HandlerFunc {
 // must handle the case where no wallet clients are connected.
 func (w *WebAPI) withWalletClients(wallets rpc.WalletConnect) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		log := w.requestLog(c)
+
 		clients, failedConnections := wallets.Clients()
+		// Bound all calls made by the clients to the request deadline, if one
+		// has been set.
+		if _, ok := c.Request.Context().Deadline(); ok {
+			for i, client := range clients {
+				clients[i] = client.WithContext(c.Request.Context())
+			}
+		}
 		if len(clients) == 0 {
-			w.log.Error("Could not connect to any wallets")
+			log.Error("Could not connect to any wallets")
 		} else if len(failedConnections) > 0 {
-			w.log.Errorf("Failed to connect to %d wallet(s), proceeding with only %d",
+			log.Errorf("Failed to connect to %d wallet(s), proceeding with only %d",
 				len(failedConnections), len(clients))
 		}
 		c.Set(walletsKey, clients)
@@ -158,6 +211,29 @@ func (w *WebAPI) withWalletClients(wallets rpc.WalletConnect) gin.HandlerFunc {
 	}
 }
 
+// withRequestTimeout middleware sets a deadline on the context of API requests,
+// after which any RPC calls still being made on behalf of the request are
+// abandoned. Requests which fail because the deadline was exceeded receive an
+// ErrRequestTimeout error. The timeout for each endpoint can be configured
+// individually by the VSP operator.
+func (w *WebAPI) withRequestTimeout(c *gin.Context) {
+	endpoint := path.Base(c.FullPath())
+
+	timeout := w.cfg.RequestTimeout
+	if t, ok := w.cfg.EndpointTimeouts[endpoint]; ok {
+		timeout = t
+	}
+	if timeout == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
+}
+
 // drainAndReplaceBody will read and return the body of the provided request. It
 // replaces the request reader with an identical one so it can be used again.
 func drainAndReplaceBody(req *http.Request) ([]byte, error) {
@@ -170,8 +246,23 @@ func drainAndReplaceBody(req *http.Request) ([]byte, error) {
 	return reqBytes, nil
 }
 
+// endpointEnabled middleware returns an error if the requested API endpoint has
+// been disabled by the VSP operator.
+func (w *WebAPI) endpointEnabled(c *gin.Context) {
+	const funcName = "endpointEnabled"
+	log := w.requestLog(c)
+
+	endpoint := path.Base(c.FullPath())
+	if _, disabled := w.disabledEndpoints[endpoint]; disabled {
+		log.Debugf("%s: Request to disabled endpoint %s (clientIP=%s)",
+			funcName, endpoint, c.ClientIP())
+		w.sendErrorWithMsg(fmt.Sprintf("%s endpoint is temporarily disabled by vsp operator", endpoint),
+			types.ErrEndpointDisabled, c)
+	}
+}
+
 func (w *WebAPI) vspMustBeOpen(c *gin.Context) {
-	if w.cfg.VspClosed {
+	if w.config().VspClosed {
 		w.sendError(types.ErrVspClosed, c)
 		return
 	}
@@ -184,11 +275,12 @@ func (w *WebAPI) vspMustBeOpen(c *gin.Context) {
 // is not aware of them.
 func (w *WebAPI) broadcastTicket(c *gin.Context) {
 	const funcName = "broadcastTicket"
+	log := w.requestLog(c)
 
 	// Read request bytes.
 	reqBytes, err := drainAndReplaceBody(c.Request)
 	if err != nil {
-		w.log.Warnf("%s: Error reading request (clientIP=%s): %v", funcName, c.ClientIP(), err)
+		log.Warnf("%s: Error reading request (clientIP=%s): %v", funcName, c.ClientIP(), err)
 		w.sendErrorWithMsg(err.Error(), types.ErrBadRequest, c)
 		return
 	}
@@ -200,7 +292,7 @@ func (w *WebAPI) broadcastTicket(c *gin.Context) {
 		ParentHex  string `json:"parenthex" binding:"required"`
 	}
 	if err := binding.JSON.BindBody(reqBytes, &request); err != nil {
-		w.log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
+		log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
 		w.sendErrorWithMsg(err.Error(), types.ErrBadRequest, c)
 		return
 	}
@@ -208,7 +300,7 @@ func (w *WebAPI) broadcastTicket(c *gin.Context) {
 	// Ensure the provided ticket hex is a valid ticket.
 	msgTx, err := decodeTransaction(request.TicketHex)
 	if err != nil {
-		w.log.Errorf("%s: Failed to decode ticket hex (ticketHash=%s): %v",
+		log.Errorf("%s: Failed to decode ticket hex (ticketHash=%s): %v",
 			funcName, request.TicketHash, err)
 		w.sendErrorWithMsg("cannot decode ticket hex", types.ErrBadRequest, c)
 		return
@@ -216,7 +308,7 @@ func (w *WebAPI) broadcastTicket(c *gin.Context) {
 
 	err = isValidTicket(msgTx)
 	if err != nil {
-		w.log.Warnf("%s: Invalid ticket (clientIP=%s, ticketHash=%s): %v",
+		log.Warnf("%s: Invalid ticket (clientIP=%s, ticketHash=%s): %v",
 			funcName, c.ClientIP(), request.TicketHash, err)
 		w.sendError(types.ErrInvalidTicket, c)
 		return
@@ -224,7 +316,7 @@ func (w *WebAPI) broadcastTicket(c *gin.Context) {
 
 	// Ensure hex matches hash.
 	if msgTx.TxHash().String() != request.TicketHash {
-		w.log.Warnf("%s: Ticket hex/hash mismatch (clientIP=%s, ticketHash=%s)",
+		log.Warnf("%s: Ticket hex/hash mismatch (clientIP=%s, ticketHash=%s)",
 			funcName, c.ClientIP(), request.TicketHash)
 		w.sendErrorWithMsg("ticket hex does not match hash", types.ErrBadRequest, c)
 		return
@@ -233,7 +325,7 @@ func (w *WebAPI) broadcastTicket(c *gin.Context) {
 	// Ensure the provided parent hex is a valid tx.
 	parentTx, err := decodeTransaction(request.ParentHex)
 	if err != nil {
-		w.log.Errorf("%s: Failed to decode parent hex (ticketHash=%s): %v", funcName, request.TicketHash, err)
+		log.Errorf("%s: Failed to decode parent hex (ticketHash=%s): %v", funcName, request.TicketHash, err)
 		w.sendErrorWithMsg("cannot decode parent hex", types.ErrBadRequest, c)
 		return
 	}
@@ -243,7 +335,7 @@ func (w *WebAPI) broadcastTicket(c *gin.Context) {
 	dcrdClient := c.MustGet(dcrdKey).(*rpc.DcrdRPC)
 	dcrdErr := c.MustGet(dcrdErrorKey)
 	if dcrdErr != nil {
-		w.log.Errorf("%s: %v", funcName, dcrdErr.(error))
+		log.Errorf("%s: %v", funcName, dcrdErr.(error))
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
@@ -267,32 +359,32 @@ func (w *WebAPI) broadcastTicket(c *gin.Context) {
 			}
 
 			if !found {
-				w.log.Errorf("%s: Invalid ticket parent (ticketHash=%s)", funcName, request.TicketHash)
+				log.Errorf("%s: Invalid ticket parent (ticketHash=%s)", funcName, request.TicketHash)
 				w.sendErrorWithMsg("invalid ticket parent", types.ErrBadRequest, c)
 				return
 			}
 
-			w.log.Debugf("%s: Broadcasting parent tx %s (ticketHash=%s)", funcName, parentHash, request.TicketHash)
-			err = dcrdClient.SendRawTransaction(request.ParentHex)
+			log.Debugf("%s: Broadcasting parent tx %s (ticketHash=%s)", funcName, parentHash, request.TicketHash)
+			_, err = dcrdClient.SendRawTransaction(request.ParentHex)
 			if err != nil {
 				// Unknown output errors have special handling because they
 				// could be resolved by waiting for network propagation. Any
 				// other errors are returned to client immediately.
 				if !strings.Contains(err.Error(), rpc.ErrUnknownOutputs) {
-					w.log.Errorf("%s: dcrd.SendRawTransaction for parent tx failed (ticketHash=%s): %v",
+					log.Errorf("%s: dcrd.SendRawTransaction for parent tx failed (ticketHash=%s): %v",
 						funcName, request.TicketHash, err)
 					w.sendError(types.ErrCannotBroadcastTicket, c)
 					return
 				}
 
-				w.log.Debugf("%s: Parent tx references an unknown output, waiting for it in mempool (ticketHash=%s)",
+				log.Debugf("%s: Parent tx references an unknown output, waiting for it in mempool (ticketHash=%s)",
 					funcName, request.TicketHash)
 
 				txBroadcast := func() bool {
 					// Wait for 1 second and try again, max 7 attempts.
 					for i := 0; i < 7; i++ {
 						time.Sleep(1 * time.Second)
-						err := dcrdClient.SendRawTransaction(request.ParentHex)
+						_, err := dcrdClient.SendRawTransaction(request.ParentHex)
 						if err == nil {
 							return true
 						}
@@ -301,7 +393,7 @@ func (w *WebAPI) broadcastTicket(c *gin.Context) {
 				}()
 
 				if !txBroadcast {
-					w.log.Errorf("%s: Failed to broadcast parent tx, waiting didn't help (ticketHash=%s)",
+					log.Errorf("%s: Failed to broadcast parent tx, waiting didn't help (ticketHash=%s)",
 						funcName, request.TicketHash)
 					w.sendError(types.ErrCannotBroadcastTicket, c)
 					return
@@ -309,7 +401,7 @@ func (w *WebAPI) broadcastTicket(c *gin.Context) {
 			}
 
 		} else {
-			w.log.Errorf("%s: dcrd.GetRawTransaction for ticket parent failed (ticketHash=%s): %v",
+			log.Errorf("%s: dcrd.GetRawTransaction for ticket parent failed (ticketHash=%s): %v",
 				funcName, request.TicketHash, err)
 			w.sendError(types.ErrInternalError, c)
 			return
@@ -327,36 +419,46 @@ func (w *WebAPI) broadcastTicket(c *gin.Context) {
 	// hex, so we can broadcast it here.
 	var e *wsrpc.Error
 	if errors.As(err, &e) && e.Code == rpc.ErrNoTxInfo {
-		w.log.Debugf("%s: Broadcasting ticket (ticketHash=%s)", funcName, request.TicketHash)
-		err = dcrdClient.SendRawTransaction(request.TicketHex)
+		log.Debugf("%s: Broadcasting ticket (ticketHash=%s)", funcName, request.TicketHash)
+		_, err = dcrdClient.SendRawTransaction(request.TicketHex)
 		if err != nil {
-			w.log.Errorf("%s: dcrd.SendRawTransaction for ticket failed (ticketHash=%s): %v",
+			log.Errorf("%s: dcrd.SendRawTransaction for ticket failed (ticketHash=%s): %v",
 				funcName, request.TicketHash, err)
 			w.sendError(types.ErrCannotBroadcastTicket, c)
 			return
 		}
 	} else {
-		w.log.Errorf("%s: dcrd.GetRawTransaction for ticket failed (ticketHash=%s): %v",
+		log.Errorf("%s: dcrd.GetRawTransaction for ticket failed (ticketHash=%s): %v",
 			funcName, request.TicketHash, err)
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
 }
 
+// allowVotingKeyAuth middleware permits the vspAuth middleware which follows it
+// to accept requests signed by the voting key of a ticket, as an alternative to
+// the commitment address.
+func (w *WebAPI) allowVotingKeyAuth(c *gin.Context) {
+	c.Set(votingKeyAuthKey, true)
+}
+
 // vspAuth middleware reads the request body and extracts the ticket hash. The
 // commitment address for the ticket is retrieved from the database if it is
 // known, or it is retrieved from the chain if not.
 // The middleware errors out if the VSP-Client-Signature header of the request
-// does not contain the request body signed with the commitment address.
+// does not contain the request body signed with the commitment address. If
+// allowVotingKeyAuth has been applied, a signature created with the voting key
+// of a known ticket may instead be provided in the VSP-Voting-Signature header.
 // Ticket information is added to the request context for downstream handlers to
 // use.
 func (w *WebAPI) vspAuth(c *gin.Context) {
 	const funcName = "vspAuth"
+	log := w.requestLog(c)
 
 	// Read request bytes.
 	reqBytes, err := drainAndReplaceBody(c.Request)
 	if err != nil {
-		w.log.Warnf("%s: Error reading request (clientIP=%s): %v", funcName, c.ClientIP(), err)
+		log.Warnf("%s: Error reading request (clientIP=%s): %v", funcName, c.ClientIP(), err)
 		w.sendErrorWithMsg(err.Error(), types.ErrBadRequest, c)
 		return
 	}
@@ -368,9 +470,10 @@ func (w *WebAPI) vspAuth(c *gin.Context) {
 	// Parse request and ensure there is a ticket hash included.
 	var request struct {
 		TicketHash string `json:"tickethash" binding:"required"`
+		Network    string `json:"network"`
 	}
 	if err := binding.JSON.BindBody(reqBytes, &request); err != nil {
-		w.log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
+		log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
 		w.sendErrorWithMsg(err.Error(), types.ErrBadRequest, c)
 		return
 	}
@@ -379,15 +482,50 @@ func (w *WebAPI) vspAuth(c *gin.Context) {
 	// Before hitting the db or any RPC, ensure this is a valid ticket hash.
 	err = validateTicketHash(hash)
 	if err != nil {
-		w.log.Errorf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
+		log.Errorf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
 		w.sendErrorWithMsg("invalid ticket hash", types.ErrBadRequest, c)
 		return
 	}
 
+	// Include the ticket hash in all subsequent log lines for this request.
+	c.Set(ticketHashKey, hash)
+
+	// Clients may optionally declare which network they are using, in which
+	// case it must match the network of the VSP.
+	if request.Network != "" && !w.cfg.Network.IsName(request.Network) {
+		log.Warnf("%s: Wrong network (clientIP=%s): client on %s, vsp on %s",
+			funcName, c.ClientIP(), request.Network, w.cfg.Network.Name)
+		w.sendErrorWithMsg(fmt.Sprintf("client is on %s but vsp is on %s",
+			request.Network, w.cfg.Network.Name), types.ErrWrongNetwork, c)
+		return
+	}
+
+	// Ensure a well-formed signature is provided before doing any further
+	// work. Commitment address signatures take precedence over voting key
+	// signatures.
+	signature := c.GetHeader(clientSignatureHeader)
+	votingSignature := c.GetHeader(votingSignatureHeader)
+	useVotingKey := signature == "" && votingSignature != "" && c.GetBool(votingKeyAuthKey)
+	header, headerValue := clientSignatureHeader, signature
+	if useVotingKey {
+		header, headerValue = votingSignatureHeader, votingSignature
+	}
+	if headerValue == "" {
+		log.Warnf("%s: No %s header (clientIP=%s)", funcName, header, c.ClientIP())
+		w.sendErrorWithMsg(fmt.Sprintf("no %s header", header), types.ErrMissingSignature, c)
+		return
+	}
+	if err := checkSignatureEncoding(headerValue); err != nil {
+		log.Warnf("%s: Malformed %s header (clientIP=%s): %v", funcName, header, c.ClientIP(), err)
+		w.sendErrorWithMsg(fmt.Sprintf("malformed %s header: %v", header, err),
+			types.ErrMalformedSignature, c)
+		return
+	}
+
 	// Check if this ticket already appears in the database.
 	ticket, ticketFound, err := w.db.GetTicketByHash(hash)
 	if err != nil {
-		w.log.Errorf("%s: db.GetTicketByHash error (ticketHash=%s): %v", funcName, hash, err)
+		log.Errorf("%s: db.GetTicketByHash error (ticketHash=%s): %v", funcName, hash, err)
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
@@ -403,7 +541,7 @@ func (w *WebAPI) vspAuth(c *gin.Context) {
 		dcrdClient := c.MustGet(dcrdKey).(*rpc.DcrdRPC)
 		dcrdErr := c.MustGet(dcrdErrorKey)
 		if dcrdErr != nil {
-			w.log.Errorf("%s: Could not get dcrd client (clientIP=%s, ticketHash=%s): %v",
+			log.Errorf("%s: Could not get dcrd client (clientIP=%s, ticketHash=%s): %v",
 				funcName, c.ClientIP(), hash, dcrdErr.(error))
 			w.sendError(types.ErrInternalError, c)
 			return
@@ -411,7 +549,7 @@ func (w *WebAPI) vspAuth(c *gin.Context) {
 
 		rawTx, err := dcrdClient.GetRawTransaction(hash)
 		if err != nil {
-			w.log.Errorf("%s: dcrd.GetRawTransaction for ticket failed (clientIP=%s, ticketHash=%s): %v",
+			log.Errorf("%s: dcrd.GetRawTransaction for ticket failed (clientIP=%s, ticketHash=%s): %v",
 				funcName, c.ClientIP(), hash, err)
 			w.sendError(types.ErrInternalError, c)
 			return
@@ -419,7 +557,7 @@ func (w *WebAPI) vspAuth(c *gin.Context) {
 
 		msgTx, err := decodeTransaction(rawTx.Hex)
 		if err != nil {
-			w.log.Errorf("%s: Failed to decode ticket hex (clientIP=%s, ticketHash=%s): %v",
+			log.Errorf("%s: Failed to decode ticket hex (clientIP=%s, ticketHash=%s): %v",
 				funcName, c.ClientIP(), hash, err)
 			w.sendError(types.ErrInternalError, c)
 			return
@@ -427,7 +565,7 @@ func (w *WebAPI) vspAuth(c *gin.Context) {
 
 		err = isValidTicket(msgTx)
 		if err != nil {
-			w.log.Errorf("%s: Invalid ticket (clientIP=%s, ticketHash=%s)",
+			log.Errorf("%s: Invalid ticket (clientIP=%s, ticketHash=%s)",
 				funcName, c.ClientIP(), hash)
 			w.sendError(types.ErrInvalidTicket, c)
 			return
@@ -435,7 +573,7 @@ func (w *WebAPI) vspAuth(c *gin.Context) {
 
 		addr, err := stake.AddrFromSStxPkScrCommitment(msgTx.TxOut[1].PkScript, w.cfg.Network)
 		if err != nil {
-			w.log.Errorf("%s: AddrFromSStxPkScrCommitment error (clientIP=%s, ticketHash=%s): %v",
+			log.Errorf("%s: AddrFromSStxPkScrCommitment error (clientIP=%s, ticketHash=%s): %v",
 				funcName, c.ClientIP(), hash, err)
 			w.sendError(types.ErrInternalError, c)
 			return
@@ -444,19 +582,28 @@ func (w *WebAPI) vspAuth(c *gin.Context) {
 		commitmentAddress = addr.String()
 	}
 
-	// Ensure a signature is provided.
-	signature := c.GetHeader("VSP-Client-Signature")
-	if signature == "" {
-		w.log.Warnf("%s: No VSP-Client-Signature header (clientIP=%s)", funcName, c.ClientIP())
-		w.sendErrorWithMsg("no VSP-Client-Signature header", types.ErrBadRequest, c)
-		return
-	}
-
 	// Validate request signature to ensure ticket ownership.
-	err = validateSignature(hash, commitmentAddress, signature, string(reqBytes), w.db, w.cfg.Network)
+	if useVotingKey {
+		// The voting key is only known for tickets which are already in the
+		// database.
+		if !ticketFound || ticket.VotingWIF == "" {
+			log.Warnf("%s: Voting key signature for ticket without voting key (clientIP=%s, ticketHash=%s)",
+				funcName, c.ClientIP(), hash)
+			w.sendErrorWithMsg("voting key not known for ticket", types.ErrBadSignature, c)
+			return
+		}
+		signature = votingSignature
+		err = validateVotingKeySignature(ticket.VotingWIF, signature, string(reqBytes), w.cfg.Network)
+	} else {
+		err = validateSignature(hash, commitmentAddress, signature, string(reqBytes), w.db, w.cfg.Network)
+	}
 	if err != nil {
-		w.log.Errorf("%s: Couldn't validate signature (clientIP=%s, ticketHash=%s): %v",
+		log.Errorf("%s: Couldn't validate signature (clientIP=%s, ticketHash=%s): %v",
 			funcName, c.ClientIP(), hash, err)
+		if errors.Is(err, errUnsupportedSigType) {
+			w.sendError(types.ErrUnsupportedSignatureType, c)
+			return
+		}
 		w.sendError(types.ErrBadSignature, c)
 		return
 	}
@@ -466,4 +613,5 @@ func (w *WebAPI) vspAuth(c *gin.Context) {
 	c.Set(ticketKey, ticket)
 	c.Set(knownTicketKey, ticketFound)
 	c.Set(commitmentAddressKey, commitmentAddress)
+	c.Set(requestSignatureKey, signature)
 }
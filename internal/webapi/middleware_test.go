@@ -1,15 +1,22 @@
-// Copyright (c) 2023 The Decred developers
+// Copyright (c) 2023-2024 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
 package webapi
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/rpc"
+	"github.com/decred/vspd/types/v3"
+	"github.com/gin-gonic/gin"
 	"github.com/gorilla/sessions"
 )
 
@@ -65,3 +72,224 @@ func TestCookieSecretError(t *testing.T) {
 			invalidCookieErr, err.Error())
 	}
 }
+
+// TestEndpointEnabled ensures requests to disabled endpoints return an error,
+// while other endpoints continue to work.
+func TestEndpointEnabled(t *testing.T) {
+	w := &WebAPI{
+		log:               api.log,
+		signPrivKey:       api.signPrivKey,
+		disabledEndpoints: map[string]struct{}{"setvotechoices": {}},
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/api/v3", w.endpointEnabled)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	group.POST("/setvotechoices", ok)
+	group.POST("/ticketstatus", ok)
+
+	tests := map[string]int{
+		"/api/v3/setvotechoices": types.ErrEndpointDisabled.HTTPStatus(),
+		"/api/v3/ticketstatus":   http.StatusOK,
+	}
+
+	for path, expectStatus := range tests {
+		t.Run(path, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != expectStatus {
+				t.Fatalf("expected status %d, got %d", expectStatus, rec.Code)
+			}
+		})
+	}
+}
+
+// TestReadOnlyAccounts ensures the read-only account can only access the
+// read-only admin endpoints when a read-only password is configured.
+func TestReadOnlyAccounts(t *testing.T) {
+	tests := map[string]struct {
+		readOnlyPass string
+		user         string
+		pass         string
+		expectStatus int
+	}{
+		"admin": {
+			user:         adminUser,
+			pass:         "adminpass",
+			expectStatus: http.StatusOK,
+		},
+		"admin wrong password": {
+			user:         adminUser,
+			pass:         "wrong",
+			expectStatus: http.StatusUnauthorized,
+		},
+		"read-only": {
+			readOnlyPass: "readonlypass",
+			user:         readOnlyUser,
+			pass:         "readonlypass",
+			expectStatus: http.StatusOK,
+		},
+		"read-only with admin password": {
+			readOnlyPass: "readonlypass",
+			user:         readOnlyUser,
+			pass:         "adminpass",
+			expectStatus: http.StatusUnauthorized,
+		},
+		"read-only not configured": {
+			user:         readOnlyUser,
+			pass:         "",
+			expectStatus: http.StatusUnauthorized,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			w := &WebAPI{
+				cfg: Config{AdminPass: "adminpass", ReadOnlyPass: test.readOnlyPass},
+			}
+
+			router := gin.New()
+			router.GET("/admin/status", gin.BasicAuth(w.readOnlyAccounts()),
+				func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req, err := http.NewRequest(http.MethodGet, "/admin/status", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.SetBasicAuth(test.user, test.pass)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != test.expectStatus {
+				t.Fatalf("expected status %d, got %d", test.expectStatus, rec.Code)
+			}
+		})
+	}
+}
+
+// TestRequestTimeout ensures errors caused by exceeding the request deadline are reported as timeouts, and that endpoint specific timeouts
+// override the default.
+func TestRequestTimeout(t *testing.T) {
+	w := &WebAPI{
+		log:         api.log,
+		signPrivKey: api.signPrivKey,
+		cfg: Config{
+			RequestTimeout:   time.Millisecond,
+			EndpointTimeouts: map[string]time.Duration{"ticketstatus": 0},
+		},
+	}
+
+	// slow simulates a handler which fails because a backend call did not
+	// complete before the request deadline.
+	slow := func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(50 * time.Millisecond):
+		}
+		w.sendError(types.ErrInternalError, c)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/api/v3", w.withRequestTimeout)
+	group.POST("/payfee", slow)
+	group.POST("/ticketstatus", slow)
+
+	tests := map[string]int{
+		"/api/v3/payfee":       types.ErrRequestTimeout.HTTPStatus(),
+		"/api/v3/ticketstatus": types.ErrInternalError.HTTPStatus(),
+	}
+
+	for path, expectStatus := range tests {
+		t.Run(path, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != expectStatus {
+				t.Fatalf("expected status %d, got %d", expectStatus, rec.Code)
+			}
+		})
+	}
+}
+
+// TestRequestTimeoutHandlerError ensures a handler which fails with an error
+// other than an internal error because the request deadline was exceeded still
+// reports the failure as a timeout.
+func TestRequestTimeoutHandlerError(t *testing.T) {
+	ticket := database.Ticket{
+		Hash:              randString(64, hexCharset),
+		CommitmentAddress: randString(35, hexCharset),
+		FeeAddress:        randString(35, hexCharset),
+		FeeTxHex:          randString(200, hexCharset),
+		FeeTxHash:         randString(64, hexCharset),
+		FeeTxStatus:       database.FeeReceieved,
+		DeferFeeBroadcast: true,
+		Confirmed:         true,
+	}
+	err := api.db.InsertNewTicket(ticket)
+	if err != nil {
+		t.Fatalf("error storing ticket in db: %v", err)
+	}
+
+	// The broadcast only fails after the request deadline has passed, as it
+	// would if the broadcast RPC was cancelled.
+	w := &WebAPI{
+		cfg:         Config{RequestTimeout: time.Millisecond},
+		signPrivKey: api.signPrivKey,
+		db:          api.db,
+		log:         api.log,
+		broadcaster: &testBroadcaster{
+			delay: 50 * time.Millisecond,
+			err:   errors.New("context deadline exceeded"),
+		},
+	}
+	dcrd := &rpc.DcrdRPC{Caller: &testDcrd{rawTxErr: errors.New("no such transaction")}}
+
+	reqBytes, err := json.Marshal(types.BroadcastFeeRequest{
+		Timestamp:  time.Now().Unix(),
+		TicketHash: ticket.Hash,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/api/v3", w.withRequestTimeout)
+	group.POST("/broadcastfee", func(c *gin.Context) {
+		c.Set(ticketKey, ticket)
+		c.Set(knownTicketKey, true)
+		c.Set(dcrdKey, dcrd)
+		c.Set(dcrdErrorKey, nil)
+		c.Set(requestBytesKey, reqBytes)
+		w.broadcastFee(c)
+	})
+
+	req, err := http.NewRequest(http.MethodPost, "/api/v3/broadcastfee", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var resp types.ErrorResponse
+	err = json.Unmarshal(rec.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatalf("could not unmarshal response: %v", err)
+	}
+	if resp.Code != types.ErrRequestTimeout {
+		t.Fatalf("expected error code %d, got %d", types.ErrRequestTimeout, resp.Code)
+	}
+	if rec.Code != types.ErrRequestTimeout.HTTPStatus() {
+		t.Fatalf("expected status %d, got %d", types.ErrRequestTimeout.HTTPStatus(), rec.Code)
+	}
+}
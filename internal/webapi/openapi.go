@@ -0,0 +1,242 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/decred/vspd/internal/version"
+	"github.com/decred/vspd/types/v3"
+	"github.com/gin-gonic/gin"
+)
+
+// openAPIEndpoint describes an API endpoint in the OpenAPI spec.
+type openAPIEndpoint struct {
+	summary string
+	// request is a value of the request body type, or nil for endpoints which
+	// have no request body.
+	request any
+	// response is a value of the response body type.
+	response any
+	// signed is true if requests must include a request signature header.
+	signed bool
+}
+
+// openAPIEndpoints describes every API endpoint, keyed by endpoint name. Every
+// API route must have an entry so the spec stays in sync with the handlers.
+var openAPIEndpoints = map[string]openAPIEndpoint{
+	"vspinfo": {
+		summary:  "Retrieve information about the VSP.",
+		response: types.VspInfoResponse{},
+	},
+	"votetallies": {
+		summary:  "Retrieve the vote choices of tickets currently voting with the VSP.",
+		response: types.VoteTalliesResponse{},
+	},
+	"status": {
+		summary:  "Retrieve the status of the VSP.",
+		response: types.StatusResponse{},
+	},
+	"setaltsignaddr": {
+		summary:  "Set an alternate signing address for a ticket.",
+		request:  types.SetAltSignAddrRequest{},
+		response: types.SetAltSignAddrResponse{},
+		signed:   true,
+	},
+	"feeaddress": {
+		summary:  "Register a ticket and retrieve its fee address and fee amount.",
+		request:  types.FeeAddressRequest{},
+		response: types.FeeAddressResponse{},
+		signed:   true,
+	},
+	"ticketstatus": {
+		summary:  "Retrieve the status of a ticket.",
+		request:  types.TicketStatusRequest{},
+		response: types.TicketStatusResponse{},
+		signed:   true,
+	},
+	"feetxtemplate": {
+		summary:  "Retrieve an unsigned fee transaction template for a ticket.",
+		request:  types.FeeTxTemplateRequest{},
+		response: types.FeeTxTemplateResponse{},
+		signed:   true,
+	},
+	"payfee": {
+		summary:  "Provide the fee transaction and voting key of a ticket.",
+		request:  types.PayFeeRequest{},
+		response: types.PayFeeResponse{},
+		signed:   true,
+	},
+	"verifysignature": {
+		summary:  "Verify a signature of a response created by the VSP.",
+		request:  types.VerifySignatureRequest{},
+		response: types.VerifySignatureResponse{},
+	},
+	"broadcastfee": {
+		summary:  "Request the deferred broadcast of the fee transaction of a ticket.",
+		request:  types.BroadcastFeeRequest{},
+		response: types.BroadcastFeeResponse{},
+		signed:   true,
+	},
+	"setvotechoices": {
+		summary:  "Update the voting preferences of a ticket.",
+		request:  types.SetVoteChoicesRequest{},
+		response: types.SetVoteChoicesResponse{},
+		signed:   true,
+	},
+}
+
+// openAPIRoute is the name of the endpoint which serves the OpenAPI spec. It is
+// not described in the spec itself.
+const openAPIRoute = "openapi.json"
+
+// buildOpenAPISpec returns an OpenAPI 3 spec describing the API routes in
+// routes. Request and response schemas are generated from the types package, so
+// they always match the structures used by the handlers. An error is returned
+// if any API route is not described by openAPIEndpoints.
+func buildOpenAPISpec(routes gin.RoutesInfo) ([]byte, error) {
+	schemas := make(map[string]any)
+	paths := make(map[string]any)
+
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, "/api/v3/") {
+			continue
+		}
+		name := path.Base(route.Path)
+		if name == openAPIRoute {
+			continue
+		}
+
+		endpoint, ok := openAPIEndpoints[name]
+		if !ok {
+			return nil, fmt.Errorf("endpoint %q is not described in the OpenAPI spec", name)
+		}
+
+		operation := map[string]any{
+			"operationId": name,
+			"summary":     endpoint.summary,
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "Success. The response is signed by the VSP in the VSP-Server-Signature header.",
+					"content":     jsonContent(openAPISchema(reflect.TypeOf(endpoint.response), schemas)),
+				},
+				"default": map[string]any{
+					"description": "Error.",
+					"content":     jsonContent(openAPISchema(reflect.TypeOf(types.ErrorResponse{}), schemas)),
+				},
+			},
+		}
+		if endpoint.request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(openAPISchema(reflect.TypeOf(endpoint.request), schemas)),
+			}
+		}
+		if endpoint.signed {
+			operation["parameters"] = []any{map[string]any{
+				"name":        clientSignatureHeader,
+				"in":          "header",
+				"description": "Signature of the request body created with the commitment address of the ticket.",
+				"schema":      map[string]any{"type": "string"},
+			}}
+		}
+
+		paths[strings.TrimPrefix(route.Path, "/api/v3")] = map[string]any{
+			strings.ToLower(route.Method): operation,
+		}
+	}
+
+	return json.Marshal(map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "vspd API",
+			"version": version.String(),
+		},
+		"servers":    []any{map[string]any{"url": "/api/v3"}},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	})
+}
+
+// jsonContent returns an OpenAPI content object for a JSON body with the
+// provided schema.
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{"schema": schema},
+	}
+}
+
+// openAPISchema returns an OpenAPI schema describing the JSON encoding of t.
+// Named struct types are added to schemas and referenced rather than being
+// described inline.
+func openAPISchema(t reflect.Type, schemas map[string]any) map[string]any {
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]any{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice:
+		// Byte slices are encoded as base64 strings.
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": openAPISchema(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": openAPISchema(t.Elem(), schemas)}
+	case reflect.Struct:
+		ref := map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+		if _, ok := schemas[t.Name()]; ok {
+			return ref
+		}
+
+		// Add a placeholder before describing fields so recursive types do not
+		// cause infinite recursion.
+		schemas[t.Name()] = map[string]any{}
+
+		properties := make(map[string]any)
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = openAPISchema(field.Type, schemas)
+			if strings.Contains(field.Tag.Get("binding"), "required") {
+				required = append(required, name)
+			}
+		}
+
+		schema := map[string]any{"type": "object", "properties": properties}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		schemas[t.Name()] = schema
+		return ref
+	default:
+		return map[string]any{}
+	}
+}
+
+// openAPI is the handler for "GET /api/v3/openapi.json".
+func (w *WebAPI) openAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", w.openAPISpec)
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/decred/vspd/internal/config"
+	"github.com/decred/vspd/rpc"
+	"github.com/gin-gonic/gin"
+)
+
+// TestOpenAPISpec ensures every API route of the real router is described by
+// the OpenAPI spec, and that schemas are generated from the types package.
+func TestOpenAPISpec(t *testing.T) {
+	// The router loads templates relative to the repository root.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir("../.."); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+
+	w := &WebAPI{
+		cfg: Config{Network: &config.MainNet, OpenAPISpec: true},
+		log: api.log,
+	}
+	router := w.router(make([]byte, 32), rpc.DcrdConnect{}, rpc.WalletConnect{})
+	gin.SetMode(gin.TestMode)
+
+	specBytes, err := buildOpenAPISpec(router.Routes())
+	if err != nil {
+		t.Fatalf("failed to build spec: %v", err)
+	}
+
+	var spec struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+				Required   []string                   `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err = json.Unmarshal(specBytes, &spec); err != nil {
+		t.Fatalf("spec is not valid JSON: %v", err)
+	}
+
+	for path, method := range map[string]string{
+		"/vspinfo":        "get",
+		"/feeaddress":     "post",
+		"/payfee":         "post",
+		"/setvotechoices": "post",
+		"/ticketstatus":   "post",
+	} {
+		if _, ok := spec.Paths[path][method]; !ok {
+			t.Fatalf("spec does not describe %s %s", method, path)
+		}
+	}
+	if _, ok := spec.Paths["/"+openAPIRoute]; ok {
+		t.Fatal("spec should not describe itself")
+	}
+
+	feeAddressReq, ok := spec.Components.Schemas["FeeAddressRequest"]
+	if !ok {
+		t.Fatal("spec does not include FeeAddressRequest schema")
+	}
+	if _, ok := feeAddressReq.Properties["tickethex"]; !ok {
+		t.Fatal("FeeAddressRequest schema does not include tickethex")
+	}
+	required := make(map[string]bool)
+	for _, name := range feeAddressReq.Required {
+		required[name] = true
+	}
+	if !required["tickethash"] || required["priority"] {
+		t.Fatalf("unexpected required FeeAddressRequest properties %v", feeAddressReq.Required)
+	}
+
+	if _, ok := spec.Components.Schemas["AgendaTally"]; !ok {
+		t.Fatal("spec does not include nested AgendaTally schema")
+	}
+}
+
+// TestOpenAPISpecUnknownEndpoint ensures building the spec fails if an API
+// route is not described.
+func TestOpenAPISpecUnknownEndpoint(t *testing.T) {
+	routes := gin.RoutesInfo{{Method: "GET", Path: "/api/v3/unknown"}}
+	if _, err := buildOpenAPISpec(routes); err == nil {
+		t.Fatal("expected error for undescribed endpoint")
+	}
+}
@@ -6,23 +6,34 @@ package webapi
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	blockchain "github.com/decred/dcrd/blockchain/standalone/v2"
+	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrd/dcrutil/v4"
+	dcrdtypes "github.com/decred/dcrd/rpc/jsonrpc/types/v4"
+	"github.com/decred/dcrd/txscript/v4"
 	"github.com/decred/dcrd/txscript/v4/stdaddr"
+	"github.com/decred/dcrd/txscript/v4/stdscript"
+	"github.com/decred/dcrd/wire"
 	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/internal/broadcast"
+	"github.com/decred/vspd/internal/config"
+	"github.com/decred/vspd/internal/events"
 	"github.com/decred/vspd/rpc"
 	"github.com/decred/vspd/types/v3"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
+	"github.com/jrick/wsrpc/v2"
 )
 
 // payFee is the handler for "POST /api/v3/payfee".
 func (w *WebAPI) payFee(c *gin.Context) {
 	const funcName = "payFee"
+	log := w.requestLog(c)
 
 	// Get values which have been added to context by middleware.
 	ticket := c.MustGet(ticketKey).(database.Ticket)
@@ -30,30 +41,52 @@ func (w *WebAPI) payFee(c *gin.Context) {
 	dcrdClient := c.MustGet(dcrdKey).(*rpc.DcrdRPC)
 	dcrdErr := c.MustGet(dcrdErrorKey)
 	if dcrdErr != nil {
-		w.log.Errorf("%s: %v", funcName, dcrdErr.(error))
+		log.Errorf("%s: %v", funcName, dcrdErr.(error))
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
 	reqBytes := c.MustGet(requestBytesKey).([]byte)
 
 	if !knownTicket {
-		w.log.Warnf("%s: Unknown ticket (clientIP=%s)", funcName, c.ClientIP())
+		log.Warnf("%s: Unknown ticket (clientIP=%s)", funcName, c.ClientIP())
 		w.sendError(types.ErrUnknownTicket, c)
 		return
 	}
 
 	var request types.PayFeeRequest
 	if err := binding.JSON.BindBody(reqBytes, &request); err != nil {
-		w.log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
+		log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
 		w.sendErrorWithMsg(err.Error(), types.ErrBadRequest, c)
 		return
 	}
 
-	// Respond early if we already have the fee tx for this ticket.
+	// Only defer the fee broadcast if the VSP permits it.
+	if request.DeferBroadcast && !w.cfg.AllowDeferredBroadcast {
+		log.Warnf("%s: Deferred fee broadcast requested but not enabled (clientIP=%s, ticketHash=%s)",
+			funcName, c.ClientIP(), ticket.Hash)
+		w.sendErrorWithMsg("deferred fee broadcast is not enabled", types.ErrBadRequest, c)
+		return
+	}
+
+	// Respond early if we already have the fee tx for this ticket. A client
+	// resubmitting the fee tx which was already received, eg. because the
+	// original response was lost to a network timeout, receives the same
+	// success response as the original request rather than an error.
 	if ticket.FeeTxStatus == database.FeeReceieved ||
 		ticket.FeeTxStatus == database.FeeBroadcast ||
 		ticket.FeeTxStatus == database.FeeConfirmed {
-		w.log.Warnf("%s: Fee tx already received (clientIP=%s, ticketHash=%s)",
+		if sameFeeTx(request.FeeTx, ticket) {
+			log.Debugf("%s: Fee tx resubmitted (clientIP=%s, ticketHash=%s, feeHash=%s)",
+				funcName, c.ClientIP(), ticket.Hash, ticket.FeeTxHash)
+			now := time.Now()
+			w.sendJSONResponse(types.PayFeeResponse{
+				Timestamp:  now.Unix(),
+				ValidUntil: validUntil(now, w.cfg.PayFeeValidity),
+				Request:    reqBytes,
+			}, c)
+			return
+		}
+		log.Warnf("%s: Fee tx already received (clientIP=%s, ticketHash=%s)",
 			funcName, c.ClientIP(), ticket.Hash)
 		w.sendError(types.ErrFeeAlreadyReceived, c)
 		return
@@ -62,7 +95,7 @@ func (w *WebAPI) payFee(c *gin.Context) {
 	// Get ticket details.
 	rawTicket, err := dcrdClient.GetRawTransaction(ticket.Hash)
 	if err != nil {
-		w.log.Errorf("%s: dcrd.GetRawTransaction for ticket failed (ticketHash=%s): %v", funcName, ticket.Hash, err)
+		log.Errorf("%s: dcrd.GetRawTransaction for ticket failed (ticketHash=%s): %v", funcName, ticket.Hash, err)
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
@@ -70,12 +103,12 @@ func (w *WebAPI) payFee(c *gin.Context) {
 	// Ensure this ticket is eligible to vote at some point in the future.
 	canVote, err := canTicketVote(rawTicket, dcrdClient, w.cfg.Network)
 	if err != nil {
-		w.log.Errorf("%s: canTicketVote error (ticketHash=%s): %v", funcName, ticket.Hash, err)
+		log.Errorf("%s: canTicketVote error (ticketHash=%s): %v", funcName, ticket.Hash, err)
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
 	if !canVote {
-		w.log.Warnf("%s: Unvotable ticket (clientIP=%s, ticketHash=%s)",
+		log.Warnf("%s: Unvotable ticket (clientIP=%s, ticketHash=%s)",
 			funcName, c.ClientIP(), ticket.Hash)
 		w.sendError(types.ErrTicketCannotVote, c)
 		return
@@ -83,19 +116,59 @@ func (w *WebAPI) payFee(c *gin.Context) {
 
 	// Respond early if the fee for this ticket is expired.
 	if ticket.FeeExpired() {
-		w.log.Warnf("%s: Expired payfee request (clientIP=%s, ticketHash=%s)",
+		log.Warnf("%s: Expired payfee request (clientIP=%s, ticketHash=%s)",
 			funcName, c.ClientIP(), ticket.Hash)
 		w.sendError(types.ErrFeeExpired, c)
 		return
 	}
 
+	// Reject payments to fee addresses derived from a retired xpub if they are
+	// being reissued. The client is told that its fee has expired so that it
+	// requests a new fee address from /feeaddress.
+	if w.cfg.ReissueRetiredFeeAddrs && ticket.FeeAddressXPubID != w.addrGen.xPubID() {
+		log.Warnf("%s: Fee address derived from retired xpub (clientIP=%s, ticketHash=%s, feeAddress=%s)",
+			funcName, c.ClientIP(), ticket.Hash, ticket.FeeAddress)
+		w.sendErrorWithMsg("fee address has been retired, request a new fee address",
+			types.ErrFeeExpired, c)
+		return
+	}
+
+	// Ensure the commitment address has not reached the limit of active
+	// tickets. The limit is already checked when a fee address is issued to a
+	// new ticket, but fees of known tickets may be reissued after expiring, so
+	// the limit is checked again before accepting the fee. The count includes
+	// this ticket because its fee has not expired.
+	if w.cfg.MaxTicketsPerAddress > 0 {
+		count, err := w.db.CountActiveTicketsByCommitmentAddress(ticket.CommitmentAddress, time.Now())
+		if err != nil {
+			log.Errorf("%s: db.CountActiveTicketsByCommitmentAddress error (ticketHash=%s): %v",
+				funcName, ticket.Hash, err)
+			w.sendError(types.ErrInternalError, c)
+			return
+		}
+		if count > int64(w.cfg.MaxTicketsPerAddress) {
+			log.Warnf("%s: Commitment address has too many active tickets (clientIP=%s, "+
+				"ticketHash=%s, commitmentAddress=%s, count=%d)",
+				funcName, c.ClientIP(), ticket.Hash, ticket.CommitmentAddress, count)
+			w.sendError(types.ErrTooManyTickets, c)
+			return
+		}
+	}
+
 	// Validate VotingKey.
 	votingKey := request.VotingKey
 	votingWIF, err := dcrutil.DecodeWIF(votingKey, w.cfg.Network.PrivateKeyID)
 	if err != nil {
-		w.log.Warnf("%s: Failed to decode WIF (clientIP=%s, ticketHash=%s): %v",
+		if keyNet := config.WIFNetwork(votingKey); keyNet != nil {
+			log.Warnf("%s: Voting key is for wrong network (clientIP=%s, ticketHash=%s): %s",
+				funcName, c.ClientIP(), ticket.Hash, keyNet.Name)
+			w.sendErrorWithMsg(fmt.Sprintf("voting key is for %s but vsp is on %s",
+				keyNet.Name, w.cfg.Network.Name), types.ErrWrongNetwork, c)
+			return
+		}
+		log.Warnf("%s: Failed to decode WIF (clientIP=%s, ticketHash=%s): %v",
 			funcName, c.ClientIP(), ticket.Hash, err)
-		w.sendError(types.ErrInvalidPrivKey, c)
+		w.sendError(types.ErrMalformedPrivKey, c)
 		return
 	}
 
@@ -103,18 +176,18 @@ func (w *WebAPI) payFee(c *gin.Context) {
 	// the ticket should still be registered.
 
 	validVoteChoices := true
-	err = validConsensusVoteChoices(w.cfg.Network, w.cfg.Network.CurrentVoteVersion(), request.VoteChoices)
+	err = validConsensusVoteChoices(w.cfg.Network, w.voteVersions(), request.VoteChoices)
 	if err != nil {
 		validVoteChoices = false
-		w.log.Warnf("%s: Invalid consensus vote choices (clientIP=%s, ticketHash=%s): %v",
+		log.Warnf("%s: Invalid consensus vote choices (clientIP=%s, ticketHash=%s): %v",
 			funcName, c.ClientIP(), ticket.Hash, err)
 	}
 
 	validTreasury := true
-	err = validTreasuryPolicy(request.TreasuryPolicy)
+	treasuryPolicy, err := validTreasuryPolicy(request.TreasuryPolicy)
 	if err != nil {
 		validTreasury = false
-		w.log.Warnf("%s: Invalid treasury policy (clientIP=%s, ticketHash=%s): %v",
+		log.Warnf("%s: Invalid treasury policy (clientIP=%s, ticketHash=%s): %v",
 			funcName, c.ClientIP(), ticket.Hash, err)
 	}
 
@@ -122,64 +195,67 @@ func (w *WebAPI) payFee(c *gin.Context) {
 	err = validTSpendPolicy(request.TSpendPolicy)
 	if err != nil {
 		validTSpend = false
-		w.log.Warnf("%s: Invalid tspend policy (clientIP=%s, ticketHash=%s): %v",
+		log.Warnf("%s: Invalid tspend policy (clientIP=%s, ticketHash=%s): %v",
 			funcName, c.ClientIP(), ticket.Hash, err)
 	}
 
 	// Validate FeeTx.
-	feeTx, err := decodeTransaction(request.FeeTx)
-	if err != nil {
-		w.log.Warnf("%s: Failed to decode fee tx hex (clientIP=%s, ticketHash=%s): %v",
-			funcName, c.ClientIP(), ticket.Hash, err)
-		w.sendError(types.ErrInvalidFeeTx, c)
-		return
-	}
-
-	err = blockchain.CheckTransactionSanity(feeTx, uint64(w.cfg.Network.MaxTxSize))
-	if err != nil {
-		w.log.Warnf("%s: Fee tx failed sanity check (clientIP=%s, ticketHash=%s): %v",
-			funcName, c.ClientIP(), ticket.Hash, err)
-		w.sendError(types.ErrInvalidFeeTx, c)
-		return
-	}
+	minFee := dcrutil.Amount(ticket.FeeAmount)
 
-	// Decode fee address to get its payment script details.
-	feeAddr, err := stdaddr.DecodeAddress(ticket.FeeAddress, w.cfg.Network)
-	if err != nil {
-		w.log.Errorf("%s: Failed to decode fee address (ticketHash=%s): %v",
-			funcName, ticket.Hash, err)
-		w.sendError(types.ErrInternalError, c)
-		return
+	// Guard against stale fee quotes, eg. because sdiff or the fee
+	// configuration has changed since the fee was issued, by also requiring
+	// the current fee to be paid when it is higher than the issued fee.
+	if w.cfg.RecomputeFee {
+		currentFee, err := w.getCurrentFee(dcrdClient, ticket.Priority)
+		if err != nil {
+			log.Errorf("%s: getCurrentFee error (ticketHash=%s): %v", funcName, ticket.Hash, err)
+			w.sendError(types.ErrInternalError, c)
+			return
+		}
+		issuedFee := minFee
+		minFee = requiredFee(issuedFee, currentFee)
+		if currentFee != issuedFee {
+			log.Warnf("%s: Issued fee differs from current fee (ticketHash=%s, issuedFee=%v, "+
+				"currentFee=%v, requiredFee=%v)", funcName, ticket.Hash, issuedFee,
+				currentFee, minFee)
+		}
 	}
 
-	wantScriptVer, wantScript := feeAddr.PaymentScript()
-
-	// Confirm the provided fee transaction contains an output which pays to the
-	// expected payment script. Both script and script version should match.
-	var feePaid dcrutil.Amount
-	for _, txOut := range feeTx.TxOut {
-		if txOut.Version == wantScriptVer && bytes.Equal(txOut.PkScript, wantScript) {
-			feePaid = dcrutil.Amount(txOut.Value)
-			break
+	// Lock times and coinbase maturity are checked against the state of the
+	// chain the fee tx would be mined on.
+	policy := w.feeTxPolicy()
+	var nextHeight int64
+	if policy.RequireFinal || w.cfg.RejectImmatureCoinbase {
+		bestBlock, err := dcrdClient.GetBestBlockHeaderVerbose()
+		if err != nil {
+			log.Errorf("%s: dcrd.GetBestBlockHeaderVerbose error (ticketHash=%s): %v",
+				funcName, ticket.Hash, err)
+			w.sendError(types.ErrInternalError, c)
+			return
 		}
+		nextHeight = int64(bestBlock.Height) + 1
+		policy.NextHeight = nextHeight
+		policy.MedianTime = time.Unix(bestBlock.MedianTime, 0)
 	}
 
-	// Confirm a fee payment was found.
-	if feePaid == 0 {
-		w.log.Warnf("%s: Fee tx did not include expected payment (ticketHash=%s, feeAddress=%s, clientIP=%s)",
-			funcName, ticket.Hash, ticket.FeeAddress, c.ClientIP())
-		w.sendErrorWithMsg(
-			fmt.Sprintf("feetx did not include any payments for fee address %s", ticket.FeeAddress),
-			types.ErrInvalidFeeTx, c)
-		return
-	}
+	feeTx, feePaid, err := ValidateFeeTx(request.FeeTx, ticket.FeeAddress, minFee,
+		w.cfg.Network, policy)
+	if err != nil {
+		var checkErr *FeeTxCheckError
+		if !errors.As(err, &checkErr) || checkErr.Code == types.ErrInternalError {
+			log.Errorf("%s: Fee tx validation error (ticketHash=%s): %v",
+				funcName, ticket.Hash, err)
+			w.sendError(types.ErrInternalError, c)
+			return
+		}
 
-	// Confirm fee payment is equal to or larger than the minimum expected.
-	minFee := dcrutil.Amount(ticket.FeeAmount)
-	if feePaid < minFee {
-		w.log.Warnf("%s: Fee too small (ticketHash=%s, clientIP=%s): was %s, expected minimum %s",
-			funcName, ticket.Hash, c.ClientIP(), feePaid, minFee)
-		w.sendError(types.ErrFeeTooSmall, c)
+		log.Warnf("%s: Invalid fee tx (clientIP=%s, ticketHash=%s): %v",
+			funcName, c.ClientIP(), ticket.Hash, err)
+		if checkErr.Msg != "" {
+			w.sendErrorWithMsg(checkErr.Msg, checkErr.Code, c)
+		} else {
+			w.sendError(checkErr.Code, c)
+		}
 		return
 	}
 
@@ -187,36 +263,108 @@ func (w *WebAPI) payFee(c *gin.Context) {
 	pkHash := stdaddr.Hash160(votingWIF.PubKey())
 	wifAddr, err := stdaddr.NewAddressPubKeyHashEcdsaSecp256k1V0(pkHash, w.cfg.Network)
 	if err != nil {
-		w.log.Errorf("%s: Failed to get voting address from WIF (ticketHash=%s, clientIP=%s): %v",
+		log.Errorf("%s: Failed to get voting address from WIF (ticketHash=%s, clientIP=%s): %v",
 			funcName, ticket.Hash, c.ClientIP(), err)
 		w.sendError(types.ErrInvalidPrivKey, c)
 		return
 	}
 
-	wantScriptVer, wantScript = wifAddr.VotingRightsScript()
+	wantScriptVer, wantScript := wifAddr.VotingRightsScript()
 
 	// Decode ticket transaction to get its voting rights script.
 	ticketTx, err := decodeTransaction(rawTicket.Hex)
 	if err != nil {
-		w.log.Warnf("%s: Failed to decode ticket hex (ticketHash=%s): %v",
+		log.Warnf("%s: Failed to decode ticket hex (ticketHash=%s): %v",
 			funcName, ticket.Hash, err)
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
 
-	actualScriptVer := ticketTx.TxOut[0].Version
-	actualScript := ticketTx.TxOut[0].PkScript
+	// Ensure the ticket has the expected structure before reading any of its
+	// outputs.
+	err = isValidTicket(ticketTx)
+	if err != nil {
+		log.Warnf("%s: Invalid ticket (clientIP=%s, ticketHash=%s): %v",
+			funcName, c.ClientIP(), ticket.Hash, err)
+		w.sendErrorWithMsg(err.Error(), types.ErrInvalidTicket, c)
+		return
+	}
+
+	submission, scriptType, err := stakeSubmission(ticketTx)
+	if err != nil {
+		log.Warnf("%s: Invalid ticket (clientIP=%s, ticketHash=%s): %v",
+			funcName, c.ClientIP(), ticket.Hash, err)
+		w.sendErrorWithMsg(err.Error(), types.ErrInvalidTicket, c)
+		return
+	}
+
+	// Voting rights held by a script hash cannot be exercised with a single
+	// voting key, so no key provided by the client can ever be correct. Such
+	// tickets are rejected by /feeaddress, but may have been registered before
+	// it checked for them.
+	if scriptType == stdscript.STStakeSubmissionScriptHash {
+		log.Warnf("%s: Ticket voting rights held by script hash (clientIP=%s, ticketHash=%s)",
+			funcName, c.ClientIP(), ticket.Hash)
+		w.sendErrorWithMsg(scriptHashTicketMsg, types.ErrInvalidTicket, c)
+		return
+	}
+
+	actualScriptVer := submission.Version
+	actualScript := submission.PkScript
 
 	// Ensure provided voting WIF matches the actual voting address of the
 	// ticket. Both script and script version should match.
 	if actualScriptVer != wantScriptVer || !bytes.Equal(actualScript, wantScript) {
-		w.log.Warnf("%s: Voting address does not match provided private key: (ticketHash=%s)",
+		log.Warnf("%s: Voting address does not match provided private key: (ticketHash=%s)",
 			funcName, ticket.Hash)
 		w.sendErrorWithMsg("voting address does not match provided private key",
 			types.ErrInvalidPrivKey, c)
 		return
 	}
 
+	// Ensure the fee tx does not spend any coinbase outputs which will still be
+	// immature when the next block is mined, as the network would reject it.
+	if w.cfg.RejectImmatureCoinbase {
+		prevTxs, err := getInputTxs(dcrdClient, feeTx)
+		if err != nil {
+			log.Errorf("%s: Failed to get fee tx inputs (ticketHash=%s): %v",
+				funcName, ticket.Hash, err)
+			w.sendError(types.ErrInternalError, c)
+			return
+		}
+
+		err = checkCoinbaseMaturity(feeTx, prevTxs, nextHeight,
+			int64(w.cfg.Network.CoinbaseMaturity))
+		if err != nil {
+			log.Warnf("%s: Fee tx spends immature coinbase (clientIP=%s, ticketHash=%s): %v",
+				funcName, c.ClientIP(), ticket.Hash, err)
+			w.sendErrorWithMsg(err.Error(), types.ErrInvalidFeeTx, c)
+			return
+		}
+	}
+
+	// Check whether the fee tx is already known to the network. A fee tx which
+	// has already been broadcast does not need to be broadcast again, but a
+	// different tx with the same hash cannot be accepted as the fee tx.
+	var feeTxKnown bool
+	if w.cfg.CheckExistingFeeTx {
+		existing, err := getExistingTx(dcrdClient, feeTx.TxHash().String())
+		if err != nil {
+			log.Errorf("%s: Failed to check for existing fee tx (ticketHash=%s): %v",
+				funcName, ticket.Hash, err)
+			w.sendError(types.ErrInternalError, c)
+			return
+		}
+
+		feeTxKnown, err = checkExistingFeeTx(feeTx, existing)
+		if err != nil {
+			log.Warnf("%s: Fee tx conflicts with existing tx (clientIP=%s, ticketHash=%s): %v",
+				funcName, c.ClientIP(), ticket.Hash, err)
+			w.sendErrorWithMsg(err.Error(), types.ErrInvalidFeeTx, c)
+			return
+		}
+	}
+
 	// At this point we are satisfied that the request is valid and the fee tx
 	// pays sufficient fees to the expected address. Proceed to update the
 	// database, and if the ticket is confirmed broadcast the fee transaction.
@@ -225,6 +373,24 @@ func (w *WebAPI) payFee(c *gin.Context) {
 	ticket.FeeTxHex = request.FeeTx
 	ticket.FeeTxHash = feeTx.TxHash().String()
 	ticket.FeeTxStatus = database.FeeReceieved
+	ticket.DeferFeeBroadcast = request.DeferBroadcast
+
+	// A fee tx which is already known to the network has effectively been
+	// broadcast, so it only needs to be checked for confirmations.
+	if feeTxKnown {
+		ticket.FeeTxStatus = database.FeeBroadcast
+		ticket.DeferFeeBroadcast = false
+	}
+
+	// Record any amount paid above the fee separately so operators can decide
+	// whether it should be refunded.
+	ticket.FeeSurplus = 0
+	if w.cfg.RecordFeeSurplus && feePaid > minFee {
+		ticket.FeeSurplus = int64(feePaid - minFee)
+		log.Infof("%s: Fee tx overpays fee (clientIP=%s, ticketHash=%s, minExpectedFee=%v, "+
+			"feePaid=%v, surplus=%v)", funcName, c.ClientIP(), ticket.Hash, minFee, feePaid,
+			dcrutil.Amount(ticket.FeeSurplus))
+	}
 
 	if validVoteChoices {
 		ticket.VoteChoices = request.VoteChoices
@@ -235,74 +401,571 @@ func (w *WebAPI) payFee(c *gin.Context) {
 	}
 
 	if validTreasury {
-		ticket.TreasuryPolicy = request.TreasuryPolicy
+		ticket.TreasuryPolicy = treasuryPolicy
 	}
 
 	err = w.db.UpdateTicket(ticket)
 	if err != nil {
-		w.log.Errorf("%s: db.UpdateTicket error, failed to set fee tx (ticketHash=%s): %v",
+		log.Errorf("%s: db.UpdateTicket error, failed to set fee tx (ticketHash=%s): %v",
 			funcName, ticket.Hash, err)
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
 
-	w.log.Debugf("%s: Fee tx received for ticket (minExpectedFee=%v, feePaid=%v, ticketHash=%s)",
+	log.Debugf("%s: Fee tx received for ticket (minExpectedFee=%v, feePaid=%v, ticketHash=%s)",
 		funcName, minFee, feePaid, ticket.Hash)
 
-	if ticket.Confirmed {
-		err = dcrdClient.SendRawTransaction(request.FeeTx)
+	switch {
+	case feeTxKnown:
+		log.Infof("%s: Fee tx already known to network, not broadcasting (ticketHash=%s, feeHash=%s)",
+			funcName, ticket.Hash, ticket.FeeTxHash)
+		w.events.Publish(events.FeeBroadcast, ticket.Hash)
+	case ticket.Confirmed && !ticket.DeferFeeBroadcast:
+		if !w.sendFeeTx(funcName, ticket, c) {
+			return
+		}
+	}
+
+	// Send success response to client.
+	now := time.Now()
+	resp, respSig := w.sendJSONResponse(types.PayFeeResponse{
+		Timestamp:  now.Unix(),
+		ValidUntil: validUntil(now, w.cfg.PayFeeValidity),
+		Request:    reqBytes,
+	}, c)
+
+	// Store a record of the vote choice change.
+	err = w.db.SaveVoteChange(
+		ticket.Hash,
+		database.VoteChangeRecord{
+			Request:           string(reqBytes),
+			RequestSignature:  c.GetHeader("VSP-Client-Signature"),
+			Response:          resp,
+			ResponseSignature: respSig,
+		})
+	if err != nil {
+		log.Errorf("%s: Failed to store vote change record (ticketHash=%s): %v", err)
+	}
+}
+
+// FeeTxCheckError is returned by ValidateFeeTx when a fee tx fails one of the
+// checks performed on it.
+type FeeTxCheckError struct {
+	// Check describes the check which failed.
+	Check string
+	// Code is the API error code which should be returned to the client.
+	Code types.ErrorCode
+	// Msg is the message which should be returned to the client. If empty,
+	// the default message for Code should be used.
+	Msg string
+	// Err is the underlying reason the check failed.
+	Err error
+}
+
+func (e *FeeTxCheckError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Check, e.Err)
+}
+
+func (e *FeeTxCheckError) Unwrap() error {
+	return e.Err
+}
+
+// FeeTxPolicy contains the optional checks which are applied to fee
+// transactions in addition to the checks which are always performed.
+type FeeTxPolicy struct {
+	// MaxSize is the maximum serialized size of a fee tx in bytes. Zero means
+	// only the consensus limit applies.
+	MaxSize int
+	// RequireStandardOutputs rejects fee transactions with any output which
+	// does not use a standard script.
+	RequireStandardOutputs bool
+	// RequireStandardInputs rejects fee transactions with any input which
+	// does not have a standard signature script.
+	RequireStandardInputs bool
+	// RejectNullData rejects fee transactions with any OP_RETURN output.
+	RejectNullData bool
+	// MaxNullDataSize is the maximum total number of bytes of data carried by
+	// the OP_RETURN outputs of a fee tx. Zero means no limit.
+	MaxNullDataSize int
+	// RequireFinal rejects fee transactions which are not final at
+	// NextHeight and MedianTime, so could not be included in the next block.
+	RequireFinal bool
+	// NextHeight is the height of the next block to be mined. Only used if
+	// RequireFinal is set.
+	NextHeight int64
+	// MedianTime is the median time of recent blocks, which lock times are
+	// compared against. Only used if RequireFinal is set.
+	MedianTime time.Time
+}
+
+// feeTxPolicy returns the optional fee tx checks enabled by the VSP config.
+func (w *WebAPI) feeTxPolicy() FeeTxPolicy {
+	policy := FeeTxPolicy{
+		MaxSize:                w.cfg.MaxFeeTxSize,
+		RequireStandardOutputs: w.cfg.RequireStandardFeeTx,
+		RequireStandardInputs:  w.cfg.StandardFeeTxInputs,
+		RequireFinal:           w.cfg.RequireFinalFeeTx,
+	}
+
+	switch {
+	case w.cfg.MaxFeeTxNullData == 0:
+		policy.RejectNullData = true
+	case w.cfg.MaxFeeTxNullData > 0:
+		policy.MaxNullDataSize = w.cfg.MaxFeeTxNullData
+	}
+
+	return policy
+}
+
+// ValidateFeeTx decodes the provided fee tx hex and ensures it is sane, passes
+// the checks enabled by policy, and pays at least minFee to feeAddress. This is
+// the validation performed on fee transactions by /payfee. The decoded fee tx
+// and the amount it pays to the fee address are returned. Any failed check is
+// reported as a *FeeTxCheckError.
+func ValidateFeeTx(feeTxHex string, feeAddress string, minFee dcrutil.Amount,
+	network *config.Network, policy FeeTxPolicy) (*wire.MsgTx, dcrutil.Amount, error) {
+
+	feeTx, err := decodeTransaction(feeTxHex)
+	if err != nil {
+		return nil, 0, &FeeTxCheckError{
+			Check: "failed to decode fee tx hex",
+			Code:  types.ErrInvalidFeeTx,
+			Err:   err,
+		}
+	}
+
+	err = blockchain.CheckTransactionSanity(feeTx, uint64(network.MaxTxSize))
+	if err != nil {
+		return nil, 0, &FeeTxCheckError{
+			Check: "fee tx failed sanity check",
+			Code:  types.ErrInvalidFeeTx,
+			Err:   err,
+		}
+	}
+
+	err = checkFeeTxSize(feeTx, policy.MaxSize)
+	if err != nil {
+		return nil, 0, &FeeTxCheckError{
+			Check: "fee tx too large",
+			Code:  types.ErrInvalidFeeTx,
+			Msg:   err.Error(),
+			Err:   err,
+		}
+	}
+
+	if policy.RequireStandardOutputs {
+		err = checkStandardOutputs(feeTx)
 		if err != nil {
-			w.log.Errorf("%s: dcrd.SendRawTransaction for fee tx failed (ticketHash=%s): %v",
-				funcName, ticket.Hash, err)
+			return nil, 0, &FeeTxCheckError{
+				Check: "fee tx has non-standard output",
+				Code:  types.ErrInvalidFeeTx,
+				Msg:   err.Error(),
+				Err:   err,
+			}
+		}
+	}
+
+	if policy.RequireStandardInputs {
+		err = checkStandardInputs(feeTx)
+		if err != nil {
+			return nil, 0, &FeeTxCheckError{
+				Check: "fee tx has non-standard input",
+				Code:  types.ErrInvalidFeeTx,
+				Msg:   err.Error(),
+				Err:   err,
+			}
+		}
+	}
 
-			ticket.FeeTxStatus = database.FeeError
+	err = checkNullData(feeTx, policy)
+	if err != nil {
+		return nil, 0, &FeeTxCheckError{
+			Check: "fee tx has disallowed OP_RETURN data",
+			Code:  types.ErrInvalidFeeTx,
+			Msg:   err.Error(),
+			Err:   err,
+		}
+	}
 
-			// Send the client an explicit error if the issue is unknown outputs.
-			if strings.Contains(err.Error(), rpc.ErrUnknownOutputs) {
-				w.sendError(types.ErrCannotBroadcastFeeUnknownOutputs, c)
-			} else {
-				w.sendError(types.ErrCannotBroadcastFee, c)
+	if policy.RequireFinal {
+		err = checkFinal(feeTx, policy.NextHeight, policy.MedianTime)
+		if err != nil {
+			return nil, 0, &FeeTxCheckError{
+				Check: "fee tx is not final",
+				Code:  types.ErrInvalidFeeTx,
+				Msg:   err.Error(),
+				Err:   err,
 			}
+		}
+	}
+
+	// Decode fee address to get its payment script details.
+	feeAddr, err := stdaddr.DecodeAddress(feeAddress, network)
+	if err != nil {
+		return nil, 0, &FeeTxCheckError{
+			Check: "failed to decode fee address",
+			Code:  types.ErrInternalError,
+			Err:   err,
+		}
+	}
+
+	wantScriptVer, wantScript := feeAddr.PaymentScript()
+
+	// Confirm the provided fee transaction contains an output which pays to the
+	// expected payment script. Both script and script version should match.
+	feePaid, found := findFeePayment(feeTx, wantScriptVer, wantScript)
+	if !found {
+		msg := fmt.Sprintf("feetx did not include any payments for fee address %s", feeAddress)
+		return nil, 0, &FeeTxCheckError{
+			Check: "fee tx did not include expected payment",
+			Code:  types.ErrInvalidFeeTx,
+			Msg:   msg,
+			Err:   errors.New(msg),
+		}
+	}
+
+	// Confirm fee payment is equal to or larger than the minimum expected.
+	if feePaid < minFee {
+		return nil, 0, &FeeTxCheckError{
+			Check: "fee too small",
+			Code:  types.ErrFeeTooSmall,
+			Err:   fmt.Errorf("was %s, expected minimum %s", feePaid, minFee),
+		}
+	}
+
+	return feeTx, feePaid, nil
+}
+
+// requiredFee returns the minimum fee which a fee tx must pay when the fee has
+// been recalculated, which is the greater of the fee issued to the ticket and
+// the current fee.
+func requiredFee(issuedFee, currentFee dcrutil.Amount) dcrutil.Amount {
+	if currentFee > issuedFee {
+		return currentFee
+	}
+	return issuedFee
+}
+
+// sameFeeTx reports whether feeTxHex encodes the fee tx which was already
+// received for the ticket. Full serializations are compared because the hash of
+// a transaction does not commit to its signature scripts, so a different tx may
+// have the same hash. Decoded transactions are compared rather than hex strings
+// so that differences in encoding, such as letter case, do not matter. The raw
+// fee tx is removed from the database once it is confirmed, after which only
+// the hash can be compared.
+func sameFeeTx(feeTxHex string, ticket database.Ticket) bool {
+	feeTx, err := decodeTransaction(feeTxHex)
+	if err != nil {
+		return false
+	}
+
+	if ticket.FeeTxHex == "" {
+		return feeTx.TxHash().String() == ticket.FeeTxHash
+	}
 
-			err = w.db.UpdateTicket(ticket)
-			if err != nil {
-				w.log.Errorf("%s: db.UpdateTicket error, failed to set fee tx error (ticketHash=%s): %v",
-					funcName, ticket.Hash, err)
+	received, err := decodeTransaction(ticket.FeeTxHex)
+	if err != nil {
+		return false
+	}
+
+	feeTxBytes, err := feeTx.Bytes()
+	if err != nil {
+		return false
+	}
+	receivedBytes, err := received.Bytes()
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(feeTxBytes, receivedBytes)
+}
+
+// getExistingTx returns the transaction with the provided hash if dcrd knows
+// of it, either in the mempool or in a mined block. Nil is returned if dcrd has
+// no information about the transaction.
+func getExistingTx(dcrdClient *rpc.DcrdRPC, txHash string) (*wire.MsgTx, error) {
+	rawTx, err := dcrdClient.GetRawTransaction(txHash)
+	if err != nil {
+		var e *wsrpc.Error
+		if errors.As(err, &e) && e.Code == rpc.ErrNoTxInfo {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return decodeTransaction(rawTx.Hex)
+}
+
+// getInputTxs returns the transactions which created the outputs spent by the
+// provided fee tx, keyed by hash. Transactions which dcrd has no information
+// about are omitted.
+func getInputTxs(dcrdClient *rpc.DcrdRPC, feeTx *wire.MsgTx) (map[chainhash.Hash]*dcrdtypes.TxRawResult, error) {
+	prevTxs := make(map[chainhash.Hash]*dcrdtypes.TxRawResult, len(feeTx.TxIn))
+	for _, txIn := range feeTx.TxIn {
+		prevHash := txIn.PreviousOutPoint.Hash
+		if _, ok := prevTxs[prevHash]; ok {
+			continue
+		}
+
+		rawTx, err := dcrdClient.GetRawTransaction(prevHash.String())
+		if err != nil {
+			var e *wsrpc.Error
+			if errors.As(err, &e) && e.Code == rpc.ErrNoTxInfo {
+				continue
 			}
+			return nil, err
+		}
+		prevTxs[prevHash] = rawTx
+	}
+	return prevTxs, nil
+}
 
-			return
+// checkCoinbaseMaturity returns an error if any input of the provided fee tx
+// spends an output of a coinbase transaction in prevTxs which is not yet mature
+// at height, meaning the fee tx could not be included in a block at that
+// height. Coinbase outputs mature once maturity blocks have been mined on top
+// of the block containing the coinbase.
+func checkCoinbaseMaturity(feeTx *wire.MsgTx, prevTxs map[chainhash.Hash]*dcrdtypes.TxRawResult,
+	height, maturity int64) error {
+
+	for i, txIn := range feeTx.TxIn {
+		prevTx, ok := prevTxs[txIn.PreviousOutPoint.Hash]
+		if !ok || len(prevTx.Vin) == 0 || prevTx.Vin[0].Coinbase == "" {
+			continue
 		}
 
-		ticket.FeeTxStatus = database.FeeBroadcast
+		matureHeight := prevTx.BlockHeight + maturity
+		if height < matureHeight {
+			return fmt.Errorf("fee tx input %d spends coinbase output %s which is immature until height %d",
+				i, txIn.PreviousOutPoint, matureHeight)
+		}
+	}
+	return nil
+}
+
+// checkExistingFeeTx compares a fee tx received from a client with an existing
+// transaction known to the network which has the same hash. Transaction hashes
+// do not commit to signature scripts, so the existing transaction may differ
+// from the fee tx despite sharing its hash. Returns true if the fee tx is
+// already known, or an error if the existing transaction has different content.
+func checkExistingFeeTx(feeTx, existing *wire.MsgTx) (bool, error) {
+	if existing == nil {
+		return false, nil
+	}
+
+	feeTxBytes, err := feeTx.Bytes()
+	if err != nil {
+		return false, err
+	}
+	existingBytes, err := existing.Bytes()
+	if err != nil {
+		return false, err
+	}
+
+	if !bytes.Equal(feeTxBytes, existingBytes) {
+		return false, fmt.Errorf("a different transaction with hash %s already exists",
+			feeTx.TxHash())
+	}
+
+	return true, nil
+}
+
+// findFeePayment searches the outputs of the provided fee transaction for one
+// which pays to the expected payment script. Both script and script version
+// must match. A boolean indicates whether a matching output was found, which is
+// necessary because the value of the output may legitimately be zero when the
+// VSP does not charge a fee.
+func findFeePayment(feeTx *wire.MsgTx, scriptVer uint16, script []byte) (dcrutil.Amount, bool) {
+	for _, txOut := range feeTx.TxOut {
+		if txOut.Version == scriptVer && bytes.Equal(txOut.PkScript, script) {
+			return dcrutil.Amount(txOut.Value), true
+		}
+	}
+	return 0, false
+}
+
+// checkFeeTxSize returns an error if the serialized size of the provided fee
+// transaction exceeds maxSize bytes. A maxSize of zero disables the check, in
+// which case only the consensus limit enforced by CheckTransactionSanity
+// applies.
+func checkFeeTxSize(feeTx *wire.MsgTx, maxSize int) error {
+	if maxSize == 0 {
+		return nil
+	}
+
+	size := feeTx.SerializeSize()
+	if size > maxSize {
+		return fmt.Errorf("fee tx size of %d bytes exceeds maximum of %d bytes", size, maxSize)
+	}
+
+	return nil
+}
+
+// checkNullData returns an error if the OP_RETURN outputs of the provided fee
+// transaction are not permitted by policy. The VSP broadcasts fee transactions,
+// so operators may not want to publish arbitrary data provided by clients.
+func checkNullData(feeTx *wire.MsgTx, policy FeeTxPolicy) error {
+	if !policy.RejectNullData && policy.MaxNullDataSize == 0 {
+		return nil
+	}
+
+	var dataSize int
+	for i, txOut := range feeTx.TxOut {
+		if len(txOut.PkScript) == 0 || txOut.PkScript[0] != txscript.OP_RETURN {
+			continue
+		}
+
+		if policy.RejectNullData {
+			return fmt.Errorf("fee tx output %d is an OP_RETURN output, which is not permitted", i)
+		}
+
+		// Count the bytes of every data push following the OP_RETURN.
+		tokenizer := txscript.MakeScriptTokenizer(txOut.Version, txOut.PkScript[1:])
+		for tokenizer.Next() {
+			dataSize += len(tokenizer.Data())
+		}
+	}
+
+	if dataSize > policy.MaxNullDataSize {
+		return fmt.Errorf("fee tx carries %d bytes of OP_RETURN data, exceeding maximum of %d bytes",
+			dataSize, policy.MaxNullDataSize)
+	}
+
+	return nil
+}
+
+// checkStandardOutputs returns an error if any output of the provided fee
+// transaction does not use a standard script, as the network may refuse to
+// relay such a transaction.
+func checkStandardOutputs(feeTx *wire.MsgTx) error {
+	for i, txOut := range feeTx.TxOut {
+		if stdscript.DetermineScriptType(txOut.Version, txOut.PkScript) == stdscript.STNonStandard {
+			return fmt.Errorf("fee tx output %d has a non-standard script", i)
+		}
+	}
+	return nil
+}
+
+// maxStandardSigScriptSize is the maximum size of a transaction input signature
+// script which dcrd considers standard and will relay.
+const maxStandardSigScriptSize = 1650
+
+// checkStandardInputs returns an error if any input of the provided fee
+// transaction has a signature script which is not standard, as the network may
+// refuse to relay such a transaction. Standard signature scripts only push data
+// and are no larger than maxStandardSigScriptSize.
+func checkStandardInputs(feeTx *wire.MsgTx) error {
+	for i, txIn := range feeTx.TxIn {
+		if len(txIn.SignatureScript) > maxStandardSigScriptSize {
+			return fmt.Errorf("fee tx input %d signature script is %d bytes, exceeding maximum of %d bytes",
+				i, len(txIn.SignatureScript), maxStandardSigScriptSize)
+		}
+		if !txscript.IsPushOnlyScript(txIn.SignatureScript) {
+			return fmt.Errorf("fee tx input %d signature script is not push only", i)
+		}
+	}
+	return nil
+}
+
+// checkFinal returns an error if the provided fee transaction is not final at
+// the provided block height and median time, meaning it cannot be included in
+// a block at that height. A transaction is final if its lock time has passed,
+// or if every input has the maximum sequence number, which disables the lock
+// time.
+func checkFinal(feeTx *wire.MsgTx, height int64, medianTime time.Time) error {
+	lockTime := int64(feeTx.LockTime)
+	if lockTime == 0 {
+		return nil
+	}
+
+	// Lock times below the threshold are block heights, and lock times above
+	// it are unix timestamps.
+	if lockTime < txscript.LockTimeThreshold {
+		if lockTime < height {
+			return nil
+		}
+	} else if lockTime < medianTime.Unix() {
+		return nil
+	}
+
+	for i, txIn := range feeTx.TxIn {
+		if txIn.Sequence != wire.MaxTxInSequenceNum {
+			return fmt.Errorf("fee tx lock time %d has not passed and input %d has non-final sequence %d",
+				lockTime, i, txIn.Sequence)
+		}
+	}
+	return nil
+}
+
+// sendFeeTx broadcasts the fee tx of the provided ticket and updates its status
+// in the database accordingly. If broadcasting fails an error response is sent
+// to the client and false is returned.
+func (w *WebAPI) sendFeeTx(funcName string, ticket database.Ticket, c *gin.Context) bool {
+	log := w.requestLog(c)
+
+	acceptedHash, err := w.broadcaster.Broadcast(ticket.FeeTxHex)
+	if errors.Is(err, broadcast.ErrThrottled) {
+		// The network is temporarily refusing transactions. Leave the fee tx
+		// to be broadcast later by the background process rather than failing
+		// the request.
+		log.Warnf("%s: Fee tx broadcast throttled, will retry later (ticketHash=%s): %v",
+			funcName, ticket.Hash, err)
 
 		err = w.db.UpdateTicket(ticket)
 		if err != nil {
-			w.log.Errorf("%s: db.UpdateTicket error, failed to set fee tx as broadcast (ticketHash=%s): %v",
+			log.Errorf("%s: db.UpdateTicket error, failed to store fee tx for later broadcast (ticketHash=%s): %v",
 				funcName, ticket.Hash, err)
 			w.sendError(types.ErrInternalError, c)
-			return
+			return false
 		}
 
-		w.log.Debugf("%s: Fee tx broadcast for ticket (ticketHash=%s, feeHash=%s)",
-			funcName, ticket.Hash, ticket.FeeTxHash)
+		return true
 	}
+	if err != nil {
+		log.Errorf("%s: Broadcast of fee tx failed (ticketHash=%s): %v",
+			funcName, ticket.Hash, err)
 
-	// Send success response to client.
-	resp, respSig := w.sendJSONResponse(types.PayFeeResponse{
-		Timestamp: time.Now().Unix(),
-		Request:   reqBytes,
-	}, c)
+		ticket.FeeTxStatus = database.FeeError
 
-	// Store a record of the vote choice change.
-	err = w.db.SaveVoteChange(
-		ticket.Hash,
-		database.VoteChangeRecord{
-			Request:           string(reqBytes),
-			RequestSignature:  c.GetHeader("VSP-Client-Signature"),
-			Response:          resp,
-			ResponseSignature: respSig,
-		})
+		// Send the client an explicit error if the issue is unknown outputs.
+		if strings.Contains(err.Error(), rpc.ErrUnknownOutputs) {
+			w.sendError(types.ErrCannotBroadcastFeeUnknownOutputs, c)
+		} else {
+			w.sendError(types.ErrCannotBroadcastFee, c)
+		}
+
+		err = w.db.UpdateTicket(ticket)
+		if err != nil {
+			log.Errorf("%s: db.UpdateTicket error, failed to set fee tx error (ticketHash=%s): %v",
+				funcName, ticket.Hash, err)
+		}
+
+		return false
+	}
+
+	// The hash accepted by the network should always match the hash stored
+	// when the fee tx was received. If it does not, the stored hash must be
+	// updated so the fee tx can be found when waiting for it to confirm.
+	if acceptedHash != "" && acceptedHash != ticket.FeeTxHash {
+		log.Warnf("%s: Broadcast fee tx hash does not match stored hash, updating "+
+			"(ticketHash=%s, storedHash=%s, acceptedHash=%s)",
+			funcName, ticket.Hash, ticket.FeeTxHash, acceptedHash)
+		ticket.FeeTxHash = acceptedHash
+	}
+
+	ticket.FeeTxStatus = database.FeeBroadcast
+
+	err = w.db.UpdateTicket(ticket)
 	if err != nil {
-		w.log.Errorf("%s: Failed to store vote change record (ticketHash=%s): %v", err)
+		log.Errorf("%s: db.UpdateTicket error, failed to set fee tx as broadcast (ticketHash=%s): %v",
+			funcName, ticket.Hash, err)
+		w.sendError(types.ErrInternalError, c)
+		return false
 	}
+
+	w.events.Publish(events.FeeBroadcast, ticket.Hash)
+
+	log.Debugf("%s: Fee tx broadcast for ticket (ticketHash=%s, feeHash=%s)",
+		funcName, ticket.Hash, ticket.FeeTxHash)
+
+	return true
 }
@@ -0,0 +1,1290 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrec"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrutil/v4"
+	dcrdtypes "github.com/decred/dcrd/rpc/jsonrpc/types/v4"
+	"github.com/decred/dcrd/txscript/v4/stdaddr"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/internal/broadcast"
+	"github.com/decred/vspd/internal/config"
+	"github.com/decred/vspd/rpc"
+	"github.com/decred/vspd/types/v3"
+	"github.com/gin-gonic/gin"
+)
+
+// TestFindFeePayment ensures fee payments are detected by matching script and
+// script version, including payments of zero value which are possible when
+// the VSP does not charge a fee.
+func TestFindFeePayment(t *testing.T) {
+	feeScript := []byte{0x76, 0xa9, 0x14, 0x01, 0x02, 0x03}
+	otherScript := []byte{0x76, 0xa9, 0x14, 0x04, 0x05, 0x06}
+
+	tests := map[string]struct {
+		outputs   []*wire.TxOut
+		wantFound bool
+		wantPaid  dcrutil.Amount
+	}{
+		"no outputs": {
+			outputs:   nil,
+			wantFound: false,
+		},
+		"no matching output": {
+			outputs:   []*wire.TxOut{{Value: 1000, PkScript: otherScript}},
+			wantFound: false,
+		},
+		"matching script, wrong version": {
+			outputs:   []*wire.TxOut{{Value: 1000, Version: 1, PkScript: feeScript}},
+			wantFound: false,
+		},
+		"matching output": {
+			outputs: []*wire.TxOut{
+				{Value: 500, PkScript: otherScript},
+				{Value: 1000, PkScript: feeScript},
+			},
+			wantFound: true,
+			wantPaid:  1000,
+		},
+		"matching zero value output": {
+			outputs:   []*wire.TxOut{{Value: 0, PkScript: feeScript}},
+			wantFound: true,
+			wantPaid:  0,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			tx := wire.NewMsgTx()
+			for _, out := range test.outputs {
+				tx.AddTxOut(out)
+			}
+
+			paid, found := findFeePayment(tx, 0, feeScript)
+			if found != test.wantFound {
+				t.Fatalf("expected found=%t, got %t", test.wantFound, found)
+			}
+			if paid != test.wantPaid {
+				t.Fatalf("expected fee paid %s, got %s", test.wantPaid, paid)
+			}
+		})
+	}
+}
+
+// TestCheckFeeTxSize ensures fee transactions larger than the configured limit
+// are rejected, and that a limit of zero disables the check.
+func TestCheckFeeTxSize(t *testing.T) {
+	tx := wire.NewMsgTx()
+	tx.AddTxOut(&wire.TxOut{Value: 1000, PkScript: randBytes(25)})
+	size := tx.SerializeSize()
+
+	tests := map[string]struct {
+		maxSize   int
+		expectErr bool
+	}{
+		"no limit": {
+			maxSize:   0,
+			expectErr: false,
+		},
+		"limit above size": {
+			maxSize:   size + 1,
+			expectErr: false,
+		},
+		"limit equal to size": {
+			maxSize:   size,
+			expectErr: false,
+		},
+		"limit below size": {
+			maxSize:   size - 1,
+			expectErr: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			err := checkFeeTxSize(tx, test.maxSize)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error=%t, got %v", test.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckFinal(t *testing.T) {
+	const height = 1000
+	medianTime := time.Unix(1700000000, 0)
+
+	tests := map[string]struct {
+		lockTime  uint32
+		sequence  uint32
+		expectErr bool
+	}{
+		"no lock time": {
+			lockTime: 0,
+			sequence: 0,
+		},
+		"lock height passed": {
+			lockTime: height - 1,
+			sequence: 0,
+		},
+		"lock height not passed": {
+			lockTime:  height,
+			sequence:  0,
+			expectErr: true,
+		},
+		"lock height not passed with final sequence": {
+			lockTime: height,
+			sequence: wire.MaxTxInSequenceNum,
+		},
+		"lock time passed": {
+			lockTime: 1700000000 - 1,
+			sequence: 0,
+		},
+		"lock time not passed": {
+			lockTime:  1700000000,
+			sequence:  0,
+			expectErr: true,
+		},
+		"lock time not passed with final sequence": {
+			lockTime: 1700000000,
+			sequence: wire.MaxTxInSequenceNum,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			tx := wire.NewMsgTx()
+			tx.LockTime = test.lockTime
+			tx.AddTxIn(&wire.TxIn{Sequence: test.sequence})
+			err := checkFinal(tx, height, medianTime)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error=%t, got %v", test.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckCoinbaseMaturity(t *testing.T) {
+	const maturity = 256
+
+	coinbaseHash := chainhash.Hash{1}
+	regularHash := chainhash.Hash{2}
+	unknownHash := chainhash.Hash{3}
+
+	// The coinbase and regular transactions were both mined at height 1000.
+	prevTxs := map[chainhash.Hash]*dcrdtypes.TxRawResult{
+		coinbaseHash: {
+			BlockHeight: 1000,
+			Vin:         []dcrdtypes.Vin{{Coinbase: "00"}},
+		},
+		regularHash: {
+			BlockHeight: 1000,
+			Vin:         []dcrdtypes.Vin{{Txid: chainhash.Hash{4}.String()}},
+		},
+	}
+
+	tests := map[string]struct {
+		prevHash  chainhash.Hash
+		height    int64
+		expectErr bool
+	}{
+		"immature coinbase": {
+			prevHash:  coinbaseHash,
+			height:    1000 + maturity - 1,
+			expectErr: true,
+		},
+		"mature coinbase": {
+			prevHash: coinbaseHash,
+			height:   1000 + maturity,
+		},
+		"regular tx": {
+			prevHash: regularHash,
+			height:   1001,
+		},
+		"unknown tx": {
+			prevHash: unknownHash,
+			height:   1001,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			feeTx := wire.NewMsgTx()
+			feeTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&test.prevHash, 0, wire.TxTreeRegular), 0, nil))
+			err := checkCoinbaseMaturity(feeTx, prevTxs, test.height, maturity)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error=%t, got %v", test.expectErr, err)
+			}
+		})
+	}
+}
+
+// TestRequiredFee ensures the greater of the issued and current fees is
+// required.
+func TestRequiredFee(t *testing.T) {
+	tests := map[string]struct {
+		issuedFee  dcrutil.Amount
+		currentFee dcrutil.Amount
+		expect     dcrutil.Amount
+	}{
+		"current fee higher": {issuedFee: 1e6, currentFee: 2e6, expect: 2e6},
+		"issued fee higher":  {issuedFee: 2e6, currentFee: 1e6, expect: 2e6},
+		"fees equal":         {issuedFee: 1e6, currentFee: 1e6, expect: 1e6},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := requiredFee(test.issuedFee, test.currentFee)
+			if actual != test.expect {
+				t.Fatalf("expected required fee %v, got %v", test.expect, actual)
+			}
+		})
+	}
+}
+
+// TestCheckExistingFeeTx ensures a fee tx is only accepted as already known if
+// the existing tx with the same hash has identical content.
+func TestCheckExistingFeeTx(t *testing.T) {
+	newTx := func(sigScript []byte) *wire.MsgTx {
+		tx := wire.NewMsgTx()
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{}, 0, wire.TxTreeRegular), 0, sigScript))
+		tx.AddTxOut(&wire.TxOut{Value: 1000, PkScript: bytes.Repeat([]byte{0x01}, 25)})
+		return tx
+	}
+
+	feeTx := newTx([]byte{0x01})
+
+	tests := map[string]struct {
+		existing    *wire.MsgTx
+		expectKnown bool
+		expectErr   bool
+	}{
+		"no existing tx": {
+			existing: nil,
+		},
+		"identical existing tx": {
+			existing:    newTx([]byte{0x01}),
+			expectKnown: true,
+		},
+		"same hash with different signature script": {
+			existing:  newTx([]byte{0x02}),
+			expectErr: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			if test.existing != nil && test.existing.TxHash() != feeTx.TxHash() {
+				t.Fatal("existing tx must have the same hash as the fee tx")
+			}
+
+			known, err := checkExistingFeeTx(feeTx, test.existing)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error=%t, got %v", test.expectErr, err)
+			}
+			if known != test.expectKnown {
+				t.Fatalf("expected known=%t, got %t", test.expectKnown, known)
+			}
+		})
+	}
+}
+
+// TestValidateFeeTx ensures each of the checks performed on fee transactions
+// reports the expected error code.
+func TestValidateFeeTx(t *testing.T) {
+	network := &config.MainNet
+
+	newAddr := func() stdaddr.Address {
+		addr, err := stdaddr.NewAddressPubKeyHashEcdsaSecp256k1V0(randBytes(20), network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return addr
+	}
+	feeAddr := newAddr()
+	otherAddr := newAddr()
+
+	const minFee = dcrutil.Amount(1000)
+
+	serialize := func(tx *wire.MsgTx) string {
+		txBytes, err := tx.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hex.EncodeToString(txBytes)
+	}
+
+	newFeeTx := func(addr stdaddr.Address, amount dcrutil.Amount) *wire.MsgTx {
+		var prevHash chainhash.Hash
+		copy(prevHash[:], randBytes(chainhash.HashSize))
+
+		tx := wire.NewMsgTx()
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevHash, 0, wire.TxTreeRegular), 0, nil))
+		scriptVer, script := addr.PaymentScript()
+		tx.AddTxOut(&wire.TxOut{Value: int64(amount), Version: scriptVer, PkScript: script})
+		return tx
+	}
+
+	noInputs := newFeeTx(feeAddr, minFee)
+	noInputs.TxIn = nil
+
+	// OP_TRUE alone is a valid but non-standard script.
+	nonStandard := newFeeTx(feeAddr, minFee)
+	nonStandard.AddTxOut(&wire.TxOut{Value: 1, PkScript: []byte{0x51}})
+
+	// OP_DUP is not a data push, so is non-standard in a signature script.
+	nonStandardInput := newFeeTx(feeAddr, minFee)
+	nonStandardInput.TxIn[0].SignatureScript = []byte{0x01, 0xff, 0x76}
+
+	largeInput := newFeeTx(feeAddr, minFee)
+	largeInput.TxIn[0].SignatureScript = append([]byte{0x4d, 0x73, 0x06}, randBytes(1651)...)
+
+	// OP_RETURN followed by a push of 10 bytes of data.
+	nullData := newFeeTx(feeAddr, minFee)
+	nullData.AddTxOut(&wire.TxOut{PkScript: append([]byte{0x6a, 0x0a}, randBytes(10)...)})
+
+	// Locked until block 1000, with a sequence which enables the lock time.
+	timeLocked := newFeeTx(feeAddr, minFee)
+	timeLocked.LockTime = 1000
+	timeLocked.TxIn[0].Sequence = 0
+
+	tests := map[string]struct {
+		feeTx      string
+		policy     FeeTxPolicy
+		expectCode types.ErrorCode
+		expectErr  bool
+	}{
+		"valid": {
+			feeTx: serialize(newFeeTx(feeAddr, minFee)),
+		},
+		"invalid hex": {
+			feeTx:      "not hex",
+			expectErr:  true,
+			expectCode: types.ErrInvalidFeeTx,
+		},
+		"no inputs": {
+			feeTx:      serialize(noInputs),
+			expectErr:  true,
+			expectCode: types.ErrInvalidFeeTx,
+		},
+		"too large": {
+			feeTx:      serialize(newFeeTx(feeAddr, minFee)),
+			policy:     FeeTxPolicy{MaxSize: 1},
+			expectErr:  true,
+			expectCode: types.ErrInvalidFeeTx,
+		},
+		"non-standard output allowed": {
+			feeTx: serialize(nonStandard),
+		},
+		"non-standard output rejected": {
+			feeTx:      serialize(nonStandard),
+			policy:     FeeTxPolicy{RequireStandardOutputs: true},
+			expectErr:  true,
+			expectCode: types.ErrInvalidFeeTx,
+		},
+		"non-standard input allowed": {
+			feeTx: serialize(nonStandardInput),
+		},
+		"non-standard input rejected": {
+			feeTx:      serialize(nonStandardInput),
+			policy:     FeeTxPolicy{RequireStandardInputs: true},
+			expectErr:  true,
+			expectCode: types.ErrInvalidFeeTx,
+		},
+		"large input rejected": {
+			feeTx:      serialize(largeInput),
+			policy:     FeeTxPolicy{RequireStandardInputs: true},
+			expectErr:  true,
+			expectCode: types.ErrInvalidFeeTx,
+		},
+		"push only input accepted": {
+			feeTx:  serialize(newFeeTx(feeAddr, minFee)),
+			policy: FeeTxPolicy{RequireStandardInputs: true},
+		},
+		"null data allowed": {
+			feeTx: serialize(nullData),
+		},
+		"null data rejected": {
+			feeTx:      serialize(nullData),
+			policy:     FeeTxPolicy{RejectNullData: true},
+			expectErr:  true,
+			expectCode: types.ErrInvalidFeeTx,
+		},
+		"null data within limit": {
+			feeTx:  serialize(nullData),
+			policy: FeeTxPolicy{MaxNullDataSize: 10},
+		},
+		"null data exceeds limit": {
+			feeTx:      serialize(nullData),
+			policy:     FeeTxPolicy{MaxNullDataSize: 9},
+			expectErr:  true,
+			expectCode: types.ErrInvalidFeeTx,
+		},
+		"time locked without final check": {
+			feeTx: serialize(timeLocked),
+		},
+		"time locked before lock height": {
+			feeTx:      serialize(timeLocked),
+			policy:     FeeTxPolicy{RequireFinal: true, NextHeight: 1000},
+			expectErr:  true,
+			expectCode: types.ErrInvalidFeeTx,
+		},
+		"time locked after lock height": {
+			feeTx:  serialize(timeLocked),
+			policy: FeeTxPolicy{RequireFinal: true, NextHeight: 1001},
+		},
+		"no payment to fee address": {
+			feeTx:      serialize(newFeeTx(otherAddr, minFee)),
+			expectErr:  true,
+			expectCode: types.ErrInvalidFeeTx,
+		},
+		"fee too small": {
+			feeTx:      serialize(newFeeTx(feeAddr, minFee-1)),
+			expectErr:  true,
+			expectCode: types.ErrFeeTooSmall,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			_, paid, err := ValidateFeeTx(test.feeTx, feeAddr.String(), minFee, network, test.policy)
+			if !test.expectErr {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if paid != minFee {
+					t.Fatalf("expected fee paid %s, got %s", minFee, paid)
+				}
+				return
+			}
+
+			var checkErr *FeeTxCheckError
+			if !errors.As(err, &checkErr) {
+				t.Fatalf("expected FeeTxCheckError, got %v", err)
+			}
+			if checkErr.Code != test.expectCode {
+				t.Fatalf("expected error code %d, got %d (%v)", test.expectCode, checkErr.Code, err)
+			}
+		})
+	}
+}
+
+// TestPayFeeResubmission ensures resubmitting the fee tx which was already
+// received for a ticket returns a success response, while submitting a
+// different fee tx returns ErrFeeAlreadyReceived, even if it has the same hash.
+func TestPayFeeResubmission(t *testing.T) {
+	newFeeTxHex := func() string {
+		var prevHash chainhash.Hash
+		copy(prevHash[:], randBytes(chainhash.HashSize))
+
+		tx := wire.NewMsgTx()
+		tx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevHash, 0, wire.TxTreeRegular), 0, nil))
+		tx.AddTxOut(&wire.TxOut{Value: 1000, PkScript: randBytes(25)})
+		txBytes, err := tx.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hex.EncodeToString(txBytes)
+	}
+
+	feeTxHex := newFeeTxHex()
+	feeTx, err := decodeTransaction(feeTxHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing the signature script does not change the tx hash.
+	feeTx.TxIn[0].SignatureScript = randBytes(10)
+	resignedBytes, err := feeTx.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resignedHex := hex.EncodeToString(resignedBytes)
+
+	ticket := database.Ticket{
+		Hash:        randString(64, hexCharset),
+		FeeTxHex:    feeTxHex,
+		FeeTxHash:   feeTx.TxHash().String(),
+		FeeTxStatus: database.FeeBroadcast,
+	}
+
+	tests := map[string]struct {
+		feeTx          string
+		wantHTTPStatus int
+		wantErrCode    types.ErrorCode
+	}{
+		"same fee tx": {
+			feeTx:          feeTxHex,
+			wantHTTPStatus: http.StatusOK,
+		},
+		"same fee tx, different hex case": {
+			feeTx:          strings.ToUpper(feeTxHex),
+			wantHTTPStatus: http.StatusOK,
+		},
+		"different fee tx": {
+			feeTx:          newFeeTxHex(),
+			wantHTTPStatus: http.StatusBadRequest,
+			wantErrCode:    types.ErrFeeAlreadyReceived,
+		},
+		"same hash, different signature script": {
+			feeTx:          resignedHex,
+			wantHTTPStatus: http.StatusBadRequest,
+			wantErrCode:    types.ErrFeeAlreadyReceived,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			reqBytes, err := json.Marshal(types.PayFeeRequest{
+				Timestamp:   time.Now().Unix(),
+				TicketHash:  ticket.Hash,
+				FeeTx:       test.feeTx,
+				VotingKey:   "key",
+				VoteChoices: map[string]string{},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			_, r := gin.CreateTestContext(w)
+			r.POST("/", func(c *gin.Context) {
+				c.Set(ticketKey, ticket)
+				c.Set(knownTicketKey, true)
+				c.Set(dcrdKey, (*rpc.DcrdRPC)(nil))
+				c.Set(dcrdErrorKey, nil)
+				c.Set(requestBytesKey, reqBytes)
+				api.payFee(c)
+			})
+
+			req, err := http.NewRequest(http.MethodPost, "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Code != test.wantHTTPStatus {
+				t.Fatalf("expected http status %d, got %d", test.wantHTTPStatus, w.Code)
+			}
+
+			if test.wantHTTPStatus != http.StatusOK {
+				var apiError types.ErrorResponse
+				err = json.Unmarshal(w.Body.Bytes(), &apiError)
+				if err != nil {
+					t.Fatalf("could not unmarshal error response: %v", err)
+				}
+				if apiError.Code != test.wantErrCode {
+					t.Fatalf("expected error code %d, got %d", test.wantErrCode, apiError.Code)
+				}
+				return
+			}
+
+			var resp types.PayFeeResponse
+			err = json.Unmarshal(w.Body.Bytes(), &resp)
+			if err != nil {
+				t.Fatalf("could not unmarshal response: %v", err)
+			}
+			if !bytes.Equal(resp.Request, reqBytes) {
+				t.Fatal("response does not contain the request")
+			}
+		})
+	}
+}
+
+// testDcrd answers getrawtransaction calls with rawTx, or rawTxErr if it is
+// set, and fails all other calls, so it can stand in for dcrd in handlers which
+// only look up transactions.
+type testDcrd struct {
+	rawTx    dcrdtypes.TxRawResult
+	rawTxErr error
+}
+
+func (d *testDcrd) String() string { return "testdcrd" }
+
+func (d *testDcrd) Call(_ context.Context, method string, res any, _ ...any) error {
+	if method != "getrawtransaction" {
+		return fmt.Errorf("unexpected call to %s", method)
+	}
+	if d.rawTxErr != nil {
+		return d.rawTxErr
+	}
+	*res.(*dcrdtypes.TxRawResult) = d.rawTx
+	return nil
+}
+
+// testBroadcaster waits for delay and then returns hash and err for every
+// broadcast, so it can stand in for the fee tx broadcaster in handlers.
+type testBroadcaster struct {
+	delay time.Duration
+	hash  string
+	err   error
+	calls int
+}
+
+func (b *testBroadcaster) Broadcast(string) (string, error) {
+	b.calls++
+	time.Sleep(b.delay)
+	return b.hash, b.err
+}
+
+// TestPayFeeRetiredFeeAddress ensures fee payments to a fee address which was
+// derived from an xpub that was retired between /feeaddress and /payfee are
+// accepted, unless fee addresses from retired xpubs are reissued, in which case
+// /payfee is rejected until /feeaddress has issued an address from the active
+// xpub.
+func TestPayFeeRetiredFeeAddress(t *testing.T) {
+	network := api.cfg.Network
+
+	privKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	votingWIF, err := dcrutil.NewWIF(privKey.Serialize(), network.PrivateKeyID, dcrec.STEcdsaSecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	votingAddr, err := stdaddr.NewAddressPubKeyHashEcdsaSecp256k1V0(
+		stdaddr.Hash160(votingWIF.PubKey()), network)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a ticket whose voting rights are held by the voting key.
+	var prevHash chainhash.Hash
+	copy(prevHash[:], randBytes(chainhash.HashSize))
+	ticketTx := wire.NewMsgTx()
+	ticketTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevHash, 0, wire.TxTreeRegular), 1e8, nil))
+	scriptVer, script := votingAddr.VotingRightsScript()
+	ticketTx.AddTxOut(&wire.TxOut{Value: 1e8, Version: scriptVer, PkScript: script})
+	scriptVer, script = votingAddr.RewardCommitmentScript(1e8, 0, 0)
+	ticketTx.AddTxOut(&wire.TxOut{Version: scriptVer, PkScript: script})
+	scriptVer, script = votingAddr.StakeChangeScript()
+	ticketTx.AddTxOut(&wire.TxOut{Version: scriptVer, PkScript: script})
+	ticketBytes, err := ticketTx.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticketHex := hex.EncodeToString(ticketBytes)
+
+	dcrd := &rpc.DcrdRPC{Caller: &testDcrd{rawTx: dcrdtypes.TxRawResult{
+		Txid:          ticketTx.TxHash().String(),
+		Hex:           ticketHex,
+		Confirmations: 1,
+	}}}
+
+	w := &WebAPI{
+		cfg:         Config{Network: network},
+		signPrivKey: api.signPrivKey,
+		db:          api.db,
+		log:         api.log,
+		cache:       &cache{},
+	}
+
+	// Register the ticket with a fee address from the active xpub, then retire
+	// the xpub.
+	activateNewXPub(t, w)
+	ticket, _, err := w.registerNewTicket(database.Ticket{
+		Hash:          ticketTx.TxHash().String(),
+		FeeAmount:     1e6,
+		FeeExpiration: time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("error registering ticket: %v", err)
+	}
+	retiredAddress, retiredXPubID := ticket.FeeAddress, ticket.FeeAddressXPubID
+	activateNewXPub(t, w)
+
+	call := func(handler gin.HandlerFunc, request any) *httptest.ResponseRecorder {
+		t.Helper()
+
+		reqBytes, err := json.Marshal(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ticket, found, err := w.db.GetTicketByHash(ticketTx.TxHash().String())
+		if err != nil || !found {
+			t.Fatalf("error retrieving ticket (found=%t): %v", found, err)
+		}
+
+		rec := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(rec)
+		r.POST("/", func(c *gin.Context) {
+			c.Set(ticketKey, ticket)
+			c.Set(knownTicketKey, true)
+			c.Set(commitmentAddressKey, votingAddr.String())
+			c.Set(dcrdKey, dcrd)
+			c.Set(dcrdErrorKey, nil)
+			c.Set(requestBytesKey, reqBytes)
+			handler(c)
+		})
+
+		req, err := http.NewRequest(http.MethodPost, "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	payFee := func(feeAddress string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		addr, err := stdaddr.DecodeAddress(feeAddress, network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		feeTx := wire.NewMsgTx()
+		var prevHash chainhash.Hash
+		copy(prevHash[:], randBytes(chainhash.HashSize))
+		feeTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevHash, 0, wire.TxTreeRegular), 1e6, nil))
+		scriptVer, script := addr.PaymentScript()
+		feeTx.AddTxOut(&wire.TxOut{Value: 1e6, Version: scriptVer, PkScript: script})
+		feeTxBytes, err := feeTx.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return call(w.payFee, types.PayFeeRequest{
+			Timestamp:   time.Now().Unix(),
+			TicketHash:  ticketTx.TxHash().String(),
+			FeeTx:       hex.EncodeToString(feeTxBytes),
+			VotingKey:   votingWIF.String(),
+			VoteChoices: map[string]string{},
+		})
+	}
+
+	expectErrCode := func(rec *httptest.ResponseRecorder, wantErrCode types.ErrorCode) {
+		t.Helper()
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected http status %d, got %d", http.StatusBadRequest, rec.Code)
+		}
+		var apiError types.ErrorResponse
+		err := json.Unmarshal(rec.Body.Bytes(), &apiError)
+		if err != nil {
+			t.Fatalf("could not unmarshal error response: %v", err)
+		}
+		if apiError.Code != wantErrCode {
+			t.Fatalf("expected error code %d, got %d", wantErrCode, apiError.Code)
+		}
+	}
+
+	// Payments to the retired fee address are rejected if fee addresses from
+	// retired xpubs are reissued.
+	w.cfg.ReissueRetiredFeeAddrs = true
+	expectErrCode(payFee(retiredAddress), types.ErrFeeExpired)
+
+	// Requesting a fee address should then issue one from the active xpub.
+	rec := call(w.feeAddress, types.FeeAddressRequest{
+		Timestamp:  time.Now().Unix(),
+		TicketHash: ticketTx.TxHash().String(),
+		TicketHex:  ticketHex,
+		ParentHex:  ticketHex,
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected http status %d from feeaddress, got %d: %s",
+			http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var feeAddrResp types.FeeAddressResponse
+	err = json.Unmarshal(rec.Body.Bytes(), &feeAddrResp)
+	if err != nil {
+		t.Fatalf("could not unmarshal feeaddress response: %v", err)
+	}
+	if feeAddrResp.FeeAddress == retiredAddress {
+		t.Fatal("expected a new fee address to be issued")
+	}
+	ticket, _, err = w.db.GetTicketByHash(ticketTx.TxHash().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticket.FeeAddressXPubID != w.addrGen.xPubID() {
+		t.Fatalf("expected fee address from xpub %d, got %d",
+			w.addrGen.xPubID(), ticket.FeeAddressXPubID)
+	}
+
+	// Payments to the retired fee address are accepted if fee addresses from
+	// retired xpubs are not reissued.
+	ticket.FeeAddress = retiredAddress
+	ticket.FeeAddressXPubID = retiredXPubID
+	err = w.db.UpdateTicket(ticket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.cfg.ReissueRetiredFeeAddrs = false
+	rec = payFee(retiredAddress)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected http status %d from payfee, got %d: %s",
+			http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	ticket, _, err = w.db.GetTicketByHash(ticketTx.TxHash().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticket.FeeTxStatus != database.FeeReceieved {
+		t.Fatalf("expected fee status %q, got %q", database.FeeReceieved, ticket.FeeTxStatus)
+	}
+}
+
+// TestPayFeeTooManyTickets ensures /payfee rejects fees of tickets whose
+// commitment address has reached the limit of active tickets, which may happen
+// if fees of known tickets are reissued after expiring.
+func TestPayFeeTooManyTickets(t *testing.T) {
+	commitmentAddress := randString(35, hexCharset)
+
+	dcrd := &rpc.DcrdRPC{Caller: &testDcrd{rawTx: dcrdtypes.TxRawResult{Confirmations: 1}}}
+
+	w := &WebAPI{
+		cfg:         Config{Network: api.cfg.Network, MaxTicketsPerAddress: 1},
+		signPrivKey: api.signPrivKey,
+		db:          api.db,
+		log:         api.log,
+		cache:       &cache{},
+	}
+
+	// Another active ticket of the commitment address has already been paid.
+	err := w.db.InsertNewTicket(database.Ticket{
+		Hash:              randString(64, hexCharset),
+		CommitmentAddress: commitmentAddress,
+		FeeAddress:        randString(35, hexCharset),
+		FeeTxStatus:       database.FeeConfirmed,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ticket := database.Ticket{
+		Hash:              randString(64, hexCharset),
+		CommitmentAddress: commitmentAddress,
+		FeeAddress:        randString(35, hexCharset),
+		FeeAmount:         1e6,
+		FeeExpiration:     time.Now().Add(time.Hour).Unix(),
+		FeeTxStatus:       database.NoFee,
+	}
+	err = w.db.InsertNewTicket(ticket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payFee := func() types.ErrorCode {
+		t.Helper()
+
+		// A malformed voting key is rejected after the ticket limit has been
+		// checked.
+		reqBytes, err := json.Marshal(types.PayFeeRequest{
+			Timestamp:   time.Now().Unix(),
+			TicketHash:  ticket.Hash,
+			FeeTx:       "00",
+			VotingKey:   "malformed",
+			VoteChoices: map[string]string{},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(rec)
+		r.POST("/", func(c *gin.Context) {
+			c.Set(ticketKey, ticket)
+			c.Set(knownTicketKey, true)
+			c.Set(dcrdKey, dcrd)
+			c.Set(dcrdErrorKey, nil)
+			c.Set(requestBytesKey, reqBytes)
+			w.payFee(c)
+		})
+
+		req, err := http.NewRequest(http.MethodPost, "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.ServeHTTP(rec, req)
+
+		var apiError types.ErrorResponse
+		err = json.Unmarshal(rec.Body.Bytes(), &apiError)
+		if err != nil {
+			t.Fatalf("could not unmarshal error response: %v", err)
+		}
+		return apiError.Code
+	}
+
+	if code := payFee(); code != types.ErrTooManyTickets {
+		t.Fatalf("expected error code %d, got %d", types.ErrTooManyTickets, code)
+	}
+
+	// The fee is not rejected for the limit once it allows both tickets.
+	w.cfg.MaxTicketsPerAddress = 2
+	if code := payFee(); code != types.ErrMalformedPrivKey {
+		t.Fatalf("expected error code %d, got %d", types.ErrMalformedPrivKey, code)
+	}
+}
+
+// TestBroadcastFee ensures /broadcastfee only broadcasts deferred fee txs, does
+// not broadcast fee txs which the client has already broadcast, and records the
+// outcome of the broadcast.
+func TestBroadcastFee(t *testing.T) {
+	broadcastErr := errors.New("rejected transaction")
+	throttledErr := fmt.Errorf("%w: too many transactions", broadcast.ErrThrottled)
+
+	tests := map[string]struct {
+		feeTxStatus       database.FeeStatus
+		deferred          bool
+		confirmed         bool
+		feeTxBroadcast    bool
+		broadcastErr      error
+		expectError       bool
+		expectCode        types.ErrorCode
+		expectBroadcast   bool
+		expectFeeTxStatus database.FeeStatus
+		expectDeferred    bool
+	}{
+		"not deferred": {
+			feeTxStatus:       database.FeeReceieved,
+			confirmed:         true,
+			expectError:       true,
+			expectCode:        types.ErrBadRequest,
+			expectFeeTxStatus: database.FeeReceieved,
+		},
+		"already broadcast by vspd": {
+			feeTxStatus:       database.FeeBroadcast,
+			deferred:          true,
+			confirmed:         true,
+			expectError:       true,
+			expectCode:        types.ErrBadRequest,
+			expectFeeTxStatus: database.FeeBroadcast,
+			expectDeferred:    true,
+		},
+		"no fee tx": {
+			feeTxStatus:       database.NoFee,
+			deferred:          true,
+			confirmed:         true,
+			expectError:       true,
+			expectCode:        types.ErrFeeNotReceived,
+			expectFeeTxStatus: database.NoFee,
+			expectDeferred:    true,
+		},
+		"ticket not confirmed": {
+			feeTxStatus:       database.FeeReceieved,
+			deferred:          true,
+			expectFeeTxStatus: database.FeeReceieved,
+		},
+		"already broadcast by client": {
+			feeTxStatus:       database.FeeReceieved,
+			deferred:          true,
+			confirmed:         true,
+			feeTxBroadcast:    true,
+			expectFeeTxStatus: database.FeeBroadcast,
+		},
+		"broadcast": {
+			feeTxStatus:       database.FeeReceieved,
+			deferred:          true,
+			confirmed:         true,
+			expectBroadcast:   true,
+			expectFeeTxStatus: database.FeeBroadcast,
+		},
+		"broadcast throttled": {
+			feeTxStatus:       database.FeeReceieved,
+			deferred:          true,
+			confirmed:         true,
+			broadcastErr:      throttledErr,
+			expectBroadcast:   true,
+			expectFeeTxStatus: database.FeeReceieved,
+		},
+		"broadcast error": {
+			feeTxStatus:       database.FeeReceieved,
+			deferred:          true,
+			confirmed:         true,
+			broadcastErr:      broadcastErr,
+			expectError:       true,
+			expectCode:        types.ErrCannotBroadcastFee,
+			expectBroadcast:   true,
+			expectFeeTxStatus: database.FeeError,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			ticket := database.Ticket{
+				Hash:              randString(64, hexCharset),
+				CommitmentAddress: randString(35, hexCharset),
+				FeeAddress:        randString(35, hexCharset),
+				FeeTxHex:          randString(200, hexCharset),
+				FeeTxHash:         randString(64, hexCharset),
+				FeeTxStatus:       test.feeTxStatus,
+				DeferFeeBroadcast: test.deferred,
+				Confirmed:         test.confirmed,
+			}
+			err := api.db.InsertNewTicket(ticket)
+			if err != nil {
+				t.Fatalf("error storing ticket in db: %v", err)
+			}
+
+			dcrd := &rpc.DcrdRPC{Caller: &testDcrd{
+				rawTx:    dcrdtypes.TxRawResult{Txid: ticket.FeeTxHash},
+				rawTxErr: errors.New("no such transaction"),
+			}}
+			if test.feeTxBroadcast {
+				dcrd = &rpc.DcrdRPC{Caller: &testDcrd{
+					rawTx: dcrdtypes.TxRawResult{Txid: ticket.FeeTxHash},
+				}}
+			}
+
+			broadcaster := &testBroadcaster{hash: ticket.FeeTxHash, err: test.broadcastErr}
+			w := &WebAPI{
+				signPrivKey: api.signPrivKey,
+				db:          api.db,
+				log:         api.log,
+				broadcaster: broadcaster,
+			}
+
+			reqBytes, err := json.Marshal(types.BroadcastFeeRequest{
+				Timestamp:  time.Now().Unix(),
+				TicketHash: ticket.Hash,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rec := httptest.NewRecorder()
+			_, r := gin.CreateTestContext(rec)
+			r.POST("/", func(c *gin.Context) {
+				c.Set(ticketKey, ticket)
+				c.Set(knownTicketKey, true)
+				c.Set(dcrdKey, dcrd)
+				c.Set(dcrdErrorKey, nil)
+				c.Set(requestBytesKey, reqBytes)
+				w.broadcastFee(c)
+			})
+
+			req, err := http.NewRequest(http.MethodPost, "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.ServeHTTP(rec, req)
+
+			if test.expectError {
+				var resp types.ErrorResponse
+				err = json.Unmarshal(rec.Body.Bytes(), &resp)
+				if err != nil {
+					t.Fatalf("could not unmarshal response: %v", err)
+				}
+				if resp.Code != test.expectCode {
+					t.Fatalf("expected error code %d, got %d (%s)",
+						test.expectCode, resp.Code, resp.Message)
+				}
+			} else {
+				if rec.Code != http.StatusOK {
+					t.Fatalf("expected status %d, got %d: %s",
+						http.StatusOK, rec.Code, rec.Body.String())
+				}
+				var resp types.BroadcastFeeResponse
+				err = json.Unmarshal(rec.Body.Bytes(), &resp)
+				if err != nil {
+					t.Fatalf("could not unmarshal response: %v", err)
+				}
+				if !bytes.Equal(resp.Request, reqBytes) {
+					t.Fatal("response does not contain the request")
+				}
+			}
+
+			if (broadcaster.calls > 0) != test.expectBroadcast {
+				t.Fatalf("expected broadcast=%t, got %d broadcasts",
+					test.expectBroadcast, broadcaster.calls)
+			}
+
+			stored, found, err := api.db.GetTicketByHash(ticket.Hash)
+			if err != nil || !found {
+				t.Fatalf("error retrieving ticket (found=%t): %v", found, err)
+			}
+			if stored.FeeTxStatus != test.expectFeeTxStatus {
+				t.Fatalf("expected fee tx status %s, got %s",
+					test.expectFeeTxStatus, stored.FeeTxStatus)
+			}
+			if stored.DeferFeeBroadcast != test.expectDeferred {
+				t.Fatalf("expected deferred=%t, got %t",
+					test.expectDeferred, stored.DeferFeeBroadcast)
+			}
+		})
+	}
+}
+
+// TestPayFeeVotingKey ensures /payfee reports voting keys which cannot be
+// decoded as malformed, and distinguishes them from keys which are for the
+// wrong network or which do not hold the voting rights of the ticket.
+func TestPayFeeVotingKey(t *testing.T) {
+	network := api.cfg.Network
+
+	newWIF := func(net *config.Network) *dcrutil.WIF {
+		t.Helper()
+
+		privKey, err := secp256k1.GeneratePrivateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		wif, err := dcrutil.NewWIF(privKey.Serialize(), net.PrivateKeyID, dcrec.STEcdsaSecp256k1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return wif
+	}
+	votingAddress := func(wif *dcrutil.WIF) *stdaddr.AddressPubKeyHashEcdsaSecp256k1V0 {
+		t.Helper()
+
+		addr, err := stdaddr.NewAddressPubKeyHashEcdsaSecp256k1V0(
+			stdaddr.Hash160(wif.PubKey()), network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return addr
+	}
+
+	// Create a ticket whose voting rights are held by the voting key.
+	votingWIF := newWIF(network)
+	votingAddr := votingAddress(votingWIF)
+	var prevHash chainhash.Hash
+	copy(prevHash[:], randBytes(chainhash.HashSize))
+	ticketTx := wire.NewMsgTx()
+	ticketTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevHash, 0, wire.TxTreeRegular), 1e8, nil))
+	scriptVer, script := votingAddr.VotingRightsScript()
+	ticketTx.AddTxOut(&wire.TxOut{Value: 1e8, Version: scriptVer, PkScript: script})
+	scriptVer, script = votingAddr.RewardCommitmentScript(1e8, 0, 0)
+	ticketTx.AddTxOut(&wire.TxOut{Version: scriptVer, PkScript: script})
+	scriptVer, script = votingAddr.StakeChangeScript()
+	ticketTx.AddTxOut(&wire.TxOut{Version: scriptVer, PkScript: script})
+	ticketBytes, err := ticketTx.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dcrd := &rpc.DcrdRPC{Caller: &testDcrd{rawTx: dcrdtypes.TxRawResult{
+		Txid:          ticketTx.TxHash().String(),
+		Hex:           hex.EncodeToString(ticketBytes),
+		Confirmations: 1,
+	}}}
+
+	// Create a fee tx which pays the fee address of the ticket.
+	feeAddr := votingAddress(newWIF(network))
+	feeTx := wire.NewMsgTx()
+	copy(prevHash[:], randBytes(chainhash.HashSize))
+	feeTx.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&prevHash, 0, wire.TxTreeRegular), 1e6, nil))
+	scriptVer, script = feeAddr.PaymentScript()
+	feeTx.AddTxOut(&wire.TxOut{Value: 1e6, Version: scriptVer, PkScript: script})
+	feeTxBytes, err := feeTx.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ticket := database.Ticket{
+		Hash:              ticketTx.TxHash().String(),
+		CommitmentAddress: votingAddr.String(),
+		FeeAddress:        feeAddr.String(),
+		FeeAmount:         1e6,
+		FeeExpiration:     time.Now().Add(time.Hour).Unix(),
+		FeeTxStatus:       database.NoFee,
+	}
+	err = api.db.InsertNewTicket(ticket)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &WebAPI{
+		cfg:         Config{Network: network},
+		signPrivKey: api.signPrivKey,
+		db:          api.db,
+		log:         api.log,
+		cache:       &cache{},
+	}
+
+	// Changing the final character of a WIF invalidates its checksum.
+	validWIF := votingWIF.String()
+	badChecksum := validWIF[:len(validWIF)-1] + "z"
+	if validWIF[len(validWIF)-1] == 'z' {
+		badChecksum = validWIF[:len(validWIF)-1] + "y"
+	}
+
+	tests := map[string]struct {
+		votingKey  string
+		expectCode types.ErrorCode
+	}{
+		"not base58": {
+			votingKey:  "0OIl",
+			expectCode: types.ErrMalformedPrivKey,
+		},
+		"truncated": {
+			votingKey:  validWIF[:len(validWIF)-4],
+			expectCode: types.ErrMalformedPrivKey,
+		},
+		"bad checksum": {
+			votingKey:  badChecksum,
+			expectCode: types.ErrMalformedPrivKey,
+		},
+		"wrong network": {
+			votingKey:  newWIF(&config.TestNet3).String(),
+			expectCode: types.ErrWrongNetwork,
+		},
+		"does not hold voting rights": {
+			votingKey:  newWIF(network).String(),
+			expectCode: types.ErrInvalidPrivKey,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			reqBytes, err := json.Marshal(types.PayFeeRequest{
+				Timestamp:   time.Now().Unix(),
+				TicketHash:  ticket.Hash,
+				FeeTx:       hex.EncodeToString(feeTxBytes),
+				VotingKey:   test.votingKey,
+				VoteChoices: map[string]string{},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rec := httptest.NewRecorder()
+			_, r := gin.CreateTestContext(rec)
+			r.POST("/", func(c *gin.Context) {
+				c.Set(ticketKey, ticket)
+				c.Set(knownTicketKey, true)
+				c.Set(dcrdKey, dcrd)
+				c.Set(dcrdErrorKey, nil)
+				c.Set(requestBytesKey, reqBytes)
+				w.payFee(c)
+			})
+
+			req, err := http.NewRequest(http.MethodPost, "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.ServeHTTP(rec, req)
+
+			var apiError types.ErrorResponse
+			err = json.Unmarshal(rec.Body.Bytes(), &apiError)
+			if err != nil {
+				t.Fatalf("could not unmarshal error response: %v", err)
+			}
+			if apiError.Code != test.expectCode {
+				t.Fatalf("expected error code %d, got %d (%s)",
+					test.expectCode, apiError.Code, apiError.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"github.com/decred/vspd/types/v3"
+	"github.com/gin-gonic/gin"
+)
+
+// isReady reports whether startup has completed, which is the case once the
+// cache has been populated using a reachable dcrd which has completed its
+// initial sync of the chain. Once the VSP is ready it remains ready.
+func (w *WebAPI) isReady() bool {
+	if w.ready.Load() {
+		return true
+	}
+
+	data := w.cache.getData()
+	if !data.Initialized || !data.Synced {
+		return false
+	}
+
+	w.ready.Store(true)
+	w.log.Info("Startup complete, accepting all requests")
+	return true
+}
+
+// initializing reports whether responses should indicate that the VSP is still
+// starting up. It is always false unless rejecting requests until ready is
+// enabled.
+func (w *WebAPI) initializing() bool {
+	return w.cfg.RejectUntilReady && !w.isReady()
+}
+
+// requireReady middleware rejects requests until startup has completed, if
+// enabled by config. It is used on endpoints which register or update tickets.
+func (w *WebAPI) requireReady(c *gin.Context) {
+	if w.initializing() {
+		w.sendError(types.ErrNotReady, c)
+		return
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/decred/vspd/types/v3"
+	"github.com/gin-gonic/gin"
+)
+
+// TestRequireReady ensures requests are only rejected before startup has
+// completed, and only if rejecting requests until ready is enabled.
+func TestRequireReady(t *testing.T) {
+	tests := map[string]struct {
+		rejectUntilReady bool
+		data             cacheData
+		expectStatus     int
+	}{
+		"cache not initialized": {
+			rejectUntilReady: true,
+			data:             cacheData{},
+			expectStatus:     types.ErrNotReady.HTTPStatus(),
+		},
+		"dcrd not synced": {
+			rejectUntilReady: true,
+			data:             cacheData{Initialized: true},
+			expectStatus:     types.ErrNotReady.HTTPStatus(),
+		},
+		"ready": {
+			rejectUntilReady: true,
+			data:             cacheData{Initialized: true, Synced: true},
+			expectStatus:     http.StatusOK,
+		},
+		"not ready but disabled": {
+			rejectUntilReady: false,
+			data:             cacheData{},
+			expectStatus:     http.StatusOK,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			w := &WebAPI{
+				log:         api.log,
+				signPrivKey: api.signPrivKey,
+				cfg:         Config{RejectUntilReady: test.rejectUntilReady},
+				cache:       &cache{data: test.data},
+			}
+
+			router := gin.New()
+			router.POST("/payfee", w.requireReady, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req, err := http.NewRequest(http.MethodPost, "/payfee", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != test.expectStatus {
+				t.Fatalf("expected status %d, got %d", test.expectStatus, rec.Code)
+			}
+		})
+	}
+}
+
+// TestReadyRemainsSet ensures the VSP remains ready once startup has completed,
+// even if dcrd later reports that it is not synced.
+func TestReadyRemainsSet(t *testing.T) {
+	w := &WebAPI{
+		log:   api.log,
+		cfg:   Config{RejectUntilReady: true},
+		cache: &cache{data: cacheData{Initialized: true, Synced: true}},
+	}
+
+	if !w.isReady() {
+		t.Fatal("expected VSP to be ready")
+	}
+
+	w.cache.data.Synced = false
+	if !w.isReady() || w.initializing() {
+		t.Fatal("expected VSP to remain ready")
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+// ReloadableConfig contains the subset of Config which can be changed while
+// the server is running.
+type ReloadableConfig struct {
+	VspClosed    bool
+	VspClosedMsg string
+	VSPFee       float64
+}
+
+// Reload replaces the values of the reloadable config options used by the
+// running server. It is safe to call concurrently with request handlers.
+func (w *WebAPI) Reload(rc ReloadableConfig) {
+	w.reloadMtx.Lock()
+	w.cfg.VspClosed = rc.VspClosed
+	w.cfg.VspClosedMsg = rc.VspClosedMsg
+	w.cfg.VSPFee = rc.VSPFee
+	w.reloadMtx.Unlock()
+
+	w.cache.setVSPFee(rc.VSPFee)
+}
+
+// config returns a copy of the server config, including the most recently
+// reloaded values. Handlers must use it rather than the cfg field to read any
+// of the options in ReloadableConfig.
+func (w *WebAPI) config() Config {
+	w.reloadMtx.RLock()
+	defer w.reloadMtx.RUnlock()
+	return w.cfg
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/decred/slog"
+	"github.com/gin-gonic/gin"
+)
+
+// requestIDHeader is the response header which contains the ID of the request.
+const requestIDHeader = "VSP-Request-ID"
+
+// newRequestID returns a random ID which can be used to identify a request.
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		// crypto/rand should never fail, but an ID is only used to correlate
+		// log lines so failing the request would be excessive.
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// withRequestID generates an ID for each request, adds it to the request
+// context so it is included in every log line for the request, and returns it
+// to the client in a response header.
+func (w *WebAPI) withRequestID(c *gin.Context) {
+	id := newRequestID()
+	c.Set(requestIDKey, id)
+	c.Header(requestIDHeader, id)
+}
+
+// requestLog returns a logger which prefixes every message with the ID of the
+// provided request, and the hash of the ticket which the request relates to if
+// it is known.
+func (w *WebAPI) requestLog(c *gin.Context) slog.Logger {
+	return &requestLogger{Logger: w.log, c: c, sampler: w.logSampler}
+}
+
+// requestLogger wraps a logger so that every message it writes includes
+// context fields of a web request. Formatted debug and warning messages are
+// subject to sampling, which is where the noise of repeated requests is found.
+// Info messages record notable events such as a fee being received, so they
+// are always logged along with trace messages and errors.
+type requestLogger struct {
+	slog.Logger
+	c       *gin.Context
+	sampler *logSampler
+}
+
+// prefix returns the context fields of the request. The ticket hash is
+// omitted if msg already contains it.
+func (l *requestLogger) prefix(msg string) string {
+	fields := []string{"requestID=" + l.c.GetString(requestIDKey)}
+	if hash := l.c.GetString(ticketHashKey); hash != "" && !strings.Contains(msg, "ticketHash=") {
+		fields = append(fields, "ticketHash="+hash)
+	}
+	return "[" + strings.Join(fields, ", ") + "] "
+}
+
+func (l *requestLogger) format(format string, params []any) string {
+	msg := fmt.Sprintf(format, params...)
+	return l.prefix(msg) + msg
+}
+
+func (l *requestLogger) sprint(v []any) string {
+	msg := fmt.Sprint(v...)
+	return l.prefix(msg) + msg
+}
+
+// sampled formats a message if it has been selected by the sampler. The
+// returned bool is false if the message should not be logged.
+func (l *requestLogger) sampled(level slog.Level, format string, params []any) (string, bool) {
+	// Avoid counting messages which would not be logged at the current level.
+	if l.Logger.Level() > level {
+		return "", false
+	}
+	ok, suffix := l.sampler.sample(format)
+	if !ok {
+		return "", false
+	}
+	return l.format(format, params) + suffix, true
+}
+
+func (l *requestLogger) Tracef(format string, params ...any) {
+	l.Logger.Trace(l.format(format, params))
+}
+
+func (l *requestLogger) Debugf(format string, params ...any) {
+	if msg, ok := l.sampled(slog.LevelDebug, format, params); ok {
+		l.Logger.Debug(msg)
+	}
+}
+
+func (l *requestLogger) Infof(format string, params ...any) {
+	l.Logger.Info(l.format(format, params))
+}
+
+func (l *requestLogger) Warnf(format string, params ...any) {
+	if msg, ok := l.sampled(slog.LevelWarn, format, params); ok {
+		l.Logger.Warn(msg)
+	}
+}
+
+func (l *requestLogger) Errorf(format string, params ...any) {
+	l.Logger.Error(l.format(format, params))
+}
+
+func (l *requestLogger) Criticalf(format string, params ...any) {
+	l.Logger.Critical(l.format(format, params))
+}
+
+func (l *requestLogger) Trace(v ...any)    { l.Logger.Trace(l.sprint(v)) }
+func (l *requestLogger) Debug(v ...any)    { l.Logger.Debug(l.sprint(v)) }
+func (l *requestLogger) Info(v ...any)     { l.Logger.Info(l.sprint(v)) }
+func (l *requestLogger) Warn(v ...any)     { l.Logger.Warn(l.sprint(v)) }
+func (l *requestLogger) Error(v ...any)    { l.Logger.Error(l.sprint(v)) }
+func (l *requestLogger) Critical(v ...any) { l.Logger.Critical(l.sprint(v)) }
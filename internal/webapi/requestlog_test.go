@@ -0,0 +1,115 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/decred/slog"
+	"github.com/gin-gonic/gin"
+)
+
+// TestRequestLogPrefix ensures request log lines are prefixed with the request
+// ID, and with the ticket hash unless the line already contains it.
+func TestRequestLogPrefix(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set(requestIDKey, "0123456789abcdef")
+
+	log := api.requestLog(c).(*requestLogger)
+
+	tests := map[string]struct {
+		ticketHash string
+		msg        string
+		expect     string
+	}{
+		"no ticket hash": {
+			msg:    "test",
+			expect: "[requestID=0123456789abcdef] ",
+		},
+		"ticket hash": {
+			ticketHash: "ab",
+			msg:        "test",
+			expect:     "[requestID=0123456789abcdef, ticketHash=ab] ",
+		},
+		"ticket hash already in message": {
+			ticketHash: "ab",
+			msg:        "test (ticketHash=ab)",
+			expect:     "[requestID=0123456789abcdef] ",
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			c.Set(ticketHashKey, test.ticketHash)
+			actual := log.prefix(test.msg)
+			if actual != test.expect {
+				t.Fatalf("expected %q, got %q", test.expect, actual)
+			}
+		})
+	}
+}
+
+// TestWithRequestID ensures a unique request ID is returned in a response
+// header and added to the request context.
+func TestWithRequestID(t *testing.T) {
+	ids := make(map[string]struct{})
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		api.withRequestID(c)
+
+		id := w.Header().Get(requestIDHeader)
+		if len(id) != 16 {
+			t.Fatalf("expected 16 character request ID, got %q", id)
+		}
+		if c.GetString(requestIDKey) != id {
+			t.Fatalf("expected context request ID %q, got %q", id, c.GetString(requestIDKey))
+		}
+		if _, ok := ids[id]; ok {
+			t.Fatalf("duplicate request ID %q", id)
+		}
+		ids[id] = struct{}{}
+	}
+}
+
+// TestRequestLogSampling ensures only debug and warning messages are sampled,
+// and info messages and errors are always logged.
+func TestRequestLogSampling(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.NewBackend(&buf).Logger("test")
+	logger.SetLevel(slog.LevelTrace)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	w := &WebAPI{log: logger, logSampler: newLogSampler(2)}
+	log := w.requestLog(c)
+
+	tests := map[string]struct {
+		logf   func(format string, params ...any)
+		expect int
+	}{
+		"trace":   {logf: log.Tracef, expect: 4},
+		"debug":   {logf: log.Debugf, expect: 2},
+		"info":    {logf: log.Infof, expect: 4},
+		"warning": {logf: log.Warnf, expect: 2},
+		"error":   {logf: log.Errorf, expect: 4},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			buf.Reset()
+			for i := 0; i < 4; i++ {
+				test.logf(testName+" %d", i)
+			}
+			actual := strings.Count(buf.String(), "\n")
+			if actual != test.expect {
+				t.Fatalf("expected %d lines logged, got %d:\n%s", test.expect, actual, buf.String())
+			}
+		})
+	}
+}
@@ -5,11 +5,13 @@
 package webapi
 
 import (
+	"fmt"
 	"time"
 
 	dcrdtypes "github.com/decred/dcrd/rpc/jsonrpc/types/v4"
 	"github.com/decred/dcrd/txscript/v4/stdaddr"
 	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/internal/config"
 	"github.com/decred/vspd/rpc"
 	"github.com/decred/vspd/types/v3"
 	"github.com/gin-gonic/gin"
@@ -29,12 +31,13 @@ type node interface {
 func (w *WebAPI) setAltSignAddr(c *gin.Context) {
 
 	const funcName = "setAltSignAddr"
+	log := w.requestLog(c)
 
 	// Get values which have been added to context by middleware.
 	dcrdClient := c.MustGet(dcrdKey).(node)
 	dcrdErr := c.MustGet(dcrdErrorKey)
 	if dcrdErr != nil {
-		w.log.Errorf("%s: %v", funcName, dcrdErr.(error))
+		log.Errorf("%s: %v", funcName, dcrdErr.(error))
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
@@ -42,7 +45,7 @@ func (w *WebAPI) setAltSignAddr(c *gin.Context) {
 
 	var request types.SetAltSignAddrRequest
 	if err := binding.JSON.BindBody(reqBytes, &request); err != nil {
-		w.log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
+		log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
 		w.sendErrorWithMsg(err.Error(), types.ErrBadRequest, c)
 		return
 	}
@@ -51,13 +54,13 @@ func (w *WebAPI) setAltSignAddr(c *gin.Context) {
 
 	currentData, err := w.db.AltSignAddrData(ticketHash)
 	if err != nil {
-		w.log.Errorf("%s: db.AltSignAddrData (ticketHash=%s): %v", funcName, ticketHash, err)
+		log.Errorf("%s: db.AltSignAddrData (ticketHash=%s): %v", funcName, ticketHash, err)
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
 	if currentData != nil {
 		const msg = "alternate sign address data already exists"
-		w.log.Warnf("%s: %s (ticketHash=%s)", funcName, msg, ticketHash)
+		log.Warnf("%s: %s (ticketHash=%s)", funcName, msg, ticketHash)
 		w.sendErrorWithMsg(msg, types.ErrBadRequest, c)
 		return
 
@@ -66,12 +69,19 @@ func (w *WebAPI) setAltSignAddr(c *gin.Context) {
 	// Fail fast if the pubkey doesn't decode properly.
 	addr, err := stdaddr.DecodeAddressV0(altSignAddr, w.cfg.Network)
 	if err != nil {
-		w.log.Warnf("%s: Alt sign address cannot be decoded (clientIP=%s): %v", funcName, c.ClientIP(), err)
+		if addrNet := config.AddressNetwork(altSignAddr); addrNet != nil {
+			log.Warnf("%s: Alt sign address is for wrong network (clientIP=%s): %s",
+				funcName, c.ClientIP(), addrNet.Name)
+			w.sendErrorWithMsg(fmt.Sprintf("alternate signing address is for %s but vsp is on %s",
+				addrNet.Name, w.cfg.Network.Name), types.ErrWrongNetwork, c)
+			return
+		}
+		log.Warnf("%s: Alt sign address cannot be decoded (clientIP=%s): %v", funcName, c.ClientIP(), err)
 		w.sendErrorWithMsg(err.Error(), types.ErrBadRequest, c)
 		return
 	}
 	if _, ok := addr.(*stdaddr.AddressPubKeyHashEcdsaSecp256k1V0); !ok {
-		w.log.Warnf("%s: Alt sign address is unexpected type (clientIP=%s, type=%T)", funcName, c.ClientIP(), addr)
+		log.Warnf("%s: Alt sign address is unexpected type (clientIP=%s, type=%T)", funcName, c.ClientIP(), addr)
 		w.sendErrorWithMsg("wrong type for alternate signing address", types.ErrBadRequest, c)
 		return
 	}
@@ -79,7 +89,7 @@ func (w *WebAPI) setAltSignAddr(c *gin.Context) {
 	// Get ticket details.
 	rawTicket, err := dcrdClient.GetRawTransaction(ticketHash)
 	if err != nil {
-		w.log.Errorf("%s: dcrd.GetRawTransaction for ticket failed (ticketHash=%s): %v", funcName, ticketHash, err)
+		log.Errorf("%s: dcrd.GetRawTransaction for ticket failed (ticketHash=%s): %v", funcName, ticketHash, err)
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
@@ -87,12 +97,12 @@ func (w *WebAPI) setAltSignAddr(c *gin.Context) {
 	// Ensure this ticket is eligible to vote at some point in the future.
 	canVote, err := canTicketVote(rawTicket, dcrdClient, w.cfg.Network)
 	if err != nil {
-		w.log.Errorf("%s: canTicketVote error (ticketHash=%s): %v", funcName, ticketHash, err)
+		log.Errorf("%s: canTicketVote error (ticketHash=%s): %v", funcName, ticketHash, err)
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
 	if !canVote {
-		w.log.Warnf("%s: unvotable ticket (clientIP=%s, ticketHash=%s)",
+		log.Warnf("%s: unvotable ticket (clientIP=%s, ticketHash=%s)",
 			funcName, c.ClientIP(), ticketHash)
 		w.sendError(types.ErrTicketCannotVote, c)
 		return
@@ -114,10 +124,10 @@ func (w *WebAPI) setAltSignAddr(c *gin.Context) {
 
 	err = w.db.InsertAltSignAddr(ticketHash, data)
 	if err != nil {
-		w.log.Errorf("%s: db.InsertAltSignAddr error (ticketHash=%s): %v",
+		log.Errorf("%s: db.InsertAltSignAddr error (ticketHash=%s): %v",
 			funcName, ticketHash, err)
 		return
 	}
 
-	w.log.Debugf("%s: New alt sign address set for ticket: (ticketHash=%s)", funcName, ticketHash)
+	log.Debugf("%s: New alt sign address set for ticket: (ticketHash=%s)", funcName, ticketHash)
 }
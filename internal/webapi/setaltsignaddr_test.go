@@ -79,7 +79,7 @@ func TestMain(m *testing.M) {
 	}
 
 	// Open the newly created database so it is ready to use.
-	db, err := database.Open(testDb, log, maxVoteChangeRecords)
+	db, err := database.Open(testDb, log, maxVoteChangeRecords, 0)
 	if err != nil {
 		panic(fmt.Errorf("error opening test database: %w", err))
 	}
@@ -171,6 +171,12 @@ func TestSetAltSignAddress(t *testing.T) {
 			wantErrCode:    types.ErrBadRequest,
 			wantErrMsg:     "failed to decode address \"xxx\": invalid format: version and/or checksum bytes missing",
 		},
+		"addr wrong network": {
+			addr:           "TsRfHdBK2EXjR83LJC6tEZ5faaXHM5TwYjr",
+			wantHTTPStatus: http.StatusBadRequest,
+			wantErrCode:    types.ErrWrongNetwork,
+			wantErrMsg:     "alternate signing address is for testnet3 but vsp is on mainnet",
+		},
 		"addr wrong type": {
 			addr:           "DkM3ZigNyiwHrsXRjkDQ8t8tW6uKGW9g61qEkG3bMqQPQWYEf5X3J",
 			wantHTTPStatus: http.StatusBadRequest,
@@ -7,6 +7,7 @@ package webapi
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/decred/vspd/database"
@@ -19,12 +20,14 @@ import (
 // setVoteChoices is the handler for "POST /api/v3/setvotechoices".
 func (w *WebAPI) setVoteChoices(c *gin.Context) {
 	const funcName = "setVoteChoices"
+	log := w.requestLog(c)
 
 	// Get values which have been added to context by middleware.
 	ticket := c.MustGet(ticketKey).(database.Ticket)
 	knownTicket := c.MustGet(knownTicketKey).(bool)
 	walletClients := c.MustGet(walletsKey).([]*rpc.WalletRPC)
 	reqBytes := c.MustGet(requestBytesKey).([]byte)
+	reqSig := c.MustGet(requestSignatureKey).(string)
 
 	// If we cannot set the vote choices on at least one voting wallet right
 	// now, don't update the database, just return an error.
@@ -34,13 +37,13 @@ func (w *WebAPI) setVoteChoices(c *gin.Context) {
 	}
 
 	if !knownTicket {
-		w.log.Warnf("%s: Unknown ticket (clientIP=%s)", funcName, c.ClientIP())
+		log.Warnf("%s: Unknown ticket (clientIP=%s)", funcName, c.ClientIP())
 		w.sendError(types.ErrUnknownTicket, c)
 		return
 	}
 
 	if ticket.FeeTxStatus == database.NoFee {
-		w.log.Warnf("%s: No fee tx for ticket (clientIP=%s, ticketHash=%s)",
+		log.Warnf("%s: No fee tx for ticket (clientIP=%s, ticketHash=%s)",
 			funcName, c.ClientIP(), ticket.Hash)
 		w.sendError(types.ErrFeeNotReceived, c)
 		return
@@ -48,7 +51,7 @@ func (w *WebAPI) setVoteChoices(c *gin.Context) {
 
 	// Only allow vote choices to be updated for mempool/immature/live tickets.
 	if ticket.Outcome != "" {
-		w.log.Warnf("%s: Ticket not eligible to vote (clientIP=%s, ticketHash=%s)",
+		log.Warnf("%s: Ticket not eligible to vote (clientIP=%s, ticketHash=%s)",
 			funcName, c.ClientIP(), ticket.Hash)
 		w.sendErrorWithMsg(fmt.Sprintf("ticket not eligible to vote (status=%s)", ticket.Outcome),
 			types.ErrTicketCannotVote, c)
@@ -57,35 +60,56 @@ func (w *WebAPI) setVoteChoices(c *gin.Context) {
 
 	var request types.SetVoteChoicesRequest
 	if err := binding.JSON.BindBody(reqBytes, &request); err != nil {
-		w.log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
+		log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
 		w.sendErrorWithMsg(err.Error(), types.ErrBadRequest, c)
 		return
 	}
 
+	// Return an error if the timestamp of this request is too far from the
+	// current time, allowing for some clock skew between client and VSP.
+	if !withinClockSkew(request.Timestamp, time.Now(), w.cfg.MaxClockSkew) {
+		log.Warnf("%s: Request timestamp %d outside of allowed clock skew %v (clientIP=%s, ticketHash=%s)",
+			funcName, request.Timestamp, w.cfg.MaxClockSkew, c.ClientIP(), ticket.Hash)
+		w.sendErrorWithMsg(fmt.Sprintf("timestamp differs from vsp time by more than %v",
+			w.cfg.MaxClockSkew), types.ErrInvalidTimestamp, c)
+		return
+	}
+
 	// Return an error if this request has a timestamp older than any previous
 	// vote change requests. This is to prevent requests from being replayed.
 	previousChanges, err := w.db.GetVoteChanges(ticket.Hash)
 	if err != nil {
-		w.log.Errorf("%s: db.GetVoteChanges error (ticketHash=%s): %v",
+		log.Errorf("%s: db.GetVoteChanges error (ticketHash=%s): %v",
 			funcName, ticket.Hash, err)
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
 
+	// The most recent time the vote choices were changed, according to the
+	// clock of the VSP rather than the client.
+	var lastChange int64
+
 	for _, change := range previousChanges {
-		var prevReq struct {
+		var prevReq, prevResp struct {
 			Timestamp int64 `json:"timestamp" binding:"required"`
 		}
 		err := json.Unmarshal([]byte(change.Request), &prevReq)
+		if err == nil {
+			err = json.Unmarshal([]byte(change.Response), &prevResp)
+		}
 		if err != nil {
-			w.log.Errorf("%s: Could not unmarshal vote change record (ticketHash=%s): %v",
+			log.Errorf("%s: Could not unmarshal vote change record (ticketHash=%s): %v",
 				funcName, ticket.Hash, err)
 			w.sendError(types.ErrInternalError, c)
 			return
 		}
 
+		if prevResp.Timestamp > lastChange {
+			lastChange = prevResp.Timestamp
+		}
+
 		if request.Timestamp <= prevReq.Timestamp {
-			w.log.Warnf("%s: Request uses invalid timestamp, %d is not greater "+
+			log.Warnf("%s: Request uses invalid timestamp, %d is not greater "+
 				"than %d (ticketHash=%s)",
 				funcName, request.Timestamp, prevReq.Timestamp, ticket.Hash)
 			w.sendError(types.ErrInvalidTimestamp, c)
@@ -93,28 +117,40 @@ func (w *WebAPI) setVoteChoices(c *gin.Context) {
 		}
 	}
 
+	// Return an error if the vote choices of this ticket were changed too
+	// recently.
+	if wait := voteChangeWait(lastChange, time.Now(), w.cfg.VoteChangeInterval); wait > 0 {
+		log.Warnf("%s: Vote choices changed too recently, %v until next change allowed (clientIP=%s, ticketHash=%s)",
+			funcName, wait, c.ClientIP(), ticket.Hash)
+		w.sendErrorWithMsg(fmt.Sprintf("vote choices can only be changed once every %v, retry in %v",
+			w.cfg.VoteChangeInterval, wait.Round(time.Second)), types.ErrVoteChangeTooSoon, c)
+		return
+	}
+
 	// Validate vote choices (consensus, tspend policy and treasury policy).
 
-	err = validConsensusVoteChoices(w.cfg.Network, w.cfg.Network.CurrentVoteVersion(), request.VoteChoices)
+	err = validConsensusVoteChoices(w.cfg.Network, w.voteVersions(), request.VoteChoices)
 	if err != nil {
-		w.log.Warnf("%s: Invalid consensus vote choices (clientIP=%s, ticketHash=%s): %v",
+		log.Warnf("%s: Invalid consensus vote choices (clientIP=%s, ticketHash=%s): %v",
 			funcName, c.ClientIP(), ticket.Hash, err)
 		w.sendErrorWithMsg(err.Error(), types.ErrInvalidVoteChoices, c)
 		return
 	}
 
-	err = validTreasuryPolicy(request.TreasuryPolicy)
+	treasuryPolicy, err := validTreasuryPolicy(request.TreasuryPolicy)
 	if err != nil {
-		w.log.Warnf("%s: Invalid treasury policy (clientIP=%s, ticketHash=%s): %v",
+		log.Warnf("%s: Invalid treasury policy (clientIP=%s, ticketHash=%s): %v",
 			funcName, c.ClientIP(), ticket.Hash, err)
 		w.sendErrorWithMsg(err.Error(), types.ErrInvalidVoteChoices, c)
+		return
 	}
 
 	err = validTSpendPolicy(request.TSpendPolicy)
 	if err != nil {
-		w.log.Warnf("%s: Invalid tspend policy (clientIP=%s, ticketHash=%s): %v",
+		log.Warnf("%s: Invalid tspend policy (clientIP=%s, ticketHash=%s): %v",
 			funcName, c.ClientIP(), ticket.Hash, err)
 		w.sendErrorWithMsg(err.Error(), types.ErrInvalidVoteChoices, c)
+		return
 	}
 
 	// Update voting preferences in the database before updating the wallets. DB
@@ -128,13 +164,23 @@ func (w *WebAPI) setVoteChoices(c *gin.Context) {
 		ticket.TSpendPolicy[newTSpend] = newChoice
 	}
 
-	for newTreasuryKey, newChoice := range request.TreasuryPolicy {
+	// Treasury keys stored before keys were normalized may not be in canonical
+	// encoding, so remove any which are being replaced by an equivalent key.
+	for key := range ticket.TreasuryPolicy {
+		if canonical := strings.ToLower(key); canonical != key {
+			if _, ok := treasuryPolicy[canonical]; ok {
+				delete(ticket.TreasuryPolicy, key)
+			}
+		}
+	}
+
+	for newTreasuryKey, newChoice := range treasuryPolicy {
 		ticket.TreasuryPolicy[newTreasuryKey] = newChoice
 	}
 
 	err = w.db.UpdateTicket(ticket)
 	if err != nil {
-		w.log.Errorf("%s: db.UpdateTicket error, failed to set consensus vote choices (ticketHash=%s): %v",
+		log.Errorf("%s: db.UpdateTicket error, failed to set consensus vote choices (ticketHash=%s): %v",
 			funcName, ticket.Hash, err)
 		w.sendError(types.ErrInternalError, c)
 		return
@@ -152,7 +198,7 @@ func (w *WebAPI) setVoteChoices(c *gin.Context) {
 			for agenda, choice := range ticket.VoteChoices {
 				err = walletClient.SetVoteChoice(agenda, choice, ticket.Hash)
 				if err != nil {
-					w.log.Errorf("%s: dcrwallet.SetVoteChoice failed (wallet=%s, ticketHash=%s): %v",
+					log.Errorf("%s: dcrwallet.SetVoteChoice failed (wallet=%s, ticketHash=%s): %v",
 						funcName, walletClient.String(), ticket.Hash, err)
 				}
 			}
@@ -161,7 +207,7 @@ func (w *WebAPI) setVoteChoices(c *gin.Context) {
 			for tspend, policy := range ticket.TSpendPolicy {
 				err = walletClient.SetTSpendPolicy(tspend, policy, ticket.Hash)
 				if err != nil {
-					w.log.Errorf("%s: dcrwallet.SetTSpendPolicy failed (wallet=%s, ticketHash=%s): %v",
+					log.Errorf("%s: dcrwallet.SetTSpendPolicy failed (wallet=%s, ticketHash=%s): %v",
 						funcName, walletClient.String(), ticket.Hash, err)
 				}
 			}
@@ -170,14 +216,14 @@ func (w *WebAPI) setVoteChoices(c *gin.Context) {
 			for key, policy := range ticket.TreasuryPolicy {
 				err = walletClient.SetTreasuryPolicy(key, policy, ticket.Hash)
 				if err != nil {
-					w.log.Errorf("%s: dcrwallet.SetTreasuryPolicy failed (wallet=%s, ticketHash=%s): %v",
+					log.Errorf("%s: dcrwallet.SetTreasuryPolicy failed (wallet=%s, ticketHash=%s): %v",
 						funcName, walletClient.String(), ticket.Hash, err)
 				}
 			}
 		}
 	}
 
-	w.log.Debugf("%s: Vote choices updated (ticketHash=%s)", funcName, ticket.Hash)
+	log.Debugf("%s: Vote choices updated (ticketHash=%s)", funcName, ticket.Hash)
 
 	// Send success response to client.
 	resp, respSig := w.sendJSONResponse(types.SetVoteChoicesResponse{
@@ -190,11 +236,11 @@ func (w *WebAPI) setVoteChoices(c *gin.Context) {
 		ticket.Hash,
 		database.VoteChangeRecord{
 			Request:           string(reqBytes),
-			RequestSignature:  c.GetHeader("VSP-Client-Signature"),
+			RequestSignature:  reqSig,
 			Response:          resp,
 			ResponseSignature: respSig,
 		})
 	if err != nil {
-		w.log.Errorf("%s: Failed to store vote change record (ticketHash=%s): %v", err)
+		log.Errorf("%s: Failed to store vote change record (ticketHash=%s): %v", err)
 	}
 }
@@ -0,0 +1,40 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/decred/vspd/types/v3"
+	"github.com/gin-gonic/gin"
+)
+
+// status is the handler for "GET /api/v3/status". It reports the best block
+// seen by the VSP and whether its dcrd instance is synced, so clients can check
+// the VSP is following the same chain before registering tickets.
+func (w *WebAPI) status(c *gin.Context) {
+	cachedStats := c.MustGet(cacheKey).(cacheData)
+
+	w.sendJSONResponse(types.StatusResponse{
+		Timestamp:    time.Now().Unix(),
+		Network:      w.cfg.Network.Name,
+		BlockHeight:  cachedStats.BlockHeight,
+		Synced:       cachedStats.Synced,
+		Initializing: w.initializing(),
+	}, c)
+}
+
+// statusPage is the handler for "GET /status", which is only served if enabled
+// by the VSP operator. It renders the same statistics as the homepage as a
+// minimal page which is easy to check in a browser.
+func (w *WebAPI) statusPage(c *gin.Context) {
+	cachedStats := c.MustGet(cacheKey).(cacheData)
+
+	c.HTML(http.StatusOK, "status.html", gin.H{
+		"WebApiCache": cachedStats,
+		"WebApiCfg":   w.config(),
+	})
+}
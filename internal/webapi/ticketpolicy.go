@@ -0,0 +1,66 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/decred/dcrd/wire"
+)
+
+// TicketPolicy decides whether the VSP will register a new ticket. It is
+// consulted by /feeaddress after the ticket tx has been decoded and validated,
+// giving operators programmable admission control over the tickets they
+// accept.
+type TicketPolicy interface {
+	// CheckTicket returns an error describing why the provided ticket is
+	// rejected, or nil if the ticket is accepted.
+	CheckTicket(ticketTx *wire.MsgTx) error
+}
+
+// TicketRules is a TicketPolicy which rejects tickets matching simple
+// configurable criteria. The zero value accepts all tickets.
+type TicketRules struct {
+	// MaxInputs is the maximum number of inputs a ticket tx may have. Zero
+	// means no limit.
+	MaxInputs int
+	// RejectScripts is a list of byte patterns. Tickets are rejected if any
+	// pattern is found in the signature script of one of their inputs or in
+	// the script of one of their outputs.
+	RejectScripts [][]byte
+}
+
+// CheckTicket implements TicketPolicy.
+func (r TicketRules) CheckTicket(ticketTx *wire.MsgTx) error {
+	if r.MaxInputs > 0 && len(ticketTx.TxIn) > r.MaxInputs {
+		return fmt.Errorf("ticket has %d inputs, exceeding maximum of %d",
+			len(ticketTx.TxIn), r.MaxInputs)
+	}
+
+	for _, pattern := range r.RejectScripts {
+		for i, txIn := range ticketTx.TxIn {
+			if bytes.Contains(txIn.SignatureScript, pattern) {
+				return fmt.Errorf("ticket input %d contains a rejected script pattern", i)
+			}
+		}
+		for i, txOut := range ticketTx.TxOut {
+			if bytes.Contains(txOut.PkScript, pattern) {
+				return fmt.Errorf("ticket output %d contains a rejected script pattern", i)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkTicketPolicy returns an error if the provided ticket is rejected by the
+// ticket policy of the VSP. All tickets are accepted if no policy is set.
+func (w *WebAPI) checkTicketPolicy(ticketTx *wire.MsgTx) error {
+	if w.cfg.TicketPolicy == nil {
+		return nil
+	}
+	return w.cfg.TicketPolicy.CheckTicket(ticketTx)
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+)
+
+// TestTicketRules ensures tickets are only rejected when they match the
+// configured criteria.
+func TestTicketRules(t *testing.T) {
+	ticketTx := wire.NewMsgTx()
+	for i := 0; i < 3; i++ {
+		prevOut := wire.NewOutPoint(&chainhash.Hash{}, uint32(i), wire.TxTreeRegular)
+		ticketTx.AddTxIn(wire.NewTxIn(prevOut, 0, []byte{0x01, 0x02, 0x03}))
+	}
+	ticketTx.AddTxOut(&wire.TxOut{PkScript: []byte{0xba, 0x76, 0xa9}})
+
+	tests := map[string]struct {
+		rules     TicketRules
+		expectErr bool
+	}{
+		"zero value accepts all": {
+			rules: TicketRules{},
+		},
+		"inputs within limit": {
+			rules: TicketRules{MaxInputs: 3},
+		},
+		"too many inputs": {
+			rules:     TicketRules{MaxInputs: 2},
+			expectErr: true,
+		},
+		"pattern not present": {
+			rules: TicketRules{RejectScripts: [][]byte{{0x03, 0x02}}},
+		},
+		"pattern in input": {
+			rules:     TicketRules{RejectScripts: [][]byte{{0x02, 0x03}}},
+			expectErr: true,
+		},
+		"pattern in output": {
+			rules:     TicketRules{RejectScripts: [][]byte{{0x76, 0xa9}}},
+			expectErr: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			err := test.rules.CheckTicket(ticketTx)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error=%t, got %v", test.expectErr, err)
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@ import (
 // ticketStatus is the handler for "POST /api/v3/ticketstatus".
 func (w *WebAPI) ticketStatus(c *gin.Context) {
 	const funcName = "ticketStatus"
+	log := w.requestLog(c)
 
 	// Get values which have been added to context by middleware.
 	ticket := c.MustGet(ticketKey).(database.Ticket)
@@ -23,14 +24,14 @@ func (w *WebAPI) ticketStatus(c *gin.Context) {
 	reqBytes := c.MustGet(requestBytesKey).([]byte)
 
 	if !knownTicket {
-		w.log.Warnf("%s: Unknown ticket (clientIP=%s)", funcName, c.ClientIP())
+		log.Warnf("%s: Unknown ticket (clientIP=%s)", funcName, c.ClientIP())
 		w.sendError(types.ErrUnknownTicket, c)
 		return
 	}
 
 	var request types.TicketStatusRequest
 	if err := binding.JSON.BindBody(reqBytes, &request); err != nil {
-		w.log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
+		log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
 		w.sendErrorWithMsg(err.Error(), types.ErrBadRequest, c)
 		return
 	}
@@ -38,7 +39,7 @@ func (w *WebAPI) ticketStatus(c *gin.Context) {
 	// Get altSignAddress from database
 	altSignAddrData, err := w.db.AltSignAddrData(ticket.Hash)
 	if err != nil {
-		w.log.Errorf("%s: db.AltSignAddrData error (ticketHash=%s): %v", funcName, ticket.Hash, err)
+		log.Errorf("%s: db.AltSignAddrData error (ticketHash=%s): %v", funcName, ticket.Hash, err)
 		w.sendError(types.ErrInternalError, c)
 		return
 	}
@@ -48,8 +49,11 @@ func (w *WebAPI) ticketStatus(c *gin.Context) {
 		altSignAddr = altSignAddrData.AltSignAddr
 	}
 
-	w.sendJSONResponse(types.TicketStatusResponse{
-		Timestamp:       time.Now().Unix(),
+	cfg := w.config()
+	now := time.Now()
+	resp := types.TicketStatusResponse{
+		Timestamp:       now.Unix(),
+		ValidUntil:      validUntil(now, w.cfg.TicketStatusValidity),
 		Request:         reqBytes,
 		TicketConfirmed: ticket.Confirmed,
 		FeeTxStatus:     string(ticket.FeeTxStatus),
@@ -58,5 +62,24 @@ func (w *WebAPI) ticketStatus(c *gin.Context) {
 		VoteChoices:     ticket.VoteChoices,
 		TreasuryPolicy:  ticket.TreasuryPolicy,
 		TSpendPolicy:    ticket.TSpendPolicy,
-	}, c)
+		Outcome:         string(ticket.Outcome),
+		Revoked:         ticket.Revoked(),
+		VspClosed:       cfg.VspClosed,
+		VspClosedMsg:    cfg.VspClosedMsg,
+	}
+
+	if ticket.Revoked() {
+		resp.RevocationTxHash = ticket.SpendingTxHash
+	}
+
+	// If no fee has been paid yet, include the details of the outstanding fee
+	// so the client is able to resume an interrupted registration.
+	if ticket.FeeTxStatus == database.NoFee {
+		resp.FeeAddress = ticket.FeeAddress
+		resp.FeeAmount = ticket.FeeAmount
+		resp.FeeExpiration = ticket.FeeExpiration
+		resp.FeeDeadlineHeight = w.feeDeadline()
+	}
+
+	w.sendJSONResponse(resp, c)
 }
@@ -0,0 +1,130 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/types/v3"
+	"github.com/gin-gonic/gin"
+)
+
+// TestTicketStatusOutstandingFee ensures /ticketstatus includes the details of
+// the outstanding fee for tickets which have not paid a fee yet, and omits them
+// once a fee tx has been received.
+func TestTicketStatusOutstandingFee(t *testing.T) {
+	tests := map[string]struct {
+		feeTxStatus          database.FeeStatus
+		feeDeadlineBlocks    int64
+		expectOutstanding    bool
+		expectDeadlineHeight int64
+	}{
+		"no fee": {
+			feeTxStatus:       database.NoFee,
+			expectOutstanding: true,
+		},
+		"no fee with deadline": {
+			feeTxStatus:          database.NoFee,
+			feeDeadlineBlocks:    10,
+			expectOutstanding:    true,
+			expectDeadlineHeight: 110,
+		},
+		"fee received": {
+			feeTxStatus:       database.FeeReceieved,
+			feeDeadlineBlocks: 10,
+		},
+		"fee confirmed": {
+			feeTxStatus: database.FeeConfirmed,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			ticket := database.Ticket{
+				Hash:              randString(64, hexCharset),
+				CommitmentAddress: randString(35, hexCharset),
+				FeeAddress:        randString(35, hexCharset),
+				FeeAmount:         1e6,
+				FeeExpiration:     time.Now().Add(time.Hour).Unix(),
+				FeeTxStatus:       test.feeTxStatus,
+			}
+			err := api.db.InsertNewTicket(ticket)
+			if err != nil {
+				t.Fatalf("error storing ticket in db: %v", err)
+			}
+
+			w := &WebAPI{
+				cfg:         Config{FeeDeadlineBlocks: test.feeDeadlineBlocks},
+				signPrivKey: api.signPrivKey,
+				db:          api.db,
+				log:         api.log,
+				cache: &cache{data: cacheData{
+					Initialized: true,
+					BlockHeight: 100,
+				}},
+			}
+
+			reqBytes, err := json.Marshal(types.TicketStatusRequest{
+				TicketHash: ticket.Hash,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rec := httptest.NewRecorder()
+			_, r := gin.CreateTestContext(rec)
+			r.POST("/", func(c *gin.Context) {
+				c.Set(ticketKey, ticket)
+				c.Set(knownTicketKey, true)
+				c.Set(requestBytesKey, reqBytes)
+				w.ticketStatus(c)
+			})
+
+			req, err := http.NewRequest(http.MethodPost, "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s",
+					http.StatusOK, rec.Code, rec.Body.String())
+			}
+
+			var resp types.TicketStatusResponse
+			err = json.Unmarshal(rec.Body.Bytes(), &resp)
+			if err != nil {
+				t.Fatalf("could not unmarshal response: %v", err)
+			}
+
+			expect := types.TicketStatusResponse{}
+			if test.expectOutstanding {
+				expect.FeeAddress = ticket.FeeAddress
+				expect.FeeAmount = ticket.FeeAmount
+				expect.FeeExpiration = ticket.FeeExpiration
+				expect.FeeDeadlineHeight = test.expectDeadlineHeight
+			}
+			if resp.FeeAddress != expect.FeeAddress {
+				t.Fatalf("expected fee address %q, got %q", expect.FeeAddress, resp.FeeAddress)
+			}
+			if resp.FeeAmount != expect.FeeAmount {
+				t.Fatalf("expected fee amount %d, got %d", expect.FeeAmount, resp.FeeAmount)
+			}
+			if resp.FeeExpiration != expect.FeeExpiration {
+				t.Fatalf("expected fee expiration %d, got %d",
+					expect.FeeExpiration, resp.FeeExpiration)
+			}
+			if resp.FeeDeadlineHeight != expect.FeeDeadlineHeight {
+				t.Fatalf("expected fee deadline height %d, got %d",
+					expect.FeeDeadlineHeight, resp.FeeDeadlineHeight)
+			}
+		})
+	}
+}
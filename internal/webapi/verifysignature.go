@@ -0,0 +1,64 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/decred/vspd/types/v3"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// verifySignature is the handler for "POST /api/v3/verifysignature". It allows
+// clients to check whether a response body and signature previously returned
+// by the VSP verify against the current signing key.
+func (w *WebAPI) verifySignature(c *gin.Context) {
+	const funcName = "verifySignature"
+	log := w.requestLog(c)
+
+	reqBytes, err := drainAndReplaceBody(c.Request)
+	if err != nil {
+		log.Warnf("%s: Error reading request (clientIP=%s): %v", funcName, c.ClientIP(), err)
+		w.sendErrorWithMsg(err.Error(), types.ErrBadRequest, c)
+		return
+	}
+
+	var request types.VerifySignatureRequest
+	if err := binding.JSON.BindBody(reqBytes, &request); err != nil {
+		log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
+		w.sendErrorWithMsg(err.Error(), types.ErrBadRequest, c)
+		return
+	}
+
+	valid, err := verifyResponseSignature(w.signPubKey, []byte(request.Response), request.Signature)
+	if err != nil {
+		log.Warnf("%s: Bad request (clientIP=%s): %v", funcName, c.ClientIP(), err)
+		w.sendErrorWithMsg(err.Error(), types.ErrBadRequest, c)
+		return
+	}
+
+	w.sendJSONResponse(types.VerifySignatureResponse{
+		Timestamp: time.Now().Unix(),
+		Valid:     valid,
+		PubKey:    w.signPubKey,
+		Request:   reqBytes,
+	}, c)
+}
+
+// verifyResponseSignature reports whether the provided base64 encoded
+// signature is a valid signature of body by pubKey. An error is returned if the
+// signature cannot be decoded.
+func verifyResponseSignature(pubKey ed25519.PublicKey, body []byte, sigBase64 string) (bool, error) {
+	sig, err := base64.StdEncoding.DecodeString(sigBase64)
+	if err != nil {
+		return false, fmt.Errorf("failed to decode signature: %w", err)
+	}
+
+	return ed25519.Verify(pubKey, body, sig), nil
+}
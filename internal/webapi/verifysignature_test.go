@@ -0,0 +1,79 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+)
+
+// TestVerifyResponseSignature ensures signatures created with the VSP signing
+// key are verified, and that any modification causes verification to fail.
+func TestVerifyResponseSignature(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	otherPubKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	body := []byte(`{"timestamp":1590509066}`)
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(privKey, body))
+
+	tests := map[string]struct {
+		pubKey    ed25519.PublicKey
+		body      []byte
+		sig       string
+		expect    bool
+		expectErr bool
+	}{
+		"valid signature": {
+			pubKey: pubKey,
+			body:   body,
+			sig:    sig,
+			expect: true,
+		},
+		"modified body": {
+			pubKey: pubKey,
+			body:   []byte(`{"timestamp":1590509067}`),
+			sig:    sig,
+			expect: false,
+		},
+		"different key": {
+			pubKey: otherPubKey,
+			body:   body,
+			sig:    sig,
+			expect: false,
+		},
+		"signature not base64": {
+			pubKey:    pubKey,
+			body:      body,
+			sig:       "not base64!",
+			expectErr: true,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			valid, err := verifyResponseSignature(test.pubKey, test.body, test.sig)
+			if test.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if valid != test.expect {
+				t.Fatalf("expected valid=%t, got %t", test.expect, valid)
+			}
+		})
+	}
+}
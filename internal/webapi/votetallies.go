@@ -0,0 +1,62 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/vspd/types/v3"
+	"github.com/gin-gonic/gin"
+)
+
+// voteTallies is the handler for "GET /api/v3/votetallies". It reports how the
+// tickets currently voting with the VSP will vote on the current agendas.
+func (w *WebAPI) voteTallies(c *gin.Context) {
+	cachedStats := c.MustGet(cacheKey).(cacheData)
+
+	voteVersion := w.cfg.Network.CurrentVoteVersion()
+
+	w.sendJSONResponse(types.VoteTalliesResponse{
+		Timestamp:   time.Now().Unix(),
+		VoteVersion: voteVersion,
+		Agendas: agendaTallies(w.cfg.Network.Deployments[voteVersion],
+			cachedStats.VoteChoiceCounts, cachedStats.Voting),
+	}, c)
+}
+
+// agendaTallies counts the choices of voting tickets for each of the provided
+// agendas. Tickets which have not set a choice for an agenda will abstain, so
+// they are included in the abstain count for that agenda.
+func agendaTallies(deployments []chaincfg.ConsensusDeployment,
+	counts map[string]map[string]int64, voting int64) []types.AgendaTally {
+
+	tallies := make([]types.AgendaTally, 0, len(deployments))
+	for _, deployment := range deployments {
+		agenda := deployment.Vote
+		choices := make(map[string]int64, len(agenda.Choices))
+
+		var set int64
+		for _, choice := range agenda.Choices {
+			count := counts[agenda.Id][choice.Id]
+			choices[choice.Id] = count
+			set += count
+		}
+
+		for _, choice := range agenda.Choices {
+			if choice.IsAbstain && voting > set {
+				choices[choice.Id] += voting - set
+				break
+			}
+		}
+
+		tallies = append(tallies, types.AgendaTally{
+			AgendaID: agenda.Id,
+			Choices:  choices,
+		})
+	}
+
+	return tallies
+}
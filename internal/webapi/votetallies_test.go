@@ -0,0 +1,91 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/vspd/types/v3"
+)
+
+func TestAgendaTallies(t *testing.T) {
+	deployments := []chaincfg.ConsensusDeployment{{
+		Vote: chaincfg.Vote{
+			Id: "agenda1",
+			Choices: []chaincfg.Choice{
+				{Id: "abstain", IsAbstain: true},
+				{Id: "no", IsNo: true},
+				{Id: "yes"},
+			},
+		},
+	}, {
+		Vote: chaincfg.Vote{
+			Id: "agenda2",
+			Choices: []chaincfg.Choice{
+				{Id: "abstain", IsAbstain: true},
+				{Id: "no", IsNo: true},
+				{Id: "yes"},
+			},
+		},
+	}}
+
+	tests := map[string]struct {
+		counts map[string]map[string]int64
+		voting int64
+		expect []types.AgendaTally
+	}{
+		"no voting tickets": {
+			counts: nil,
+			voting: 0,
+			expect: []types.AgendaTally{
+				{AgendaID: "agenda1", Choices: map[string]int64{"abstain": 0, "no": 0, "yes": 0}},
+				{AgendaID: "agenda2", Choices: map[string]int64{"abstain": 0, "no": 0, "yes": 0}},
+			},
+		},
+		"unset choices abstain": {
+			counts: map[string]map[string]int64{
+				"agenda1": {"yes": 3, "no": 1},
+			},
+			voting: 5,
+			expect: []types.AgendaTally{
+				{AgendaID: "agenda1", Choices: map[string]int64{"abstain": 1, "no": 1, "yes": 3}},
+				{AgendaID: "agenda2", Choices: map[string]int64{"abstain": 5, "no": 0, "yes": 0}},
+			},
+		},
+		"explicit abstain": {
+			counts: map[string]map[string]int64{
+				"agenda1": {"abstain": 2, "yes": 1},
+				"agenda2": {"no": 3},
+			},
+			voting: 3,
+			expect: []types.AgendaTally{
+				{AgendaID: "agenda1", Choices: map[string]int64{"abstain": 2, "no": 0, "yes": 1}},
+				{AgendaID: "agenda2", Choices: map[string]int64{"abstain": 0, "no": 3, "yes": 0}},
+			},
+		},
+		"unknown agendas and choices ignored": {
+			counts: map[string]map[string]int64{
+				"agenda1": {"maybe": 2, "yes": 1},
+				"agenda3": {"yes": 2},
+			},
+			voting: 3,
+			expect: []types.AgendaTally{
+				{AgendaID: "agenda1", Choices: map[string]int64{"abstain": 2, "no": 0, "yes": 1}},
+				{AgendaID: "agenda2", Choices: map[string]int64{"abstain": 3, "no": 0, "yes": 0}},
+			},
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := agendaTallies(deployments, test.counts, test.voting)
+			if !reflect.DeepEqual(actual, test.expect) {
+				t.Fatalf("expected %+v, got %+v", test.expect, actual)
+			}
+		})
+	}
+}
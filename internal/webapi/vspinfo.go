@@ -12,26 +12,76 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// apiVersions are the versions of the API supported by the VSP.
+var apiVersions = []int64{3}
+
+// apiVersionFees returns the fees charged to clients using each supported API
+// version. All versions currently share the same fee model.
+func apiVersionFees(feePercentage, priorityFeePercentage float64) []types.APIVersionFee {
+	fees := make([]types.APIVersionFee, 0, len(apiVersions))
+	for _, v := range apiVersions {
+		fees = append(fees, types.APIVersionFee{
+			APIVersion:            v,
+			FeePercentage:         feePercentage,
+			PriorityFeePercentage: priorityFeePercentage,
+		})
+	}
+	return fees
+}
+
 // vspInfo is the handler for "GET /api/v3/vspinfo".
 func (w *WebAPI) vspInfo(c *gin.Context) {
 	cachedStats := c.MustGet(cacheKey).(cacheData)
 
+	// Report the VSP as closed while it is at capacity, unless the operator has
+	// closed it anyway.
+	cfg := w.config()
+	vspClosed := cfg.VspClosed
+	vspClosedMsg := cfg.VspClosedMsg
+	if !vspClosed && w.atCapacity() {
+		vspClosed = true
+		vspClosedMsg = vspAtCapacityMsg
+	}
+
+	// The priority fee is a premium charged on top of the current fee.
+	var priorityFee float64
+	if w.cfg.PriorityFee > 0 {
+		priorityFee = cachedStats.FeePercentage + w.cfg.PriorityFee
+	}
+
+	var versionFees []types.APIVersionFee
+	if w.cfg.APIVersionFees {
+		versionFees = apiVersionFees(cachedStats.FeePercentage, priorityFee)
+	}
+
+	now := time.Now()
 	w.sendJSONResponse(types.VspInfoResponse{
-		APIVersions:         []int64{3},
-		Timestamp:           time.Now().Unix(),
-		PubKey:              w.signPubKey,
-		FeePercentage:       w.cfg.VSPFee,
-		Network:             w.cfg.Network.Name,
-		VspClosed:           w.cfg.VspClosed,
-		VspClosedMsg:        w.cfg.VspClosedMsg,
-		VspdVersion:         version.String(),
-		Voting:              cachedStats.Voting,
-		Voted:               cachedStats.Voted,
-		TotalVotingWallets:  cachedStats.TotalVotingWallets,
-		VotingWalletsOnline: cachedStats.VotingWalletsOnline,
-		Expired:             cachedStats.Expired,
-		Missed:              cachedStats.Missed,
-		BlockHeight:         cachedStats.BlockHeight,
-		NetworkProportion:   cachedStats.NetworkProportion,
+		APIVersions:           apiVersions,
+		Timestamp:             now.Unix(),
+		ValidUntil:            validUntil(now, w.cfg.VspInfoValidity),
+		PubKey:                w.signPubKey,
+		FeePercentage:         cachedStats.FeePercentage,
+		PriorityFeePercentage: priorityFee,
+		FeeConfirmations:      w.cfg.FeeConfirmations,
+		Network:               w.cfg.Network.Name,
+		VspClosed:             vspClosed,
+		VspClosedMsg:          vspClosedMsg,
+		VspdVersion:           version.String(),
+		BuildCommit:           version.Commit(),
+		BuildDate:             version.BuildDate(),
+		Voting:                cachedStats.Voting,
+		Voted:                 cachedStats.Voted,
+		TotalVotingWallets:    cachedStats.TotalVotingWallets,
+		VotingWalletsOnline:   cachedStats.VotingWalletsOnline,
+		Expired:               cachedStats.Expired,
+		Missed:                cachedStats.Missed,
+		BlockHeight:           cachedStats.BlockHeight,
+		NetworkProportion:     cachedStats.NetworkProportion,
+		ExpiredProportion:     cachedStats.ExpiredProportion,
+		MissedProportion:      cachedStats.MissedProportion,
+		StatsUpdated:          cachedStats.LastUpdated,
+		StatsStale:            statsStale(cachedStats.LastUpdated, now, w.cfg.StatsMaxAge),
+		Initializing:          w.initializing(),
+		APIVersionFees:        versionFees,
 	}, c)
 }
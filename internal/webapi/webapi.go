@@ -7,19 +7,27 @@ package webapi
 import (
 	"context"
 	"crypto/ed25519"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"net"
 	"net/http"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/decred/dcrd/dcrutil/v4"
 	"github.com/decred/slog"
 	"github.com/decred/vspd/database"
+	"github.com/decred/vspd/internal/broadcast"
 	"github.com/decred/vspd/internal/config"
+	"github.com/decred/vspd/internal/events"
+	"github.com/decred/vspd/internal/metrics"
 	"github.com/decred/vspd/rpc"
 	"github.com/decred/vspd/types/v3"
 	"github.com/dustin/go-humanize"
@@ -28,27 +36,90 @@ import (
 )
 
 type Config struct {
-	Listen               string
-	VSPFee               float64
-	Network              *config.Network
-	FeeAccountName       string
-	SupportEmail         string
-	VspClosed            bool
-	VspClosedMsg         string
-	AdminPass            string
-	Debug                bool
-	Designation          string
-	MaxVoteChangeRecords int
-	VspdVersion          string
+	Listen                 string
+	ReadTimeout            time.Duration
+	ReadHeaderTimeout      time.Duration
+	WriteTimeout           time.Duration
+	IdleTimeout            time.Duration
+	VSPFee                 float64
+	FeeSchedule            config.FeeSchedule
+	PriorityFee            float64
+	NominalFee             dcrutil.Amount
+	Network                *config.Network
+	FeeAccountName         string
+	SupportEmail           string
+	StatusPage             bool
+	OpenAPISpec            bool
+	VspClosed              bool
+	VspClosedMsg           string
+	AdminPass              string
+	ReadOnlyPass           string
+	Debug                  bool
+	Designation            string
+	MaxVoteChangeRecords   int
+	MaxTicketsPerAddress   int
+	MaxActiveTickets       int
+	MinTicketPrice         dcrutil.Amount
+	MaxTicketPrice         dcrutil.Amount
+	FeeConfirmations       int64
+	MaxFeeTxSize           int
+	RequireStandardFeeTx   bool
+	StandardFeeTxInputs    bool
+	RequireFinalFeeTx      bool
+	RejectImmatureCoinbase bool
+	CheckExistingFeeTx     bool
+	MaxFeeTxNullData       int
+	TicketFeeLimitCheck    string
+	TicketPolicy           TicketPolicy
+	RecomputeFee           bool
+	APIVersionFees         bool
+	RecordFeeSurplus       bool
+	LogSampleRate          int
+	AuditLog               io.Writer
+	FeeReservationTimeout  time.Duration
+	FeeQuoteMinValidity    time.Duration
+	FeeDeadlineBlocks      int64
+	AllowDeferredBroadcast bool
+	VspInfoValidity        time.Duration
+	StatsMaxAge            time.Duration
+	PayFeeValidity         time.Duration
+	TicketStatusValidity   time.Duration
+	DisabledEndpoints      []string
+	RequestTimeout         time.Duration
+	EndpointTimeouts       map[string]time.Duration
+	MaxClockSkew           time.Duration
+	VoteChangeInterval     time.Duration
+	WalletLagThreshold     int64
+	DiskSpaceDir           string
+	LowDiskSpace           uint64
+	LowDiskMaintenance     bool
+	RejectUntilReady       bool
+	AdminListen            string
+	AdminTLSCert           string
+	AdminTLSKey            string
+	AdminClientCA          string
+	RecycleFeeAddresses    bool
+	ReissueRetiredFeeAddrs bool
+	VspdVersion            string
 }
 
+// Behaviors when a ticket declares a fee limit lower than the VSP fee.
+const (
+	feeLimitCheckOff    = "off"
+	feeLimitCheckWarn   = "warn"
+	feeLimitCheckReject = "reject"
+)
+
 const (
 	// requiredConfs is the number of confirmations required to consider a
 	// ticket purchase or a fee transaction to be final.
 	requiredConfs = 6
-	// feeAddressExpiration is the length of time a fee returned by /feeaddress
-	// remains valid. After this time, a new fee must be requested.
-	feeAddressExpiration = 1 * time.Hour
+	// vspAtCapacityMsg is returned to clients when the VSP is not accepting new
+	// tickets because it has reached its configured maximum.
+	vspAtCapacityMsg = "vsp is at capacity and not accepting new tickets"
+	// scriptHashTicketMsg is returned to clients which try to register a
+	// ticket whose voting rights are held by a script hash.
+	scriptHashTicketMsg = "ticket voting rights are held by a script hash, which is not supported"
 )
 
 // Hard-coded keys used for storing values in the web context.
@@ -64,22 +135,54 @@ const (
 	ticketKey            = "Ticket"
 	knownTicketKey       = "KnownTicket"
 	commitmentAddressKey = "CommitmentAddress"
+	requestSignatureKey  = "RequestSignature"
+	votingKeyAuthKey     = "VotingKeyAuth"
+	requestIDKey         = "RequestID"
+	ticketHashKey        = "TicketHash"
 )
 
 type WebAPI struct {
+	// cfg is the config of the server. reloadMtx must be held to write the
+	// options in ReloadableConfig, and those options must be read via config.
 	cfg         Config
+	reloadMtx   sync.RWMutex
 	db          *database.VspDatabase
 	log         slog.Logger
 	addrGen     *addressGenerator
 	cache       *cache
+	broadcaster broadcast.Broadcaster
+	metrics     *metrics.Registry
+	events      *events.Publisher
+	logSampler  *logSampler
 	signPrivKey ed25519.PrivateKey
 	signPubKey  ed25519.PublicKey
 	server      *http.Server
 	listener    net.Listener
+
+	// adminServer and adminListener serve the admin TLS listener, if it has
+	// been enabled by config.
+	adminServer   *http.Server
+	adminListener net.Listener
+
+	// disabledEndpoints contains the names of API endpoints which have been
+	// disabled by config, eg. "setvotechoices".
+	disabledEndpoints map[string]struct{}
+
+	// openAPISpec is the OpenAPI spec served by /api/v3/openapi.json, if it
+	// has been enabled by config.
+	openAPISpec []byte
+
+	// maintenance is true while the VSP is in maintenance mode, in which case
+	// requests to endpoints which write to the database are rejected.
+	maintenance atomic.Bool
+
+	// ready is set once startup has completed. See isReady.
+	ready atomic.Bool
 }
 
 func New(vdb *database.VspDatabase, log slog.Logger, dcrd rpc.DcrdConnect,
-	wallets rpc.WalletConnect, cfg Config) (*WebAPI, error) {
+	wallets rpc.WalletConnect, broadcaster broadcast.Broadcaster, registry *metrics.Registry,
+	events *events.Publisher, cfg Config) (*WebAPI, error) {
 
 	// Get keys for signing API responses from the database.
 	signPrivKey, signPubKey, err := vdb.KeyPair()
@@ -89,7 +192,8 @@ func New(vdb *database.VspDatabase, log slog.Logger, dcrd rpc.DcrdConnect,
 
 	// Populate cached VSP stats before starting webserver.
 	encodedPubKey := base64.StdEncoding.EncodeToString(signPubKey)
-	cache := newCache(encodedPubKey, log, vdb, dcrd, wallets)
+	cache := newCache(encodedPubKey, cfg.VSPFee, cfg.FeeSchedule, log, vdb, dcrd, wallets,
+		registry)
 	err = cache.update()
 	if err != nil {
 		log.Errorf("Could not initialize VSP stats cache: %v", err)
@@ -114,27 +218,92 @@ func New(vdb *database.VspDatabase, log slog.Logger, dcrd rpc.DcrdConnect,
 		return nil, fmt.Errorf("db.GetCookieSecret error: %w", err)
 	}
 
-	// Create TCP listener.
-	listener, err := net.Listen("tcp", cfg.Listen)
+	disabledEndpoints := make(map[string]struct{}, len(cfg.DisabledEndpoints))
+	for _, endpoint := range cfg.DisabledEndpoints {
+		disabledEndpoints[endpoint] = struct{}{}
+	}
+
+	w := &WebAPI{
+		cfg:               cfg,
+		db:                vdb,
+		log:               log,
+		addrGen:           addrGen,
+		cache:             cache,
+		broadcaster:       broadcaster,
+		metrics:           registry,
+		events:            events,
+		logSampler:        newLogSampler(cfg.LogSampleRate),
+		signPrivKey:       signPrivKey,
+		signPubKey:        signPubKey,
+		disabledEndpoints: disabledEndpoints,
+	}
+
+	// Check free disk space before accepting any requests, so maintenance
+	// mode is entered immediately if disk space is already low.
+	if cfg.LowDiskSpace > 0 {
+		w.checkDiskSpace()
+	}
+
+	router := w.router(cookieSecret, dcrd, wallets)
+
+	// Ensure only endpoints which actually exist have been disabled or given
+	// timeouts, so typos in config are not silently ignored.
+	err = validateEndpoints("disableendpoints", cfg.DisabledEndpoints, router.Routes())
+	if err != nil {
+		return nil, err
+	}
+	timeoutEndpoints := make([]string, 0, len(cfg.EndpointTimeouts))
+	for endpoint := range cfg.EndpointTimeouts {
+		timeoutEndpoints = append(timeoutEndpoints, endpoint)
+	}
+	sort.Strings(timeoutEndpoints)
+	err = validateEndpoints("endpointtimeouts", timeoutEndpoints, router.Routes())
 	if err != nil {
 		return nil, err
 	}
 
-	w := &WebAPI{
-		cfg:         cfg,
-		db:          vdb,
-		log:         log,
-		addrGen:     addrGen,
-		cache:       cache,
-		signPrivKey: signPrivKey,
-		signPubKey:  signPubKey,
-		listener:    listener,
+	if cfg.OpenAPISpec {
+		w.openAPISpec, err = buildOpenAPISpec(router.Routes())
+		if err != nil {
+			return nil, fmt.Errorf("failed to build OpenAPI spec: %w", err)
+		}
+	}
+
+	// Create TCP listener.
+	w.listener, err = net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return nil, err
 	}
 
 	w.server = &http.Server{
-		Handler:      w.router(cookieSecret, dcrd, wallets),
-		ReadTimeout:  5 * time.Second,  // slow requests should not hold connections opened
-		WriteTimeout: 60 * time.Second, // hung responses must die
+		Handler:           router,
+		ReadTimeout:       cfg.ReadTimeout,       // slow requests should not hold connections opened
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout, // nor should slow headers
+		WriteTimeout:      cfg.WriteTimeout,      // hung responses must die
+		IdleTimeout:       cfg.IdleTimeout,       // idle keep-alive connections are closed
+	}
+
+	// Create TLS listener for admin access with client certificates.
+	if cfg.AdminListen != "" {
+		tlsCfg, err := adminTLSConfig(cfg.AdminTLSCert, cfg.AdminTLSKey, cfg.AdminClientCA)
+		if err != nil {
+			w.listener.Close()
+			return nil, err
+		}
+
+		w.adminListener, err = tls.Listen("tcp", cfg.AdminListen, tlsCfg)
+		if err != nil {
+			w.listener.Close()
+			return nil, err
+		}
+
+		w.adminServer = &http.Server{
+			Handler:           router,
+			ReadTimeout:       cfg.ReadTimeout,
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			WriteTimeout:      cfg.WriteTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+		}
 	}
 
 	return w, nil
@@ -151,6 +320,9 @@ func (w *WebAPI) Run(ctx context.Context) {
 
 		w.log.Debug("Stopping webserver...")
 		_ = w.server.Shutdown(ctx)
+		if w.adminServer != nil {
+			_ = w.adminServer.Shutdown(ctx)
+		}
 		w.log.Debug("Webserver stopped")
 
 		wg.Done()
@@ -169,6 +341,28 @@ func (w *WebAPI) Run(ctx context.Context) {
 		wg.Done()
 	}()
 
+	// Start admin TLS webserver if enabled.
+	if w.adminServer != nil {
+		wg.Add(1)
+		go func() {
+			w.log.Infof("Listening for admin connections on %s", w.adminListener.Addr())
+			err := w.adminServer.Serve(w.adminListener)
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				w.log.Errorf("Unexpected admin webserver error: %v", err)
+			}
+			wg.Done()
+		}()
+	}
+
+	// Periodically check free disk space if enabled.
+	if w.cfg.LowDiskSpace > 0 {
+		wg.Add(1)
+		go func() {
+			w.monitorDiskSpace(ctx)
+			wg.Done()
+		}()
+	}
+
 	// Periodically update cached VSP stats.
 	wg.Add(1)
 	go func() {
@@ -225,6 +419,13 @@ func (w *WebAPI) router(cookieSecret []byte, dcrd rpc.DcrdConnect, wallets rpc.W
 	// sending no response at all.
 	router.Use(recovery(w.log))
 
+	// Assign an ID to every request so that all of the log lines for a single
+	// request can be correlated.
+	router.Use(w.withRequestID)
+
+	// Count every request and its response status.
+	router.Use(w.countRequest)
+
 	if w.cfg.Debug {
 		// Logger middleware outputs very detailed logging of webserver requests
 		// to the terminal. Does not get logged to file.
@@ -239,36 +440,52 @@ func (w *WebAPI) router(cookieSecret []byte, dcrd rpc.DcrdConnect, wallets rpc.W
 
 	// API routes.
 
-	api := router.Group("/api/v3")
+	api := router.Group("/api/v3", w.auditRequest, w.endpointEnabled, w.withRequestTimeout)
 	api.GET("/vspinfo", w.requireWebCache, w.vspInfo)
-	api.POST("/setaltsignaddr", w.vspMustBeOpen, w.withDcrdClient(dcrd), w.broadcastTicket, w.vspAuth, w.setAltSignAddr)
-	api.POST("/feeaddress", w.vspMustBeOpen, w.withDcrdClient(dcrd), w.broadcastTicket, w.vspAuth, w.feeAddress)
+	api.GET("/votetallies", w.requireWebCache, w.voteTallies)
+	api.GET("/status", w.requireWebCache, w.status)
+	api.POST("/setaltsignaddr", w.vspMustBeOpen, w.requireReady, w.notInMaintenance, w.withDcrdClient(dcrd), w.broadcastTicket, w.vspAuth, w.setAltSignAddr)
+	api.POST("/feeaddress", w.vspMustBeOpen, w.requireReady, w.notInMaintenance, w.withDcrdClient(dcrd), w.broadcastTicket, w.vspAuth, w.feeAddress)
 	api.POST("/ticketstatus", w.withDcrdClient(dcrd), w.vspAuth, w.ticketStatus)
-	api.POST("/payfee", w.vspMustBeOpen, w.withDcrdClient(dcrd), w.vspAuth, w.payFee)
-	api.POST("/setvotechoices", w.withDcrdClient(dcrd), w.withWalletClients(wallets), w.vspAuth, w.setVoteChoices)
+	api.POST("/feetxtemplate", w.vspMustBeOpen, w.requireReady, w.withDcrdClient(dcrd), w.vspAuth, w.feeTxTemplate)
+	api.POST("/payfee", w.vspMustBeOpen, w.requireReady, w.notInMaintenance, w.withDcrdClient(dcrd), w.vspAuth, w.payFee)
+	api.POST("/verifysignature", w.verifySignature)
+	api.POST("/broadcastfee", w.requireReady, w.notInMaintenance, w.withDcrdClient(dcrd), w.vspAuth, w.broadcastFee)
+	api.POST("/setvotechoices", w.requireReady, w.notInMaintenance, w.withDcrdClient(dcrd), w.withWalletClients(wallets), w.allowVotingKeyAuth, w.vspAuth, w.setVoteChoices)
+	if w.cfg.OpenAPISpec {
+		api.GET("/"+openAPIRoute, w.openAPI)
+	}
 
 	// Website routes.
 
 	router.GET("", w.requireWebCache, w.homepage)
 
+	if w.cfg.StatusPage {
+		router.GET("/status", w.requireWebCache, w.statusPage)
+	}
+
 	login := router.Group("/admin").Use(
+		w.requireClientCert,
 		w.withSession(cookieStore),
 	)
 
 	// Limit login attempts to 3 per second.
 	loginRateLmiter := rateLimit(3, func(c *gin.Context) {
+		log := w.requestLog(c)
+
 		cacheData := c.MustGet(cacheKey).(cacheData)
 
-		w.log.Warnf("Login rate limit exceeded by %s", c.ClientIP())
+		log.Warnf("Login rate limit exceeded by %s", c.ClientIP())
 		c.HTML(http.StatusTooManyRequests, "login.html", gin.H{
 			"WebApiCache":    cacheData,
-			"WebApiCfg":      w.cfg,
+			"WebApiCfg":      w.config(),
 			"FailedLoginMsg": "Rate limit exceeded",
 		})
 	})
 	login.POST("", w.requireWebCache, loginRateLmiter, w.adminLogin)
 
 	admin := router.Group("/admin").Use(
+		w.requireClientCert,
 		w.requireWebCache,
 		w.withWalletClients(wallets),
 		w.withSession(cookieStore),
@@ -276,16 +493,17 @@ func (w *WebAPI) router(cookieSecret []byte, dcrd rpc.DcrdConnect, wallets rpc.W
 
 	admin.GET("", w.withDcrdClient(dcrd), w.adminPage)
 	admin.POST("/ticket", w.withDcrdClient(dcrd), w.ticketSearch)
+	admin.POST("/ticket/notes", w.withDcrdClient(dcrd), w.ticketNotes)
+	admin.POST("/ticket/refund", w.withDcrdClient(dcrd), w.ticketRefund)
 	admin.GET("/backup", w.downloadDatabaseBackup)
 	admin.POST("/logout", w.adminLogout)
 
-	// Require Basic HTTP Auth on /admin/status endpoint.
+	// Require Basic HTTP Auth on the read-only admin endpoints.
 	basic := router.Group("/admin").Use(
-		w.withDcrdClient(dcrd), w.withWalletClients(wallets), gin.BasicAuth(gin.Accounts{
-			"admin": w.cfg.AdminPass,
-		}),
+		w.requireClientCert, gin.BasicAuth(w.readOnlyAccounts()),
 	)
-	basic.GET("/status", w.statusJSON)
+	basic.GET("/status", w.withDcrdClient(dcrd), w.withWalletClients(wallets), w.statusJSON)
+	basic.GET("/stats", w.requireWebCache, w.statsJSON)
 
 	return router
 }
@@ -294,9 +512,11 @@ func (w *WebAPI) router(cookieSecret []byte, dcrd rpc.DcrdConnect, wallets rpc.W
 // response to the client with a 200 OK status. Returns the seralized response
 // and the signature.
 func (w *WebAPI) sendJSONResponse(resp any, c *gin.Context) (string, string) {
+	log := w.requestLog(c)
+
 	dec, err := json.Marshal(resp)
 	if err != nil {
-		w.log.Errorf("JSON marshal error: %v", err)
+		log.Errorf("JSON marshal error: %v", err)
 		w.sendError(types.ErrInternalError, c)
 		return "", ""
 	}
@@ -310,6 +530,28 @@ func (w *WebAPI) sendJSONResponse(resp any, c *gin.Context) (string, string) {
 	return string(dec), sigStr
 }
 
+// atCapacity reports whether the number of voting tickets plus the number of
+// tickets which have reserved capacity, as of the most recent cache update, has
+// reached the configured maximum. New tickets are not accepted while the VSP is
+// at capacity.
+func (w *WebAPI) atCapacity() bool {
+	data := w.cache.getData()
+	return w.cfg.MaxActiveTickets > 0 &&
+		data.Voting+data.Reserved >= int64(w.cfg.MaxActiveTickets)
+}
+
+// feeDeadline returns the block height by which clients are asked to pay a fee,
+// which is the current height as of the most recent cache update plus the
+// configured deadline window. Zero is returned if no window is configured or
+// the current height is not yet known.
+func (w *WebAPI) feeDeadline() int64 {
+	data := w.cache.getData()
+	if w.cfg.FeeDeadlineBlocks == 0 || !data.Initialized {
+		return 0
+	}
+	return int64(data.BlockHeight) + w.cfg.FeeDeadlineBlocks
+}
+
 // sendError sends an error response with the provided error code and the
 // default message for that code.
 func (w *WebAPI) sendError(e types.ErrorCode, c *gin.Context) {
@@ -320,6 +562,19 @@ func (w *WebAPI) sendError(e types.ErrorCode, c *gin.Context) {
 // sendErrorWithMsg sends an error response with the provided error code and
 // message.
 func (w *WebAPI) sendErrorWithMsg(msg string, e types.ErrorCode, c *gin.Context) {
+	log := w.requestLog(c)
+
+	// Any error sent after the request deadline has been exceeded is reported
+	// as a timeout, regardless of the code chosen by the handler, because the
+	// failure was most likely caused by a backend call being cancelled. This
+	// lets the client know the VSP was too slow to respond.
+	if c.Request != nil && errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		log.Debugf("Request deadline exceeded, reporting error code %d as a timeout (clientIP=%s)",
+			e, c.ClientIP())
+		e = types.ErrRequestTimeout
+		msg = e.DefaultMessage()
+	}
+
 	status := e.HTTPStatus()
 
 	resp := types.ErrorResponse{
@@ -330,7 +585,7 @@ func (w *WebAPI) sendErrorWithMsg(msg string, e types.ErrorCode, c *gin.Context)
 	// Try to sign the error response. If it fails, send it without a signature.
 	dec, err := json.Marshal(resp)
 	if err != nil {
-		w.log.Warnf("Sending error response without signature: %v", err)
+		log.Warnf("Sending error response without signature: %v", err)
 	} else {
 		sig := ed25519.Sign(w.signPrivKey, dec)
 		c.Writer.Header().Set("VSP-Server-Signature", base64.StdEncoding.EncodeToString(sig))
@@ -1,4 +1,4 @@
-// Copyright (c) 2020-2022 The Decred developers
+// Copyright (c) 2020-2024 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
@@ -8,7 +8,9 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/decred/slog"
 	"github.com/jrick/wsrpc/v2"
@@ -37,9 +39,74 @@ type client struct {
 	authOpt  wsrpc.Option
 	notifier wsrpc.Notifier
 	log      slog.Logger
+
+	// slowCallThreshold is the duration after which a completed RPC is logged
+	// as slow. Zero disables logging of slow calls.
+	slowCallThreshold time.Duration
+
+	// allowedMethods is the set of RPC methods which may be called using this
+	// client. Calls to any other method fail without being sent.
+	allowedMethods map[string]struct{}
+}
+
+// methodSet returns a set containing the provided RPC method names.
+func methodSet(methods ...string) map[string]struct{} {
+	set := make(map[string]struct{}, len(methods))
+	for _, m := range methods {
+		set[m] = struct{}{}
+	}
+	return set
+}
+
+// allowedCaller wraps a Caller and refuses to perform any call to a method
+// which is not in the allowed set. This ensures vspd cannot be made to call RPC
+// methods which it has no need for.
+type allowedCaller struct {
+	Caller
+	allowed map[string]struct{}
+	log     slog.Logger
+}
+
+func (a *allowedCaller) Call(ctx context.Context, method string, res any, args ...any) error {
+	if _, ok := a.allowed[method]; !ok {
+		a.log.Errorf("Refusing to call disallowed RPC method (method=%s, host=%s)",
+			method, a.Caller.String())
+		return fmt.Errorf("rpc method %q is not allowed", method)
+	}
+	return a.Caller.Call(ctx, method, res, args...)
+}
+
+// timedCaller wraps a Caller and logs a warning for any call which takes longer
+// than threshold to complete.
+type timedCaller struct {
+	Caller
+	threshold time.Duration
+	log       slog.Logger
+}
+
+func (t *timedCaller) Call(ctx context.Context, method string, res any, args ...any) error {
+	start := time.Now()
+	err := t.Caller.Call(ctx, method, res, args...)
+	if elapsed := time.Since(start); elapsed > t.threshold {
+		t.log.Warnf("Slow RPC call (method=%s, duration=%v, host=%s)",
+			method, elapsed.Round(time.Millisecond), t.Caller.String())
+	}
+	return err
 }
 
-func setup(user, pass, addr string, cert []byte, log slog.Logger) *client {
+// contextCaller wraps a Caller and performs every call using ctx instead of the
+// context provided to Call, so that calls are abandoned once ctx is done.
+type contextCaller struct {
+	Caller
+	ctx context.Context
+}
+
+func (c *contextCaller) Call(_ context.Context, method string, res any, args ...any) error {
+	return c.Caller.Call(c.ctx, method, res, args...)
+}
+
+func setup(user, pass, addr string, cert []byte, slowCallThreshold time.Duration,
+	allowedMethods map[string]struct{}, log slog.Logger) *client {
 
 	// Create TLS options.
 	pool := x509.NewCertPool()
@@ -65,7 +132,7 @@ func setup(user, pass, addr string, cert []byte, log slog.Logger) *client {
 	var mu sync.Mutex
 	var c *wsrpc.Client
 	fullAddr := "wss://" + addr + "/ws"
-	return &client{&mu, c, fullAddr, tlsOpt, authOpt, nil, log}
+	return &client{&mu, c, fullAddr, tlsOpt, authOpt, nil, log, slowCallThreshold, allowedMethods}
 }
 
 func (c *client) Close() {
@@ -97,7 +164,7 @@ func (c *client) dial(ctx context.Context) (Caller, bool, error) {
 			c.log.Debugf("RPC client %s errored (%v); reconnecting...", c.addr, c.client.Err())
 			c.client = nil
 		default:
-			return c.client, false, nil
+			return c.caller(), false, nil
 		}
 	}
 
@@ -106,5 +173,15 @@ func (c *client) dial(ctx context.Context) (Caller, bool, error) {
 	if err != nil {
 		return nil, false, err
 	}
-	return c.client, true, nil
+	return c.caller(), true, nil
+}
+
+// caller returns the current wsrpc client as a Caller, wrapped to reject calls
+// to methods which are not allowed, and to log slow calls if enabled.
+func (c *client) caller() Caller {
+	var caller Caller = &allowedCaller{c.client, c.allowedMethods, c.log}
+	if c.slowCallThreshold == 0 {
+		return caller
+	}
+	return &timedCaller{caller, c.slowCallThreshold, c.log}
 }
@@ -0,0 +1,74 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/decred/slog"
+)
+
+type fakeCaller struct {
+	called []string
+}
+
+func (f *fakeCaller) String() string { return "fake" }
+
+func (f *fakeCaller) Call(_ context.Context, method string, _ any, _ ...any) error {
+	f.called = append(f.called, method)
+	return nil
+}
+
+func TestAllowedCaller(t *testing.T) {
+	fake := &fakeCaller{}
+	caller := &allowedCaller{fake, methodSet("getinfo"), slog.Disabled}
+
+	err := caller.Call(context.Background(), "getinfo", nil)
+	if err != nil {
+		t.Fatalf("unexpected error calling allowed method: %v", err)
+	}
+
+	err = caller.Call(context.Background(), "dumpprivkey", nil)
+	if err == nil {
+		t.Fatal("expected error calling disallowed method")
+	}
+
+	if len(fake.called) != 1 || fake.called[0] != "getinfo" {
+		t.Fatalf("expected only getinfo to be called, got %v", fake.called)
+	}
+}
+
+// TestMethodSetsComplete ensures every RPC method called by DcrdRPC,
+// WalletRPC and FeeWalletRPC is present in the corresponding allowlist.
+func TestMethodSetsComplete(t *testing.T) {
+	callRe := regexp.MustCompile(`\.Call\([^,]+, "([a-z0-9]+)"`)
+
+	tests := map[string]map[string]struct{}{
+		"dcrd.go":      dcrdMethods,
+		"dcrwallet.go": walletMethods,
+		"feewallet.go": feeWalletMethods,
+	}
+
+	for file, allowed := range tests {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("error reading %s: %v", file, err)
+		}
+
+		matches := callRe.FindAllSubmatch(src, -1)
+		if len(matches) == 0 {
+			t.Fatalf("found no RPC calls in %s", file)
+		}
+
+		for _, m := range matches {
+			if _, ok := allowed[string(m[1])]; !ok {
+				t.Errorf("%s calls %q which is not in its allowlist", file, m[1])
+			}
+		}
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2021-2023 The Decred developers
+// Copyright (c) 2021-2024 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
@@ -11,6 +11,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/decred/dcrd/blockchain/standalone/v2"
 	"github.com/decred/dcrd/chaincfg/chainhash"
@@ -38,6 +39,26 @@ const (
 	ErrUnknownOutputs = "references outputs of unknown or fully-spent transaction"
 )
 
+// dcrdMethods is the set of dcrd RPC methods which vspd is permitted to call.
+// It must be kept in sync with the methods used by DcrdRPC.
+var dcrdMethods = methodSet(
+	"decoderawtransaction",
+	"existslivetickets",
+	"getbestblockhash",
+	"getblock",
+	"getblockcount",
+	"getblockchaininfo",
+	"getblockhash",
+	"getblockheader",
+	"getcfilterv2",
+	"getcurrentnet",
+	"getinfo",
+	"getrawtransaction",
+	"notifyblocks",
+	"sendrawtransaction",
+	"version",
+)
+
 // DcrdRPC provides methods for calling dcrd JSON-RPCs without exposing the details
 // of JSON encoding.
 type DcrdRPC struct {
@@ -51,8 +72,8 @@ type DcrdConnect struct {
 }
 
 func SetupDcrd(user, pass, addr string, cert []byte, params *chaincfg.Params, log slog.Logger,
-	blockConnectedChan chan *wire.BlockHeader) DcrdConnect {
-	client := setup(user, pass, addr, cert, log)
+	blockConnectedChan chan *wire.BlockHeader, slowCallThreshold time.Duration) DcrdConnect {
+	client := setup(user, pass, addr, cert, slowCallThreshold, dcrdMethods, log)
 
 	client.notifier = &blockConnectedHandler{
 		blockConnected: blockConnectedChan,
@@ -144,6 +165,12 @@ func (d *DcrdConnect) Client() (*DcrdRPC, string, error) {
 	return &DcrdRPC{c}, d.client.addr, nil
 }
 
+// WithContext returns a copy of the client which performs every call using ctx,
+// so that all calls made on its behalf are abandoned once ctx is done.
+func (c *DcrdRPC) WithContext(ctx context.Context) *DcrdRPC {
+	return &DcrdRPC{&contextCaller{c.Caller, ctx}}
+}
+
 // GetRawTransaction uses getrawtransaction RPC to retrieve details about the
 // transaction with the provided hash.
 func (c *DcrdRPC) GetRawTransaction(txHash string) (*dcrdtypes.TxRawResult, error) {
@@ -168,10 +195,13 @@ func (c *DcrdRPC) DecodeRawTransaction(txHex string) (*dcrdtypes.TxRawDecodeResu
 }
 
 // SendRawTransaction uses sendrawtransaction RPC to broadcast a transaction to
-// the network. It ignores errors caused by duplicate transactions.
-func (c *DcrdRPC) SendRawTransaction(txHex string) error {
+// the network, and returns the hash of the transaction as reported by dcrd. It
+// ignores errors caused by duplicate transactions, in which case an empty hash
+// is returned because dcrd does not report one.
+func (c *DcrdRPC) SendRawTransaction(txHex string) (string, error) {
 	const allowHighFees = false
-	err := c.Call(context.TODO(), "sendrawtransaction", nil, txHex, allowHighFees)
+	var txHash string
+	err := c.Call(context.TODO(), "sendrawtransaction", &txHash, txHex, allowHighFees)
 	if err != nil {
 
 		// Ignore errors caused by the transaction already existing in the
@@ -181,7 +211,7 @@ func (c *DcrdRPC) SendRawTransaction(txHex string) error {
 		// indicates that dcrd definitely already has this transaction.
 		var e *wsrpc.Error
 		if errors.As(err, &e) && e.Code == ErrRPCDuplicateTx {
-			return nil
+			return "", nil
 		}
 
 		// Errors about orphan/spent outputs indicate that dcrd *might* already
@@ -189,13 +219,13 @@ func (c *DcrdRPC) SendRawTransaction(txHex string) error {
 		if strings.Contains(err.Error(), ErrUnknownOutputs) {
 			_, getErr := c.GetRawTransaction(txHex)
 			if getErr == nil {
-				return nil
+				return "", nil
 			}
 		}
 
-		return err
+		return "", err
 	}
-	return nil
+	return txHash, nil
 }
 
 // NotifyBlocks uses notifyblocks RPC to request new block notifications from dcrd.
@@ -219,6 +249,25 @@ func (c *DcrdRPC) GetBestBlockHeader() (*wire.BlockHeader, error) {
 	return blockHeader, nil
 }
 
+// GetBestBlockHeaderVerbose uses getbestblockhash RPC, followed by
+// getblockheader RPC with verbose=true, to retrieve details of the best block
+// known to the dcrd instance, including its median time.
+func (c *DcrdRPC) GetBestBlockHeaderVerbose() (*dcrdtypes.GetBlockHeaderVerboseResult, error) {
+	var bestBlockHash string
+	err := c.Call(context.TODO(), "getbestblockhash", &bestBlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	const verbose = true
+	var header dcrdtypes.GetBlockHeaderVerboseResult
+	err = c.Call(context.TODO(), "getblockheader", &header, bestBlockHash, verbose)
+	if err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
 // GetBlockHeader uses getblockheader RPC with verbose=false to retrieve
 // the header of the requested block.
 func (c *DcrdRPC) GetBlockHeader(blockHash string) (*wire.BlockHeader, error) {
@@ -297,6 +346,17 @@ func (c *DcrdRPC) GetBlockCount() (int64, error) {
 	return count, nil
 }
 
+// GetBlockchainInfo uses getblockchaininfo RPC to retrieve the state of the
+// chain, including whether dcrd is still performing its initial sync.
+func (c *DcrdRPC) GetBlockchainInfo() (*dcrdtypes.GetBlockChainInfoResult, error) {
+	var info dcrdtypes.GetBlockChainInfoResult
+	err := c.Call(context.TODO(), "getblockchaininfo", &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
 func (c *DcrdRPC) GetBlockHash(height int64) (string, error) {
 	var resp string
 	err := c.Call(context.TODO(), "getblockhash", &resp, height)
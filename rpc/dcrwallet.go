@@ -7,6 +7,7 @@ package rpc
 import (
 	"context"
 	"fmt"
+	"time"
 
 	wallettypes "decred.org/dcrwallet/v4/rpc/jsonrpc/types"
 	"github.com/decred/dcrd/chaincfg/v3"
@@ -19,6 +20,22 @@ var (
 	requiredWalletVersion = semver{Major: 9, Minor: 0, Patch: 0}
 )
 
+// walletMethods is the set of dcrwallet RPC methods which vspd is permitted to
+// call. It must be kept in sync with the methods used by WalletRPC.
+var walletMethods = methodSet(
+	"addtransaction",
+	"getblockcount",
+	"getcurrentnet",
+	"importprivkey",
+	"rescanwallet",
+	"settreasurypolicy",
+	"settspendpolicy",
+	"setvotechoice",
+	"ticketinfo",
+	"version",
+	"walletinfo",
+)
+
 // WalletRPC provides methods for calling dcrwallet JSON-RPCs without exposing the details
 // of JSON encoding.
 type WalletRPC struct {
@@ -29,20 +46,39 @@ type WalletConnect struct {
 	clients []*client
 	params  *chaincfg.Params
 	log     slog.Logger
+
+	// minVersion is the minimum dcrwallet application version which is
+	// accepted, or nil if any version is accepted.
+	minVersion *semver
 }
 
-func SetupWallet(user, pass, addrs []string, cert [][]byte, params *chaincfg.Params, log slog.Logger) WalletConnect {
+// SetupWallet creates clients for each of the provided dcrwallet instances. If
+// minVersion is not empty, wallets running a version of dcrwallet older than
+// minVersion, which must be of the form major.minor.patch, are not used.
+func SetupWallet(user, pass, addrs []string, cert [][]byte, params *chaincfg.Params, log slog.Logger,
+	slowCallThreshold time.Duration, minVersion string) (WalletConnect, error) {
+
+	var minVer *semver
+	if minVersion != "" {
+		ver, err := parseSemver(minVersion)
+		if err != nil {
+			return WalletConnect{}, fmt.Errorf("invalid minimum dcrwallet version: %w", err)
+		}
+		minVer = &ver
+	}
+
 	clients := make([]*client, len(addrs))
 
 	for i := 0; i < len(addrs); i++ {
-		clients[i] = setup(user[i], pass[i], addrs[i], cert[i], log)
+		clients[i] = setup(user[i], pass[i], addrs[i], cert[i], slowCallThreshold, walletMethods, log)
 	}
 
 	return WalletConnect{
-		clients: clients,
-		params:  params,
-		log:     log,
-	}
+		clients:    clients,
+		params:     params,
+		log:        log,
+		minVersion: minVer,
+	}, nil
 }
 
 func (w *WalletConnect) Close() {
@@ -52,6 +88,17 @@ func (w *WalletConnect) Close() {
 	w.log.Debug("dcrwallet clients closed")
 }
 
+// Addrs returns the addresses of all configured wallets, in the same form as
+// the String method of connected wallet clients and the failed connections
+// returned by Clients.
+func (w *WalletConnect) Addrs() []string {
+	addrs := make([]string, 0, len(w.clients))
+	for _, client := range w.clients {
+		addrs = append(addrs, client.addr)
+	}
+	return addrs
+}
+
 // Clients loops over each wallet and tries to establish a connection. It
 // increments a count of failed connections if a connection cannot be
 // established, or if the wallet is misconfigured.
@@ -104,6 +151,28 @@ func (w *WalletConnect) Clients() ([]*WalletRPC, []string) {
 			continue
 		}
 
+		// Verify dcrwallet is at least the minimum application version, if
+		// one is configured.
+		if w.minVersion != nil {
+			appVer, exists := verMap["dcrwallet"]
+			if !exists {
+				w.log.Errorf("dcrwallet.Version response missing 'dcrwallet' (wallet=%s)",
+					c.String())
+				failedConnections = append(failedConnections, connect.addr)
+				connect.Close()
+				continue
+			}
+
+			sAppVer := semver{appVer.Major, appVer.Minor, appVer.Patch}
+			if !semverAtLeast(*w.minVersion, sAppVer) {
+				w.log.Errorf("dcrwallet version is too old, wallet will not be used (wallet=%s): "+
+					"got %s, minimum %s", c.String(), sAppVer, *w.minVersion)
+				failedConnections = append(failedConnections, connect.addr)
+				connect.Close()
+				continue
+			}
+		}
+
 		// Verify dcrwallet is on the correct network.
 		var netID wire.CurrencyNet
 		err = c.Call(ctx, "getcurrentnet", &netID)
@@ -155,6 +224,12 @@ func (w *WalletConnect) Clients() ([]*WalletRPC, []string) {
 	return walletClients, failedConnections
 }
 
+// WithContext returns a copy of the client which performs every call using ctx,
+// so that all calls made on its behalf are abandoned once ctx is done.
+func (c *WalletRPC) WithContext(ctx context.Context) *WalletRPC {
+	return &WalletRPC{&contextCaller{c.Caller, ctx}}
+}
+
 // WalletInfo uses walletinfo RPC to retrieve information about how the
 // dcrwallet instance is configured.
 func (c *WalletRPC) WalletInfo() (*wallettypes.WalletInfoResult, error) {
@@ -0,0 +1,167 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	wallettypes "decred.org/dcrwallet/v4/rpc/jsonrpc/types"
+	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/dcrd/dcrutil/v4"
+	dcrdtypes "github.com/decred/dcrd/rpc/jsonrpc/types/v4"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/slog"
+)
+
+// feeWalletMethods is the set of dcrwallet RPC methods which vspd is permitted
+// to call on the fee wallet. It must be kept in sync with the methods used by
+// FeeWalletRPC.
+var feeWalletMethods = methodSet(
+	"getbalance",
+	"getcurrentnet",
+	"sendrawtransaction",
+	"signrawtransaction",
+	"sweepaccount",
+	"version",
+)
+
+// FeeWalletRPC provides methods for calling JSON-RPCs on the dcrwallet instance
+// which holds the private keys of the fee xpub. It is only used to sweep fees,
+// and is entirely separate from the voting wallets.
+type FeeWalletRPC struct {
+	Caller
+}
+
+type FeeWalletConnect struct {
+	client *client
+	params *chaincfg.Params
+	log    slog.Logger
+}
+
+func SetupFeeWallet(user, pass, addr string, cert []byte, params *chaincfg.Params,
+	log slog.Logger, slowCallThreshold time.Duration) FeeWalletConnect {
+	return FeeWalletConnect{
+		client: setup(user, pass, addr, cert, slowCallThreshold, feeWalletMethods, log),
+		params: params,
+		log:    log,
+	}
+}
+
+func (f *FeeWalletConnect) Close() {
+	f.client.Close()
+	f.log.Debug("Fee dcrwallet client closed")
+}
+
+// Client creates a new FeeWalletRPC client instance. Returns an error if
+// dialing the wallet fails or if the wallet is misconfigured.
+func (f *FeeWalletConnect) Client() (*FeeWalletRPC, error) {
+	ctx := context.TODO()
+	c, newConnection, err := f.client.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fee dcrwallet dial error: %w", err)
+	}
+
+	// If this is a reused connection, we don't need to validate the
+	// dcrwallet config again.
+	if !newConnection {
+		return &FeeWalletRPC{c}, nil
+	}
+
+	// Verify dcrwallet is at the required api version.
+	var verMap map[string]dcrdtypes.VersionResult
+	err = c.Call(ctx, "version", &verMap)
+	if err != nil {
+		f.client.Close()
+		return nil, fmt.Errorf("fee dcrwallet.Version error: %w", err)
+	}
+
+	ver, exists := verMap["dcrwalletjsonrpcapi"]
+	if !exists {
+		f.client.Close()
+		return nil, errors.New("fee dcrwallet.Version response missing 'dcrwalletjsonrpcapi'")
+	}
+
+	sVer := semver{ver.Major, ver.Minor, ver.Patch}
+	if !semverCompatible(requiredWalletVersion, sVer) {
+		f.client.Close()
+		return nil, fmt.Errorf("fee dcrwallet has incompatible JSON-RPC version: got %s, expected %s",
+			sVer, requiredWalletVersion)
+	}
+
+	// Verify dcrwallet is on the correct network.
+	var netID wire.CurrencyNet
+	err = c.Call(ctx, "getcurrentnet", &netID)
+	if err != nil {
+		f.client.Close()
+		return nil, fmt.Errorf("fee dcrwallet.GetCurrentNet error: %w", err)
+	}
+	if netID != f.params.Net {
+		f.client.Close()
+		return nil, fmt.Errorf("fee dcrwallet on wrong network: running on %s, expected %s",
+			netID, f.params.Net)
+	}
+
+	return &FeeWalletRPC{c}, nil
+}
+
+// SpendableBalance uses getbalance RPC to retrieve the balance of the given
+// account which is spendable with at least minConf confirmations.
+func (c *FeeWalletRPC) SpendableBalance(account string, minConf int32) (dcrutil.Amount, error) {
+	var result wallettypes.GetBalanceResult
+	err := c.Call(context.TODO(), "getbalance", &result, account, minConf)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, balance := range result.Balances {
+		if balance.AccountName == account {
+			return dcrutil.NewAmount(balance.Spendable)
+		}
+	}
+
+	return 0, fmt.Errorf("getbalance response missing account %q", account)
+}
+
+// SweepAccount uses sweepaccount RPC to create an unsigned transaction which
+// spends every output of the given account with at least minConf
+// confirmations to a single output paying destAddr. The transaction is
+// returned as a hex string.
+func (c *FeeWalletRPC) SweepAccount(account, destAddr string, minConf uint32) (string, error) {
+	var result wallettypes.SweepAccountResult
+	err := c.Call(context.TODO(), "sweepaccount", &result, account, destAddr, minConf)
+	if err != nil {
+		return "", err
+	}
+	return result.UnsignedTransaction, nil
+}
+
+// SignRawTransaction uses signrawtransaction RPC to sign every input of the
+// provided transaction. An error is returned if any input could not be signed.
+func (c *FeeWalletRPC) SignRawTransaction(txHex string) (string, error) {
+	var result wallettypes.SignRawTransactionResult
+	err := c.Call(context.TODO(), "signrawtransaction", &result, txHex)
+	if err != nil {
+		return "", err
+	}
+	if !result.Complete {
+		return "", fmt.Errorf("signrawtransaction failed to sign %d inputs", len(result.Errors))
+	}
+	return result.Hex, nil
+}
+
+// SendRawTransaction uses sendrawtransaction RPC to broadcast a signed
+// transaction. The hash of the transaction is returned.
+func (c *FeeWalletRPC) SendRawTransaction(txHex string) (string, error) {
+	const allowHighFees = false
+	var txHash string
+	err := c.Call(context.TODO(), "sendrawtransaction", &txHash, txHex, allowHighFees)
+	if err != nil {
+		return "", err
+	}
+	return txHash, nil
+}
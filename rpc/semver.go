@@ -1,10 +1,14 @@
-// Copyright (c) 2020 The Decred developers
+// Copyright (c) 2020-2024 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
 package rpc
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 type semver struct {
 	Major uint32
@@ -25,6 +29,46 @@ func semverCompatible(required, actual semver) bool {
 	}
 }
 
+// semverAtLeast reports whether actual is the same version as minVer or newer.
+// Unlike semverCompatible, newer major versions are accepted.
+func semverAtLeast(minVer, actual semver) bool {
+	switch {
+	case actual.Major != minVer.Major:
+		return actual.Major > minVer.Major
+	case actual.Minor != minVer.Minor:
+		return actual.Minor > minVer.Minor
+	default:
+		return actual.Patch >= minVer.Patch
+	}
+}
+
+// ValidateVersion returns an error if s is not a version string of the form
+// major.minor.patch, as required for the minimum version passed to SetupWallet.
+func ValidateVersion(s string) error {
+	_, err := parseSemver(s)
+	return err
+}
+
+// parseSemver parses a version string of the form major.minor.patch, for
+// example "2.0.1".
+func parseSemver(s string) (semver, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return semver{}, fmt.Errorf("version %q is not of the form major.minor.patch", s)
+	}
+
+	var nums [3]uint32
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return semver{}, fmt.Errorf("version %q is not of the form major.minor.patch", s)
+		}
+		nums[i] = uint32(n)
+	}
+
+	return semver{Major: nums[0], Minor: nums[1], Patch: nums[2]}, nil
+}
+
 func (s semver) String() string {
 	return fmt.Sprintf("%d.%d.%d", s.Major, s.Minor, s.Patch)
 }
@@ -0,0 +1,65 @@
+// Copyright (c) 2024 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"testing"
+)
+
+func TestParseSemver(t *testing.T) {
+	tests := map[string]struct {
+		version   string
+		expect    semver
+		expectErr bool
+	}{
+		"valid":           {version: "2.0.1", expect: semver{2, 0, 1}},
+		"large numbers":   {version: "10.20.30", expect: semver{10, 20, 30}},
+		"missing patch":   {version: "2.0", expectErr: true},
+		"too many parts":  {version: "2.0.1.4", expectErr: true},
+		"not a number":    {version: "2.x.1", expectErr: true},
+		"negative number": {version: "2.-1.1", expectErr: true},
+		"prerelease":      {version: "2.0.1-pre", expectErr: true},
+		"empty":           {version: "", expectErr: true},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual, err := parseSemver(test.version)
+			if (err != nil) != test.expectErr {
+				t.Fatalf("expected error=%t, got %v", test.expectErr, err)
+			}
+			if actual != test.expect {
+				t.Fatalf("expected %s, got %s", test.expect, actual)
+			}
+		})
+	}
+}
+
+func TestSemverAtLeast(t *testing.T) {
+	minVer := semver{2, 1, 3}
+
+	tests := map[string]struct {
+		actual semver
+		expect bool
+	}{
+		"same version":       {actual: semver{2, 1, 3}, expect: true},
+		"newer patch":        {actual: semver{2, 1, 4}, expect: true},
+		"newer minor":        {actual: semver{2, 2, 0}, expect: true},
+		"newer major":        {actual: semver{3, 0, 0}, expect: true},
+		"older patch":        {actual: semver{2, 1, 2}, expect: false},
+		"older minor":        {actual: semver{2, 0, 9}, expect: false},
+		"older major":        {actual: semver{1, 9, 9}, expect: false},
+		"older major higher": {actual: semver{1, 20, 20}, expect: false},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			actual := semverAtLeast(minVer, test.actual)
+			if actual != test.expect {
+				t.Fatalf("expected %t, got %t", test.expect, actual)
+			}
+		})
+	}
+}
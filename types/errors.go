@@ -1,4 +1,4 @@
-// Copyright (c) 2020-2022 The Decred developers
+// Copyright (c) 2020-2024 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
@@ -29,6 +29,20 @@ const (
 	ErrCannotBroadcastFee
 	ErrCannotBroadcastFeeUnknownOutputs
 	ErrInvalidTimestamp
+	ErrTooManyTickets
+	ErrMalformedPrivKey
+	ErrTicketPriceOutOfRange
+	ErrEndpointDisabled
+	ErrMissingSignature
+	ErrMalformedSignature
+	ErrTicketFeeLimitTooLow
+	ErrWrongNetwork
+	ErrTicketRejectedByPolicy
+	ErrRequestTimeout
+	ErrUnsupportedSignatureType
+	ErrVoteChangeTooSoon
+	ErrMaintenance
+	ErrNotReady
 )
 
 // HTTPStatus returns a corresponding HTTP status code for a given error code.
@@ -70,6 +84,34 @@ func (e ErrorCode) HTTPStatus() int {
 		return http.StatusPreconditionRequired
 	case ErrInvalidTimestamp:
 		return http.StatusBadRequest
+	case ErrTooManyTickets:
+		return http.StatusBadRequest
+	case ErrMalformedPrivKey:
+		return http.StatusBadRequest
+	case ErrTicketPriceOutOfRange:
+		return http.StatusBadRequest
+	case ErrEndpointDisabled:
+		return http.StatusServiceUnavailable
+	case ErrMissingSignature:
+		return http.StatusBadRequest
+	case ErrMalformedSignature:
+		return http.StatusBadRequest
+	case ErrTicketFeeLimitTooLow:
+		return http.StatusBadRequest
+	case ErrWrongNetwork:
+		return http.StatusBadRequest
+	case ErrTicketRejectedByPolicy:
+		return http.StatusBadRequest
+	case ErrRequestTimeout:
+		return http.StatusGatewayTimeout
+	case ErrUnsupportedSignatureType:
+		return http.StatusBadRequest
+	case ErrVoteChangeTooSoon:
+		return http.StatusTooManyRequests
+	case ErrMaintenance:
+		return http.StatusServiceUnavailable
+	case ErrNotReady:
+		return http.StatusServiceUnavailable
 	default:
 		return http.StatusInternalServerError
 	}
@@ -114,6 +156,34 @@ func (e ErrorCode) DefaultMessage() string {
 		return "fee transaction could not be broadcast due to unknown outputs"
 	case ErrInvalidTimestamp:
 		return "old or reused timestamp"
+	case ErrTooManyTickets:
+		return "too many tickets registered for commitment address"
+	case ErrMalformedPrivKey:
+		return "private key is not a valid WIF"
+	case ErrTicketPriceOutOfRange:
+		return "ticket price outside of range accepted by vsp"
+	case ErrEndpointDisabled:
+		return "endpoint disabled by vsp operator"
+	case ErrMissingSignature:
+		return "no request signature header"
+	case ErrMalformedSignature:
+		return "request signature header is not a valid signature"
+	case ErrTicketFeeLimitTooLow:
+		return "ticket fee limit is lower than vsp fee"
+	case ErrWrongNetwork:
+		return "request is for a different network than the vsp"
+	case ErrTicketRejectedByPolicy:
+		return "ticket rejected by vsp policy"
+	case ErrRequestTimeout:
+		return "vsp did not complete the request in time"
+	case ErrUnsupportedSignatureType:
+		return "signing address type cannot be used to sign requests"
+	case ErrVoteChangeTooSoon:
+		return "vote choices were changed too recently"
+	case ErrMaintenance:
+		return "vsp is temporarily in maintenance mode"
+	case ErrNotReady:
+		return "vsp is still starting up"
 	default:
 		return "unknown error"
 	}
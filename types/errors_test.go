@@ -1,4 +1,4 @@
-// Copyright (c) 2022 The Decred developers
+// Copyright (c) 2022-2024 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
@@ -34,6 +34,20 @@ func TestErrorDefaultMessages(t *testing.T) {
 		{ErrCannotBroadcastFee, "fee transaction could not be broadcast"},
 		{ErrCannotBroadcastFeeUnknownOutputs, "fee transaction could not be broadcast due to unknown outputs"},
 		{ErrInvalidTimestamp, "old or reused timestamp"},
+		{ErrTooManyTickets, "too many tickets registered for commitment address"},
+		{ErrMalformedPrivKey, "private key is not a valid WIF"},
+		{ErrTicketPriceOutOfRange, "ticket price outside of range accepted by vsp"},
+		{ErrEndpointDisabled, "endpoint disabled by vsp operator"},
+		{ErrMissingSignature, "no request signature header"},
+		{ErrMalformedSignature, "request signature header is not a valid signature"},
+		{ErrTicketFeeLimitTooLow, "ticket fee limit is lower than vsp fee"},
+		{ErrWrongNetwork, "request is for a different network than the vsp"},
+		{ErrTicketRejectedByPolicy, "ticket rejected by vsp policy"},
+		{ErrRequestTimeout, "vsp did not complete the request in time"},
+		{ErrUnsupportedSignatureType, "signing address type cannot be used to sign requests"},
+		{ErrVoteChangeTooSoon, "vote choices were changed too recently"},
+		{ErrMaintenance, "vsp is temporarily in maintenance mode"},
+		{ErrNotReady, "vsp is still starting up"},
 		{ErrorCode(9999), "unknown error"},
 	}
 
@@ -71,6 +85,20 @@ func TestErrorHTTPStatus(t *testing.T) {
 		{ErrCannotBroadcastFee, http.StatusInternalServerError},
 		{ErrCannotBroadcastFeeUnknownOutputs, http.StatusPreconditionRequired},
 		{ErrInvalidTimestamp, http.StatusBadRequest},
+		{ErrTooManyTickets, http.StatusBadRequest},
+		{ErrMalformedPrivKey, http.StatusBadRequest},
+		{ErrTicketPriceOutOfRange, http.StatusBadRequest},
+		{ErrEndpointDisabled, http.StatusServiceUnavailable},
+		{ErrMissingSignature, http.StatusBadRequest},
+		{ErrMalformedSignature, http.StatusBadRequest},
+		{ErrTicketFeeLimitTooLow, http.StatusBadRequest},
+		{ErrWrongNetwork, http.StatusBadRequest},
+		{ErrTicketRejectedByPolicy, http.StatusBadRequest},
+		{ErrRequestTimeout, http.StatusGatewayTimeout},
+		{ErrUnsupportedSignatureType, http.StatusBadRequest},
+		{ErrVoteChangeTooSoon, http.StatusTooManyRequests},
+		{ErrMaintenance, http.StatusServiceUnavailable},
+		{ErrNotReady, http.StatusServiceUnavailable},
 		{ErrorCode(9999), http.StatusInternalServerError},
 	}
 
@@ -12,22 +12,91 @@ type ErrorResponse struct {
 func (e ErrorResponse) Error() string { return e.Message }
 
 type VspInfoResponse struct {
-	APIVersions         []int64 `json:"apiversions"`
-	Timestamp           int64   `json:"timestamp"`
-	PubKey              []byte  `json:"pubkey"`
-	FeePercentage       float64 `json:"feepercentage"`
-	VspClosed           bool    `json:"vspclosed"`
-	VspClosedMsg        string  `json:"vspclosedmsg"`
-	Network             string  `json:"network"`
-	VspdVersion         string  `json:"vspdversion"`
-	Voting              int64   `json:"voting"`
-	Voted               int64   `json:"voted"`
-	TotalVotingWallets  int64   `json:"totalvotingwallets"`
-	VotingWalletsOnline int64   `json:"votingwalletsonline"`
-	Expired             int64   `json:"expired"`
-	Missed              int64   `json:"missed"`
-	BlockHeight         uint32  `json:"blockheight"`
-	NetworkProportion   float32 `json:"estimatednetworkproportion"`
+	APIVersions           []int64 `json:"apiversions"`
+	Timestamp             int64   `json:"timestamp"`
+	PubKey                []byte  `json:"pubkey"`
+	FeePercentage         float64 `json:"feepercentage"`
+	PriorityFeePercentage float64 `json:"priorityfeepercentage"`
+	FeeConfirmations      int64   `json:"feeconfirmations"`
+	VspClosed             bool    `json:"vspclosed"`
+	VspClosedMsg          string  `json:"vspclosedmsg"`
+	Network               string  `json:"network"`
+	VspdVersion           string  `json:"vspdversion"`
+	BuildCommit           string  `json:"buildcommit"`
+	BuildDate             string  `json:"builddate"`
+	Voting                int64   `json:"voting"`
+	Voted                 int64   `json:"voted"`
+	TotalVotingWallets    int64   `json:"totalvotingwallets"`
+	VotingWalletsOnline   int64   `json:"votingwalletsonline"`
+	Expired               int64   `json:"expired"`
+	Missed                int64   `json:"missed"`
+	BlockHeight           uint32  `json:"blockheight"`
+	NetworkProportion     float32 `json:"estimatednetworkproportion"`
+	ExpiredProportion     float32 `json:"expiredproportion"`
+	MissedProportion      float32 `json:"missedproportion"`
+	StatsUpdated          int64   `json:"statsupdated"`
+	StatsStale            bool    `json:"statsstale,omitempty"`
+	Initializing          bool    `json:"initializing,omitempty"`
+	ValidUntil            int64   `json:"validuntil,omitempty"`
+	// APIVersionFees describes the fees charged to clients using each API
+	// version. It is omitted by VSPs which charge the same fees regardless of
+	// API version, in which case FeePercentage and PriorityFeePercentage apply
+	// to every version in APIVersions.
+	APIVersionFees []APIVersionFee `json:"apiversionfees,omitempty"`
+}
+
+// APIVersionFee contains the fees charged to clients using a single API version.
+type APIVersionFee struct {
+	APIVersion            int64   `json:"apiversion"`
+	FeePercentage         float64 `json:"feepercentage"`
+	PriorityFeePercentage float64 `json:"priorityfeepercentage"`
+}
+
+// FeesForVersion returns the fee percentages charged to clients using the
+// provided API version. The top level fees are returned if the VSP does not
+// advertise fees for individual API versions. False is returned if the VSP does
+// not support the API version.
+func (r VspInfoResponse) FeesForVersion(apiVersion int64) (APIVersionFee, bool) {
+	for _, fee := range r.APIVersionFees {
+		if fee.APIVersion == apiVersion {
+			return fee, true
+		}
+	}
+	if len(r.APIVersionFees) > 0 {
+		return APIVersionFee{}, false
+	}
+
+	for _, v := range r.APIVersions {
+		if v == apiVersion {
+			return APIVersionFee{
+				APIVersion:            apiVersion,
+				FeePercentage:         r.FeePercentage,
+				PriorityFeePercentage: r.PriorityFeePercentage,
+			}, true
+		}
+	}
+	return APIVersionFee{}, false
+}
+
+type StatusResponse struct {
+	Timestamp    int64  `json:"timestamp"`
+	Network      string `json:"network"`
+	BlockHeight  uint32 `json:"blockheight"`
+	Synced       bool   `json:"synced"`
+	Initializing bool   `json:"initializing,omitempty"`
+}
+
+type VoteTalliesResponse struct {
+	Timestamp   int64         `json:"timestamp"`
+	VoteVersion uint32        `json:"voteversion"`
+	Agendas     []AgendaTally `json:"agendas"`
+}
+
+// AgendaTally contains the number of tickets currently voting with the VSP
+// which have chosen each choice of a single agenda.
+type AgendaTally struct {
+	AgendaID string           `json:"agendaid"`
+	Choices  map[string]int64 `json:"choices"`
 }
 
 type FeeAddressRequest struct {
@@ -35,10 +104,44 @@ type FeeAddressRequest struct {
 	TicketHash string `json:"tickethash" binding:"required"`
 	TicketHex  string `json:"tickethex" binding:"required"`
 	ParentHex  string `json:"parenthex" binding:"required"`
+	Priority   bool   `json:"priority"`
+	Network    string `json:"network,omitempty"`
 }
 
 type FeeAddressResponse struct {
+	Timestamp      int64  `json:"timestamp"`
+	FeeAddress     string `json:"feeaddress"`
+	FeeAmount      int64  `json:"feeamount"`
+	Expiration     int64  `json:"expiration"`
+	DeadlineHeight int64  `json:"deadlineheight,omitempty"`
+	PaymentURI     string `json:"paymenturi"`
+	Request        []byte `json:"request"`
+}
+
+type FeeTxTemplateRequest struct {
+	Timestamp  int64         `json:"timestamp" binding:"required"`
+	TicketHash string        `json:"tickethash" binding:"required"`
+	Inputs     []FeeTxInput  `json:"inputs" binding:"required"`
+	Change     []FeeTxOutput `json:"change"`
+	Network    string        `json:"network,omitempty"`
+}
+
+// FeeTxInput identifies an output which is spent by a fee tx.
+type FeeTxInput struct {
+	TxHash string `json:"txhash" binding:"required"`
+	Index  uint32 `json:"index"`
+	Tree   int8   `json:"tree"`
+}
+
+// FeeTxOutput is a change output of a fee tx.
+type FeeTxOutput struct {
+	Address string `json:"address" binding:"required"`
+	Amount  int64  `json:"amount" binding:"required"`
+}
+
+type FeeTxTemplateResponse struct {
 	Timestamp  int64  `json:"timestamp"`
+	FeeTx      string `json:"feetx"`
 	FeeAddress string `json:"feeaddress"`
 	FeeAmount  int64  `json:"feeamount"`
 	Expiration int64  `json:"expiration"`
@@ -53,9 +156,35 @@ type PayFeeRequest struct {
 	VoteChoices    map[string]string `json:"votechoices" binding:"required"`
 	TSpendPolicy   map[string]string `json:"tspendpolicy" binding:"max=3"`
 	TreasuryPolicy map[string]string `json:"treasurypolicy" binding:"max=3"`
+	DeferBroadcast bool              `json:"deferbroadcast"`
+	Network        string            `json:"network,omitempty"`
 }
 
 type PayFeeResponse struct {
+	Timestamp  int64  `json:"timestamp"`
+	ValidUntil int64  `json:"validuntil,omitempty"`
+	Request    []byte `json:"request"`
+}
+
+type VerifySignatureRequest struct {
+	Response  string `json:"response" binding:"required"`
+	Signature string `json:"signature" binding:"required"`
+}
+
+type VerifySignatureResponse struct {
+	Timestamp int64  `json:"timestamp"`
+	Valid     bool   `json:"valid"`
+	PubKey    []byte `json:"pubkey"`
+	Request   []byte `json:"request"`
+}
+
+type BroadcastFeeRequest struct {
+	Timestamp  int64  `json:"timestamp" binding:"required"`
+	TicketHash string `json:"tickethash" binding:"required"`
+	Network    string `json:"network,omitempty"`
+}
+
+type BroadcastFeeResponse struct {
 	Timestamp int64  `json:"timestamp"`
 	Request   []byte `json:"request"`
 }
@@ -66,6 +195,7 @@ type SetVoteChoicesRequest struct {
 	VoteChoices    map[string]string `json:"votechoices" binding:"required"`
 	TSpendPolicy   map[string]string `json:"tspendpolicy" binding:"max=3"`
 	TreasuryPolicy map[string]string `json:"treasurypolicy" binding:"max=3"`
+	Network        string            `json:"network,omitempty"`
 }
 
 type SetVoteChoicesResponse struct {
@@ -75,18 +205,29 @@ type SetVoteChoicesResponse struct {
 
 type TicketStatusRequest struct {
 	TicketHash string `json:"tickethash" binding:"required"`
+	Network    string `json:"network,omitempty"`
 }
 
 type TicketStatusResponse struct {
-	Timestamp       int64             `json:"timestamp"`
-	TicketConfirmed bool              `json:"ticketconfirmed"`
-	FeeTxStatus     string            `json:"feetxstatus"`
-	FeeTxHash       string            `json:"feetxhash"`
-	AltSignAddress  string            `json:"altsignaddress"`
-	VoteChoices     map[string]string `json:"votechoices"`
-	TSpendPolicy    map[string]string `json:"tspendpolicy"`
-	TreasuryPolicy  map[string]string `json:"treasurypolicy"`
-	Request         []byte            `json:"request"`
+	Timestamp         int64             `json:"timestamp"`
+	TicketConfirmed   bool              `json:"ticketconfirmed"`
+	FeeTxStatus       string            `json:"feetxstatus"`
+	FeeTxHash         string            `json:"feetxhash"`
+	AltSignAddress    string            `json:"altsignaddress"`
+	FeeAddress        string            `json:"feeaddress,omitempty"`
+	FeeAmount         int64             `json:"feeamount,omitempty"`
+	FeeExpiration     int64             `json:"feeexpiration,omitempty"`
+	FeeDeadlineHeight int64             `json:"feedeadlineheight,omitempty"`
+	VoteChoices       map[string]string `json:"votechoices"`
+	TSpendPolicy      map[string]string `json:"tspendpolicy"`
+	TreasuryPolicy    map[string]string `json:"treasurypolicy"`
+	Outcome           string            `json:"outcome,omitempty"`
+	Revoked           bool              `json:"revoked"`
+	RevocationTxHash  string            `json:"revocationtxhash,omitempty"`
+	VspClosed         bool              `json:"vspclosed"`
+	VspClosedMsg      string            `json:"vspclosedmsg,omitempty"`
+	ValidUntil        int64             `json:"validuntil,omitempty"`
+	Request           []byte            `json:"request"`
 }
 
 type SetAltSignAddrRequest struct {
@@ -95,6 +236,7 @@ type SetAltSignAddrRequest struct {
 	TicketHex      string `json:"tickethex" binding:"required"`
 	ParentHex      string `json:"parenthex" binding:"required"`
 	AltSignAddress string `json:"altsignaddress" binding:"required"`
+	Network        string `json:"network,omitempty"`
 }
 
 type SetAltSignAddrResponse struct {
@@ -51,3 +51,62 @@ func TestAPIErrorAs(t *testing.T) {
 		})
 	}
 }
+
+// TestFeesForVersion ensures the fees for an API version are found in a
+// vspinfo response, both with and without per-version fees.
+func TestFeesForVersion(t *testing.T) {
+	withoutVersionFees := VspInfoResponse{
+		APIVersions:           []int64{3},
+		FeePercentage:         2,
+		PriorityFeePercentage: 4,
+	}
+	withVersionFees := VspInfoResponse{
+		APIVersions:           []int64{3, 4},
+		FeePercentage:         2,
+		PriorityFeePercentage: 4,
+		APIVersionFees: []APIVersionFee{
+			{APIVersion: 3, FeePercentage: 2, PriorityFeePercentage: 4},
+			{APIVersion: 4, FeePercentage: 1.5, PriorityFeePercentage: 3},
+		},
+	}
+
+	tests := map[string]struct {
+		info       VspInfoResponse
+		apiVersion int64
+		expectFee  APIVersionFee
+		expectOK   bool
+	}{
+		"top level fees": {
+			info:       withoutVersionFees,
+			apiVersion: 3,
+			expectFee:  APIVersionFee{APIVersion: 3, FeePercentage: 2, PriorityFeePercentage: 4},
+			expectOK:   true,
+		},
+		"unsupported version without version fees": {
+			info:       withoutVersionFees,
+			apiVersion: 4,
+		},
+		"version fees": {
+			info:       withVersionFees,
+			apiVersion: 4,
+			expectFee:  APIVersionFee{APIVersion: 4, FeePercentage: 1.5, PriorityFeePercentage: 3},
+			expectOK:   true,
+		},
+		"unsupported version with version fees": {
+			info:       withVersionFees,
+			apiVersion: 5,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(testName, func(t *testing.T) {
+			fee, ok := test.info.FeesForVersion(test.apiVersion)
+			if ok != test.expectOK {
+				t.Fatalf("expected ok %t, got %t", test.expectOK, ok)
+			}
+			if fee != test.expectFee {
+				t.Fatalf("expected fee %+v, got %+v", test.expectFee, fee)
+			}
+		})
+	}
+}